@@ -0,0 +1,27 @@
+#!/bin/sh
+
+mainFile="main.go"
+version="2.3.74"
+buildDate=$(date "+%Y-%m-%dT%H:%M:%S")
+targetName="cross_share_linux"
+cliName="crossshare"
+buildFolder="./build"
+
+ldflags="-X rtk-cross-share/client/buildConfig.Version=$version -X rtk-cross-share/client/buildConfig.BuildDate=$buildDate -X rtk-cross-share/client/buildConfig.Debug=0 -s -w"
+
+echo "Compile Start"
+cd "client/platform/linux"
+
+rm -rf $buildFolder
+mkdir -p $buildFolder
+
+export GOOS=linux
+export CGO_ENABLED=0
+for arch in amd64 arm64; do
+    echo "build $arch ..."
+    GOARCH=$arch go build -trimpath -ldflags "$ldflags" -o "$buildFolder/${targetName}_$arch" $mainFile
+    GOARCH=$arch go build -trimpath -ldflags "-s -w" -o "$buildFolder/${cliName}_$arch" ./$cliName
+done
+
+cd -
+echo "Compile Done"
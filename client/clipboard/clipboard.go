@@ -44,14 +44,16 @@ func ResetLastClipboardData() {
 	rtkPlatform.GoCleanClipboard()
 }
 
-func updateXClipHead(id string, nText, nImage, nHtml, nRtf int64) {
-	log.Printf("[%s] ID:[%s] Text:%d Image:%d Html:%d Rtf:%d", rtkMisc.GetFuncInfo(), id, nText, nImage, nHtml, nRtf)
+func updateXClipHead(id, originID string, clock uint64, nText, nImage, nHtml, nRtf int64, items []rtkCommon.XClipItem) {
+	log.Printf("[%s] ID:[%s] origin:[%s] clock:[%d] Text:%d Image:%d Html:%d Rtf:%d items:%v", rtkMisc.GetFuncInfo(), id, originID, clock, nText, nImage, nHtml, nRtf, getXClipItemMimeTypes(items))
 
 	clipboardData := rtkCommon.ClipBoardData{
 		SourceID:  id,
 		FmtType:   rtkCommon.XCLIP_CB,
 		Hash:      "",
 		TimeStamp: uint64(time.Now().Unix()),
+		OriginID:  originID,
+		Clock:     clock,
 		ExtData: rtkCommon.ExtDataXClip{
 			Text:     nil,
 			Image:    nil,
@@ -61,15 +63,23 @@ func updateXClipHead(id string, nText, nImage, nHtml, nRtf int64) {
 			ImageLen: nImage,
 			HtmlLen:  nHtml,
 			RtfLen:   nRtf,
+			Items:    GetXClipItemHeads(items),
 		},
 	}
 	updateLastClipboardData(clipboardData)
 }
 
-func updateXClipData(id string, cbText, cbImage, cbHtml, cbRtf []byte) {
-	log.Printf("[%s] ID:[%s] Text:%d Image:%d Html:%d Rtf:%d", rtkMisc.GetFuncInfo(), id, len(cbText), len(cbImage), len(cbHtml), len(cbRtf))
-	hash, _ := rtkUtils.CreateMD5Hash(cbText, cbImage, cbHtml, cbRtf)
-	clipboardData := rtkCommon.ClipBoardData{
+func newXClipData(id string, cbText, cbImage, cbHtml, cbRtf []byte, items []rtkCommon.XClipItem) rtkCommon.ClipBoardData {
+	chunks := [][]byte{cbText, cbImage, cbHtml, cbRtf}
+	for _, item := range items {
+		chunks = append(chunks, []byte(item.MimeType), item.Data)
+	}
+	hash, _ := rtkUtils.CreateMD5Hash(chunks...)
+	imageFormat, imageWidth, imageHeight := "", 0, 0
+	if len(cbImage) > 0 {
+		imageFormat, imageWidth, imageHeight = rtkUtils.GetByteImageInfo(cbImage)
+	}
+	return rtkCommon.ClipBoardData{
 		SourceID:  id,
 		FmtType:   rtkCommon.XCLIP_CB,
 		Hash:      hash.B58String(),
@@ -83,8 +93,21 @@ func updateXClipData(id string, cbText, cbImage, cbHtml, cbRtf []byte) {
 			ImageLen: int64(len(cbImage)),
 			HtmlLen:  int64(len(cbHtml)),
 			RtfLen:   int64(len(cbRtf)),
+			Items:    items,
+
+			ImageFormat: imageFormat,
+			ImageWidth:  int32(imageWidth),
+			ImageHeight: int32(imageHeight),
 		},
 	}
+}
+
+func updateXClipData(clipboardData rtkCommon.ClipBoardData, version xClipVersion) rtkCommon.ClipBoardData {
+	id := clipboardData.SourceID
+	extData, _ := clipboardData.ExtData.(rtkCommon.ExtDataXClip)
+	log.Printf("[%s] ID:[%s] origin:[%s] clock:[%d] Text:%d Image:%d Html:%d Rtf:%d", rtkMisc.GetFuncInfo(), id, version.OriginID, version.Clock, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen)
+	clipboardData.OriginID = version.OriginID
+	clipboardData.Clock = version.Clock
 
 	if rtkUtils.ContentEqual([]byte(rtkMisc.PlatformAndroid), []byte(rtkGlobal.NodeInfo.Platform)) &&
 		rtkUtils.ContentEqual([]byte(id), []byte(rtkGlobal.NodeInfo.ID)) &&
@@ -93,6 +116,7 @@ func updateXClipData(id string, cbText, cbImage, cbHtml, cbRtf []byte) {
 	}
 
 	updateLastClipboardData(clipboardData)
+	return clipboardData
 }
 
 func init() {
@@ -100,7 +124,24 @@ func init() {
 }
 
 func updateClipboardFromPlatform(cbText, cbImage, cbHtml, cbRtf []byte) {
-	updateXClipData(rtkGlobal.NodeInfo.ID, cbText, cbImage, cbHtml, cbRtf)
+	updateClipboardItemsFromPlatform(cbText, cbImage, cbHtml, cbRtf, nil)
+}
+
+func updateClipboardItemsFromPlatform(cbText, cbImage, cbHtml, cbRtf []byte, items []rtkCommon.XClipItem) {
+	clipboardData := newXClipData(rtkGlobal.NodeInfo.ID, cbText, cbImage, cbHtml, cbRtf, items)
+	if isXClipEcho(clipboardData) {
+		log.Printf("[%s] hash:[%s] is the echo of data pasted from ID:[%s], skip it", rtkMisc.GetFuncInfo(), clipboardData.Hash, GetLastClipboardData().SourceID)
+		isNextCopyConcealed.Store(false)
+		return
+	}
+	clipboardData = updateXClipData(clipboardData, newLocalXClipVersion())
+	if isNextCopyConcealed.Swap(false) {
+		log.Printf("[%s] hash:[%s] is concealed by platform, not kept in history", rtkMisc.GetFuncInfo(), clipboardData.Hash)
+		setLastConcealedHash(clipboardData.Hash)
+	} else {
+		setLastConcealedHash("")
+		addClipboardHistory(clipboardData)
+	}
 
 	nCount := rtkUtils.GetClientCount()
 	for i := 0; i < nCount; i++ {
@@ -124,12 +165,19 @@ func WatchXClipData(ctx context.Context, id string, resultChan chan<- rtkCommon.
 				currentTimeStamp := lastData.TimeStamp
 
 				if !rtkUtils.ContentEqual([]byte(lastHash), []byte(currentHash)) || lastTimeStamp != currentTimeStamp {
-					if extData, ok := lastData.ExtData.(rtkCommon.ExtDataXClip); ok {
-						ipAddr, _ := rtkUtils.GetClientIp(id)
-						log.Printf("[WatchXClipData][%s] - got new data text:%d, image:%d, html:%d, rtf:%d", ipAddr, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen)
+					if _, ok := lastData.ExtData.(rtkCommon.ExtDataXClip); ok {
 						lastHash = currentHash
 						lastTimeStamp = currentTimeStamp
-						resultChan <- lastData
+						peerData := transcodeImageForPeer(id, filterXClipItemsForPeer(id, lastData))
+						cbData, reason := applyClipboardPolicy(clipboardPolicySrc, id, peerData)
+						logClipboardPolicyDecision(clipboardPolicySrc, id, peerData, cbData, reason)
+						if reason != "" {
+							continue
+						}
+						extData := cbData.ExtData.(rtkCommon.ExtDataXClip)
+						ipAddr, _ := rtkUtils.GetClientIp(id)
+						log.Printf("[WatchXClipData][%s] - got new data text:%d, image:%d, html:%d, rtf:%d, items:%d", ipAddr, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen, len(extData.Items))
+						resultChan <- cbData
 					} else {
 						log.Printf("[%s %d] Err: Invalid text extData", rtkMisc.GetFuncName(), rtkMisc.GetLine())
 					}
@@ -144,11 +192,32 @@ func SetupDstPasteFile(desc, fileName, platform string, fileSizeHigh uint32, fil
 	rtkPlatform.GoSetupDstPasteFile(desc, fileName, platform, fileSizeHigh, fileSizeLow)
 }
 
-func SetupDstPasteXClipHead(id string, nText, nImage, nHtml, nRtf int64) {
-	updateXClipHead(id, nText, nImage, nHtml, nRtf)
+func SetupDstPasteXClipHead(id, originID string, clock uint64, nText, nImage, nHtml, nRtf int64, items []rtkCommon.XClipItem) {
+	updateXClipHead(id, originID, clock, nText, nImage, nHtml, nRtf, items)
 }
 
-func SetupDstPasteXClipData(id string, text, image, html, rtf []byte) {
-	updateXClipData(id, text, image, html, rtf)
-	rtkPlatform.GoSetupDstPasteXClipData(text, image, html, rtf)
+// SetupDstPasteXClipData pastes the data from peer ID, empty originID means the data from old version peer or local history
+// The items are got by platform with GetLastXClipItems after the paste XClip callback.
+func SetupDstPasteXClipData(id, originID string, clock uint64, text, image, html, rtf []byte, items []rtkCommon.XClipItem) {
+	clipboardData := newXClipData(id, text, image, html, rtf, items)
+	if id != rtkGlobal.NodeInfo.ID {
+		cbData, reason := applyClipboardPolicy(clipboardPolicyDst, id, clipboardData)
+		logClipboardPolicyDecision(clipboardPolicyDst, id, clipboardData, cbData, reason)
+		if reason != "" {
+			return
+		}
+		extData := cbData.ExtData.(rtkCommon.ExtDataXClip)
+		if len(extData.Text) != len(text) || len(extData.Image) != len(image) || len(extData.Html) != len(html) || len(extData.Rtf) != len(rtf) || len(extData.Items) != len(items) {
+			text, image, html, rtf, items = extData.Text, extData.Image, extData.Html, extData.Rtf, extData.Items
+			clipboardData = newXClipData(id, text, image, html, rtf, items)
+		}
+	}
+
+	version, ok := acceptXClipVersion(id, originID, clock)
+	if !ok {
+		return
+	}
+	clipboardData = updateXClipData(clipboardData, version)
+	addClipboardHistory(clipboardData)
+	rtkPlatform.GoSetupDstPasteXClipData(id, text, image, html, rtf)
 }
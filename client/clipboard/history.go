@@ -0,0 +1,317 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+// Clipboard history keeps the latest XClip data from local and peers, the newest is the first.
+// The same data (by hash) is moved to the first instead of added again.
+// The data larger than clipboardHistorySpillSize is saved in GetClipboardHistoryPath folder, and it is read when used.
+const (
+	clipboardHistoryDefaultMaxCount = 20
+	clipboardHistorySpillSize       = 1 << 20 // 1MB
+)
+
+type ClipboardHistoryItem struct {
+	Index       uint64 // unique in this process, used by platform to fetch the item
+	SourceID    string
+	TimeStamp   int64 // unix milli
+	Hash        string
+	Formats     []string
+	TextLen     int64
+	ImageLen    int64
+	HtmlLen     int64
+	RtfLen      int64
+	IsSpilled   bool
+	ImageFormat string
+	ImageWidth  int32
+	ImageHeight int32
+	Items       []rtkCommon.XClipItem // heads of items, their MIME types are in Formats too
+}
+
+// ClipboardHistoryData is the data of one item for platform, Image is base64 in JSON
+type ClipboardHistoryData struct {
+	Text  string
+	Image []byte
+	Html  string
+	Rtf   string
+	Items []ClipboardItem
+}
+
+type clipboardHistoryEntry struct {
+	ClipboardHistoryItem
+	data rtkCommon.ExtDataXClip // empty if IsSpilled
+}
+
+var (
+	clipboardHistoryList     = make([]clipboardHistoryEntry, 0)
+	clipboardHistoryMaxCount = clipboardHistoryDefaultMaxCount
+	clipboardHistoryIndex    = uint64(0)
+	isClipboardHistoryDirSet = false
+	clipboardHistoryMutex    sync.Mutex
+)
+
+func getClipboardHistorySpillFile(index uint64) string {
+	return filepath.Join(rtkPlatform.GetClipboardHistoryPath(), fmt.Sprintf("%d.dat", index))
+}
+
+// spillClipboardHistoryData must be called with clipboardHistoryMutex locked
+func spillClipboardHistoryData(index uint64, extData *rtkCommon.ExtDataXClip) error {
+	dir := rtkPlatform.GetClipboardHistoryPath()
+	if dir == "" {
+		return fmt.Errorf("clipboard history path is empty")
+	}
+	if !isClipboardHistoryDirSet {
+		os.RemoveAll(dir) // the history is not kept after restart
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		isClipboardHistoryDirSet = true
+	}
+
+	file, err := os.Create(getClipboardHistorySpillFile(index))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	dataList := [][]byte{extData.Text, extData.Image, extData.Html, extData.Rtf}
+	for _, item := range extData.Items {
+		dataList = append(dataList, item.Data)
+	}
+	for _, data := range dataList {
+		if _, err = file.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadClipboardHistoryData(entry *clipboardHistoryEntry) (rtkCommon.ExtDataXClip, error) {
+	if !entry.IsSpilled {
+		return entry.data, nil
+	}
+
+	data, err := os.ReadFile(getClipboardHistorySpillFile(entry.Index))
+	if err != nil {
+		return rtkCommon.ExtDataXClip{}, err
+	}
+	if int64(len(data)) != entry.TextLen+entry.ImageLen+entry.HtmlLen+entry.RtfLen+getXClipItemsLen(entry.Items) {
+		return rtkCommon.ExtDataXClip{}, fmt.Errorf("invalid spill file size:[%d]", len(data))
+	}
+	nText, nImage, nHtml := entry.TextLen, entry.TextLen+entry.ImageLen, entry.TextLen+entry.ImageLen+entry.HtmlLen
+	nRtf := nHtml + entry.RtfLen
+	return rtkCommon.ExtDataXClip{
+		Text:     data[:nText],
+		Image:    data[nText:nImage],
+		Html:     data[nImage:nHtml],
+		Rtf:      data[nHtml:nRtf],
+		Items:    SplitXClipItemsData(entry.Items, data[nRtf:]),
+		TextLen:  entry.TextLen,
+		ImageLen: entry.ImageLen,
+		HtmlLen:  entry.HtmlLen,
+		RtfLen:   entry.RtfLen,
+
+		ImageFormat: entry.ImageFormat,
+		ImageWidth:  entry.ImageWidth,
+		ImageHeight: entry.ImageHeight,
+	}, nil
+}
+
+// removeClipboardHistoryEntry must be called with clipboardHistoryMutex locked
+func removeClipboardHistoryEntry(i int) {
+	if clipboardHistoryList[i].IsSpilled {
+		os.Remove(getClipboardHistorySpillFile(clipboardHistoryList[i].Index))
+	}
+	clipboardHistoryList = append(clipboardHistoryList[:i], clipboardHistoryList[i+1:]...)
+}
+
+func findClipboardHistoryEntry(index uint64) int {
+	for i := range clipboardHistoryList {
+		if clipboardHistoryList[i].Index == index {
+			return i
+		}
+	}
+	return -1
+}
+
+func addClipboardHistory(cbData rtkCommon.ClipBoardData) {
+	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok {
+		return
+	}
+	nTotalLen := extData.TextLen + extData.ImageLen + extData.HtmlLen + extData.RtfLen + getXClipItemsLen(extData.Items)
+	if nTotalLen == 0 || cbData.Hash == "" {
+		return
+	}
+
+	clipboardHistoryMutex.Lock()
+	defer clipboardHistoryMutex.Unlock()
+	if clipboardHistoryMaxCount <= 0 {
+		return
+	}
+
+	for i := range clipboardHistoryList {
+		if clipboardHistoryList[i].Hash == cbData.Hash {
+			entry := clipboardHistoryList[i]
+			entry.SourceID = cbData.SourceID
+			entry.TimeStamp = time.Now().UnixMilli()
+			clipboardHistoryList = append(clipboardHistoryList[:i], clipboardHistoryList[i+1:]...)
+			clipboardHistoryList = append([]clipboardHistoryEntry{entry}, clipboardHistoryList...)
+			return
+		}
+	}
+
+	clipboardHistoryIndex++
+	entry := clipboardHistoryEntry{
+		ClipboardHistoryItem: ClipboardHistoryItem{
+			Index:       clipboardHistoryIndex,
+			SourceID:    cbData.SourceID,
+			TimeStamp:   time.Now().UnixMilli(),
+			Hash:        cbData.Hash,
+			Formats:     make([]string, 0),
+			TextLen:     extData.TextLen,
+			ImageLen:    extData.ImageLen,
+			HtmlLen:     extData.HtmlLen,
+			RtfLen:      extData.RtfLen,
+			ImageFormat: extData.ImageFormat,
+			ImageWidth:  extData.ImageWidth,
+			ImageHeight: extData.ImageHeight,
+			Items:       GetXClipItemHeads(extData.Items),
+		},
+		data: extData,
+	}
+	for _, format := range []struct {
+		name string
+		size int64
+	}{{"text", extData.TextLen}, {"image", extData.ImageLen}, {"html", extData.HtmlLen}, {"rtf", extData.RtfLen}} {
+		if format.size > 0 {
+			entry.Formats = append(entry.Formats, format.name)
+		}
+	}
+	entry.Formats = append(entry.Formats, getXClipItemMimeTypes(extData.Items)...)
+
+	if nTotalLen > clipboardHistorySpillSize {
+		if err := spillClipboardHistoryData(entry.Index, &extData); err != nil {
+			log.Printf("[%s] spill clipboard history index:[%d] size:[%d] err:%+v, keep it in memory", rtkMisc.GetFuncInfo(), entry.Index, nTotalLen, err)
+		} else {
+			entry.IsSpilled = true
+			entry.data = rtkCommon.ExtDataXClip{}
+		}
+	}
+
+	clipboardHistoryList = append([]clipboardHistoryEntry{entry}, clipboardHistoryList...)
+	for len(clipboardHistoryList) > clipboardHistoryMaxCount {
+		removeClipboardHistoryEntry(len(clipboardHistoryList) - 1)
+	}
+}
+
+// SetClipboardHistoryMaxCount sets the max count of history, 0 disables the history and clears it
+func SetClipboardHistoryMaxCount(count int) {
+	clipboardHistoryMutex.Lock()
+	defer clipboardHistoryMutex.Unlock()
+	clipboardHistoryMaxCount = max(count, 0)
+	for len(clipboardHistoryList) > clipboardHistoryMaxCount {
+		removeClipboardHistoryEntry(len(clipboardHistoryList) - 1)
+	}
+	log.Printf("[%s] clipboard history max count:[%d]", rtkMisc.GetFuncInfo(), clipboardHistoryMaxCount)
+}
+
+// GetClipboardHistoryList returns JSON of []ClipboardHistoryItem, the newest is the first
+func GetClipboardHistoryList() string {
+	clipboardHistoryMutex.Lock()
+	itemList := make([]ClipboardHistoryItem, 0, len(clipboardHistoryList))
+	for _, entry := range clipboardHistoryList {
+		itemList = append(itemList, entry.ClipboardHistoryItem)
+	}
+	clipboardHistoryMutex.Unlock()
+
+	encodedData, err := json.Marshal(itemList)
+	if err != nil {
+		log.Printf("[%s] Marshal clipboard history list err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+func getClipboardHistoryData(index uint64) (ClipboardHistoryItem, rtkCommon.ExtDataXClip, bool) {
+	clipboardHistoryMutex.Lock()
+	defer clipboardHistoryMutex.Unlock()
+	i := findClipboardHistoryEntry(index)
+	if i < 0 {
+		log.Printf("[%s] clipboard history index:[%d] is not found", rtkMisc.GetFuncInfo(), index)
+		return ClipboardHistoryItem{}, rtkCommon.ExtDataXClip{}, false
+	}
+
+	extData, err := loadClipboardHistoryData(&clipboardHistoryList[i])
+	if err != nil {
+		log.Printf("[%s] load clipboard history index:[%d] err:%+v, remove it", rtkMisc.GetFuncInfo(), index, err)
+		removeClipboardHistoryEntry(i)
+		return ClipboardHistoryItem{}, rtkCommon.ExtDataXClip{}, false
+	}
+	return clipboardHistoryList[i].ClipboardHistoryItem, extData, true
+}
+
+// GetClipboardHistoryData returns JSON of ClipboardHistoryData, empty if index is not found
+func GetClipboardHistoryData(index uint64) string {
+	_, extData, ok := getClipboardHistoryData(index)
+	if !ok {
+		return ""
+	}
+
+	cbItems := make([]ClipboardItem, 0, len(extData.Items))
+	for _, item := range extData.Items {
+		cbItems = append(cbItems, ClipboardItem{MimeType: item.MimeType, Data: item.Data})
+	}
+	encodedData, err := json.Marshal(ClipboardHistoryData{
+		Text:  string(extData.Text),
+		Image: extData.Image,
+		Html:  string(extData.Html),
+		Rtf:   string(extData.Rtf),
+		Items: cbItems,
+	})
+	if err != nil {
+		log.Printf("[%s] Marshal clipboard history index:[%d] err:%+v", rtkMisc.GetFuncInfo(), index, err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+// ApplyClipboardHistory pastes the history item to local clipboard as it is received from its source again
+func ApplyClipboardHistory(index uint64) bool {
+	item, extData, ok := getClipboardHistoryData(index)
+	if !ok {
+		return false
+	}
+	log.Printf("[%s] apply clipboard history index:[%d] from ID:[%s]", rtkMisc.GetFuncInfo(), index, item.SourceID)
+	SetupDstPasteXClipData(item.SourceID, "", 0, extData.Text, extData.Image, extData.Html, extData.Rtf, extData.Items)
+	return true
+}
+
+func DeleteClipboardHistory(index uint64) bool {
+	clipboardHistoryMutex.Lock()
+	defer clipboardHistoryMutex.Unlock()
+	i := findClipboardHistoryEntry(index)
+	if i < 0 {
+		return false
+	}
+	removeClipboardHistoryEntry(i)
+	return true
+}
+
+func ClearClipboardHistory() {
+	clipboardHistoryMutex.Lock()
+	defer clipboardHistoryMutex.Unlock()
+	for len(clipboardHistoryList) > 0 {
+		removeClipboardHistoryEntry(len(clipboardHistoryList) - 1)
+	}
+	log.Printf("[%s] clear clipboard history success", rtkMisc.GetFuncInfo())
+}
@@ -0,0 +1,152 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"log"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Clipboard image pipeline: the image of local copy is kept in its format (png, jpeg or bmp),
+// and it is converted for every peer to the source format if peer accepts it, otherwise to the first format peer prefers.
+// Every format is converted once for the last data, and downscaled if it is over the pixel or byte budget.
+var (
+	clipboardImageMaxPixels int64 // 0 means no limit
+	clipboardImageMaxBytes  int64 // 0 means no limit
+	imageCacheHash          string
+	imageCacheMap           = make(map[string]rtkCommon.ExtDataXClip) // key: image format, only image fields are set
+	clipboardImageMutex     sync.Mutex
+)
+
+type ClipboardImageInfo struct {
+	Format string
+	Width  int32
+	Height int32
+	Size   int64
+}
+
+// SetClipboardImageBudget sets the max pixels and bytes of image sent to peers, <= 0 means no limit
+func SetClipboardImageBudget(maxPixels, maxBytes int64) {
+	clipboardImageMutex.Lock()
+	defer clipboardImageMutex.Unlock()
+	clipboardImageMaxPixels = max(maxPixels, 0)
+	clipboardImageMaxBytes = max(maxBytes, 0)
+	imageCacheHash = ""
+	clear(imageCacheMap)
+	log.Printf("[%s] clipboard image max pixels:[%d] max bytes:[%d]", rtkMisc.GetFuncInfo(), clipboardImageMaxPixels, clipboardImageMaxBytes)
+}
+
+func isSupportImageFormat(format string) bool {
+	switch format {
+	case rtkCommon.ImageFormatPng, rtkCommon.ImageFormatJpeg, rtkCommon.ImageFormatBmp:
+		return true
+	}
+	return false
+}
+
+// SetClipboardImageFormats sets the image formats the native clipboard of platform accepts in preference order, in JSON of []string.
+// It is advertised to peers when they connect, so it should be set before the client is started
+func SetClipboardImageFormats(formatsJson string) bool {
+	var formatList []string
+	if err := json.Unmarshal([]byte(formatsJson), &formatList); err != nil {
+		log.Printf("[%s] Unmarshal image formats:[%s] err:%+v", rtkMisc.GetFuncInfo(), formatsJson, err)
+		return false
+	}
+	imageFormats := make([]string, 0, len(formatList))
+	for _, format := range formatList {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if !isSupportImageFormat(format) {
+			log.Printf("[%s] image format:[%s] is not supported", rtkMisc.GetFuncInfo(), format)
+			return false
+		}
+		if !slices.Contains(imageFormats, format) {
+			imageFormats = append(imageFormats, format)
+		}
+	}
+	if len(imageFormats) == 0 {
+		log.Printf("[%s] image formats is empty", rtkMisc.GetFuncInfo())
+		return false
+	}
+	rtkGlobal.ClientImageFormats = imageFormats
+	log.Printf("[%s] clipboard image formats:%v", rtkMisc.GetFuncInfo(), imageFormats)
+	return true
+}
+
+// getPeerImageFormat returns the image format sent to peer ID, it is JPEG if peer does not advertise image formats
+func getPeerImageFormat(id, srcFormat string) string {
+	peerFormats := rtkUtils.GetPeerClientImageFormats(id)
+	if slices.Contains(peerFormats, srcFormat) {
+		return srcFormat
+	}
+	for _, format := range peerFormats {
+		if isSupportImageFormat(format) {
+			return format
+		}
+	}
+	return rtkCommon.ImageFormatJpeg
+}
+
+// transcodeImageForPeer returns the data with image converted for peer ID, the image is removed if it can not be converted
+func transcodeImageForPeer(id string, cbData rtkCommon.ClipBoardData) rtkCommon.ClipBoardData {
+	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok || extData.ImageLen == 0 {
+		return cbData
+	}
+	format := getPeerImageFormat(id, extData.ImageFormat)
+
+	clipboardImageMutex.Lock()
+	defer clipboardImageMutex.Unlock()
+	if imageCacheHash != cbData.Hash {
+		imageCacheHash = cbData.Hash
+		clear(imageCacheMap)
+	}
+	imgData, ok := imageCacheMap[format]
+	if !ok {
+		image, width, height, err := rtkUtils.TranscodeImage(extData.Image, format, clipboardImageMaxPixels, clipboardImageMaxBytes)
+		if err != nil {
+			log.Printf("[%s] ID:[%s] transcode image %s to %s err:%+v, remove it", rtkMisc.GetFuncInfo(), id, extData.ImageFormat, format, err)
+		} else {
+			imgData = rtkCommon.ExtDataXClip{
+				Image:       image,
+				ImageLen:    int64(len(image)),
+				ImageFormat: format,
+				ImageWidth:  int32(width),
+				ImageHeight: int32(height),
+			}
+		}
+		imageCacheMap[format] = imgData
+	}
+
+	extData.Image = imgData.Image
+	extData.ImageLen = imgData.ImageLen
+	extData.ImageFormat = imgData.ImageFormat
+	extData.ImageWidth = imgData.ImageWidth
+	extData.ImageHeight = imgData.ImageHeight
+	cbData.ExtData = extData
+	return cbData
+}
+
+// GetLastClipboardImageInfo returns JSON of ClipboardImageInfo detected from the image of last clipboard data, empty if there is no image
+func GetLastClipboardImageInfo() string {
+	extData, ok := GetLastClipboardData().ExtData.(rtkCommon.ExtDataXClip)
+	if !ok || extData.ImageLen == 0 {
+		return ""
+	}
+
+	encodedData, err := json.Marshal(ClipboardImageInfo{
+		Format: extData.ImageFormat,
+		Width:  extData.ImageWidth,
+		Height: extData.ImageHeight,
+		Size:   extData.ImageLen,
+	})
+	if err != nil {
+		log.Printf("[%s] Marshal clipboard image info err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
@@ -0,0 +1,167 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"log"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"slices"
+	"strings"
+)
+
+// XClip items: the clipboard data of MIME types other than text, image, html and rtf (e.g. text/uri-list and custom application types)
+// are kept in ExtDataXClip.Items, and they are sent after rtf only to the peer with CapabilityMimeXClip.
+// The old version peer gets the legacy four formats only.
+
+// ClipboardItem is the clipboard data of platform in JSON, Data is base64 encoded
+type ClipboardItem struct {
+	MimeType string
+	Data     []byte
+}
+
+func isMimeXClipSupported(id string) bool {
+	return rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityMimeXClip)
+}
+
+func getXClipItemsLen(items []rtkCommon.XClipItem) int64 {
+	var nLen int64
+	for _, item := range items {
+		nLen += item.Len
+	}
+	return nLen
+}
+
+// GetXClipItemHeads returns the items without data for XClip head
+func GetXClipItemHeads(items []rtkCommon.XClipItem) []rtkCommon.XClipItem {
+	if len(items) == 0 {
+		return nil
+	}
+	heads := make([]rtkCommon.XClipItem, 0, len(items))
+	for _, item := range items {
+		heads = append(heads, rtkCommon.XClipItem{MimeType: item.MimeType, Len: item.Len})
+	}
+	return heads
+}
+
+// SplitXClipItemsData fills the data of item heads from the data sent after rtf in order
+func SplitXClipItemsData(heads []rtkCommon.XClipItem, data []byte) []rtkCommon.XClipItem {
+	if len(heads) == 0 {
+		return nil
+	}
+	items := make([]rtkCommon.XClipItem, 0, len(heads))
+	var offset int64
+	for _, head := range heads {
+		if head.Len < 0 || offset+head.Len > int64(len(data)) {
+			log.Printf("[%s] item:[%s] len:%d out of data len:%d", rtkMisc.GetFuncInfo(), head.MimeType, head.Len, len(data))
+			break
+		}
+		items = append(items, rtkCommon.XClipItem{MimeType: head.MimeType, Len: head.Len, Data: slices.Clone(data[offset : offset+head.Len])})
+		offset += head.Len
+	}
+	return items
+}
+
+// filterXClipItemsForPeer removes the items if peer ID does not support them
+func filterXClipItemsForPeer(id string, cbData rtkCommon.ClipBoardData) rtkCommon.ClipBoardData {
+	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok || len(extData.Items) == 0 || isMimeXClipSupported(id) {
+		return cbData
+	}
+	log.Printf("[%s] ID:[%s] not support MIME items, skip %d items", rtkMisc.GetFuncInfo(), id, len(extData.Items))
+	extData.Items = nil
+	cbData.ExtData = extData
+	return cbData
+}
+
+func getXClipItemMimeTypes(items []rtkCommon.XClipItem) []string {
+	mimeTypes := make([]string, 0, len(items))
+	for _, item := range items {
+		mimeTypes = append(mimeTypes, item.MimeType)
+	}
+	return mimeTypes
+}
+
+// CopyXClipItems is called by platform for the local copy with JSON of []ClipboardItem,
+// text, html, rtf and the image can be decoded are kept in the legacy formats, the others are kept as items
+func CopyXClipItems(itemsJson string) bool {
+	var cbItems []ClipboardItem
+	if err := json.Unmarshal([]byte(itemsJson), &cbItems); err != nil {
+		log.Printf("[%s] Unmarshal clipboard items err:%+v", rtkMisc.GetFuncInfo(), err)
+		return false
+	}
+
+	var text, image, html, rtf []byte
+	items := make([]rtkCommon.XClipItem, 0)
+	for _, cbItem := range cbItems {
+		mimeType := strings.ToLower(strings.TrimSpace(strings.Split(cbItem.MimeType, ";")[0]))
+		if mimeType == "" || len(cbItem.Data) == 0 {
+			continue
+		}
+		switch {
+		case mimeType == rtkCommon.MimeTypeText && text == nil:
+			text = cbItem.Data
+			continue
+		case mimeType == rtkCommon.MimeTypeHtml && html == nil:
+			html = cbItem.Data
+			continue
+		case (mimeType == rtkCommon.MimeTypeRtf || mimeType == "application/rtf") && rtf == nil:
+			rtf = cbItem.Data
+			continue
+		case strings.HasPrefix(mimeType, rtkCommon.MimeTypeImagePrefix) && image == nil:
+			if format, _, _ := rtkUtils.GetByteImageInfo(cbItem.Data); format != "" {
+				if data, err := rtkUtils.ImageToClipboardFormat(format, cbItem.Data); err == nil {
+					image = data
+					continue
+				}
+			}
+		}
+		items = append(items, rtkCommon.XClipItem{MimeType: mimeType, Len: int64(len(cbItem.Data)), Data: cbItem.Data})
+	}
+	if len(text)+len(image)+len(html)+len(rtf) == 0 && len(items) == 0 {
+		log.Printf("[%s] there is no valid clipboard item", rtkMisc.GetFuncInfo())
+		return false
+	}
+
+	log.Printf("[%s] Text:%d Image:%d Html:%d Rtf:%d items:%v", rtkMisc.GetFuncInfo(), len(text), len(image), len(html), len(rtf), getXClipItemMimeTypes(items))
+	updateClipboardItemsFromPlatform(text, image, html, rtf, items)
+	return true
+}
+
+// GetLastXClipItems returns JSON of []ClipboardItem of the last clipboard data, including text, image, html and rtf.
+// It is called by platform for the formats other than the legacy four after the paste XClip callback.
+func GetLastXClipItems() string {
+	lastData := GetLastClipboardData()
+	extData, ok := lastData.ExtData.(rtkCommon.ExtDataXClip)
+	if lastData.FmtType != rtkCommon.XCLIP_CB || !ok {
+		return "[]"
+	}
+
+	cbItems := make([]ClipboardItem, 0, 4+len(extData.Items))
+	if len(extData.Text) > 0 {
+		cbItems = append(cbItems, ClipboardItem{MimeType: rtkCommon.MimeTypeText, Data: extData.Text})
+	}
+	if len(extData.Image) > 0 {
+		format := extData.ImageFormat
+		if format == "" {
+			format = rtkCommon.ImageFormatPng
+		}
+		cbItems = append(cbItems, ClipboardItem{MimeType: rtkCommon.MimeTypeImagePrefix + format, Data: extData.Image})
+	}
+	if len(extData.Html) > 0 {
+		cbItems = append(cbItems, ClipboardItem{MimeType: rtkCommon.MimeTypeHtml, Data: extData.Html})
+	}
+	if len(extData.Rtf) > 0 {
+		cbItems = append(cbItems, ClipboardItem{MimeType: rtkCommon.MimeTypeRtf, Data: extData.Rtf})
+	}
+	for _, item := range extData.Items {
+		cbItems = append(cbItems, ClipboardItem{MimeType: item.MimeType, Data: item.Data})
+	}
+
+	encodedData, err := json.Marshal(cbItems)
+	if err != nil {
+		log.Printf("[%s] Marshal clipboard items err:%+v", rtkMisc.GetFuncInfo(), err)
+		return "[]"
+	}
+	return string(encodedData)
+}
@@ -0,0 +1,171 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"log"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lazy XClip: Src always sends the XClip head with formats, sizes and hash first. In lazy mode Dst keeps the head
+// and notifies platform instead of requiring the data, and the data is pulled by COMM_CB_LAZY_PULL_REQ/RSP only when platform pastes it.
+// Src answers the pull with the last local copy only, until it is replaced by the next copy or lazyXClipTTL expires.
+const lazyXClipTTL = 10 * time.Minute
+
+// LazyXClipHead is the XClip head notified to platform in lazy mode
+type LazyXClipHead struct {
+	SourceID    string
+	Hash        string
+	TimeStamp   int64 // unix milli, the time head is received
+	Formats     []string
+	TextLen     int64
+	ImageLen    int64
+	HtmlLen     int64
+	RtfLen      int64
+	ImageFormat string
+	ImageWidth  int32
+	ImageHeight int32
+	Items       []rtkCommon.XClipItem // heads of items, their MIME types are in Formats too
+}
+
+type CallbackSendLazyXClipPullReqFunc func(id, hash string) rtkMisc.CrossShareErr
+
+var (
+	isClipboardLazyMode          atomic.Bool
+	lazyXClipHead                LazyXClipHead // Dst: the last head from peer, empty SourceID means none
+	lazyXClipMutex               sync.Mutex
+	callbackSendLazyXClipPullReq CallbackSendLazyXClipPullReqFunc = nil
+)
+
+func SetSendLazyXClipPullReqCallback(cb CallbackSendLazyXClipPullReqFunc) {
+	callbackSendLazyXClipPullReq = cb
+}
+
+// SetClipboardLazyMode enables Dst to pull XClip data only when platform pastes it, platform must handle the lazy head callback then
+func SetClipboardLazyMode(enable bool) {
+	isClipboardLazyMode.Store(enable)
+	if !enable {
+		lazyXClipMutex.Lock()
+		lazyXClipHead = LazyXClipHead{}
+		lazyXClipMutex.Unlock()
+	}
+	log.Printf("[%s] clipboard lazy mode:[%t]", rtkMisc.GetFuncInfo(), enable)
+}
+
+func IsClipboardLazyMode() bool {
+	return isClipboardLazyMode.Load()
+}
+
+// SetupDstLazyXClipHead keeps the head from peer ID and notifies platform, the data is not transferred yet
+func SetupDstLazyXClipHead(id string, extData rtkCommon.ExtDataXClip) {
+	head := LazyXClipHead{
+		SourceID:    id,
+		Hash:        extData.Hash,
+		TimeStamp:   time.Now().UnixMilli(),
+		Formats:     make([]string, 0),
+		TextLen:     extData.TextLen,
+		ImageLen:    extData.ImageLen,
+		HtmlLen:     extData.HtmlLen,
+		RtfLen:      extData.RtfLen,
+		ImageFormat: extData.ImageFormat,
+		ImageWidth:  extData.ImageWidth,
+		ImageHeight: extData.ImageHeight,
+		Items:       GetXClipItemHeads(extData.Items),
+	}
+	for _, format := range []struct {
+		name string
+		size int64
+	}{{ClipboardFormatText, extData.TextLen}, {ClipboardFormatImage, extData.ImageLen}, {ClipboardFormatHtml, extData.HtmlLen}, {ClipboardFormatRtf, extData.RtfLen}} {
+		if format.size > 0 {
+			head.Formats = append(head.Formats, format.name)
+		}
+	}
+	head.Formats = append(head.Formats, getXClipItemMimeTypes(extData.Items)...)
+
+	lazyXClipMutex.Lock()
+	lazyXClipHead = head
+	lazyXClipMutex.Unlock()
+	log.Printf("[%s] ID:[%s] hash:[%s] Text:%d Image:%d Html:%d Rtf:%d", rtkMisc.GetFuncInfo(), id, head.Hash, head.TextLen, head.ImageLen, head.HtmlLen, head.RtfLen)
+
+	encodedData, err := json.Marshal(head)
+	if err != nil {
+		log.Printf("[%s] Marshal lazy XClip head err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	rtkPlatform.GoNotifyLazyXClipHead(id, string(encodedData))
+}
+
+func getLazyXClipHead() LazyXClipHead {
+	lazyXClipMutex.Lock()
+	defer lazyXClipMutex.Unlock()
+	return lazyXClipHead
+}
+
+// ResetLazyXClipHead clears the head from peer ID when it is offline
+func ResetLazyXClipHead(id string) {
+	lazyXClipMutex.Lock()
+	defer lazyXClipMutex.Unlock()
+	if lazyXClipHead.SourceID == id {
+		lazyXClipHead = LazyXClipHead{}
+	}
+}
+
+// GetLazyXClipHead returns JSON of the last LazyXClipHead, empty if there is none
+func GetLazyXClipHead() string {
+	head := getLazyXClipHead()
+	if head.SourceID == "" {
+		return ""
+	}
+
+	encodedData, err := json.Marshal(head)
+	if err != nil {
+		log.Printf("[%s] Marshal lazy XClip head err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+// RequestLazyXClipData is called by platform when it pastes the last lazy head, the data is pasted by the paste XClip callback as usual
+func RequestLazyXClipData() bool {
+	head := getLazyXClipHead()
+	if head.SourceID == "" {
+		log.Printf("[%s] there is no lazy XClip head", rtkMisc.GetFuncInfo())
+		return false
+	}
+	if time.Since(time.UnixMilli(head.TimeStamp)) > lazyXClipTTL {
+		log.Printf("[%s] ID:[%s] hash:[%s] lazy XClip head is expired", rtkMisc.GetFuncInfo(), head.SourceID, head.Hash)
+		return false
+	}
+	if callbackSendLazyXClipPullReq == nil {
+		log.Printf("[%s] callbackSendLazyXClipPullReq is null!", rtkMisc.GetFuncInfo())
+		return false
+	}
+
+	log.Printf("[%s] ID:[%s] hash:[%s] pull lazy XClip data", rtkMisc.GetFuncInfo(), head.SourceID, head.Hash)
+	return callbackSendLazyXClipPullReq(head.SourceID, head.Hash) == rtkMisc.SUCCESS
+}
+
+// GetLazyXClipPullData returns the data pulled by peer ID (Src), it must be the last local copy with the hash in head and not expired
+func GetLazyXClipPullData(id, hash string) (rtkCommon.ClipBoardData, rtkMisc.CrossShareErr) {
+	lastData := GetLastClipboardData()
+	if lastData.FmtType != rtkCommon.XCLIP_CB || lastData.SourceID != rtkGlobal.NodeInfo.ID || lastData.Hash != hash {
+		log.Printf("[%s] ID:[%s] hash:[%s] is replaced by the next copy", rtkMisc.GetFuncInfo(), id, hash)
+		return rtkCommon.ClipBoardData{}, rtkMisc.ERR_BIZ_CB_NO_DATA
+	}
+	if time.Since(time.Unix(int64(lastData.TimeStamp), 0)) > lazyXClipTTL {
+		log.Printf("[%s] ID:[%s] hash:[%s] is expired", rtkMisc.GetFuncInfo(), id, hash)
+		return rtkCommon.ClipBoardData{}, rtkMisc.ERR_BIZ_CB_NO_DATA
+	}
+
+	cbData, isAllowed := GetLastClipboardDataForPeer(id)
+	if _, ok := cbData.ExtData.(rtkCommon.ExtDataXClip); !ok || !isAllowed {
+		log.Printf("[%s] ID:[%s] hash:[%s] invalid data or rejected by policy", rtkMisc.GetFuncInfo(), id, hash)
+		return rtkCommon.ClipBoardData{}, rtkMisc.ERR_BIZ_CB_INVALID_DATA
+	}
+	return cbData, rtkMisc.SUCCESS
+}
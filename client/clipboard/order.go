@@ -0,0 +1,136 @@
+package clipboard
+
+import (
+	"log"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+// Clipboard ordering across peers: every XClip data has the ID of the client where it is copied (OriginID) and a Lamport clock.
+// The clock is increased by local copy and raised to the clock of every received data. The data with larger (Clock, OriginID) is newer,
+// so all clients keep the same value when several of them copy at the same time, and the older data is dropped.
+// The local copy reported by platform just after the data from peer is pasted is its echo, and it is not sent again.
+const xClipEchoWindow = 2 * time.Second
+
+type xClipVersion struct {
+	OriginID string
+	Clock    uint64
+}
+
+var (
+	clipboardClock      uint64
+	currentXClipVersion xClipVersion
+	lastPasteTime       time.Time // the time data from peer is pasted
+	clipboardOrderMutex sync.Mutex
+)
+
+func (v xClipVersion) isNewerThan(other xClipVersion) bool {
+	if v.Clock != other.Clock {
+		return v.Clock > other.Clock
+	}
+	return v.OriginID > other.OriginID
+}
+
+// newLocalXClipVersion increases the clock for local copy
+func newLocalXClipVersion() xClipVersion {
+	clipboardOrderMutex.Lock()
+	defer clipboardOrderMutex.Unlock()
+	clipboardClock++
+	currentXClipVersion = xClipVersion{OriginID: rtkGlobal.NodeInfo.ID, Clock: clipboardClock}
+	return currentXClipVersion
+}
+
+// ReceiveXClipVersion raises the clock to the XClip head from peer ID, and returns false if the data is older than the current one.
+// The data from old version peer has no clock, and it is always newer.
+func ReceiveXClipVersion(id, originID string, clock uint64) bool {
+	if clock == 0 || originID == "" {
+		return true
+	}
+
+	clipboardOrderMutex.Lock()
+	defer clipboardOrderMutex.Unlock()
+	clipboardClock = max(clipboardClock, clock)
+	version := xClipVersion{OriginID: originID, Clock: clock}
+	if !version.isNewerThan(currentXClipVersion) {
+		log.Printf("[%s] ID:[%s] drop older XClip data origin:[%s] clock:[%d], current origin:[%s] clock:[%d]", rtkMisc.GetFuncInfo(), id, originID, clock, currentXClipVersion.OriginID, currentXClipVersion.Clock)
+		return false
+	}
+	return true
+}
+
+// acceptXClipVersion makes the data from peer ID the current one, and returns false if it is older than the current one
+func acceptXClipVersion(id, originID string, clock uint64) (xClipVersion, bool) {
+	clipboardOrderMutex.Lock()
+	defer clipboardOrderMutex.Unlock()
+	if clock == 0 || originID == "" {
+		// old version peer or local history, it is taken as the data with the current clock
+		currentXClipVersion = xClipVersion{OriginID: id, Clock: clipboardClock}
+		lastPasteTime = time.Now()
+		return currentXClipVersion, true
+	}
+
+	clipboardClock = max(clipboardClock, clock)
+	version := xClipVersion{OriginID: originID, Clock: clock}
+	if !version.isNewerThan(currentXClipVersion) {
+		log.Printf("[%s] ID:[%s] drop older XClip data origin:[%s] clock:[%d], current origin:[%s] clock:[%d]", rtkMisc.GetFuncInfo(), id, originID, clock, currentXClipVersion.OriginID, currentXClipVersion.Clock)
+		return version, false
+	}
+	currentXClipVersion = version
+	lastPasteTime = time.Now()
+	return version, true
+}
+
+// getXClipImageSize returns the decoded size of image, the size in ExtDataXClip is empty from old version peer
+func getXClipImageSize(extData *rtkCommon.ExtDataXClip) (int32, int32) {
+	if extData.ImageWidth > 0 && extData.ImageHeight > 0 {
+		return extData.ImageWidth, extData.ImageHeight
+	}
+	_, width, height := rtkUtils.GetByteImageInfo(extData.Image)
+	return int32(width), int32(height)
+}
+
+// isXClipEcho returns true if the local copy is the data from peer pasted just now.
+// Every format is compared by content, except the image which the platform may convert when it is pasted,
+// so in xClipEchoWindow the image with the same decoded size is also taken as the echo.
+func isXClipEcho(cbData rtkCommon.ClipBoardData) bool {
+	lastData := GetLastClipboardData()
+	if lastData.FmtType != rtkCommon.XCLIP_CB || lastData.SourceID == "" || lastData.SourceID == rtkGlobal.NodeInfo.ID {
+		return false
+	}
+	if lastData.Hash == cbData.Hash {
+		return true
+	}
+
+	clipboardOrderMutex.Lock()
+	pasteTime := lastPasteTime
+	clipboardOrderMutex.Unlock()
+	if time.Since(pasteTime) > xClipEchoWindow {
+		return false
+	}
+	lastExtData, ok := lastData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok {
+		return false
+	}
+	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok {
+		return false
+	}
+	if !rtkUtils.ContentEqual(lastExtData.Text, extData.Text) ||
+		!rtkUtils.ContentEqual(lastExtData.Html, extData.Html) ||
+		!rtkUtils.ContentEqual(lastExtData.Rtf, extData.Rtf) {
+		return false
+	}
+	if (lastExtData.ImageLen > 0) != (extData.ImageLen > 0) {
+		return false
+	}
+	if extData.ImageLen == 0 {
+		return true
+	}
+	lastWidth, lastHeight := getXClipImageSize(&lastExtData)
+	width, height := getXClipImageSize(&extData)
+	return width > 0 && height > 0 && lastWidth == width && lastHeight == height
+}
@@ -0,0 +1,300 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"regexp"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkMisc "rtk-cross-share/misc"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Clipboard policy is evaluated on Src before XClip data is sent to every peer, and again on Dst before it is pasted.
+// The data is rejected if the peer is excluded, the data is concealed by platform or looks sensitive,
+// otherwise the formats not allowed by the peer or larger than max size are removed, and the data is rejected if nothing is left.
+const (
+	ClipboardFormatText  = "text"
+	ClipboardFormatImage = "image"
+	ClipboardFormatHtml  = "html"
+	ClipboardFormatRtf   = "rtf"
+	ClipboardFormatItem  = "item" // every XClipItem of other MIME types
+)
+
+type clipboardPolicyDirection string
+
+const (
+	clipboardPolicySrc clipboardPolicyDirection = "SRC"
+	clipboardPolicyDst clipboardPolicyDirection = "DST"
+)
+
+// ClipboardPolicy is set by platform in JSON, the missing fields keep the default value
+type ClipboardPolicy struct {
+	MaxFormatSize     map[string]int64    // key: format, missing or <= 0 means no limit
+	PeerFormats       map[string][]string // key: peer ID, the formats allowed with the peer, missing peer allows all formats
+	ExcludedPeers     []string            // peer ID, clipboard is not synced with them in both directions
+	SensitivePatterns []string            // regexp matched with text, html, rtf and text/* items
+	DetectCardNumber  bool                // the digits of plain text match Luhn checksum are taken as credit card number, opt-in
+	SuppressConcealed bool                // the local copy marked as concealed by platform is not sent
+}
+
+var defaultClipboardPolicy = ClipboardPolicy{
+	MaxFormatSize: map[string]int64{},
+	PeerFormats:   map[string][]string{},
+	ExcludedPeers: []string{},
+	SensitivePatterns: []string{
+		`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+		`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                // AWS access key
+		`\bgh[pousr]_[0-9A-Za-z]{36}\b`,                                // GitHub token
+		`\bxox[baprs]-[0-9A-Za-z-]{10,}\b`,                             // Slack token
+		`\bsk-[0-9A-Za-z_-]{20,}\b`,                                    // API secret key
+		`\beyJ[0-9A-Za-z_-]{8,}\.eyJ[0-9A-Za-z_-]{8,}\.[0-9A-Za-z_-]+`, // JWT
+	},
+	DetectCardNumber:  false, // ids, tracking numbers and the digits in html or rtf often pass Luhn checksum
+	SuppressConcealed: true,
+}
+
+var (
+	clipboardPolicy      = getDefaultClipboardPolicy()
+	sensitiveRegexpList  = compileSensitivePatterns(defaultClipboardPolicy.SensitivePatterns)
+	clipboardPolicyMutex sync.RWMutex
+	cardNumberRegexp     = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	lastConcealedHash    atomic.Value // hash of the last local copy marked as concealed
+	isNextCopyConcealed  atomic.Bool
+)
+
+func compileSensitivePatterns(patterns []string) []*regexp.Regexp {
+	regexpList := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("[%s] skip invalid sensitive pattern:[%s] err:%+v", rtkMisc.GetFuncInfo(), pattern, err)
+			continue
+		}
+		regexpList = append(regexpList, re)
+	}
+	return regexpList
+}
+
+// getDefaultClipboardPolicy returns a copy of default policy, so JSON decoding never changes the default maps and slices
+func getDefaultClipboardPolicy() ClipboardPolicy {
+	policy := defaultClipboardPolicy
+	policy.MaxFormatSize = maps.Clone(defaultClipboardPolicy.MaxFormatSize)
+	policy.PeerFormats = maps.Clone(defaultClipboardPolicy.PeerFormats)
+	policy.ExcludedPeers = slices.Clone(defaultClipboardPolicy.ExcludedPeers)
+	policy.SensitivePatterns = slices.Clone(defaultClipboardPolicy.SensitivePatterns)
+	return policy
+}
+
+func parseClipboardPolicy(policyJson string) (ClipboardPolicy, error) {
+	policy := getDefaultClipboardPolicy()
+	if strings.TrimSpace(policyJson) == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(policyJson), &policy); err != nil {
+		return policy, fmt.Errorf("invalid clipboard policy: %w", err)
+	}
+	for format := range policy.MaxFormatSize {
+		if !isClipboardFormat(format) {
+			return policy, fmt.Errorf("invalid clipboard policy, unknown format:[%s] in MaxFormatSize", format)
+		}
+	}
+	for id, formats := range policy.PeerFormats {
+		for _, format := range formats {
+			if !isClipboardFormat(format) {
+				return policy, fmt.Errorf("invalid clipboard policy, unknown format:[%s] of ID:[%s]", format, id)
+			}
+		}
+	}
+	return policy, nil
+}
+
+func isClipboardFormat(format string) bool {
+	switch format {
+	case ClipboardFormatText, ClipboardFormatImage, ClipboardFormatHtml, ClipboardFormatRtf, ClipboardFormatItem:
+		return true
+	}
+	return false
+}
+
+// SetClipboardPolicy sets the policy in JSON of ClipboardPolicy, empty resets it to default
+func SetClipboardPolicy(policyJson string) bool {
+	policy, err := parseClipboardPolicy(policyJson)
+	if err != nil {
+		log.Printf("[%s] err:%+v", rtkMisc.GetFuncInfo(), err)
+		return false
+	}
+
+	clipboardPolicyMutex.Lock()
+	defer clipboardPolicyMutex.Unlock()
+	clipboardPolicy = policy
+	sensitiveRegexpList = compileSensitivePatterns(policy.SensitivePatterns)
+	log.Printf("[%s] clipboard policy:%s", rtkMisc.GetFuncInfo(), policyJson)
+	return true
+}
+
+// GetClipboardPolicy returns JSON of ClipboardPolicy in use
+func GetClipboardPolicy() string {
+	clipboardPolicyMutex.RLock()
+	defer clipboardPolicyMutex.RUnlock()
+	encodedData, err := json.Marshal(clipboardPolicy)
+	if err != nil {
+		log.Printf("[%s] Marshal clipboard policy err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+// MarkNextCopyConcealed is called by platform just before the local copy which is concealed (e.g. password manager)
+func MarkNextCopyConcealed() {
+	isNextCopyConcealed.Store(true)
+}
+
+func setLastConcealedHash(hash string) {
+	lastConcealedHash.Store(hash)
+}
+
+func isConcealedHash(hash string) bool {
+	val, ok := lastConcealedHash.Load().(string)
+	return ok && val != "" && val == hash
+}
+
+func isLuhnNumber(digits string) bool {
+	sum := 0
+	isDouble := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		n := int(digits[i] - '0')
+		if isDouble {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		isDouble = !isDouble
+	}
+	return sum%10 == 0
+}
+
+func containsCardNumber(data []byte) bool {
+	for _, match := range cardNumberRegexp.FindAll(data, -1) {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(string(match))
+		if len(digits) >= 13 && len(digits) <= 19 && isLuhnNumber(digits) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSensitiveReason must be called with clipboardPolicyMutex locked
+func getSensitiveReason(extData *rtkCommon.ExtDataXClip) string {
+	textList := [][]byte{extData.Text, extData.Html, extData.Rtf}
+	for _, item := range extData.Items {
+		if strings.HasPrefix(item.MimeType, "text/") {
+			textList = append(textList, item.Data)
+		}
+	}
+	for _, data := range textList {
+		if len(data) == 0 {
+			continue
+		}
+		for _, re := range sensitiveRegexpList {
+			if re.Match(data) {
+				return fmt.Sprintf("sensitive pattern:[%s]", re.String())
+			}
+		}
+	}
+	if clipboardPolicy.DetectCardNumber && containsCardNumber(extData.Text) {
+		return "credit card number"
+	}
+	return ""
+}
+
+// applyClipboardPolicy returns the data with the formats allowed for peer ID, and the reason if it is rejected
+func applyClipboardPolicy(direction clipboardPolicyDirection, id string, cbData rtkCommon.ClipBoardData) (rtkCommon.ClipBoardData, string) {
+	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	if !ok {
+		return cbData, "invalid XClip data"
+	}
+
+	clipboardPolicyMutex.RLock()
+	defer clipboardPolicyMutex.RUnlock()
+	if slices.Contains(clipboardPolicy.ExcludedPeers, id) {
+		return cbData, "peer is excluded"
+	}
+	if direction == clipboardPolicySrc && clipboardPolicy.SuppressConcealed && isConcealedHash(cbData.Hash) {
+		return cbData, "concealed by platform"
+	}
+	if reason := getSensitiveReason(&extData); reason != "" {
+		return cbData, reason
+	}
+
+	allowFormats, isPeerLimited := clipboardPolicy.PeerFormats[id]
+	isAllowed := func(format string, size int64) bool {
+		if size == 0 {
+			return false
+		}
+		if isPeerLimited && !slices.Contains(allowFormats, format) {
+			return false
+		}
+		maxSize := clipboardPolicy.MaxFormatSize[format]
+		return maxSize <= 0 || size <= maxSize
+	}
+	if !isAllowed(ClipboardFormatText, extData.TextLen) {
+		extData.Text, extData.TextLen = nil, 0
+	}
+	if !isAllowed(ClipboardFormatImage, extData.ImageLen) {
+		extData.Image, extData.ImageLen = nil, 0
+	}
+	if !isAllowed(ClipboardFormatHtml, extData.HtmlLen) {
+		extData.Html, extData.HtmlLen = nil, 0
+	}
+	if !isAllowed(ClipboardFormatRtf, extData.RtfLen) {
+		extData.Rtf, extData.RtfLen = nil, 0
+	}
+	if len(extData.Items) > 0 {
+		items := make([]rtkCommon.XClipItem, 0, len(extData.Items))
+		for _, item := range extData.Items {
+			if isAllowed(ClipboardFormatItem, item.Len) {
+				items = append(items, item)
+			}
+		}
+		extData.Items = items
+	}
+	if extData.TextLen+extData.ImageLen+extData.HtmlLen+extData.RtfLen+getXClipItemsLen(extData.Items) == 0 {
+		return cbData, "no format is allowed"
+	}
+
+	cbData.ExtData = extData
+	return cbData, ""
+}
+
+func logClipboardPolicyDecision(direction clipboardPolicyDirection, id string, src, dst rtkCommon.ClipBoardData, reason string) {
+	srcData, _ := src.ExtData.(rtkCommon.ExtDataXClip)
+	if reason != "" {
+		log.Printf("[%s] (%s) ID:[%s] hash:[%s] reject, reason:%s", rtkMisc.GetFuncInfo(), direction, id, src.Hash, reason)
+		return
+	}
+	dstData, _ := dst.ExtData.(rtkCommon.ExtDataXClip)
+	if srcData.TextLen != dstData.TextLen || srcData.ImageLen != dstData.ImageLen || srcData.HtmlLen != dstData.HtmlLen || srcData.RtfLen != dstData.RtfLen || len(srcData.Items) != len(dstData.Items) {
+		log.Printf("[%s] (%s) ID:[%s] hash:[%s] allow with formats filtered, text:%d->%d image:%d->%d html:%d->%d rtf:%d->%d items:%d->%d", rtkMisc.GetFuncInfo(), direction, id, src.Hash,
+			srcData.TextLen, dstData.TextLen, srcData.ImageLen, dstData.ImageLen, srcData.HtmlLen, dstData.HtmlLen, srcData.RtfLen, dstData.RtfLen, len(srcData.Items), len(dstData.Items))
+		return
+	}
+	log.Printf("[%s] (%s) ID:[%s] hash:[%s] allow", rtkMisc.GetFuncInfo(), direction, id, src.Hash)
+}
+
+// GetLastClipboardDataForPeer returns the last local XClip data with image converted and filtered by policy for peer ID, it is the data really sent to the peer
+func GetLastClipboardDataForPeer(id string) (rtkCommon.ClipBoardData, bool) {
+	lastData := GetLastClipboardData()
+	if lastData.FmtType != rtkCommon.XCLIP_CB || lastData.SourceID != rtkGlobal.NodeInfo.ID {
+		return lastData, true
+	}
+	cbData, reason := applyClipboardPolicy(clipboardPolicySrc, id, transcodeImageForPeer(id, filterXClipItemsForPeer(id, lastData)))
+	return cbData, reason == ""
+}
@@ -8,6 +8,7 @@ import (
 	rtkCommon "rtk-cross-share/client/common"
 	rtkConnection "rtk-cross-share/client/connection"
 	rtkDebug "rtk-cross-share/client/debug"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
 	rtkGlobal "rtk-cross-share/client/global"
 	rtkLogin "rtk-cross-share/client/login"
 	rtkPeer2Peer "rtk-cross-share/client/peer2peer"
@@ -173,6 +174,7 @@ func Run() {
 		log.Fatalf("Another instance is already running, so Exit!\n\n")
 	}
 	defer rtkPlatform.UnlockFile()
+	rtkFileDrop.LoadTransferJournal()
 
 	if rtkBuildConfig.CmdDebug == "1" {
 		rtkMisc.GoSafe(func() { rtkDebug.DebugCmdLine() })
@@ -213,6 +215,7 @@ func MainInit(serverId, serverIpInfo, listenHost string, listentPort int) {
 		log.Fatalf("Another instance is already running, so Exit!\n\n")
 	}
 	defer rtkPlatform.UnlockFile()
+	rtkFileDrop.LoadTransferJournal()
 
 	rtkMisc.GoSafe(func() { businessProcess(context.Background()) })
 
@@ -43,8 +43,42 @@ type RegistMdnsMessage struct {
 	Version         string
 	FileTransNodeID string
 	UdpPort         string
+	Capabilities    []string // nil means an old version peer, its capabilities are decided by Version
 }
 
+// Capabilities advertised in RegistMdnsMessage, a peer feature is enabled only when both sides have it
+const (
+	CapabilityXClip          = "XClip"          // clipboard with text, image, html and rtf in one message
+	CapabilityQueueFileTrans = "QueueFileTrans" // file drop queue transfer
+	CapabilityRmFileCntLimit = "RmFileCntLimit" // no file count limit in file drop
+	CapabilityQuicXClip      = "QuicXClip"      // XClip image is transferred by fileTransNode(QUIC)
+	CapabilityFramedMsg      = "FramedMsg"      // Peer2PeerMessage is sent with a length header, no length limit
+	CapabilityFileHash       = "FileHash"       // file drop verifies every file by SHA-256 and resends the mismatched ones
+	CapabilityChunkedFile    = "ChunkedFile"    // large file is sent in ranges over several QUIC streams at once
+	CapabilityCompress       = "Compress"       // file and XClip data is sent in blocks, and the block is compressed if it becomes smaller
+	CapabilityLazyXClip      = "LazyXClip"      // XClip data is pulled by dst with COMM_CB_LAZY_PULL_REQ/RSP only when platform pastes it
+	CapabilityMimeXClip      = "MimeXClip"      // XClip has Items of any MIME type after text, image, html and rtf
+)
+
+// Clipboard MIME types, text, image, html and rtf are kept in the legacy fields of ExtDataXClip, and the others in Items
+const (
+	MimeTypeText        = "text/plain"
+	MimeTypeHtml        = "text/html"
+	MimeTypeRtf         = "text/rtf"
+	MimeTypeUriList     = "text/uri-list" // file URI list, one URI per line
+	MimeTypeImagePrefix = "image/"
+)
+
+// Clipboard image formats, the formats accepted by a client are advertised as CapabilityImagePrefix+format in preference order.
+// The image of XClip from a peer without them is JPEG.
+const (
+	CapabilityImagePrefix = "Image:"
+
+	ImageFormatPng  = "png"
+	ImageFormatJpeg = "jpeg"
+	ImageFormatBmp  = "bmp" // 32 bits BGRA bottom-up, the DIB is the data after 14 bytes file header
+)
+
 type RegResponseMessage struct {
 	GUEST_LIST            []string
 	GUEST_PUBLIC_TCP_IP   string
@@ -80,7 +114,7 @@ const (
 	SendFilesRequestInProgressBySrc
 	SendFilesRequestInProgressByDst
 	SendFilesRequestCallbackNotSet
-	SendFilesRequestLengthOverRange
+	SendFilesRequestLengthOverRange // deprecated, the file list length is not limited by p2p message any more
 	SendFilesRequestSizeOverRange
 	SendFilesRequestCacheOverRange
 	UnsupportMobileDragFile
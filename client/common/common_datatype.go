@@ -20,6 +20,12 @@ type FileSize struct {
 	SizeLow  uint32
 }
 
+// FileRange is a byte range [Offset, Offset+Size) of a file
+type FileRange struct {
+	Offset int64
+	Size   int64
+}
+
 type FileInfo struct {
 	FileSize_ FileSize
 	FilePath  string //full path
@@ -69,14 +75,28 @@ type ExtDataImg struct {
 }
 
 type ExtDataXClip struct {
-	Text     []byte // Text,UTF-8
-	Image    []byte // Image decode base64
-	Html     []byte // Html
-	Rtf      []byte // Rtf
-	TextLen  int64
-	ImageLen int64
-	HtmlLen  int64
-	RtfLen   int64
+	Text        []byte // Text,UTF-8
+	Image       []byte // Image decode base64
+	Html        []byte // Html
+	Rtf         []byte // Rtf
+	TextLen     int64
+	ImageLen    int64
+	HtmlLen     int64
+	RtfLen      int64
+	ImageFormat string // ImageFormatXxx, empty from old version peer
+	ImageWidth  int32
+	ImageHeight int32
+	Hash        string      // hash of the source data, empty from old version peer
+	OriginID    string      // ClipBoardData.OriginID, empty from old version peer
+	Clock       uint64      // ClipBoardData.Clock
+	Items       []XClipItem // the formats other than text, image, html and rtf, only to the peer with CapabilityMimeXClip
+}
+
+// XClipItem is the clipboard data of any MIME type, Data is nil in XClip head and is sent after Rtf in order of Items
+type XClipItem struct {
+	MimeType string
+	Len      int64
+	Data     []byte
 }
 
 type ClipBoardData struct {
@@ -85,6 +105,8 @@ type ClipBoardData struct {
 	TimeStamp uint64
 	FmtType   TransFmtType
 	ExtData   interface{} // ExtDataFile(future), ExtDataXClip
+	OriginID  string      // ID of the client where the data is copied, empty if it is unknown
+	Clock     uint64      // Lamport clock of the copy, (Clock, OriginID) orders the data across all clients
 }
 
 type ExtDataFilesTransferInterrupt struct {
@@ -97,9 +119,26 @@ type ExtDataFilesTransferRecoverReq struct {
 	InterruptSrcFileName string // Src fileName
 	InterruptFileOffSet  int64
 	InterruptErrCode     rtkMisc.CrossShareErr
+	InterruptDoneRanges  []FileRange // completed ranges of a chunked file, InterruptFileOffSet is 0 then
 }
 
 type ExtDataFilesTransferRecoverRsp struct {
 	ReqResultCode rtkMisc.CrossShareErr
 	TimeStamp     uint64
 }
+
+type ExtDataXClipLazyPullReq struct {
+	Hash string
+}
+
+type ExtDataXClipLazyPullRsp struct {
+	Hash          string
+	ReqResultCode rtkMisc.CrossShareErr
+	OriginID      string
+	Clock         uint64
+	TextLen       int64
+	ImageLen      int64
+	HtmlLen       int64
+	RtfLen        int64
+	Items         []XClipItem // heads of items
+}
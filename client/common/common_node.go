@@ -37,6 +37,9 @@ type ClientInfoEx struct {
 	IsSupportXClip      bool
 	IsSupportQueueTrans bool
 	IsRmFileCntLimit    bool
+	IsSupportQuicXClip  bool
+	Capabilities        []string // negotiated capabilities, nil if peer is an old version
+	ImageFormats        []string // clipboard image formats accepted by peer in preference order, nil if not advertised
 	FileTransNodeID     string
 	UpdPort             string
 }
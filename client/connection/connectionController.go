@@ -17,6 +17,7 @@ import (
 	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
+	"slices"
 
 	"github.com/libp2p/go-libp2p"
 
@@ -123,7 +124,7 @@ func Run(ctx context.Context) {
 
 	condGroupAdd()
 
-	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac { // only computer need watch network info
+	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux { // only computer need watch network info
 		rtkMisc.GoSafe(func() { WatchNetworkInfo(ctx) })
 	}
 
@@ -306,23 +307,60 @@ func buildListener(ctx context.Context) {
 
 	node.SetStreamHandler(protocol.ID(rtkGlobal.ProtocolDirectID), network.StreamHandler(func(stream network.Stream) {
 		onlineEvent(ctx, stream, true, nil)
-	})）
+	}))
 
 	node.SetStreamHandler(protocol.ID(rtkGlobal.ProtocolImageTransmission), network.StreamHandler(func(stream network.Stream) {
 		id := stream.Conn().RemotePeer().String()
-		if isLastXClipStreamExisted(id) {
-			time.Sleep(50 * time.Millisecond)
+		if !isTrustedPeer(id) {
+			log.Printf("[%s] ID:[%s] is not trusted, reset XClip stream", rtkMisc.GetFuncInfo(), id)
+			stream.Reset()
+			return
 		}
-		updateFmtTypeStreamSrc(stream, rtkCommon.XCLIP_CB)
-		noticeFmtTypeStreamReady(stream.Conn().RemotePeer().String(), rtkCommon.XCLIP_CB)
-	})）
+		handlerXClipStream(id, stream)
+	}))
 
 	node.SetStreamHandler(protocol.ID(rtkGlobal.ProtocolFileTransmission), network.StreamHandler(func(stream network.Stream) {
-		updateFmtTypeStreamSrc(stream, rtkCommon.FILE_DROP)
-		noticeFmtTypeStreamReady(stream.Conn().RemotePeer().String(), rtkCommon.FILE_DROP)
+		id := stream.Conn().RemotePeer().String()
+		if !isTrustedPeer(id) {
+			log.Printf("[%s] ID:[%s] is not trusted, reset file stream", rtkMisc.GetFuncInfo(), id)
+			stream.Reset()
+			return
+		}
+		updateFmtTypeStreamSrc(id, stream, rtkCommon.FILE_DROP)
+		noticeFmtTypeStreamReady(id, rtkCommon.FILE_DROP)
+	}))
+
+	if fileTransNode == nil {
+		log.Printf("[%s] fileTransNode is nil! QuicXClip is not available", rtkMisc.GetFuncInfo())
+		return
+	}
+
+	// XClip stream from the peer which negotiated QuicXClip, the remote peer is its fileTransNode
+	fileTransNode.SetStreamHandler(protocol.ID(rtkGlobal.ProtocolImageTransmission), network.StreamHandler(func(stream network.Stream) {
+		fileTransId := stream.Conn().RemotePeer().String()
+		id, ok := rtkUtils.GetClientIdByFileTransId(fileTransId)
+		if !ok {
+			log.Printf("[%s] not found client by file trans node ID:[%s], reset XClip stream", rtkMisc.GetFuncInfo(), fileTransId)
+			stream.Reset()
+			return
+		}
+		if !isTrustedPeer(id) {
+			log.Printf("[%s] ID:[%s] is not trusted, reset XClip stream", rtkMisc.GetFuncInfo(), id)
+			stream.Reset()
+			return
+		}
+		handlerXClipStream(id, stream)
 	}))
 }
 
+func handlerXClipStream(id string, stream network.Stream) {
+	if isLastXClipStreamExisted(id) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	updateFmtTypeStreamSrc(id, stream, rtkCommon.XCLIP_CB)
+	noticeFmtTypeStreamReady(id, rtkCommon.XCLIP_CB)
+}
+
 func BuildFileDropItemStreamListener(timestamp uint64) {
 	nodeMutex.Lock()
 	defer nodeMutex.Unlock()
@@ -353,7 +391,19 @@ func handlerFileDropItemStream(stream network.Stream) {
 		log.Printf("[%s] ID:[%s] Stream ID:[%s] json.NewDecoder.Decode err:%+v", rtkMisc.GetFuncInfo(), stream.Conn().RemotePeer().String(), stream.ID(), err)
 		return
 	}
+	// the remote peer is the fileTransNode of client, it must be the trusted client which the stream claims to be
+	fileTransId := stream.Conn().RemotePeer().String()
+	id, ok := rtkUtils.GetClientIdByFileTransId(fileTransId)
+	if !ok || id != reqMsg.ID || !isTrustedPeer(id) {
+		log.Printf("[%s] file trans node ID:[%s] client ID:[%s] is not the trusted ID:[%s], reset file drop stream", rtkMisc.GetFuncInfo(), fileTransId, id, reqMsg.ID)
+		stream.Reset()
+		return
+	}
 	reqMsg.StreamId = stream.ID()
+	if reqMsg.ChunkIndex > 0 {
+		addFileDropChunkStream(reqMsg.ID, reqMsg.Timestamp, reqMsg.ChunkIndex, stream)
+		return
+	}
 	addFileDropItemStreamAsSrc(reqMsg.ID, reqMsg.Timestamp, stream)
 	noticeFmtTypeStreamReady(reqMsg.ID, rtkCommon.FILE_DROP)
 }
@@ -368,35 +418,57 @@ func NewFileDropItemStream(ctxMain context.Context, id string, timestamp uint64)
 		return errCode
 	}
 
+	// chunk streams are opened before the item stream, src is ready to send when the item stream is noticed
+	if rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityChunkedFile) {
+		for chunkIndex := 1; chunkIndex <= rtkGlobal.FileDropChunkStreamCount; chunkIndex++ {
+			stream, errCode := newFileDropStream(ctx, id, quicNodePeer, timestamp, chunkIndex)
+			if errCode != rtkMisc.SUCCESS {
+				return errCode
+			}
+			addFileDropChunkStream(id, timestamp, chunkIndex, stream)
+		}
+	}
+
+	stream, errCode := newFileDropStream(ctx, id, quicNodePeer, timestamp, 0)
+	if errCode != rtkMisc.SUCCESS {
+		return errCode
+	}
+
+	addFileDropItemStreamAsDst(id, timestamp, stream)
+	log.Printf("ID:[%s] new a file drop stream success! use [%d] ms", id, time.Now().UnixMilli()-startTime)
+	return rtkMisc.SUCCESS
+}
+
+func newFileDropStream(ctx context.Context, id string, quicNodePeer *peer.AddrInfo, timestamp uint64, chunkIndex int) (network.Stream, rtkMisc.CrossShareErr) {
 	protocolId := getFileDropStreamProtocol(timestamp)
 	stream, err := fileTransNode.NewStream(ctx, quicNodePeer.ID, protocol.ID(protocolId))
 	if err != nil {
 		log.Printf("[%s] ID:[%s] IP:[%+v] open protocolId:%s stream failed:%+v", rtkMisc.GetFuncInfo(), id, quicNodePeer.Addrs, protocolId, err)
 		if errors.Is(err, context.DeadlineExceeded) {
-			return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_DEADLINE
+			return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_DEADLINE
 		} else if errors.Is(err, context.Canceled) {
-			return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_CANCEL
+			return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_CANCEL
 		}
-		return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM
+		return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM
 	}
 	Writer := bufio.NewWriter(stream)
 	registMsg := FileDropItemStreamInfo{
-		Timestamp: timestamp,
-		ID:        rtkGlobal.NodeInfo.ID,
-		StreamId:  stream.ID(),
+		Timestamp:  timestamp,
+		ID:         rtkGlobal.NodeInfo.ID,
+		StreamId:   stream.ID(),
+		ChunkIndex: chunkIndex,
 	}
 	if err = json.NewEncoder(Writer).Encode(registMsg); err != nil {
 		log.Println("failed to send register message: %w", err)
-		return rtkMisc.ERR_NETWORK_P2P_WRITER
+		stream.Reset()
+		return nil, rtkMisc.ERR_NETWORK_P2P_WRITER
 	}
 	if err = Writer.Flush(); err != nil {
 		log.Printf("[%s] ID:[%s] Error flushing write buffer: %+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_NETWORK_P2P_FLUSH
+		stream.Reset()
+		return nil, rtkMisc.ERR_NETWORK_P2P_FLUSH
 	}
-
-	addFileDropItemStreamAsDst(id, timestamp, stream)
-	log.Printf("ID:[%s] new a file drop stream success! use [%d] ms", id, time.Now().UnixMilli()-startTime)
-	return rtkMisc.SUCCESS
+	return stream, rtkMisc.SUCCESS
 }
 
 func getFileDropStreamProtocol(timestamp uint64) string {
@@ -417,11 +489,21 @@ func buildTalker(ctxMain context.Context, client rtkMisc.ClientInfo) rtkMisc.Cro
 		Addrs: []ma.Multiaddr{addr},
 	}
 
+	stream, errCode := newDirectStream(ctxMain, peer, ip)
+	if errCode != rtkMisc.SUCCESS || stream == nil {
+		return errCode
+	}
+	// nodeMutex is not held here, onlineEvent may wait for the pairing confirmation
+	return onlineEvent(ctxMain, stream, false, &client)
+}
+
+// newDirectStream returns nil stream and SUCCESS if the stream with peer is already existed
+func newDirectStream(ctxMain context.Context, peer peer.AddrInfo, ip string) (network.Stream, rtkMisc.CrossShareErr) {
 	nodeMutex.Lock()
 	defer nodeMutex.Unlock()
 	if node == nil {
 		log.Printf("[%s] node is nil!", rtkMisc.GetFuncInfo())
-		return rtkMisc.ERR_BIZ_P2P_NODE_NULL
+		return nil, rtkMisc.ERR_BIZ_P2P_NODE_NULL
 	}
 	startTime := time.Now().UnixMilli()
 	ctx, cancel := context.WithTimeout(ctxMain, ctxTimeout_normal)
@@ -430,19 +512,19 @@ func buildTalker(ctxMain context.Context, client rtkMisc.ClientInfo) rtkMisc.Cro
 	if node.Network().Connectedness(peer.ID) != network.Connected {
 		node.Network().ClosePeer(peer.ID)
 		log.Printf("begin to connect %+v ...", peer)
-		if err = node.Connect(ctx, peer); err != nil {
+		if err := node.Connect(ctx, peer); err != nil {
 			log.Printf("[%s] Connect peer%+v failed:%+v", rtkMisc.GetFuncInfo(), peer, err)
 			if errors.Is(err, context.DeadlineExceeded) {
-				return rtkMisc.ERR_NETWORK_P2P_CONNECT_DEADLINE
+				return nil, rtkMisc.ERR_NETWORK_P2P_CONNECT_DEADLINE
 			} else if errors.Is(err, context.Canceled) {
-				return rtkMisc.ERR_NETWORK_P2P_CONNECT_CANCEL
+				return nil, rtkMisc.ERR_NETWORK_P2P_CONNECT_CANCEL
 			} else if netErr, ok := err.(net.Error); ok {
 				log.Printf("[Socket][%s] Err: Read fail network error(%v)", rtkMisc.GetFuncInfo(), netErr.Error())
 				if netErr.Timeout() {
-					return rtkMisc.ERR_NETWORK_P2P_TIMEOUT
+					return nil, rtkMisc.ERR_NETWORK_P2P_TIMEOUT
 				}
 			}
-			return rtkMisc.ERR_NETWORK_P2P_CONNECT
+			return nil, rtkMisc.ERR_NETWORK_P2P_CONNECT
 		}
 		log.Printf("connect %s success! use [%d] ms", peer.ID.String(), time.Now().UnixMilli()-startTime)
 	}
@@ -450,22 +532,22 @@ func buildTalker(ctxMain context.Context, client rtkMisc.ClientInfo) rtkMisc.Cro
 	if IsStreamExisted(peer.ID.String()) {
 		// DEBUG
 		// log.Printf("[%s] ID:[%s] a Stream is already existed, skip NewStream.", rtkMisc.GetFuncInfo(), peer.ID.String())
-		return rtkMisc.SUCCESS
+		return nil, rtkMisc.SUCCESS
 	}
 
 	stream, err := node.NewStream(ctx, peer.ID, protocol.ID(rtkGlobal.ProtocolDirectID))
 	if err != nil {
 		log.Printf("[%s] ID:[%s] open a stream failed:%+v", rtkMisc.GetFuncInfo(), peer.ID.String(), err)
 		if errors.Is(err, context.DeadlineExceeded) {
-			return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_DEADLINE
+			return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_DEADLINE
 		} else if errors.Is(err, context.Canceled) {
-			return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_CANCEL
+			return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM_CANCEL
 		}
-		return rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM
+		return nil, rtkMisc.ERR_NETWORK_P2P_OPEN_STREAM
 	}
 
 	log.Printf("IP:[%s] open a stream success! use [%d] ms", ip, time.Now().UnixMilli()-startTime)
-	return onlineEvent(ctxMain, stream, false, &client)
+	return stream, rtkMisc.SUCCESS
 }
 
 func BuildFmtTypeTalker(ctx context.Context, id string, fmtType rtkCommon.TransFmtType) rtkMisc.CrossShareErr {
@@ -597,20 +679,24 @@ func ReadSocket(id string, buffer []byte) (int, rtkMisc.CrossShareErr) {
 	sInfo.s.SetReadDeadline(time.Time{}) //Cancel timeout limit
 	n, err := sInfo.s.Read(buffer)
 	if err != nil {
-		if CheckStreamReset(id, sInfo.timeStamp) {
-			log.Printf("[%s] IP:[%s] Read failed [%+v], stream is reset, so continue! ", rtkMisc.GetFuncInfo(), sInfo.ipAddr, err)
-			return 0, rtkMisc.ERR_BIZ_GET_STREAM_RESET
-		}
-
-		log.Printf("[%s][%s] Read failed [%+v],  execute offlineEvent ", rtkMisc.GetFuncInfo(), sInfo.ipAddr, err)
-		isEOF, errCode := isTcpEOF(id, err)
-		offlineEvent(sInfo.s, isEOF)
-		return 0, errCode
+		return 0, handleReadSocketErr(id, sInfo, err)
 	}
 
 	return n, rtkMisc.SUCCESS
 }
 
+func handleReadSocketErr(id string, sInfo streamInfo, err error) rtkMisc.CrossShareErr {
+	if CheckStreamReset(id, sInfo.timeStamp) {
+		log.Printf("[%s] IP:[%s] Read failed [%+v], stream is reset, so continue! ", rtkMisc.GetFuncInfo(), sInfo.ipAddr, err)
+		return rtkMisc.ERR_BIZ_GET_STREAM_RESET
+	}
+
+	log.Printf("[%s][%s] Read failed [%+v],  execute offlineEvent ", rtkMisc.GetFuncInfo(), sInfo.ipAddr, err)
+	isEOF, errCode := isTcpEOF(id, err)
+	offlineEvent(sInfo.s, isEOF)
+	return errCode
+}
+
 func onlineEvent(ctx context.Context, stream network.Stream, isFromListener bool, clientInfo *rtkMisc.ClientInfo) rtkMisc.CrossShareErr {
 	id := stream.Conn().RemotePeer().String()
 	mutex := getMutex(id)
@@ -619,15 +705,16 @@ func onlineEvent(ctx context.Context, stream network.Stream, isFromListener bool
 
 	ipAddr := rtkUtils.GetRemoteAddrFromStream(stream)
 	var peerDeviceName, peerPlatForm, srcPortType, peerVer, peerFileTransID, peerUdpPort string
+	var peerCapabilities []string
 	if isFromListener {
-		resultCode := handleNotice(stream, &peerPlatForm, &peerDeviceName, &srcPortType, &peerVer, &peerFileTransID, &peerUdpPort)
+		resultCode := handleNotice(stream, &peerPlatForm, &peerDeviceName, &srcPortType, &peerVer, &peerFileTransID, &peerUdpPort, &peerCapabilities)
 		if resultCode != rtkMisc.SUCCESS {
 			stream.Reset()
 			log.Printf("[%s] ID:[%s] IP:[%s] errCode:%d, so reset this stream, onlineEvent failed!", rtkMisc.GetFuncInfo(), id, ipAddr, resultCode)
 			return resultCode
 		}
 	} else {
-		resultCode := noticeToPeer(stream, &peerVer, &peerFileTransID, &peerUdpPort)
+		resultCode := noticeToPeer(stream, &peerVer, &peerFileTransID, &peerUdpPort, &peerCapabilities)
 		if resultCode != rtkMisc.SUCCESS {
 			stream.Reset()
 			log.Printf("[%s] ID:[%s] IP:[%s] errCode:%d, so reset this stream, onlineEvent failed!", rtkMisc.GetFuncInfo(), id, ipAddr, resultCode)
@@ -638,7 +725,14 @@ func onlineEvent(ctx context.Context, stream network.Stream, isFromListener bool
 		srcPortType = clientInfo.SourcePortType
 	}
 
-	updateStream(ctx, id, stream)
+	if resultCode := checkPeerTrust(id, ipAddr, peerDeviceName, peerPlatForm); resultCode != rtkMisc.SUCCESS {
+		stream.Reset()
+		log.Printf("[%s] ID:[%s] IP:[%s] errCode:%d, peer is not trusted, so reset this stream, onlineEvent failed!", rtkMisc.GetFuncInfo(), id, ipAddr, resultCode)
+		return resultCode
+	}
+
+	isFramedMsg := slices.Contains(rtkUtils.NegotiateCapabilities(peerCapabilities), rtkCommon.CapabilityFramedMsg)
+	updateStream(ctx, id, stream, isFramedMsg)
 	log.Println("****************************************************************************************")
 	if isFromListener {
 		log.Println("Connected from ID:", id, " IP:", ipAddr)
@@ -647,7 +741,7 @@ func onlineEvent(ctx context.Context, stream network.Stream, isFromListener bool
 	}
 	log.Println("****************************************************************************************")
 
-	updateUIOnlineStatus(true, id, ipAddr, peerPlatForm, peerDeviceName, srcPortType, peerVer, peerFileTransID, peerUdpPort)
+	updateUIOnlineStatus(true, id, ipAddr, peerPlatForm, peerDeviceName, srcPortType, peerVer, peerFileTransID, peerUdpPort, peerCapabilities)
 	return rtkMisc.SUCCESS
 }
 
@@ -662,7 +756,7 @@ func offlineEvent(stream network.Stream, isFromPeer bool) {
 
 	clientInfo, err := rtkUtils.GetClientInfo(id)
 	if err == nil {
-		updateUIOnlineStatus(false, id, clientInfo.IpAddr, clientInfo.Platform, clientInfo.DeviceName, clientInfo.SourcePortType, "", "", "")
+		updateUIOnlineStatus(false, id, clientInfo.IpAddr, clientInfo.Platform, clientInfo.DeviceName, clientInfo.SourcePortType, "", "", "", nil)
 	} else {
 		log.Printf("[%s] %s, so not need updateUIOnlineStatus!", rtkMisc.GetFuncInfo(), err.Error())
 		return
@@ -683,10 +777,10 @@ func OfflineEvent(id string) {
 	offlineEvent(s, true)
 }
 
-func updateUIOnlineStatus(isOnline bool, id, ipAddr, platfrom, deviceName, srcPortType, ver, fileTransId, udpPort string) {
+func updateUIOnlineStatus(isOnline bool, id, ipAddr, platfrom, deviceName, srcPortType, ver, fileTransId, udpPort string, capabilities []string) {
 	if isOnline {
 		log.Printf("[%s] IP:[%s] Online: increase client count\n\n", rtkMisc.GetFuncInfo(), ipAddr)
-		rtkUtils.InsertClientInfoMap(id, ipAddr, platfrom, deviceName, srcPortType, ver, fileTransId, udpPort)
+		rtkUtils.InsertClientInfoMap(id, ipAddr, platfrom, deviceName, srcPortType, ver, fileTransId, udpPort, capabilities)
 		rtkPlatform.GoUpdateClientStatusEx(id, 1)
 	} else {
 		log.Printf("[%s] IP:[%s] Offline: decrease client count", rtkMisc.GetFuncInfo(), ipAddr)
@@ -695,7 +789,7 @@ func updateUIOnlineStatus(isOnline bool, id, ipAddr, platfrom, deviceName, srcPo
 	}
 }
 
-func noticeToPeer(s network.Stream, ver, fileTransId, udpPort *string) rtkMisc.CrossShareErr {
+func noticeToPeer(s network.Stream, ver, fileTransId, udpPort *string, capabilities *[]string) rtkMisc.CrossShareErr {
 	ipAddr := rtkUtils.GetRemoteAddrFromStream(s)
 	id := s.Conn().RemotePeer().String()
 	registMsg := rtkCommon.RegistMdnsMessage{
@@ -707,6 +801,7 @@ func noticeToPeer(s network.Stream, ver, fileTransId, udpPort *string) rtkMisc.C
 		Version:         rtkGlobal.ClientVersion,
 		FileTransNodeID: rtkGlobal.NodeInfo.FileTransNodeID,
 		UdpPort:         rtkGlobal.NodeInfo.IPAddr.UpdPort,
+		Capabilities:    rtkUtils.GetClientCapabilities(),
 	}
 
 	write := bufio.NewWriter(s)
@@ -747,16 +842,17 @@ func noticeToPeer(s network.Stream, ver, fileTransId, udpPort *string) rtkMisc.C
 			return rtkMisc.ERR_NETWORK_P2P_READER
 		}
 	} else {
-		log.Printf("[%s] IP:[%s] handle decoder success! verison:%s", rtkMisc.GetFuncInfo(), ipAddr, reqMsg.Version)
+		log.Printf("[%s] IP:[%s] handle decoder success! verison:%s capabilities:%v", rtkMisc.GetFuncInfo(), ipAddr, reqMsg.Version, reqMsg.Capabilities)
 		*ver = reqMsg.Version
 		*fileTransId = reqMsg.FileTransNodeID
 		*udpPort = reqMsg.UdpPort
+		*capabilities = reqMsg.Capabilities
 	}
 
 	return rtkMisc.SUCCESS
 }
 
-func handleNotice(s network.Stream, platForm, name, srcPortType, ver, fileTransId, udpPort *string) rtkMisc.CrossShareErr {
+func handleNotice(s network.Stream, platForm, name, srcPortType, ver, fileTransId, udpPort *string, capabilities *[]string) rtkMisc.CrossShareErr {
 	id := s.Conn().RemotePeer().String()
 	ipAddr := rtkUtils.GetRemoteAddrFromStream(s)
 
@@ -780,6 +876,7 @@ func handleNotice(s network.Stream, platForm, name, srcPortType, ver, fileTransI
 	*srcPortType = regMsg.SourcePortType
 	*fileTransId = regMsg.FileTransNodeID
 	*udpPort = regMsg.UdpPort
+	*capabilities = regMsg.Capabilities
 
 	if regMsg.Version == "" { // old version
 		*ver = rtkGlobal.ClientDefaultVersion
@@ -795,6 +892,7 @@ func handleNotice(s network.Stream, platForm, name, srcPortType, ver, fileTransI
 			Version:         rtkGlobal.ClientVersion,
 			FileTransNodeID: rtkGlobal.NodeInfo.FileTransNodeID,
 			UdpPort:         rtkGlobal.NodeInfo.IPAddr.UpdPort,
+			Capabilities:    rtkUtils.GetClientCapabilities(),
 		}
 
 		write := bufio.NewWriter(s)
@@ -813,7 +911,7 @@ func handleNotice(s network.Stream, platForm, name, srcPortType, ver, fileTransI
 			log.Printf("[%s] ID:[%s] Error flushing write buffer: %+v", rtkMisc.GetFuncInfo(), id, err)
 			return rtkMisc.ERR_NETWORK_P2P_FLUSH
 		}
-		log.Printf("[%s] IP:[%s] handle encoder success! version:%s", rtkMisc.GetFuncInfo(), ipAddr, regMsg.Version)
+		log.Printf("[%s] IP:[%s] handle encoder success! version:%s capabilities:%v", rtkMisc.GetFuncInfo(), ipAddr, regMsg.Version, regMsg.Capabilities)
 	}
 
 	return rtkMisc.SUCCESS
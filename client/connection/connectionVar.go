@@ -4,6 +4,7 @@ import (
 	"context"
 	rtkCommon "rtk-cross-share/client/common"
 	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
 	"sync"
 	"time"
 
@@ -17,9 +18,10 @@ const (
 )
 
 type FileDropItemStreamInfo struct {
-	Timestamp uint64
-	ID        string
-	StreamId  string // stream ID
+	Timestamp  uint64
+	ID         string
+	StreamId   string // stream ID
+	ChunkIndex int    // 0 is the item stream, 1~FileDropChunkStreamCount are the streams of chunked file
 }
 
 type (
@@ -27,6 +29,8 @@ type (
 	callbackSendDisconnectMsgToPeerFunc func(id string)
 )
 
+var logger = rtkMisc.NewLogger("connection")
+
 var (
 	node          host.Host
 	fileTransNode host.Host
@@ -0,0 +1,113 @@
+package connection
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+// Framed Peer2PeerMessage: [4 bytes payload length, big endian][payload]
+// Only used with the peer which negotiated CapabilityFramedMsg, otherwise one read is one message and the length is limited by P2PMsgMaxLength
+const p2pMsgFrameHeaderLen = 4
+
+var p2pMsgBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, rtkGlobal.P2PMsgMaxLength)
+		return &buf
+	},
+}
+
+func getMsgBuffer(size int) *[]byte {
+	buf := p2pMsgBufferPool.Get().(*[]byte)
+	if cap(*buf) < size {
+		p2pMsgBufferPool.Put(buf)
+		newBuf := make([]byte, size)
+		return &newBuf
+	}
+	*buf = (*buf)[:size]
+	return buf
+}
+
+// PutMsgBuffer gives back the buffer from ReadSocketMsg after the message is handled
+func PutMsgBuffer(buf *[]byte) {
+	if buf == nil || cap(*buf) > rtkGlobal.P2PMsgPoolBufferMaxLength {
+		return
+	}
+	*buf = (*buf)[:cap(*buf)]
+	p2pMsgBufferPool.Put(buf)
+}
+
+func IsFramedMsgStream(id string) bool {
+	sInfo, ok := GetStreamInfo(id)
+	if !ok {
+		return false
+	}
+	return sInfo.isFramedMsg
+}
+
+// WriteSocketMsg writes one Peer2PeerMessage, with a length header if the peer negotiated FramedMsg
+func WriteSocketMsg(id string, data []byte) rtkMisc.CrossShareErr {
+	if !IsFramedMsgStream(id) {
+		if len(data) > rtkGlobal.P2PMsgMaxLength {
+			log.Printf("[%s] ID:[%s] message length:[%d] is over range:[%d], peer is not support FramedMsg", rtkMisc.GetFuncInfo(), id, len(data), rtkGlobal.P2PMsgMaxLength)
+			return rtkMisc.ERR_BIZ_P2P_MSG_OVER_RANGE
+		}
+		return WriteSocket(id, data)
+	}
+
+	if len(data) > rtkGlobal.P2PMsgFrameMaxLength {
+		log.Printf("[%s] ID:[%s] message length:[%d] is over range:[%d]", rtkMisc.GetFuncInfo(), id, len(data), rtkGlobal.P2PMsgFrameMaxLength)
+		return rtkMisc.ERR_BIZ_P2P_MSG_OVER_RANGE
+	}
+
+	buf := getMsgBuffer(p2pMsgFrameHeaderLen + len(data))
+	defer PutMsgBuffer(buf)
+	binary.BigEndian.PutUint32((*buf)[:p2pMsgFrameHeaderLen], uint32(len(data)))
+	copy((*buf)[p2pMsgFrameHeaderLen:], data)
+	return WriteSocket(id, *buf)
+}
+
+// ReadSocketMsg reads one Peer2PeerMessage, the returned buffer must be given back by PutMsgBuffer
+func ReadSocketMsg(id string) (*[]byte, rtkMisc.CrossShareErr) {
+	sInfo, ok := GetStreamInfo(id)
+	if !ok {
+		return nil, rtkMisc.ERR_BIZ_GET_STREAM_EMPTY
+	}
+
+	if !sInfo.isFramedMsg {
+		buf := getMsgBuffer(rtkGlobal.P2PMsgMaxLength)
+		nLen, errCode := ReadSocket(id, *buf)
+		if errCode != rtkMisc.SUCCESS {
+			PutMsgBuffer(buf)
+			return nil, errCode
+		}
+		*buf = (*buf)[:nLen]
+		return buf, rtkMisc.SUCCESS
+	}
+
+	sInfo.s.SetReadDeadline(time.Time{}) //Cancel timeout limit
+	var header [p2pMsgFrameHeaderLen]byte
+	if _, err := io.ReadFull(sInfo.s, header[:]); err != nil {
+		return nil, handleReadSocketErr(id, sInfo, err)
+	}
+
+	nLen := binary.BigEndian.Uint32(header[:])
+	if nLen == 0 || nLen > rtkGlobal.P2PMsgFrameMaxLength {
+		// the rest of stream can not be located any more, reset it and wait for peer reconnect
+		log.Printf("[%s] ID:[%s] IP:[%s] invalid frame length:[%d], reset stream and execute offlineEvent", rtkMisc.GetFuncInfo(), id, sInfo.ipAddr, nLen)
+		sInfo.s.Reset()
+		offlineEvent(sInfo.s, false)
+		return nil, rtkMisc.ERR_BIZ_P2P_MSG_FRAME_INVALID
+	}
+
+	buf := getMsgBuffer(int(nLen))
+	if _, err := io.ReadFull(sInfo.s, *buf); err != nil {
+		PutMsgBuffer(buf)
+		return nil, handleReadSocketErr(id, sInfo, err)
+	}
+	return buf, rtkMisc.SUCCESS
+}
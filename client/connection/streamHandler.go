@@ -3,14 +3,17 @@ package connection
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net"
 	rtkCommon "rtk-cross-share/client/common"
 	rtkGlobal "rtk-cross-share/client/global"
+	rtkMetrics "rtk-cross-share/client/metrics"
 	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -35,15 +38,17 @@ type streamInfo struct {
 	sFileDrop      network.Stream
 	sImage         network.Stream
 	transFileState TransFileStateType
+	isFramedMsg    bool // Peer2PeerMessage with length header, negotiated by CapabilityFramedMsg
 
 	cancelFn func(source rtkCommon.CancelBusinessSource)
 	cxt      context.Context
 }
 
 var (
-	streamPoolMap           = make(map[string](streamInfo))              //client stream map
-	clientFileDataStreamMap = make(map[string]map[uint64]network.Stream) // client file transfer data stream map
-	streamPoolMutex         sync.RWMutex
+	streamPoolMap            = make(map[string](streamInfo))                //client stream map
+	clientFileDataStreamMap  = make(map[string]map[uint64]network.Stream)   // client file transfer data stream map
+	clientFileChunkStreamMap = make(map[string]map[uint64][]network.Stream) // client chunked file data streams, index is ChunkIndex-1
+	streamPoolMutex          sync.RWMutex
 )
 
 func init() {
@@ -53,6 +58,7 @@ func init() {
 
 func CheckAllStreamAlive(ctx context.Context) {
 	pingFailFunc := func(key string, sInfo streamInfo) {
+		rtkMetrics.IncPingFailure(key)
 		if CheckStreamReset(key, sInfo.timeStamp) { // if stream is update, not need go through this flow
 			return
 		}
@@ -79,15 +85,17 @@ func CheckAllStreamAlive(ctx context.Context) {
 			select {
 			case pingResult := <-pingServer.Ping(pingCtx, sInfo.s.Conn().RemotePeer()):
 				if pingResult.Error != nil {
-					log.Printf("[%s] IP[%s] Ping err:%+v", rtkMisc.GetFuncInfo(), sInfo.ipAddr, pingResult.Error)
+					logger.Warn("ping failed", rtkMisc.PeerIDAttr(key), rtkMisc.IPAttr(sInfo.ipAddr), rtkMisc.ErrAttr(pingResult.Error))
 					pingFailFunc(key, sInfo)
 				} else {
+					rtkMetrics.ObservePingRtt(key, pingResult.RTT)
 					if sInfo.pingErrCnt > 0 {
-						log.Printf("[%s] ID:[%s] IP:[%s]  RTT [%d]ms", rtkMisc.GetFuncInfo(), sInfo.s.Conn().RemotePeer().String(), sInfo.ipAddr, pingResult.RTT.Milliseconds())
+						logger.Info("ping recovered", rtkMisc.PeerIDAttr(key), rtkMisc.IPAttr(sInfo.ipAddr), "rtt_ms", pingResult.RTT.Milliseconds())
 						updateStreamPingErrCntReset(key)
 					}
 				}
 			case <-pingCtx.Done():
+				logger.Warn("ping timeout", rtkMisc.PeerIDAttr(key), rtkMisc.IPAttr(sInfo.ipAddr))
 				pingFailFunc(key, sInfo)
 			}
 
@@ -95,18 +103,18 @@ func CheckAllStreamAlive(ctx context.Context) {
 	}
 }
 
-func updateStream(ctx context.Context, id string, stream network.Stream) {
+func updateStream(ctx context.Context, id string, stream network.Stream, isFramedMsg bool) {
 	streamPoolMutex.Lock()
 	defer streamPoolMutex.Unlock()
 
 	ipAddr := rtkUtils.GetRemoteAddrFromStream(stream)
 	if oldSinfo, ok := streamPoolMap[id]; ok {
-		log.Printf("[%s] UpdateStream ID:%s  IP:[%s],Stream existed, the old streamID:[%s] ", rtkMisc.GetFuncInfo(), id, ipAddr, oldSinfo.s.ID())
+		logger.Info("update stream, old stream existed", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), "stream_id", oldSinfo.s.ID())
 		if oldSinfo.cancelFn != nil {
 			if oldSinfo.cxt.Err() == nil {
 				oldSinfo.cancelFn(rtkCommon.OldP2PBusinessCancel)
 			}
-			log.Printf("[%s] UpdateStream ID:[%s] IP:[%s], ProcessForPeer existed, Cancel the old StartProcessForPeer first!", rtkMisc.GetFuncInfo(), id, ipAddr)
+			logger.Info("update stream, cancel the old ProcessForPeer first", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr))
 		}
 		if oldSinfo.sImage != nil {
 			oldSinfo.sImage.Close()
@@ -124,6 +132,7 @@ func updateStream(ctx context.Context, id string, stream network.Stream) {
 		sFileDrop:      nil,
 		sImage:         nil,
 		transFileState: TRANS_FILE_NOT_PREFORMED,
+		isFramedMsg:    isFramedMsg,
 		cancelFn:       callbackStartProcessForPeer(ctx, id, ipAddr), // StartProcessForPeer
 		cxt:            ctx,
 	}
@@ -133,13 +142,14 @@ func updateStream(ctx context.Context, id string, stream network.Stream) {
 			itemStream.CloseRead()
 			itemStream.Close()
 			delete(fileStreamMap, timestamp)
-			log.Printf("[%s] ID:[%s] close old file drop Item stream success! timestamp:[%d] id:[%s]!", rtkMisc.GetFuncInfo(), id, timestamp, itemStream.ID())
+			logger.Info("close old file drop item stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "stream_id", itemStream.ID())
 		}
 		clientFileDataStreamMap[id] = fileStreamMap
 	}
 
 	clientFileDataStreamMap[id] = make(map[uint64]network.Stream)
-	log.Printf("updateStream ID:[%s] IP:[%s] streamID:[%s]", id, ipAddr, stream.ID())
+	closeFileDropChunkStreams(id, nil)
+	logger.Info("update stream", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), "stream_id", stream.ID())
 }
 
 func GetStreamInfo(id string) (streamInfo, bool) {
@@ -200,7 +210,76 @@ func addFileDropItemStream(id string, timestamp uint64, stream network.Stream, i
 		streamPoolMap[id] = sInfo
 	}
 
-	log.Printf("[%s] ID:[%s] add file drop Item stream success! timestamp:%d ID:[%s]", rtkMisc.GetFuncInfo(), id, timestamp, stream.ID())
+	logger.Info("add file drop item stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "stream_id", stream.ID())
+}
+
+func addFileDropChunkStream(id string, timestamp uint64, chunkIndex int, stream network.Stream) {
+	if chunkIndex <= 0 || chunkIndex > rtkGlobal.FileDropChunkStreamCount {
+		logger.Warn("invalid chunk index, close stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "chunk_index", chunkIndex)
+		stream.Reset()
+		return
+	}
+
+	streamPoolMutex.Lock()
+	defer streamPoolMutex.Unlock()
+	chunkStreamMap, ok := clientFileChunkStreamMap[id]
+	if !ok {
+		chunkStreamMap = make(map[uint64][]network.Stream)
+		clientFileChunkStreamMap[id] = chunkStreamMap
+	}
+	chunkStreams, ok := chunkStreamMap[timestamp]
+	if !ok {
+		chunkStreams = make([]network.Stream, rtkGlobal.FileDropChunkStreamCount)
+	}
+	if chunkStreams[chunkIndex-1] != nil {
+		chunkStreams[chunkIndex-1].Reset()
+	}
+	chunkStreams[chunkIndex-1] = stream
+	chunkStreamMap[timestamp] = chunkStreams
+	logger.Info("add file drop chunk stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "chunk_index", chunkIndex, "stream_id", stream.ID())
+}
+
+// GetFileDropChunkStreams returns all chunk streams of the item, wait at most timeout for the streams opened by dst
+func GetFileDropChunkStreams(id string, timestamp uint64, timeout time.Duration) ([]network.Stream, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		streamPoolMutex.RLock()
+		chunkStreams := clientFileChunkStreamMap[id][timestamp]
+		isReady := len(chunkStreams) == rtkGlobal.FileDropChunkStreamCount
+		for _, stream := range chunkStreams {
+			if stream == nil {
+				isReady = false
+			}
+		}
+		streamPoolMutex.RUnlock()
+
+		if isReady {
+			return chunkStreams, true
+		}
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// closeFileDropChunkStreams must be called with streamPoolMutex locked, nil timestamp closes all chunk streams of ID
+func closeFileDropChunkStreams(id string, timestamp *uint64) {
+	chunkStreamMap, ok := clientFileChunkStreamMap[id]
+	if !ok {
+		return
+	}
+	for ts, chunkStreams := range chunkStreamMap {
+		if timestamp != nil && *timestamp != ts {
+			continue
+		}
+		for _, stream := range chunkStreams {
+			if stream != nil {
+				stream.Close()
+			}
+		}
+		delete(chunkStreamMap, ts)
+	}
 }
 
 func GetFileDropItemStream(id string, timestamp uint64) (network.Stream, bool) {
@@ -217,6 +296,7 @@ func GetFileDropItemStream(id string, timestamp uint64) (network.Stream, bool) {
 func CloseFileDropItemStream(id string, timestamp uint64) {
 	streamPoolMutex.Lock()
 	defer streamPoolMutex.Unlock()
+	closeFileDropChunkStreams(id, &timestamp)
 	if fileStreamMap, ok := clientFileDataStreamMap[id]; ok {
 		if itemStream, bOk := fileStreamMap[timestamp]; bOk {
 			itemStream.CloseRead()
@@ -227,19 +307,19 @@ func CloseFileDropItemStream(id string, timestamp uint64) {
 				if sInfo, ok := streamPoolMap[id]; ok {
 					sInfo.transFileState = TRANS_FILE_NOT_PREFORMED
 				}
-				log.Printf("[%s] ID:[%s] close file drop Item stream success! timestamp:[%d] id:[%s], all file drop Item stream done!", rtkMisc.GetFuncInfo(), id, timestamp, itemStream.ID())
+				logger.Info("close file drop item stream, all item streams done", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "stream_id", itemStream.ID())
 			} else {
-				log.Printf("[%s] ID:[%s] close file drop Item stream success! timestamp:[%d] id:[%s], still %d records left!", rtkMisc.GetFuncInfo(), id, timestamp, itemStream.ID(), nCount)
+				logger.Info("close file drop item stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "stream_id", itemStream.ID(), "left_count", nCount)
 			}
 
 			clientFileDataStreamMap[id] = fileStreamMap
 			return
 		} else {
-			log.Printf("[%s] ID:[%s] Unknown file drop Item stream, timestamp:[%d]", rtkMisc.GetFuncInfo(), id, timestamp)
+			logger.Warn("unknown file drop item stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp))
 			return
 		}
 	}
-	log.Printf("[%s] ID:[%s] Unknown clientFileDataStreamMap info, timestamp:%d", rtkMisc.GetFuncInfo(), id, timestamp)
+	logger.Warn("unknown file drop streams of peer", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp))
 }
 
 func IsQuicClose(err error) bool {
@@ -310,29 +390,30 @@ func isTcpEOF(id string, err error) (bool, rtkMisc.CrossShareErr) {
 func CloseAllFileDropStream(id string) {
 	nFlagCount := clearFmtTypeStreamReadyFlag(id, string(rtkCommon.FILE_DROP))
 	if nFlagCount > 0 {
-		log.Printf("ID[%s] Clear file drop StreamReadyFlag count:[%d]", id, nFlagCount)
+		logger.Info("clear file drop stream ready flag", rtkMisc.PeerIDAttr(id), "count", nFlagCount)
 	}
 
 	streamPoolMutex.Lock()
 	defer streamPoolMutex.Unlock()
+	closeFileDropChunkStreams(id, nil)
 	if fileStreamMap, ok := clientFileDataStreamMap[id]; ok {
 		for timestamp, itemStream := range fileStreamMap {
 			itemStream.Close()
 			delete(fileStreamMap, timestamp)
-			log.Printf("[%s] ID:[%s] close file drop Item stream success! timestamp:[%d] id:[%s]!", rtkMisc.GetFuncInfo(), id, timestamp, itemStream.ID())
+			logger.Info("close file drop item stream", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(timestamp), "stream_id", itemStream.ID())
 		}
 
 		clientFileDataStreamMap[id] = fileStreamMap
 		return
 	}
 
-	log.Printf("[%s] ID:[%s] Unknown clientFileDataStreamMap info", rtkMisc.GetFuncInfo(), id)
+	logger.Warn("unknown file drop streams of peer", rtkMisc.PeerIDAttr(id))
 }
 
-func updateFmtTypeStreamInternal(stream network.Stream, fmtType rtkCommon.TransFmtType, isDst bool) {
+// id is the peer node ID, it differs from stream remote peer when the stream is opened by fileTransNode(QUIC)
+func updateFmtTypeStreamInternal(id string, stream network.Stream, fmtType rtkCommon.TransFmtType, isDst bool) {
 	streamPoolMutex.Lock()
 	defer streamPoolMutex.Unlock()
-	id := stream.Conn().RemotePeer().String()
 	if sInfo, ok := streamPoolMap[id]; ok {
 		if fmtType == rtkCommon.XCLIP_CB {
 			if sInfo.sImage != nil {
@@ -362,12 +443,12 @@ func updateFmtTypeStreamInternal(stream network.Stream, fmtType rtkCommon.TransF
 		log.Printf("[%s] cannot found stream Info from streamPoolMap by ID:[%s]", rtkMisc.GetFuncInfo(), id)
 	}
 }
-func updateFmtTypeStreamSrc(stream network.Stream, fmtType rtkCommon.TransFmtType) {
-	updateFmtTypeStreamInternal(stream, fmtType, false)
+func updateFmtTypeStreamSrc(id string, stream network.Stream, fmtType rtkCommon.TransFmtType) {
+	updateFmtTypeStreamInternal(id, stream, fmtType, false)
 }
 
-func updateFmtTypeStreamDst(stream network.Stream, fmtType rtkCommon.TransFmtType) {
-	updateFmtTypeStreamInternal(stream, fmtType, true)
+func updateFmtTypeStreamDst(id string, stream network.Stream, fmtType rtkCommon.TransFmtType) {
+	updateFmtTypeStreamInternal(id, stream, fmtType, true)
 }
 
 func LastXClipStreamReset(id string) {
@@ -527,22 +608,22 @@ func closeStream(id string, isFromPeer bool) {
 			if isFromPeer {
 				if sInfo.cxt.Err() == nil {
 					sInfo.cancelFn(rtkCommon.PeerDisconnectCancel)
-					log.Printf("ID:[%s] IP:[%s] ProcessEventsForPeer is Cancel by peer disconnect! ", id, sInfo.ipAddr)
+					logger.Info("ProcessEventsForPeer is canceled by peer disconnect", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr))
 				}
 			} else {
 				if sInfo.cxt.Err() == nil { // tcp err
 					sInfo.cancelFn(rtkCommon.TcpNetworkCancel)
-					log.Printf("ID:[%s] IP:[%s] ProcessEventsForPeer is Cancel by TCP network err! ", id, sInfo.ipAddr)
+					logger.Info("ProcessEventsForPeer is canceled by TCP network err", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr))
 				}
 			}
 
 			sInfo.cancelFn = nil
 		}
 		delete(streamPoolMap, id)
-		log.Printf("ID:[%s] IP:[%s] CloseStream,  StreamID:[%s]", id, sInfo.ipAddr, sInfo.s.ID())
+		logger.Info("close stream", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr), "stream_id", sInfo.s.ID())
 		sInfo.s.Close()
 	} else {
-		log.Printf("[%s] Err: Unknown stream of id:%s", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("unknown stream", rtkMisc.PeerIDAttr(id))
 	}
 }
 
@@ -556,17 +637,17 @@ func CloseFmtTypeStream(id string, fmtType rtkCommon.TransFmtType) {
 				sInfo.sFileDrop = nil
 				sInfo.transFileState = TRANS_FILE_NOT_PREFORMED
 				streamPoolMap[id] = sInfo
-				log.Printf("[%s] ID:[%s] IP:[%s] fmtType:[%s] CloseFmtTypeStream success!", rtkMisc.GetFuncInfo(), id, sInfo.ipAddr, fmtType)
+				logger.Info("close fmtType stream", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr), "fmt_type", fmtType)
 			}
 		} else if fmtType == rtkCommon.XCLIP_CB {
 			if sInfo.sImage != nil {
 				sInfo.sImage.Close()
 				sInfo.sImage = nil
 				streamPoolMap[id] = sInfo
-				log.Printf("[%s] ID:[%s] IP:[%s] fmtType:[%s] CloseFmtTypeStream success!", rtkMisc.GetFuncInfo(), id, sInfo.ipAddr, fmtType)
+				logger.Info("close fmtType stream", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr), "fmt_type", fmtType)
 			}
 		} else {
-			log.Printf("[%s] ID:[%s] IP:[%s] Unknown fmtType:[%s], close fmtType stream error!", rtkMisc.GetFuncInfo(), id, sInfo.ipAddr, fmtType)
+			logger.Error("close fmtType stream failed, unknown fmtType", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr), "fmt_type", fmtType)
 			return
 		}
 	}
@@ -588,7 +669,7 @@ func ClosePeer(id string) {
 		}
 
 		if sInfo.cancelFn != nil { // StopProcessForPeer
-			log.Printf("ID:[%s] IP:[%s] ProcessEventsForPeer is Cancel! ", id, sInfo.ipAddr)
+			logger.Info("ProcessEventsForPeer is canceled", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr))
 			if sInfo.cxt.Err() == nil {
 				sInfo.cancelFn(rtkCommon.UpperLevelBusinessCancel)
 			}
@@ -596,29 +677,29 @@ func ClosePeer(id string) {
 		sInfo.s.Close()
 		node.Network().ClosePeer(sInfo.s.Conn().RemotePeer())
 		delete(streamPoolMap, id)
-		log.Println("ClosePeer id:", id)
+		logger.Info("close peer", rtkMisc.PeerIDAttr(id))
 	} else {
-		log.Printf("[%s] Err: Unknown stream of id:%s", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("unknown stream", rtkMisc.PeerIDAttr(id))
 	}
 }
 
 func closePeer(id string) {
 	idB58, err := peer.Decode(id)
 	if err != nil {
-		log.Printf("[%s] ID decode failed: %s", rtkMisc.GetFuncInfo(), id)
+		logger.Error("close peer failed, ID decode failed", rtkMisc.PeerIDAttr(id), rtkMisc.ErrAttr(err))
 		return
 	}
 
 	nodeMutex.RLock()
 	defer nodeMutex.RUnlock()
 	if node == nil {
-		log.Printf("[%s] node is nil!", rtkMisc.GetFuncInfo())
+		logger.Error("close peer failed, node is nil", rtkMisc.PeerIDAttr(id))
 		return
 	}
 
 	err = node.Network().ClosePeer(idB58)
 	if err != nil {
-		log.Printf("[%s] ClosePeer peer%s error:%+v", rtkMisc.GetFuncInfo(), id, err)
+		logger.Error("close peer failed", rtkMisc.PeerIDAttr(id), rtkMisc.ErrAttr(err))
 	}
 }
 
@@ -651,7 +732,7 @@ func CancelAllProcessForPeer() {
 		if sInfo.cancelFn != nil { // StopProcessForPeer
 			if sInfo.cxt.Err() == nil {
 				sInfo.cancelFn(rtkCommon.LanServerBusinessCancel) // will cancel all file transfer business
-				log.Printf("[%s] ID:[%s] IP:[%s] ProcessEventsForPeer is Cancel by LanServer disconnect! ", rtkMisc.GetFuncInfo(), id, sInfo.ipAddr)
+				logger.Info("ProcessEventsForPeer is canceled by LanServer disconnect", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(sInfo.ipAddr))
 			}
 			sInfo.cancelFn = nil
 		}
@@ -659,7 +740,7 @@ func CancelAllProcessForPeer() {
 		nCount++
 	}
 
-	log.Printf("[%s] close stream count:%d", rtkMisc.GetFuncInfo(), nCount)
+	logger.Info("cancel all ProcessForPeer", "count", nCount)
 }
 
 func PrintfStreamPool() {
@@ -670,13 +751,49 @@ func PrintfStreamPool() {
 	}
 }
 
+// DumpStreamPool writes the stream pool and the file drop streams in text, it is for diagnostics
+func DumpStreamPool(w io.Writer) error {
+	streamPoolMutex.RLock()
+	defer streamPoolMutex.RUnlock()
+
+	idList := make([]string, 0, len(streamPoolMap))
+	for id := range streamPoolMap {
+		idList = append(idList, id)
+	}
+	sort.Strings(idList)
+	for _, id := range idList {
+		sInfo := streamPoolMap[id]
+		fmt.Fprintf(w, "ID:[%s] IP:[%s] streamID:[%s] updateTime:[%s] pingErrCnt:[%d] transFileState:[%s] framedMsg:[%+v] fileDropStream:[%+v] imageStream:[%+v] processAlive:[%+v]\n",
+			id, sInfo.ipAddr, sInfo.s.ID(), time.UnixMilli(sInfo.timeStamp).Format(time.RFC3339), sInfo.pingErrCnt, sInfo.transFileState,
+			sInfo.isFramedMsg, sInfo.sFileDrop != nil, sInfo.sImage != nil, sInfo.cxt != nil && sInfo.cxt.Err() == nil)
+	}
+
+	for id, fileStreamMap := range clientFileDataStreamMap {
+		for timestamp, itemStream := range fileStreamMap {
+			fmt.Fprintf(w, "file drop item stream ID:[%s] timestamp:[%d] streamID:[%s]\n", id, timestamp, itemStream.ID())
+		}
+	}
+	for id, chunkStreamMap := range clientFileChunkStreamMap {
+		for timestamp, chunkStreams := range chunkStreamMap {
+			nCount := 0
+			for _, chunkStream := range chunkStreams {
+				if chunkStream != nil {
+					nCount++
+				}
+			}
+			fmt.Fprintf(w, "file drop chunk streams ID:[%s] timestamp:[%d] count:[%d]\n", id, timestamp, nCount)
+		}
+	}
+	return nil
+}
+
 func updateStreamPingErrCntIncrease(id string) int {
 	streamPoolMutex.Lock()
 	defer streamPoolMutex.Unlock()
 	if sInfo, ok := streamPoolMap[id]; ok {
 		sInfo.pingErrCnt = sInfo.pingErrCnt + 1
 		streamPoolMap[id] = sInfo
-		log.Printf("Update PingErrCnt id:[%s] Ping err cnt:[%d]", id, sInfo.pingErrCnt)
+		logger.Debug("ping err count increase", rtkMisc.PeerIDAttr(id), "ping_err_cnt", sInfo.pingErrCnt)
 		return sInfo.pingErrCnt
 	}
 	return 0
@@ -688,6 +805,6 @@ func updateStreamPingErrCntReset(id string) {
 	if sInfo, ok := streamPoolMap[id]; ok {
 		sInfo.pingErrCnt = 0
 		streamPoolMap[id] = sInfo
-		log.Printf("Update PingErrCnt id:[%s] Ping err cnt reset!", id)
+		logger.Debug("ping err count reset", rtkMisc.PeerIDAttr(id))
 	}
 }
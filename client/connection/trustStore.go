@@ -0,0 +1,310 @@
+package connection
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trusted peer: the peer ID is pinned in trust store after the first contact is confirmed by platform.
+// Both sides show the same short authentication string (SAS) derived from both peer IDs, and the user confirms it on each side.
+// The stream from unknown ID waits for the confirmation in onlineEvent, and the stream from revoked ID is refused at once.
+// The unknown ID is also refused at once if platform has no pairing handler.
+// First run migration: the client before pairing trusted all peers, so if there is no trust store when it is loaded first
+// (upgrade or new install), the peers met in trustStoreMigratePeriod since then are pinned without confirmation.
+const (
+	pairingTimeout          = 60 * time.Second
+	trustStoreMigratePeriod = 24 * time.Hour
+)
+
+type TrustedPeer struct {
+	ID         string
+	Name       string // set by platform, DeviceName by default
+	DeviceName string
+	Platform   string
+	PairTime   int64 // unix milli
+	IsRevoked  bool
+}
+
+// PairingRequest is notified to platform on first contact, platform calls ConfirmPairing with the result
+type PairingRequest struct {
+	ID         string
+	IpAddr     string
+	DeviceName string
+	Platform   string
+	SAS        string
+}
+
+var (
+	trustedPeerMap       = make(map[string]TrustedPeer)
+	isTrustStoreLoaded   = false
+	trustStoreMutex      sync.Mutex
+	pairingResultChanMap sync.Map  // key: ID, value: chan bool
+	trustStoreMigrateEnd time.Time // zero if the trust store existed when it was loaded
+)
+
+// loadTrustStore must be called with trustStoreMutex locked
+func loadTrustStore() {
+	if isTrustStoreLoaded {
+		return
+	}
+	isTrustStoreLoaded = true
+
+	storePath := rtkPlatform.GetTrustStorePath()
+	if storePath == "" {
+		return
+	}
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			trustStoreMigrateEnd = time.Now().Add(trustStoreMigratePeriod)
+			saveTrustStore() // the next start is not the first run
+			log.Printf("[%s] no trust store, pin the peers without confirmation until:[%s]", rtkMisc.GetFuncInfo(), trustStoreMigrateEnd.Format(time.DateTime))
+		} else {
+			log.Printf("[%s] read trust store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+		}
+		return
+	}
+
+	var peerList []TrustedPeer
+	if err = json.Unmarshal(data, &peerList); err != nil {
+		log.Printf("[%s] Unmarshal trust store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+		return
+	}
+	for _, trustedPeer := range peerList {
+		trustedPeerMap[trustedPeer.ID] = trustedPeer
+	}
+	log.Printf("[%s] load [%d] trusted peers", rtkMisc.GetFuncInfo(), len(trustedPeerMap))
+}
+
+// getTrustedPeerList must be called with trustStoreMutex locked
+func getTrustedPeerList() []TrustedPeer {
+	peerList := make([]TrustedPeer, 0, len(trustedPeerMap))
+	for _, trustedPeer := range trustedPeerMap {
+		peerList = append(peerList, trustedPeer)
+	}
+	sort.Slice(peerList, func(i, j int) bool { return peerList[i].PairTime < peerList[j].PairTime })
+	return peerList
+}
+
+// saveTrustStore must be called with trustStoreMutex locked
+func saveTrustStore() {
+	storePath := rtkPlatform.GetTrustStorePath()
+	if storePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(getTrustedPeerList())
+	if err != nil {
+		log.Printf("[%s] Marshal trust store err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	tmpPath := storePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		log.Printf("[%s] write trust store:[%s] err:%+v", rtkMisc.GetFuncInfo(), tmpPath, err)
+		return
+	}
+	if err = os.Rename(tmpPath, storePath); err != nil {
+		log.Printf("[%s] rename trust store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+	}
+}
+
+func getTrustedPeer(id string) (TrustedPeer, bool) {
+	trustStoreMutex.Lock()
+	defer trustStoreMutex.Unlock()
+	loadTrustStore()
+	trustedPeer, ok := trustedPeerMap[id]
+	return trustedPeer, ok
+}
+
+// isTrustedPeer returns true if peer ID is paired and not revoked
+func isTrustedPeer(id string) bool {
+	trustedPeer, ok := getTrustedPeer(id)
+	return ok && !trustedPeer.IsRevoked
+}
+
+// getPairingSAS returns 6 digits derived from both peer IDs, it is the same on both sides
+func getPairingSAS(localID, peerID string) string {
+	ids := []string{localID, peerID}
+	sort.Strings(ids)
+	sum := sha256.Sum256([]byte(ids[0] + "|" + ids[1]))
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[:4])%1000000)
+}
+
+func isTrustStoreMigrating() bool {
+	trustStoreMutex.Lock()
+	defer trustStoreMutex.Unlock()
+	loadTrustStore()
+	return time.Now().Before(trustStoreMigrateEnd)
+}
+
+func pinTrustedPeer(id, deviceName, platform string) {
+	trustStoreMutex.Lock()
+	defer trustStoreMutex.Unlock()
+	trustedPeerMap[id] = TrustedPeer{
+		ID:         id,
+		Name:       deviceName,
+		DeviceName: deviceName,
+		Platform:   platform,
+		PairTime:   time.Now().UnixMilli(),
+	}
+	saveTrustStore()
+}
+
+// checkPeerTrust is called in onlineEvent before updateStream, it blocks until platform confirms the pairing of unknown peer ID
+func checkPeerTrust(id, ipAddr, deviceName, platform string) rtkMisc.CrossShareErr {
+	if trustedPeer, ok := getTrustedPeer(id); ok {
+		if trustedPeer.IsRevoked {
+			log.Printf("[%s] ID:[%s] IP:[%s] is revoked, refuse it", rtkMisc.GetFuncInfo(), id, ipAddr)
+			return rtkMisc.ERR_BIZ_P2P_PEER_REVOKED
+		}
+		return rtkMisc.SUCCESS
+	}
+
+	if isTrustStoreMigrating() {
+		pinTrustedPeer(id, deviceName, platform)
+		log.Printf("[%s] ID:[%s] IP:[%s] is pinned without confirmation by the first run migration", rtkMisc.GetFuncInfo(), id, ipAddr)
+		return rtkMisc.SUCCESS
+	}
+
+	resultChan := make(chan bool, 1)
+	if _, loaded := pairingResultChanMap.LoadOrStore(id, resultChan); loaded {
+		log.Printf("[%s] ID:[%s] IP:[%s] pairing is already in progress, refuse it", rtkMisc.GetFuncInfo(), id, ipAddr)
+		return rtkMisc.ERR_BIZ_P2P_PEER_UNTRUSTED
+	}
+	defer pairingResultChanMap.Delete(id)
+
+	request := PairingRequest{
+		ID:         id,
+		IpAddr:     ipAddr,
+		DeviceName: deviceName,
+		Platform:   platform,
+		SAS:        getPairingSAS(rtkGlobal.NodeInfo.ID, id),
+	}
+	encodedData, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("[%s] Marshal pairing request err:%+v", rtkMisc.GetFuncInfo(), err)
+		return rtkMisc.ERR_BIZ_JSON_MARSHAL
+	}
+	// published before the platform callback, the linux daemon callback checks if any control API subscriber handles it
+	rtkEvent.Publish(rtkEvent.PairingRequestEvent{ID: id, IPAddr: ipAddr, DeviceName: deviceName, Platform: platform, SAS: request.SAS})
+	if !rtkPlatform.GoNotifyPairingRequest(id, string(encodedData)) {
+		log.Printf("[%s] ID:[%s] IP:[%s] unknown peer, no pairing handler, refuse it", rtkMisc.GetFuncInfo(), id, ipAddr)
+		return rtkMisc.ERR_BIZ_P2P_PAIRING_NO_HANDLER
+	}
+	log.Printf("[%s] ID:[%s] IP:[%s] unknown peer, wait for pairing confirmation, SAS:[%s]", rtkMisc.GetFuncInfo(), id, ipAddr, request.SAS)
+
+	select {
+	case isAccepted := <-resultChan:
+		if !isAccepted {
+			log.Printf("[%s] ID:[%s] IP:[%s] pairing is rejected", rtkMisc.GetFuncInfo(), id, ipAddr)
+			return rtkMisc.ERR_BIZ_P2P_PEER_UNTRUSTED
+		}
+	case <-time.After(pairingTimeout):
+		log.Printf("[%s] ID:[%s] IP:[%s] pairing is not confirmed in %v", rtkMisc.GetFuncInfo(), id, ipAddr, pairingTimeout)
+		return rtkMisc.ERR_BIZ_P2P_PAIRING_TIMEOUT
+	}
+
+	pinTrustedPeer(id, deviceName, platform)
+	log.Printf("[%s] ID:[%s] IP:[%s] is paired and pinned", rtkMisc.GetFuncInfo(), id, ipAddr)
+	return rtkMisc.SUCCESS
+}
+
+// ConfirmPairing is called by platform with the result of PairingRequest, it returns false if there is no pairing in progress with ID
+func ConfirmPairing(id string, isAccepted bool) bool {
+	val, ok := pairingResultChanMap.Load(id)
+	if !ok {
+		log.Printf("[%s] ID:[%s] no pairing is in progress", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+	select {
+	case val.(chan bool) <- isAccepted:
+	default:
+	}
+	return true
+}
+
+// GetTrustedPeerList returns JSON of []TrustedPeer, including the revoked peers
+func GetTrustedPeerList() string {
+	trustStoreMutex.Lock()
+	loadTrustStore()
+	peerList := getTrustedPeerList()
+	trustStoreMutex.Unlock()
+
+	encodedData, err := json.Marshal(peerList)
+	if err != nil {
+		log.Printf("[%s] Marshal trusted peer list err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+func RenameTrustedPeer(id, name string) bool {
+	trustStoreMutex.Lock()
+	defer trustStoreMutex.Unlock()
+	loadTrustStore()
+	trustedPeer, ok := trustedPeerMap[id]
+	if !ok {
+		log.Printf("[%s] ID:[%s] is not found in trust store", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+	trustedPeer.Name = name
+	trustedPeerMap[id] = trustedPeer
+	saveTrustStore()
+	return true
+}
+
+// RevokeTrustedPeer refuses peer ID from now on and disconnects it, the peer is kept in trust store as revoked
+func RevokeTrustedPeer(id string) bool {
+	trustStoreMutex.Lock()
+	loadTrustStore()
+	trustedPeer, ok := trustedPeerMap[id]
+	if ok {
+		trustedPeer.IsRevoked = true
+		trustedPeerMap[id] = trustedPeer
+		saveTrustStore()
+	}
+	trustStoreMutex.Unlock()
+	if !ok {
+		log.Printf("[%s] ID:[%s] is not found in trust store", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+
+	log.Printf("[%s] ID:[%s] is revoked", rtkMisc.GetFuncInfo(), id)
+	if s, ok := GetStream(id); ok {
+		offlineEvent(s, false)
+	}
+	return true
+}
+
+// DeleteTrustedPeer removes peer ID from trust store, the next contact requires pairing again
+func DeleteTrustedPeer(id string) bool {
+	trustStoreMutex.Lock()
+	loadTrustStore()
+	_, ok := trustedPeerMap[id]
+	if ok {
+		delete(trustedPeerMap, id)
+		saveTrustStore()
+	}
+	trustStoreMutex.Unlock()
+	if !ok {
+		log.Printf("[%s] ID:[%s] is not found in trust store", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+
+	log.Printf("[%s] ID:[%s] is deleted", rtkMisc.GetFuncInfo(), id)
+	if s, ok := GetStream(id); ok {
+		offlineEvent(s, false)
+	}
+	return true
+}
@@ -0,0 +1,381 @@
+//go:build linux && !android
+
+package control
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkControlApi "rtk-cross-share/client/controlApi"
+	rtkDiagnostics "rtk-cross-share/client/diagnostics"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkLogin "rtk-cross-share/client/login"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"sort"
+	"time"
+)
+
+type methodHandler func(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error)
+
+var methodMap = map[string]methodHandler{
+	rtkControlApi.MethodStatus:           dealStatus,
+	rtkControlApi.MethodPeerList:         dealPeerList,
+	rtkControlApi.MethodTransferSend:     dealTransferSend,
+	rtkControlApi.MethodTransferAccept:   dealTransferAccept,
+	rtkControlApi.MethodTransferReject:   dealTransferReject,
+	rtkControlApi.MethodTransferCancel:   dealTransferCancel,
+	rtkControlApi.MethodTransferList:     dealTransferList,
+	rtkControlApi.MethodClipboardSetText: dealClipboardSetText,
+	rtkControlApi.MethodClipboardGetText: dealClipboardGetText,
+	rtkControlApi.MethodEventSubscribe:   dealEventSubscribe,
+	rtkControlApi.MethodEventUnsubscribe: dealEventUnsubscribe,
+	rtkControlApi.MethodDiagCollect:      dealDiagCollect,
+	rtkControlApi.MethodHistoryList:      dealHistoryList,
+	rtkControlApi.MethodHistoryDelete:    dealHistoryDelete,
+	rtkControlApi.MethodHistoryClear:     dealHistoryClear,
+	rtkControlApi.MethodHistoryReveal:    dealHistoryReveal,
+	rtkControlApi.MethodPairingAccept:    dealPairingAccept,
+	rtkControlApi.MethodPairingReject:    dealPairingReject,
+}
+
+var lanServerStatusDescMap = map[rtkLogin.CrossShareDiasStatus]string{
+	rtkLogin.DIAS_Status_Wait_DiasMonitor:             "WaitDiasMonitor",
+	rtkLogin.DIAS_Status_Connectting_DiasService:      "ConnectingDiasService",
+	rtkLogin.DIAS_Status_Checking_Authorization:       "CheckingAuthorization",
+	rtkLogin.DIAS_Status_Wait_screenCasting:           "WaitScreenCasting",
+	rtkLogin.DIAS_Status_Authorization_Failed:         "AuthorizationFailed",
+	rtkLogin.DIAS_Status_Wait_Other_Clients:           "WaitOtherClients",
+	rtkLogin.DIAS_Status_Get_Clients_Success:          "GetClientsSuccess",
+	rtkLogin.DIAS_Status_Connected_DiasService_Failed: "ConnectDiasServiceFailed",
+}
+
+func unmarshalParams(params json.RawMessage, v interface{}) *rtkControlApi.Error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return newRpcError(rtkControlApi.ErrCodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+func getPeerInfoMap() map[string]rtkControlApi.PeerInfo {
+	peerMap := make(map[string]rtkControlApi.PeerInfo)
+	for id, info := range rtkUtils.GetClientMap() {
+		peerMap[id] = rtkControlApi.PeerInfo{
+			ID:             info.ID,
+			IpAddr:         info.IpAddr,
+			Platform:       info.Platform,
+			DeviceName:     info.DeviceName,
+			SourcePortType: info.SourcePortType,
+			Version:        info.Version,
+			Capabilities:   info.Capabilities,
+		}
+	}
+	return peerMap
+}
+
+func getPeerIp(id string) (string, *rtkControlApi.Error) {
+	if id == "" {
+		return "", newRpcError(rtkControlApi.ErrCodeInvalidParams, "id is empty")
+	}
+	ipAddr, ok := rtkUtils.GetClientIp(id)
+	if !ok {
+		return "", newRpcBizError(rtkMisc.ERR_BIZ_GET_CLIENT_INFO_EMPTY, "peer [%s] is offline", id)
+	}
+	return ipAddr, nil
+}
+
+func dealStatus(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	lanServerStatus := rtkLogin.GetDIASStatus()
+	lanServerStatusDesc, ok := lanServerStatusDescMap[lanServerStatus]
+	if !ok {
+		lanServerStatusDesc = "Idle"
+	}
+
+	return rtkControlApi.StatusInfo{
+		ID:                  rtkGlobal.NodeInfo.ID,
+		DeviceName:          rtkGlobal.NodeInfo.DeviceName,
+		Platform:            rtkGlobal.NodeInfo.Platform,
+		Version:             rtkGlobal.ClientVersion,
+		DownloadPath:        rtkPlatform.GetDownloadPath(),
+		LanServerStatus:     uint32(lanServerStatus),
+		LanServerStatusDesc: lanServerStatusDesc,
+		PeerCount:           rtkUtils.GetClientCount(),
+		TransferCount:       len(getTransferStateList("")),
+	}, nil
+}
+
+func dealPeerList(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	peerMap := getPeerInfoMap()
+	peerList := make([]rtkControlApi.PeerInfo, 0, len(peerMap))
+	for _, info := range peerMap {
+		peerList = append(peerList, info)
+	}
+	sort.Slice(peerList, func(i, j int) bool { return peerList[i].ID < peerList[j].ID })
+	return peerList, nil
+}
+
+func dealTransferSend(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.TransferSendParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	ipAddr, rpcErr := getPeerIp(req.ID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if len(req.Paths) == 0 {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "paths is empty")
+	}
+
+	fileList := make([]string, 0, len(req.Paths))
+	for _, path := range req.Paths {
+		if !filepath.IsAbs(path) {
+			return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "path [%s] is not absolute", path)
+		}
+		if !rtkMisc.FileExists(path) && !rtkMisc.FolderExists(path) {
+			return nil, newRpcBizError(rtkMisc.ERR_BIZ_FD_FILE_NOT_EXISTS, "path [%s] is not exists", path)
+		}
+		fileList = append(fileList, filepath.Clean(path))
+	}
+
+	timestamp := uint64(time.Now().UnixMilli())
+	if code := rtkPlatform.GoMultiFilesDropRequest(req.ID, ipAddr, &fileList, timestamp); code != rtkCommon.SendFilesRequestSuccess {
+		rpcErr = newRpcBizError(rtkMisc.ERR_BIZ_FD_DATA_INVALID, "send files request failed")
+		rpcErr.Data = map[string]int{"sendFilesRequestCode": int(code)}
+		return nil, rpcErr
+	}
+	return rtkControlApi.TransferResult{TimeStamp: timestamp}, nil
+}
+
+func dealTransferAccept(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.TransferAcceptParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if req.Path == "" {
+		req.Path = rtkFileDrop.GetPeerDownloadPath(req.ID)
+	}
+	if !rtkMisc.FolderExists(req.Path) {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_FD_FOLDER_NOT_EXISTS, "folder [%s] is not exists", req.Path)
+	}
+
+	fileDrop, ok := takePendingRequest(req.ID)
+	if !ok {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_FD_DATA_EMPTY, "no pending file drop from peer [%s]", req.ID)
+	}
+	log.Printf("[%s] ID:[%s] timestamp:[%d] accept file drop to [%s]", rtkMisc.GetFuncInfo(), req.ID, fileDrop.TimeStamp, req.Path)
+	rtkPlatform.GoFileDropResponse(req.ID, rtkCommon.FILE_DROP_ACCEPT, req.Path)
+	return rtkControlApi.TransferResult{TimeStamp: fileDrop.TimeStamp}, nil
+}
+
+func dealTransferReject(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.PeerParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	fileDrop, ok := takePendingRequest(req.ID)
+	if !ok {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_FD_DATA_EMPTY, "no pending file drop from peer [%s]", req.ID)
+	}
+	log.Printf("[%s] ID:[%s] timestamp:[%d] reject file drop", rtkMisc.GetFuncInfo(), req.ID, fileDrop.TimeStamp)
+	rtkPlatform.GoFileDropResponse(req.ID, rtkCommon.FILE_DROP_REJECT, "")
+	return rtkControlApi.TransferResult{TimeStamp: fileDrop.TimeStamp}, nil
+}
+
+func dealTransferCancel(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.TransferCancelParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	ipAddr, rpcErr := getPeerIp(req.ID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if req.TimeStamp == 0 {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "timestamp is empty")
+	}
+
+	rtkPlatform.GoCancelFileTrans(ipAddr, req.ID, int64(req.TimeStamp))
+	return nil, nil
+}
+
+func dealTransferList(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.PeerParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return getTransferStateList(req.ID), nil
+}
+
+func dealClipboardSetText(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.ClipboardTextParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if req.Text == "" {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "text is empty")
+	}
+
+	rtkPlatform.GoCopyXClipData(req.Text, "", "", "")
+	return nil, nil
+}
+
+func dealClipboardGetText(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	provider := rtkPlatform.GetClipboardProvider()
+	if provider == nil {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_CB_INVALID_DATA, "clipboard is not ready")
+	}
+	content, err := provider.Read()
+	if err != nil {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_CB_INVALID_DATA, "read clipboard err:%v", err)
+	}
+	return rtkControlApi.ClipboardTextParams{Text: string(content.Text)}, nil
+}
+
+func dealEventSubscribe(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.EventSubscribeParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	filter := rtkEvent.Filter{PeerID: req.ID}
+	for _, evType := range req.Types {
+		filter.Types = append(filter.Types, rtkEvent.EventType(evType))
+	}
+	return rtkControlApi.SubscriptionParams{Subscription: c.subscribe(filter)}, nil
+}
+
+func dealEventUnsubscribe(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.SubscriptionParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if !c.unsubscribe(req.Subscription) {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "subscription [%d] not found", req.Subscription)
+	}
+	return nil, nil
+}
+
+func dealDiagCollect(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.DiagnosticsParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if req.Path != "" && !filepath.IsAbs(req.Path) {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "path [%s] is not absolute", req.Path)
+	}
+
+	path, errCode := rtkDiagnostics.Collect(req.Path)
+	if errCode != rtkMisc.SUCCESS {
+		return nil, newRpcBizError(errCode, "collect diagnostics failed")
+	}
+	return rtkControlApi.DiagnosticsParams{Path: path}, nil
+}
+
+func dealHistoryList(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.HistoryListParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	itemList, errCode := rtkTransferHistory.QueryTransferHistory(rtkTransferHistory.TransferHistoryFilter{
+		ID:        req.ID,
+		Direction: req.Direction,
+		Result:    req.Result,
+		Keyword:   req.Keyword,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Offset:    req.Offset,
+		Limit:     req.Limit,
+	})
+	if errCode != rtkMisc.SUCCESS {
+		return nil, newRpcBizError(errCode, "query transfer history failed")
+	}
+
+	historyList := make([]rtkControlApi.HistoryItem, 0, len(itemList))
+	for _, item := range itemList {
+		historyList = append(historyList, rtkControlApi.HistoryItem{
+			Index:      item.Index,
+			ID:         item.ID,
+			DeviceName: item.ClientName,
+			Platform:   item.Platform,
+			Direction:  item.Direction,
+			TimeStamp:  item.TimeStamp,
+			FileCnt:    item.FileCnt,
+			FolderCnt:  item.FolderCnt,
+			FileNames:  item.FileNames,
+			TotalDesc:  item.TotalDesc,
+			LocalPath:  item.LocalPath,
+			TotalSize:  item.TotalSize,
+			TransSize:  item.TransSize,
+			StartTime:  item.StartTime,
+			EndTime:    item.EndTime,
+			Duration:   item.Duration,
+			AvgSpeed:   item.AvgSpeed,
+			Success:    item.ResultCode == rtkMisc.SUCCESS,
+			ResultCode: int(item.ResultCode),
+		})
+	}
+	return historyList, nil
+}
+
+func dealHistoryDelete(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.HistoryIndexParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if !rtkTransferHistory.DeleteTransferHistory(req.Index) {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "history index [%d] is not found", req.Index)
+	}
+	return nil, nil
+}
+
+func dealHistoryClear(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	rtkTransferHistory.ClearTransferHistory()
+	return nil, nil
+}
+
+func dealHistoryReveal(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.HistoryIndexParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	path := rtkTransferHistory.RevealTransferHistory(req.Index)
+	if path == "" {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "history index [%d] is not found or its path is not exist", req.Index)
+	}
+	return rtkControlApi.HistoryRevealResult{Path: path}, nil
+}
+
+// dealPairingAccept the ID is the exact ID of PairingRequest event, the unknown peer is not in the peer list until it's paired
+func dealPairingAccept(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	return confirmPairing(params, true)
+}
+
+func dealPairingReject(c *rpcConn, params json.RawMessage) (interface{}, *rtkControlApi.Error) {
+	return confirmPairing(params, false)
+}
+
+func confirmPairing(params json.RawMessage, isAccepted bool) (interface{}, *rtkControlApi.Error) {
+	var req rtkControlApi.PeerParams
+	if rpcErr := unmarshalParams(params, &req); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if req.ID == "" {
+		return nil, newRpcError(rtkControlApi.ErrCodeInvalidParams, "id is required")
+	}
+	log.Printf("[%s] ID:[%s] confirm pairing, isAccepted:[%v]", rtkMisc.GetFuncInfo(), req.ID, isAccepted)
+	if !rtkConnection.ConfirmPairing(req.ID, isAccepted) {
+		return nil, newRpcBizError(rtkMisc.ERR_BIZ_P2P_PEER_UNTRUSTED, "no pairing in progress with peer [%s]", req.ID)
+	}
+	return nil, nil
+}
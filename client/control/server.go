@@ -0,0 +1,315 @@
+//go:build linux && !android
+
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	rtkControlApi "rtk-cross-share/client/controlApi"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"syscall"
+)
+
+// Only the processes of the same user (or root) are allowed to connect the control API,
+// the protocol is in rtkControlApi.
+
+const eventChanSize = 256
+
+func newRpcError(code int, format string, args ...interface{}) *rtkControlApi.Error {
+	return &rtkControlApi.Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func newRpcBizError(errCode rtkMisc.CrossShareErr, format string, args ...interface{}) *rtkControlApi.Error {
+	return &rtkControlApi.Error{Code: int(errCode), Message: fmt.Sprintf(format, args...)}
+}
+
+type subscription struct {
+	filter rtkEvent.Filter
+	done   chan struct{}
+}
+
+type rpcConn struct {
+	conn          net.Conn
+	writeMutex    sync.Mutex
+	subMutex      sync.Mutex
+	subscriptions map[uint64]subscription
+}
+
+var (
+	listener      net.Listener
+	listenerMutex sync.Mutex
+	connMap       sync.Map
+)
+
+// Start listens the control API on sockPath, the stale socket file is replaced unless another instance is listening on it
+func Start(sockPath string) error {
+	listenerMutex.Lock()
+	defer listenerMutex.Unlock()
+	if listener != nil {
+		return errors.New("control API is already started")
+	}
+
+	if _, err := os.Stat(sockPath); err == nil {
+		if conn, err := net.Dial("unix", sockPath); err == nil {
+			conn.Close()
+			return fmt.Errorf("control socket [%s] is in use", sockPath)
+		}
+		if err = os.Remove(sockPath); err != nil {
+			return err
+		}
+	}
+
+	oldMask := syscall.Umask(0177)
+	l, err := net.Listen("unix", sockPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(sockPath, 0600); err != nil {
+		l.Close()
+		return err
+	}
+
+	listener = l
+	startTransferTracker()
+	rtkMisc.GoSafe(func() { acceptLoop(l) })
+	log.Printf("[%s] control API is listening on [%s]", rtkMisc.GetFuncInfo(), sockPath)
+	return nil
+}
+
+// Stop closes the listener and all connections, the socket file is removed by net.UnixListener
+func Stop() {
+	listenerMutex.Lock()
+	defer listenerMutex.Unlock()
+	if listener == nil {
+		return
+	}
+	listener.Close()
+	listener = nil
+
+	connMap.Range(func(key, value interface{}) bool {
+		key.(*rpcConn).conn.Close()
+		return true
+	})
+	stopTransferTracker()
+}
+
+func acceptLoop(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("[%s] accept err:%+v", rtkMisc.GetFuncInfo(), err)
+			continue
+		}
+
+		if !isPeerAllowed(conn) {
+			conn.Close()
+			continue
+		}
+
+		c := &rpcConn{conn: conn, subscriptions: make(map[uint64]subscription)}
+		connMap.Store(c, struct{}{})
+		rtkMisc.GoSafe(func() { c.serve() })
+	}
+}
+
+// isPeerAllowed checks the peer credential of unix socket, the socket file mode is not enough if its folder is shared
+func isPeerAllowed(conn net.Conn) bool {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return false
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		log.Printf("[%s] get raw conn err:%+v", rtkMisc.GetFuncInfo(), err)
+		return false
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		log.Printf("[%s] get peer credential err:%+v %+v", rtkMisc.GetFuncInfo(), err, credErr)
+		return false
+	}
+
+	if cred.Uid != 0 && int(cred.Uid) != os.Getuid() {
+		log.Printf("[%s] reject pid:[%d] uid:[%d]", rtkMisc.GetFuncInfo(), cred.Pid, cred.Uid)
+		return false
+	}
+	return true
+}
+
+func (c *rpcConn) serve() {
+	defer func() {
+		c.unsubscribeAll()
+		connMap.Delete(c)
+		c.conn.Close()
+	}()
+
+	decoder := json.NewDecoder(c.conn)
+	for {
+		var rawMsg json.RawMessage
+		if err := decoder.Decode(&rawMsg); err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("[%s] decode err:%+v", rtkMisc.GetFuncInfo(), err)
+				c.writeResponse(nil, nil, newRpcError(rtkControlApi.ErrCodeParse, "parse error: %v", err))
+			}
+			return
+		}
+
+		var req rtkControlApi.Request
+		if err := json.Unmarshal(rawMsg, &req); err != nil || req.JsonRpc != rtkControlApi.JsonRpcVersion || req.Method == "" {
+			c.writeResponse(nil, nil, newRpcError(rtkControlApi.ErrCodeInvalidRequest, "invalid request"))
+			continue
+		}
+
+		result, rpcErr := c.call(req)
+		if len(req.ID) == 0 {
+			continue
+		}
+		c.writeResponse(req.ID, result, rpcErr)
+	}
+}
+
+func (c *rpcConn) call(req rtkControlApi.Request) (result interface{}, rpcErr *rtkControlApi.Error) {
+	handler, ok := methodMap[req.Method]
+	if !ok {
+		return nil, newRpcError(rtkControlApi.ErrCodeMethodNotFound, "method [%s] not found", req.Method)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] method:[%s] panic:%+v", rtkMisc.GetFuncInfo(), req.Method, r)
+			result, rpcErr = nil, newRpcError(rtkControlApi.ErrCodeInternal, "internal error")
+		}
+	}()
+	return handler(c, req.Params)
+}
+
+func (c *rpcConn) writeResponse(id json.RawMessage, result interface{}, rpcErr *rtkControlApi.Error) {
+	rsp := rtkControlApi.Response{JsonRpc: rtkControlApi.JsonRpcVersion, Error: rpcErr, ID: id}
+	if len(rsp.ID) == 0 {
+		rsp.ID = json.RawMessage("null")
+	}
+	if rpcErr == nil {
+		if result == nil {
+			result = struct{}{}
+		}
+		encodedResult, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("[%s] Marshal result err:%+v", rtkMisc.GetFuncInfo(), err)
+			rsp.Error = newRpcError(rtkControlApi.ErrCodeInternal, "internal error")
+		} else {
+			rsp.Result = encodedResult
+		}
+	}
+	c.write(rsp)
+}
+
+func (c *rpcConn) write(msg interface{}) {
+	encodedData, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[%s] Marshal err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	encodedData = append(encodedData, '\n')
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if _, err = c.conn.Write(encodedData); err != nil {
+		log.Printf("[%s] write err:%+v", rtkMisc.GetFuncInfo(), err)
+	}
+}
+
+// subscribe streams the matched events as "event" notifications until unsubscribe or the connection is closed
+func (c *rpcConn) subscribe(filter rtkEvent.Filter) uint64 {
+	subID, eventChan := rtkEvent.SubscribeChan(filter, eventChanSize)
+	done := make(chan struct{})
+	c.subMutex.Lock()
+	c.subscriptions[subID] = subscription{filter: filter, done: done}
+	c.subMutex.Unlock()
+
+	rtkMisc.GoSafe(func() {
+		for {
+			select {
+			case <-done:
+				return
+			case ev := <-eventChan:
+				c.writeEvent(subID, ev)
+			}
+		}
+	})
+	return subID
+}
+
+func (c *rpcConn) unsubscribe(subID uint64) bool {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	sub, ok := c.subscriptions[subID]
+	if !ok {
+		return false
+	}
+	rtkEvent.Unsubscribe(subID)
+	close(sub.done)
+	delete(c.subscriptions, subID)
+	return true
+}
+
+func (c *rpcConn) unsubscribeAll() {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	for subID, sub := range c.subscriptions {
+		rtkEvent.Unsubscribe(subID)
+		close(sub.done)
+	}
+	c.subscriptions = make(map[uint64]subscription)
+}
+
+func (c *rpcConn) isSubscribed(ev rtkEvent.Event) bool {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	for _, sub := range c.subscriptions {
+		if sub.filter.Match(ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPairingWatched returns true if any control API connection subscribes the pairing request of ID,
+// so it can be confirmed by pairing.accept or pairing.reject
+func IsPairingWatched(id string) bool {
+	isWatched := false
+	connMap.Range(func(key, value interface{}) bool {
+		isWatched = key.(*rpcConn).isSubscribed(rtkEvent.PairingRequestEvent{ID: id})
+		return !isWatched
+	})
+	return isWatched
+}
+
+func (c *rpcConn) writeEvent(subID uint64, ev rtkEvent.Event) {
+	encodedEvent, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[%s] event:[%s] Marshal err:%+v", rtkMisc.GetFuncInfo(), ev.Type(), err)
+		return
+	}
+	encodedParams, err := json.Marshal(rtkControlApi.EventParams{Subscription: subID, Type: string(ev.Type()), Event: encodedEvent})
+	if err != nil {
+		log.Printf("[%s] event:[%s] Marshal params err:%+v", rtkMisc.GetFuncInfo(), ev.Type(), err)
+		return
+	}
+	c.write(rtkControlApi.Notification{JsonRpc: rtkControlApi.JsonRpcVersion, Method: rtkControlApi.MethodEventNotification, Params: encodedParams})
+}
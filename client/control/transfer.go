@@ -0,0 +1,159 @@
+//go:build linux && !android
+
+package control
+
+import (
+	rtkCommon "rtk-cross-share/client/common"
+	rtkControlApi "rtk-cross-share/client/controlApi"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	"sort"
+	"sync"
+)
+
+type transferKey struct {
+	id        string
+	timestamp uint64
+}
+
+// the transfer cache of filedrop has no progress and pending request, they are tracked from the event bus
+var (
+	trackerMutex        sync.RWMutex
+	trackerSubID        uint64
+	pendingRequestMap   = make(map[string]rtkEvent.TransferRequestEvent)
+	transferProgressMap = make(map[transferKey]rtkControlApi.TransferProgress)
+)
+
+func startTransferTracker() {
+	trackerMutex.Lock()
+	defer trackerMutex.Unlock()
+	trackerSubID = rtkEvent.Subscribe(rtkEvent.Filter{Types: []rtkEvent.EventType{
+		rtkEvent.EventTransferRequest,
+		rtkEvent.EventTransferProgress,
+		rtkEvent.EventTransferDone,
+		rtkEvent.EventTransferFailed,
+	}}, trackTransferEvent)
+}
+
+func stopTransferTracker() {
+	trackerMutex.Lock()
+	defer trackerMutex.Unlock()
+	rtkEvent.Unsubscribe(trackerSubID)
+	pendingRequestMap = make(map[string]rtkEvent.TransferRequestEvent)
+	transferProgressMap = make(map[transferKey]rtkControlApi.TransferProgress)
+}
+
+func trackTransferEvent(ev rtkEvent.Event) {
+	trackerMutex.Lock()
+	defer trackerMutex.Unlock()
+
+	switch e := ev.(type) {
+	case rtkEvent.TransferRequestEvent:
+		pendingRequestMap[e.ID] = e
+	case rtkEvent.TransferProgressEvent:
+		transferProgressMap[transferKey{e.ID, e.TimeStamp}] = rtkControlApi.TransferProgress{
+			CurrentFileName: e.CurrentFileName,
+			FileCnt:         e.FileCnt,
+			TotalFileCnt:    e.TotalFileCnt,
+			CurrentFileSize: e.CurrentFileSize,
+			TotalSize:       e.TotalSize,
+			TransSize:       e.TransSize,
+		}
+	case rtkEvent.TransferDoneEvent:
+		delete(transferProgressMap, transferKey{e.ID, e.TimeStamp})
+		removePendingRequest(e.ID, e.TimeStamp)
+	case rtkEvent.TransferFailedEvent:
+		delete(transferProgressMap, transferKey{e.ID, e.TimeStamp})
+		removePendingRequest(e.ID, e.TimeStamp)
+	}
+}
+
+func removePendingRequest(id string, timestamp uint64) {
+	if req, ok := pendingRequestMap[id]; ok && req.TimeStamp == timestamp {
+		delete(pendingRequestMap, id)
+	}
+}
+
+// getPendingRequest returns the received file drop of peer which is still not accepted or rejected
+func getPendingRequest(id string) (rtkEvent.TransferRequestEvent, bool) {
+	trackerMutex.RLock()
+	req, ok := pendingRequestMap[id]
+	trackerMutex.RUnlock()
+	if !ok {
+		return req, false
+	}
+
+	fileDropData, ok := rtkFileDrop.GetFileDropData(id)
+	if !ok || fileDropData.Cmd != rtkCommon.FILE_DROP_REQUEST || fileDropData.TimeStamp != req.TimeStamp {
+		trackerMutex.Lock()
+		removePendingRequest(id, req.TimeStamp)
+		trackerMutex.Unlock()
+		return req, false
+	}
+	return req, true
+}
+
+func takePendingRequest(id string) (rtkEvent.TransferRequestEvent, bool) {
+	req, ok := getPendingRequest(id)
+	if ok {
+		trackerMutex.Lock()
+		removePendingRequest(id, req.TimeStamp)
+		trackerMutex.Unlock()
+	}
+	return req, ok
+}
+
+func getTransferStateList(id string) []rtkControlApi.TransferState {
+	idList := make([]string, 0)
+	if id != "" {
+		idList = append(idList, id)
+	} else {
+		for peerID := range getPeerInfoMap() {
+			idList = append(idList, peerID)
+		}
+		sort.Strings(idList)
+	}
+
+	stateList := make([]rtkControlApi.TransferState, 0)
+	for _, peerID := range idList {
+		if req, ok := getPendingRequest(peerID); ok {
+			stateList = append(stateList, rtkControlApi.TransferState{
+				ID:        req.ID,
+				TimeStamp: req.TimeStamp,
+				Direction: rtkControlApi.TransferDirectionReceive,
+				State:     rtkControlApi.TransferStatePending,
+				TotalDesc: req.TotalDesc,
+				TotalSize: req.TotalSize,
+				FileCnt:   req.FileCnt,
+				FolderCnt: req.FolderCnt,
+			})
+		}
+
+		for _, item := range rtkFileDrop.GetFilesTransferDataList(peerID) {
+			state := rtkControlApi.TransferState{
+				ID:        peerID,
+				TimeStamp: item.TimeStamp,
+				Direction: rtkControlApi.TransferDirectionReceive,
+				State:     rtkControlApi.TransferStateQueued,
+				TotalDesc: item.TotalDescribe,
+				TotalSize: item.TotalSize,
+				FileCnt:   uint32(len(item.SrcFileList)),
+				FolderCnt: uint32(len(item.FolderList)),
+			}
+			if item.FileTransDirection == rtkFileDrop.FilesTransfer_As_Src {
+				state.Direction = rtkControlApi.TransferDirectionSend
+			}
+			if rtkFileDrop.IsFileTransInProgress(peerID, item.TimeStamp) {
+				state.State = rtkControlApi.TransferStateInProgress
+			}
+
+			trackerMutex.RLock()
+			if progress, ok := transferProgressMap[transferKey{peerID, item.TimeStamp}]; ok {
+				state.Progress = &progress
+			}
+			trackerMutex.RUnlock()
+			stateList = append(stateList, state)
+		}
+	}
+	return stateList
+}
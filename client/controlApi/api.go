@@ -0,0 +1,226 @@
+package controlApi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The local control API of client daemon is JSON-RPC 2.0 over a unix socket, one JSON object per line.
+// This package only has the protocol and the client, so the tools using it don't link the client business.
+
+const (
+	JsonRpcVersion = "2.0"
+	SocketName     = "crossShare.sock" // in the root folder of client daemon
+)
+
+const (
+	MethodStatus            = "status"
+	MethodPeerList          = "peer.list"
+	MethodTransferSend      = "transfer.send"
+	MethodTransferAccept    = "transfer.accept"
+	MethodTransferReject    = "transfer.reject"
+	MethodTransferCancel    = "transfer.cancel"
+	MethodTransferList      = "transfer.list"
+	MethodClipboardSetText  = "clipboard.setText"
+	MethodClipboardGetText  = "clipboard.getText"
+	MethodEventSubscribe    = "event.subscribe"
+	MethodEventUnsubscribe  = "event.unsubscribe"
+	MethodDiagCollect       = "diagnostics.collect"
+	MethodHistoryList       = "history.list"
+	MethodHistoryDelete     = "history.delete"
+	MethodHistoryClear      = "history.clear"
+	MethodHistoryReveal     = "history.reveal"
+	MethodPairingAccept     = "pairing.accept"
+	MethodPairingReject     = "pairing.reject"
+	MethodEventNotification = "event" // server to client notification of subscribed events
+)
+
+// JSON-RPC 2.0 error codes, the business errors use rtkMisc.CrossShareErr as code
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+)
+
+type Request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"` // no ID is a notification without response
+}
+
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("code:%d %s", e.Code, e.Message)
+}
+
+type Response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type Notification struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type StatusInfo struct {
+	ID                  string `json:"id"`
+	DeviceName          string `json:"deviceName"`
+	Platform            string `json:"platform"`
+	Version             string `json:"version"`
+	DownloadPath        string `json:"downloadPath"`
+	LanServerStatus     uint32 `json:"lanServerStatus"`
+	LanServerStatusDesc string `json:"lanServerStatusDesc"`
+	PeerCount           int    `json:"peerCount"`
+	TransferCount       int    `json:"transferCount"`
+}
+
+type PeerInfo struct {
+	ID             string   `json:"id"`
+	IpAddr         string   `json:"ipAddr"`
+	Platform       string   `json:"platform"`
+	DeviceName     string   `json:"deviceName"`
+	SourcePortType string   `json:"sourcePortType"`
+	Version        string   `json:"version"`
+	Capabilities   []string `json:"capabilities,omitempty"`
+}
+
+type PeerParams struct {
+	ID string `json:"id"`
+}
+
+type TransferSendParams struct {
+	ID    string   `json:"id"`
+	Paths []string `json:"paths"` // absolute paths of files or folders
+}
+
+// TransferResult TimeStamp is the file transfer ID
+type TransferResult struct {
+	TimeStamp uint64 `json:"timestamp"`
+}
+
+type TransferAcceptParams struct {
+	ID   string `json:"id"`
+	Path string `json:"path"` // the folder to save files, default is the download path of the peer in file drop rule
+}
+
+type TransferCancelParams struct {
+	ID        string `json:"id"`
+	TimeStamp uint64 `json:"timestamp"`
+}
+
+const (
+	TransferStatePending    = "pending" // received file drop waits for accept or reject
+	TransferStateQueued     = "queued"
+	TransferStateInProgress = "inProgress"
+
+	TransferDirectionSend    = "send"
+	TransferDirectionReceive = "receive"
+)
+
+type TransferProgress struct {
+	CurrentFileName string `json:"currentFileName"`
+	FileCnt         uint32 `json:"fileCnt"`
+	TotalFileCnt    uint32 `json:"totalFileCnt"`
+	CurrentFileSize uint64 `json:"currentFileSize"`
+	TotalSize       uint64 `json:"totalSize"`
+	TransSize       uint64 `json:"transSize"`
+}
+
+type TransferState struct {
+	ID        string            `json:"id"`
+	TimeStamp uint64            `json:"timestamp"`
+	Direction string            `json:"direction"`
+	State     string            `json:"state"`
+	TotalDesc string            `json:"totalDesc"`
+	TotalSize uint64            `json:"totalSize"`
+	FileCnt   uint32            `json:"fileCnt"`
+	FolderCnt uint32            `json:"folderCnt"`
+	Progress  *TransferProgress `json:"progress,omitempty"`
+}
+
+type ClipboardTextParams struct {
+	Text string `json:"text"`
+}
+
+// DiagnosticsParams Path is the output folder in params, default is the log folder, and the archive file in result
+type DiagnosticsParams struct {
+	Path string `json:"path"`
+}
+
+const (
+	HistoryResultSuccess = "success"
+	HistoryResultFailed  = "failed"
+)
+
+// HistoryListParams the empty field is not filtered, Keyword is searched in file names, device name and local path.
+// StartTime and EndTime are the range of transfer end time in unix milli, default Limit is 100
+type HistoryListParams struct {
+	ID        string `json:"id"`
+	Direction string `json:"direction"` // TransferDirectionSend or TransferDirectionReceive
+	Result    string `json:"result"`    // HistoryResultSuccess or HistoryResultFailed
+	Keyword   string `json:"keyword"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	Offset    int    `json:"offset"`
+	Limit     int    `json:"limit"`
+}
+
+// HistoryItem is an ended file transfer, LocalPath is the download folder of received or the source folder of sent
+type HistoryItem struct {
+	Index      int64    `json:"index"`
+	ID         string   `json:"id"`
+	DeviceName string   `json:"deviceName"`
+	Platform   string   `json:"platform"`
+	Direction  string   `json:"direction"`
+	TimeStamp  uint64   `json:"timestamp"`
+	FileCnt    uint32   `json:"fileCnt"`
+	FolderCnt  uint32   `json:"folderCnt"`
+	FileNames  []string `json:"fileNames"`
+	TotalDesc  string   `json:"totalDesc"`
+	LocalPath  string   `json:"localPath"`
+	TotalSize  uint64   `json:"totalSize"`
+	TransSize  uint64   `json:"transSize"`
+	StartTime  int64    `json:"startTime"` // unix milli
+	EndTime    int64    `json:"endTime"`
+	Duration   int64    `json:"duration"` // milli
+	AvgSpeed   uint64   `json:"avgSpeed"` // bytes per second
+	Success    bool     `json:"success"`
+	ResultCode int      `json:"resultCode"`
+}
+
+type HistoryIndexParams struct {
+	Index int64 `json:"index"`
+}
+
+// HistoryRevealResult Path is the existing local path of the history to be shown in file manager
+type HistoryRevealResult struct {
+	Path string `json:"path"`
+}
+
+type EventSubscribeParams struct {
+	Types []string `json:"types"` // the EventType of client/event, empty means all events
+	ID    string   `json:"id"`    // empty means all peers
+}
+
+type SubscriptionParams struct {
+	Subscription uint64 `json:"subscription"`
+}
+
+// EventParams is the params of event notification, Event is the JSON of client/event struct named by Type
+type EventParams struct {
+	Subscription uint64          `json:"subscription"`
+	Type         string          `json:"type"`
+	Event        json.RawMessage `json:"event"`
+}
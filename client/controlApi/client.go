@@ -0,0 +1,152 @@
+package controlApi
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+)
+
+const notificationChanSize = 1024
+
+var ErrClosed = errors.New("control API connection is closed")
+
+// Client calls the control API, it is safe for concurrent use
+type Client struct {
+	conn         net.Conn
+	writeMutex   sync.Mutex
+	mutex        sync.Mutex
+	nextID       uint64
+	pendingMap   map[uint64]chan Response
+	notification chan Notification
+	closed       bool
+}
+
+func Dial(sockPath string) (*Client, error) {
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:         conn,
+		pendingMap:   make(map[uint64]chan Response),
+		notification: make(chan Notification, notificationChanSize),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Notifications is closed when the connection is closed, the reading of responses is blocked if it is full
+func (c *Client) Notifications() <-chan Notification {
+	return c.notification
+}
+
+// Call sends the request and waits for its response, result can be nil if it is not needed
+func (c *Client) Call(method string, params, result interface{}) error {
+	req := Request{JsonRpc: JsonRpcVersion, Method: method}
+	if params != nil {
+		encodedParams, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = encodedParams
+	}
+
+	rspChan := make(chan Response, 1)
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	id := c.nextID
+	c.pendingMap[id] = rspChan
+	c.mutex.Unlock()
+	req.ID = json.RawMessage(strconv.FormatUint(id, 10))
+
+	encodedData, err := json.Marshal(req)
+	if err != nil {
+		c.removePending(id)
+		return err
+	}
+	encodedData = append(encodedData, '\n')
+
+	c.writeMutex.Lock()
+	_, err = c.conn.Write(encodedData)
+	c.writeMutex.Unlock()
+	if err != nil {
+		c.removePending(id)
+		return err
+	}
+
+	rsp, ok := <-rspChan
+	if !ok {
+		return ErrClosed
+	}
+	if rsp.Error != nil {
+		return rsp.Error
+	}
+	if result == nil || len(rsp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rsp.Result, result)
+}
+
+func (c *Client) removePending(id uint64) {
+	c.mutex.Lock()
+	delete(c.pendingMap, id)
+	c.mutex.Unlock()
+}
+
+func (c *Client) readLoop() {
+	defer func() {
+		c.mutex.Lock()
+		c.closed = true
+		for id, rspChan := range c.pendingMap {
+			close(rspChan)
+			delete(c.pendingMap, id)
+		}
+		c.mutex.Unlock()
+		close(c.notification)
+	}()
+
+	decoder := json.NewDecoder(c.conn)
+	for {
+		var rawMsg json.RawMessage
+		if err := decoder.Decode(&rawMsg); err != nil {
+			return
+		}
+
+		var msg struct {
+			Response
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(rawMsg, &msg); err != nil {
+			continue
+		}
+
+		if msg.Method != "" {
+			c.notification <- Notification{JsonRpc: msg.JsonRpc, Method: msg.Method, Params: msg.Params}
+			continue
+		}
+
+		id, err := strconv.ParseUint(string(msg.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+		c.mutex.Lock()
+		rspChan, ok := c.pendingMap[id]
+		delete(c.pendingMap, id)
+		c.mutex.Unlock()
+		if ok {
+			rspChan <- msg.Response
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	rtkConnection "rtk-cross-share/client/connection"
+	rtkDiagnostics "rtk-cross-share/client/diagnostics"
 	rtkLogin "rtk-cross-share/client/login"
 	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"strings"
@@ -117,6 +119,28 @@ func DebugCmdLine() {
 			rtkMisc.SetupLogConsole()
 		} else if strings.Contains(line, "SetupLogAll") {
 			rtkMisc.SetupLogConsoleFile()
+		} else if strings.HasPrefix(line, "SetLogLevel ") {
+			// SetLogLevel info,peer2peer=debug
+			rtkMisc.SetLogLevelSpec(strings.TrimPrefix(line, "SetLogLevel "))
+		} else if strings.HasPrefix(line, "SetLogFormat ") {
+			// SetLogFormat text|json
+			rtkMisc.SetLogFormat(strings.TrimPrefix(line, "SetLogFormat "))
+		} else if strings.Contains(line, "GetLogLevels") {
+			fmt.Println("LogLevels:", rtkMisc.GetLogLevels())
+		} else if strings.HasPrefix(line, "CollectDiagnostics") {
+			// CollectDiagnostics [outputDir]
+			path, errCode := rtkDiagnostics.Collect(strings.TrimSpace(strings.TrimPrefix(line, "CollectDiagnostics")))
+			fmt.Println("Diagnostics:", path, "errCode:", errCode)
+		} else if strings.HasPrefix(line, "TransferHistory") {
+			// TransferHistory [keyword]
+			keyword := strings.TrimSpace(strings.TrimPrefix(line, "TransferHistory"))
+			itemList, errCode := rtkTransferHistory.QueryTransferHistory(rtkTransferHistory.TransferHistoryFilter{Keyword: keyword})
+			for _, item := range itemList {
+				fmt.Printf("%+v\n", item)
+			}
+			fmt.Println("TransferHistory count:", len(itemList), "errCode:", errCode)
+		} else if strings.HasPrefix(line, "ClearTransferHistory") {
+			rtkTransferHistory.ClearTransferHistory()
 		} else if strings.Contains(line, "reqClient") {
 			rtkLogin.SendReqClientListToLanServer()
 		} /*else if strings.Contains(line, "StopLanServerRun") {
@@ -0,0 +1,91 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	rtkBuildConfig "rtk-cross-share/client/buildConfig"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+const (
+	bundleName          = "crossShareDiag"
+	recentErrorMaxCount = 100
+)
+
+type errorRecord struct {
+	Time  string
+	Type  rtkEvent.EventType
+	Event rtkEvent.Event
+}
+
+var (
+	recentErrorList  = make([]errorRecord, 0, recentErrorMaxCount)
+	recentErrorMutex sync.Mutex
+)
+
+func init() {
+	rtkEvent.Subscribe(rtkEvent.Filter{Types: []rtkEvent.EventType{
+		rtkEvent.EventError,
+		rtkEvent.EventTransferFailed,
+	}}, func(ev rtkEvent.Event) {
+		recentErrorMutex.Lock()
+		defer recentErrorMutex.Unlock()
+		if len(recentErrorList) >= recentErrorMaxCount {
+			recentErrorList = append(recentErrorList[:0], recentErrorList[1:]...)
+		}
+		recentErrorList = append(recentErrorList, errorRecord{Time: time.Now().Format(time.RFC3339Nano), Type: ev.Type(), Event: ev})
+	})
+}
+
+func getRecentErrorList() []errorRecord {
+	recentErrorMutex.Lock()
+	defer recentErrorMutex.Unlock()
+	return append([]errorRecord(nil), recentErrorList...)
+}
+
+// Collect writes the diagnostics archive into outputDir and returns its path, empty outputDir is the log folder
+func Collect(outputDir string) (string, rtkMisc.CrossShareErr) {
+	if outputDir == "" {
+		outputDir = os.TempDir()
+		if rtkMisc.LogPath != "" {
+			outputDir = filepath.Dir(rtkMisc.LogPath)
+		}
+	}
+
+	bundle, err := rtkMisc.NewDiagBundle(outputDir, bundleName)
+	if err != nil {
+		log.Printf("[%s] create diagnostics archive in [%s] err:%+v", rtkMisc.GetFuncInfo(), outputDir, err)
+		return "", rtkMisc.ERR_BIZ_DIAG_CREATE_FILE
+	}
+
+	bundle.AddSystemInfo(rtkGlobal.NodeInfo.Platform+" client", rtkGlobal.ClientVersion, rtkBuildConfig.BuildDate)
+	bundle.AddJson("nodeInfo.json", rtkGlobal.NodeInfo)
+	bundle.AddWriterFunc("streamPool.txt", rtkConnection.DumpStreamPool)
+	bundle.AddWriterFunc("clientInfoMap.json", func(w io.Writer) error {
+		rtkGlobal.ClientListRWMutex.RLock()
+		defer rtkGlobal.ClientListRWMutex.RUnlock()
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rtkGlobal.ClientInfoMap)
+	})
+	bundle.AddJson("filesTransferCache.json", rtkFileDrop.GetFilesTransferCacheDump())
+	bundle.AddJson("recentErrors.json", getRecentErrorList())
+	bundle.AddLogFiles()
+
+	path, err := bundle.Close()
+	if err != nil {
+		log.Printf("[%s] close diagnostics archive err:%+v", rtkMisc.GetFuncInfo(), err)
+		return "", rtkMisc.ERR_BIZ_DIAG_CREATE_FILE
+	}
+	log.Printf("[%s] diagnostics archive:[%s]", rtkMisc.GetFuncInfo(), path)
+	return path, rtkMisc.SUCCESS
+}
@@ -0,0 +1,120 @@
+package event
+
+import (
+	"log"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+)
+
+type Handler func(ev Event)
+
+// Filter matches all events if it is empty
+type Filter struct {
+	Types  []EventType
+	PeerID string
+}
+
+func (f Filter) Match(ev Event) bool {
+	if f.PeerID != "" && f.PeerID != ev.PeerID() {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, evType := range f.Types {
+		if evType == ev.Type() {
+			return true
+		}
+	}
+	return false
+}
+
+type subscriber struct {
+	id      uint64
+	filter  Filter
+	handler Handler
+}
+
+// Bus calls the handlers synchronously in the publisher goroutine by subscribing order,
+// so the events of one transfer keep their order for every subscriber
+type Bus struct {
+	mutex       sync.RWMutex
+	nextID      uint64
+	subscribers []subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make([]subscriber, 0)}
+}
+
+func (b *Bus) Subscribe(filter Filter, handler Handler) uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.nextID++
+	b.subscribers = append(b.subscribers, subscriber{id: b.nextID, filter: filter, handler: handler})
+	return b.nextID
+}
+
+// SubscribeChan is for the observer out of the publisher goroutine, the event is dropped if the channel is full.
+// The channel is not closed by Unsubscribe
+func (b *Bus) SubscribeChan(filter Filter, size int) (uint64, <-chan Event) {
+	ch := make(chan Event, size)
+	id := b.Subscribe(filter, func(ev Event) {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("[%s] event channel is full, drop event:[%s]", rtkMisc.GetFuncInfo(), ev.Type())
+		}
+	})
+	return id, ch
+}
+
+func (b *Bus) Unsubscribe(id uint64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, sub := range b.subscribers {
+		if sub.id == id {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Bus) Publish(ev Event) {
+	b.mutex.RLock()
+	subscribers := b.subscribers
+	b.mutex.RUnlock()
+
+	for _, sub := range subscribers {
+		if sub.filter.Match(ev) {
+			callHandler(sub.handler, ev)
+		}
+	}
+}
+
+func callHandler(handler Handler, ev Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] event:[%s] handler panic:%+v", rtkMisc.GetFuncInfo(), ev.Type(), r)
+		}
+	}()
+	handler(ev)
+}
+
+var defaultBus = NewBus()
+
+func Subscribe(filter Filter, handler Handler) uint64 {
+	return defaultBus.Subscribe(filter, handler)
+}
+
+func SubscribeChan(filter Filter, size int) (uint64, <-chan Event) {
+	return defaultBus.SubscribeChan(filter, size)
+}
+
+func Unsubscribe(id uint64) {
+	defaultBus.Unsubscribe(id)
+}
+
+func Publish(ev Event) {
+	defaultBus.Publish(ev)
+}
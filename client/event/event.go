@@ -0,0 +1,138 @@
+package event
+
+import (
+	rtkMisc "rtk-cross-share/misc"
+)
+
+type EventType string
+
+const (
+	EventPeerOnline        EventType = "PeerOnline"
+	EventPeerOffline       EventType = "PeerOffline"
+	EventTransferRequest   EventType = "TransferRequest"
+	EventTransferProgress  EventType = "TransferProgress"
+	EventTransferDone      EventType = "TransferDone"
+	EventTransferFailed    EventType = "TransferFailed"
+	EventClipboardReceived EventType = "ClipboardReceived"
+	EventLanServerStatus   EventType = "LanServerStatus"
+	EventPairingRequest    EventType = "PairingRequest"
+	EventError             EventType = "Error"
+)
+
+// Event is published to Bus, PeerID is empty if the event is not about a peer
+type Event interface {
+	Type() EventType
+	PeerID() string
+}
+
+type PeerStatusEvent struct {
+	rtkMisc.ClientInfo // only ID is valid if the peer is offline
+	IsOnline           bool
+	TimeStamp          int64
+}
+
+func (e PeerStatusEvent) Type() EventType {
+	if e.IsOnline {
+		return EventPeerOnline
+	}
+	return EventPeerOffline
+}
+
+func (e PeerStatusEvent) PeerID() string { return e.ID }
+
+// TransferRequestEvent is the received file drop which waits for accepting or rejecting by platform
+type TransferRequestEvent struct {
+	ID        string
+	IPAddr    string
+	Platform  string
+	TotalDesc string
+	FileCnt   uint32
+	FolderCnt uint32
+	TotalSize uint64
+	TimeStamp uint64
+}
+
+func (e TransferRequestEvent) Type() EventType { return EventTransferRequest }
+func (e TransferRequestEvent) PeerID() string  { return e.ID }
+
+type TransferProgressEvent struct {
+	ID              string
+	IPAddr          string
+	CurrentFileName string
+	FileCnt         uint32
+	TotalFileCnt    uint32
+	CurrentFileSize uint64
+	TotalSize       uint64
+	TransSize       uint64
+	TimeStamp       uint64 // the file transfer ID
+	IsSender        bool
+}
+
+func (e TransferProgressEvent) Type() EventType { return EventTransferProgress }
+func (e TransferProgressEvent) PeerID() string  { return e.ID }
+
+type TransferDoneEvent struct {
+	ID         string
+	FileName   string // the description of transferred files, e.g. "3 files"
+	ClientName string
+	Platform   string
+	TimeStamp  uint64
+	IsSender   bool
+}
+
+func (e TransferDoneEvent) Type() EventType { return EventTransferDone }
+func (e TransferDoneEvent) PeerID() string  { return e.ID }
+
+type TransferFailedEvent struct {
+	ID        string
+	IPAddr    string
+	TimeStamp uint64
+	Code      rtkMisc.CrossShareErr
+}
+
+func (e TransferFailedEvent) Type() EventType { return EventTransferFailed }
+func (e TransferFailedEvent) PeerID() string  { return e.ID }
+
+type ClipboardReceivedEvent struct {
+	ID    string
+	Text  []byte
+	Image []byte
+	Html  []byte
+	Rtf   []byte
+}
+
+func (e ClipboardReceivedEvent) Type() EventType { return EventClipboardReceived }
+func (e ClipboardReceivedEvent) PeerID() string  { return e.ID }
+
+// LanServerStatusEvent Status is the DIAS status of client login flow
+type LanServerStatusEvent struct {
+	Status uint32
+}
+
+func (e LanServerStatusEvent) Type() EventType { return EventLanServerStatus }
+func (e LanServerStatusEvent) PeerID() string  { return "" }
+
+// PairingRequestEvent is the unknown peer which waits for confirming by comparing SAS on both sides
+type PairingRequestEvent struct {
+	ID         string
+	IPAddr     string
+	DeviceName string
+	Platform   string
+	SAS        string
+}
+
+func (e PairingRequestEvent) Type() EventType { return EventPairingRequest }
+func (e PairingRequestEvent) PeerID() string  { return e.ID }
+
+// ErrorEvent is the error notified to platform, the meaning of args depends on Code
+type ErrorEvent struct {
+	ID   string
+	Code rtkMisc.CrossShareErr
+	Arg1 string
+	Arg2 string
+	Arg3 string
+	Arg4 string
+}
+
+func (e ErrorEvent) Type() EventType { return EventError }
+func (e ErrorEvent) PeerID() string  { return e.ID }
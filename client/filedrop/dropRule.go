@@ -0,0 +1,218 @@
+package filedrop
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File drop rule is evaluated on Dst when a FILE_DROP_REQUEST is received.
+// The request is rejected if it is larger than MaxTotalSize or any file is not allowed by extension, even from the trusted peer.
+// Otherwise it is accepted at once if the peer is trusted or GetConfirmDocumentsAccept is false, else it waits for the platform
+// confirmation and is rejected if it's not confirmed in ConfirmTimeout. The peer is matched only by ID, because the device
+// name is set by the peer itself and anyone can claim it.
+type fileDropDecision string
+
+const (
+	fileDropDecisionAccept  fileDropDecision = "ACCEPT"
+	fileDropDecisionReject  fileDropDecision = "REJECT"
+	fileDropDecisionConfirm fileDropDecision = "CONFIRM"
+)
+
+// FileDropRule is set by platform in JSON, the missing fields keep the default value which confirms or accepts all as before
+type FileDropRule struct {
+	TrustedPeers      []string          // peer ID, accepted without confirmation
+	MaxTotalSize      uint64            // bytes, 0 means no limit
+	AllowExtensions   []string          // e.g. ".pdf", empty allows all, the file without extension is not allowed if it is set
+	DenyExtensions    []string          // e.g. ".exe"
+	PeerDownloadPaths map[string]string // key: peer ID, the absolute folder to save files, missing peer uses download path
+	ConfirmTimeout    int               // seconds, 0 means waiting for the confirmation forever
+}
+
+var (
+	fileDropRule      = getDefaultFileDropRule()
+	fileDropRuleMutex sync.RWMutex
+
+	confirmTimerMap   = make(map[string]*time.Timer) // key: ID
+	confirmTimerMutex sync.Mutex
+)
+
+func getDefaultFileDropRule() FileDropRule {
+	return FileDropRule{
+		TrustedPeers:      []string{},
+		AllowExtensions:   []string{},
+		DenyExtensions:    []string{},
+		PeerDownloadPaths: map[string]string{},
+	}
+}
+
+// normalizeExtensions makes the extensions lower case and start with '.'
+func normalizeExtensions(extList []string) []string {
+	normalList := make([]string, 0, len(extList))
+	for _, ext := range extList {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalList = append(normalList, ext)
+	}
+	return normalList
+}
+
+func parseFileDropRule(ruleJson string) (FileDropRule, error) {
+	rule := getDefaultFileDropRule()
+	if strings.TrimSpace(ruleJson) == "" {
+		return rule, nil
+	}
+	if err := json.Unmarshal([]byte(ruleJson), &rule); err != nil {
+		return rule, fmt.Errorf("invalid file drop rule: %w", err)
+	}
+	if rule.ConfirmTimeout < 0 {
+		return rule, fmt.Errorf("invalid file drop rule, ConfirmTimeout:[%d] is negative", rule.ConfirmTimeout)
+	}
+	for peer, path := range rule.PeerDownloadPaths {
+		if !filepath.IsAbs(path) {
+			return rule, fmt.Errorf("invalid file drop rule, download path:[%s] of peer:[%s] is not absolute", path, peer)
+		}
+	}
+	rule.AllowExtensions = normalizeExtensions(rule.AllowExtensions)
+	rule.DenyExtensions = normalizeExtensions(rule.DenyExtensions)
+	if rule.TrustedPeers == nil {
+		rule.TrustedPeers = []string{}
+	}
+	if rule.PeerDownloadPaths == nil {
+		rule.PeerDownloadPaths = map[string]string{}
+	}
+	return rule, nil
+}
+
+// SetFileDropRule sets the rule in JSON of FileDropRule, empty resets it to default
+func SetFileDropRule(ruleJson string) bool {
+	rule, err := parseFileDropRule(ruleJson)
+	if err != nil {
+		logger.Error("set file drop rule failed", rtkMisc.ErrAttr(err))
+		return false
+	}
+
+	fileDropRuleMutex.Lock()
+	fileDropRule = rule
+	fileDropRuleMutex.Unlock()
+	logger.Info("set file drop rule", "rule", ruleJson)
+	return true
+}
+
+// GetFileDropRule returns JSON of FileDropRule in use
+func GetFileDropRule() string {
+	fileDropRuleMutex.RLock()
+	defer fileDropRuleMutex.RUnlock()
+	encodedData, err := json.Marshal(fileDropRule)
+	if err != nil {
+		logger.Error("marshal file drop rule failed", rtkMisc.ErrAttr(err))
+		return ""
+	}
+	return string(encodedData)
+}
+
+func getFileDropRule() FileDropRule {
+	fileDropRuleMutex.RLock()
+	defer fileDropRuleMutex.RUnlock()
+	rule := fileDropRule
+	rule.PeerDownloadPaths = maps.Clone(fileDropRule.PeerDownloadPaths)
+	return rule
+}
+
+// GetPeerDownloadPath returns the folder to save files from peer ID, the download path of platform if the peer has no its own folder
+func GetPeerDownloadPath(id string) string {
+	path, ok := getFileDropRule().PeerDownloadPaths[id]
+	if !ok {
+		return rtkPlatform.GetDownloadPath()
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		logger.Warn("create peer download path failed, use the default", rtkMisc.PeerIDAttr(id), "path", path, rtkMisc.ErrAttr(err))
+		return rtkPlatform.GetDownloadPath()
+	}
+	return path
+}
+
+// getFileExtension returns the lower case extension with '.', the trailing dots and spaces are trimmed first
+// because windows drops them when the file is created, e.g. "evil.exe." is saved as "evil.exe"
+func getFileExtension(fileName string) string {
+	baseName := strings.TrimRight(filepath.Base(rtkMisc.AdaptationPath(fileName)), ". ")
+	return strings.ToLower(filepath.Ext(baseName))
+}
+
+// evaluateFileDropRule returns the decision of received file drop, and the reason if it is rejected.
+// MaxTotalSize is checked with the file sizes which are really transferred, not the TotalSize declared by peer
+func evaluateFileDropRule(id string, fileList []rtkCommon.FileInfo) (fileDropDecision, string) {
+	rule := getFileDropRule()
+	totalSize := uint64(0)
+	for _, fileInfo := range fileList {
+		totalSize += uint64(fileInfo.FileSize_.SizeHigh)<<32 | uint64(fileInfo.FileSize_.SizeLow)
+		if rule.MaxTotalSize > 0 && totalSize > rule.MaxTotalSize { // check in loop so the sum never wraps around
+			return fileDropDecisionReject, fmt.Sprintf("total size of files is over max size:[%d]", rule.MaxTotalSize)
+		}
+	}
+	for _, fileInfo := range fileList {
+		ext := getFileExtension(fileInfo.FileName)
+		if slices.Contains(rule.DenyExtensions, ext) {
+			return fileDropDecisionReject, fmt.Sprintf("file:[%s] extension is denied", fileInfo.FileName)
+		}
+		if len(rule.AllowExtensions) > 0 && (ext == "" || !slices.Contains(rule.AllowExtensions, ext)) {
+			return fileDropDecisionReject, fmt.Sprintf("file:[%s] extension is not allowed", fileInfo.FileName)
+		}
+	}
+
+	if slices.Contains(rule.TrustedPeers, id) {
+		return fileDropDecisionAccept, ""
+	}
+	if rtkPlatform.GetConfirmDocumentsAccept() {
+		return fileDropDecisionConfirm, ""
+	}
+	return fileDropDecisionAccept, ""
+}
+
+// startConfirmTimer rejects the file drop of ID and timestamp if it is still not accepted or rejected by platform after ConfirmTimeout
+func startConfirmTimer(id, ipAddr string, timestamp uint64) {
+	timeout := time.Duration(getFileDropRule().ConfirmTimeout) * time.Second
+	stopConfirmTimer(id)
+	if timeout <= 0 {
+		return
+	}
+
+	confirmTimerMutex.Lock()
+	defer confirmTimerMutex.Unlock()
+	confirmTimerMap[id] = time.AfterFunc(timeout, func() {
+		confirmTimerMutex.Lock()
+		delete(confirmTimerMap, id)
+		confirmTimerMutex.Unlock()
+
+		fileDropData, ok := GetFileDropData(id)
+		if !ok || fileDropData.Cmd != rtkCommon.FILE_DROP_REQUEST || fileDropData.TimeStamp != timestamp {
+			return
+		}
+		logger.Warn("file drop is not confirmed in time, reject it", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), rtkMisc.TransferIDAttr(timestamp), "timeout", timeout)
+		UpdateFileDropRespDataFromDst(id, rtkCommon.FILE_DROP_REJECT, "")
+		rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timestamp, rtkMisc.ERR_BIZ_FD_CONFIRM_TIMEOUT)
+	})
+}
+
+func stopConfirmTimer(id string) {
+	confirmTimerMutex.Lock()
+	defer confirmTimerMutex.Unlock()
+	if timer, ok := confirmTimerMap[id]; ok {
+		timer.Stop()
+		delete(confirmTimerMap, id)
+	}
+}
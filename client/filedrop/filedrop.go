@@ -3,9 +3,9 @@ package filedrop
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"path/filepath"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
 	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
@@ -41,7 +41,7 @@ func UpdateFileListDropReqDataFromLocal(id string, fileInfoList []rtkCommon.File
 	}
 
 	if ipAddr == "" {
-		log.Printf("[%s] ID:[%s] Not found client map data\n\n", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("not found client map data", rtkMisc.PeerIDAttr(id))
 		return
 	}
 
@@ -62,6 +62,10 @@ func UpdateFileListDropReqDataFromDst(id string, fileInfoList []rtkCommon.FileIn
 }
 
 func UpdateFileDropRespDataFromLocal(id string, cmd rtkCommon.FileDropCmd, filePath string) {
+	stopConfirmTimer(id)
+	if cmd == rtkCommon.FILE_DROP_ACCEPT && filepath.Clean(filePath) == filepath.Clean(rtkPlatform.GetDownloadPath()) {
+		filePath = GetPeerDownloadPath(id) // platform confirms with its default folder, the folders are already renamed in the peer folder
+	}
 	updateFileDropRespData(id, cmd, filePath)
 
 	nCount := rtkUtils.GetClientCount()
@@ -83,7 +87,7 @@ func updateFileDropRespData(id string, cmd rtkCommon.FileDropCmd, filePath strin
 	fileDropDataMutex.Lock()
 	if fileDropData, ok := fileDropDataMap[id]; ok {
 		if fileDropData.ActionType != rtkCommon.P2PFileActionType_Drop {
-			log.Printf("[%s] Err: Update file drop failed. Invalid ActionType: %s", rtkMisc.GetFuncInfo(), fileDropData.ActionType)
+			logger.Error("update file drop failed, invalid action type", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(fileDropData.TimeStamp), "action_type", fileDropData.ActionType)
 			return
 		}
 
@@ -100,7 +104,7 @@ func updateFileDropRespData(id string, cmd rtkCommon.FileDropCmd, filePath strin
 			fileDropData.Cmd = cmd
 			fileDropDataMap[id] = fileDropData
 		} else {
-			log.Printf("[%s] Err: Update file drop failed. Invalid state:%s", rtkMisc.GetFuncInfo(), fileDropData.Cmd)
+			logger.Error("update file drop failed, invalid state", rtkMisc.PeerIDAttr(id), rtkMisc.TransferIDAttr(fileDropData.TimeStamp), "state", fileDropData.Cmd, "cmd", cmd)
 		}
 	}
 	fileDropDataMutex.Unlock()
@@ -113,6 +117,12 @@ func GetFileDropData(id string) (FileDropData, bool) {
 	return fileDropData, ok
 }
 
+// IsFileDropRejected returns true if the received file drop of ID is rejected and the response is still not sent to Src
+func IsFileDropRejected(id string) bool {
+	fileDropData, ok := GetFileDropData(id)
+	return ok && fileDropData.ActionType == rtkCommon.P2PFileActionType_Drop && fileDropData.Cmd == rtkCommon.FILE_DROP_REJECT
+}
+
 func GetFileDropDataLen(id string) int {
 	fileDropDataMutex.RLock()
 	fileDropData, ok := fileDropDataMap[id]
@@ -120,12 +130,12 @@ func GetFileDropDataLen(id string) int {
 	if ok {
 		encodedData, err := json.Marshal(fileDropData)
 		if err != nil {
-			log.Printf("[%s] Failed to Marshal fileDropData data, err: %+v", rtkMisc.GetFuncInfo(), err)
+			logger.Error("marshal file drop data failed", rtkMisc.PeerIDAttr(id), rtkMisc.ErrAttr(err))
 		} else {
 			return len(encodedData)
 		}
 	} else {
-		log.Printf("[%s], ID[%s] Not fount file data", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("not found file drop data", rtkMisc.PeerIDAttr(id))
 	}
 	return 0
 }
@@ -137,12 +147,12 @@ func GetFileDropDataDetailsData(id string) []byte {
 	if ok {
 		encodedData, err := json.Marshal(fileDropData)
 		if err != nil {
-			log.Printf("[%s] Failed to Marshal fileDropData data, err: %+v", rtkMisc.GetFuncInfo(), err)
+			logger.Error("marshal file drop data failed", rtkMisc.PeerIDAttr(id), rtkMisc.ErrAttr(err))
 		} else {
 			return encodedData
 		}
 	} else {
-		log.Printf("[%s], ID[%s] Not fount file data", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("not found file drop data", rtkMisc.PeerIDAttr(id))
 	}
 	return nil
 }
@@ -153,7 +163,7 @@ func getFileDropDataDetails(id, ipAddr string) string {
 	fileDropDataMutex.RUnlock()
 
 	if !ok {
-		log.Printf("[%s], ID[%s] Not fount file data", rtkMisc.GetFuncInfo(), id)
+		logger.Warn("not found file drop data", rtkMisc.PeerIDAttr(id))
 		return ""
 	}
 
@@ -198,7 +208,7 @@ func getFileDropDataDetails(id, ipAddr string) string {
 
 	encodedData, err := json.Marshal(notifyInfo)
 	if err != nil {
-		log.Printf("[%s] Failed to Marshal FileDataTransDetails data, err: %+v", rtkMisc.GetFuncInfo(), err)
+		logger.Error("marshal file drop details failed", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), rtkMisc.TransferIDAttr(fileDropData.TimeStamp), rtkMisc.ErrAttr(err))
 		return ""
 	}
 
@@ -260,26 +270,52 @@ func WatchFileDropRespEvent(ctx context.Context, id string, resultChan chan<- st
 // ********************  Setup Dst file info ****************
 
 func SetupDstFileListDrop(id, ip, platform, totalDesc string, fileList []rtkCommon.FileInfo, folderList []string, totalSize, timestamp uint64) {
+	decision, reason := evaluateFileDropRule(id, fileList)
+	setupDstFileDropRequest(id, ip, platform, totalDesc, fileList, folderList, totalSize, timestamp, decision, reason)
+}
+
+// setupDstFileDropRequest accepts, rejects or asks platform to confirm the received file drop by the decision of file drop rule
+func setupDstFileDropRequest(id, ip, platform, totalDesc string, fileList []rtkCommon.FileInfo, folderList []string, totalSize, timestamp uint64, decision fileDropDecision, reason string) {
+	downloadPath := rtkPlatform.GetDownloadPath()
+	if decision != fileDropDecisionReject {
+		downloadPath = GetPeerDownloadPath(id)
+	}
 	if len(folderList) > 0 {
-		fileList, folderList = rtkUtils.GetTargetFileList(rtkPlatform.GetDownloadPath(), fileList, folderList)
+		fileList, folderList = rtkUtils.GetTargetFileList(downloadPath, fileList, folderList)
 	}
 	UpdateFileListDropReqDataFromDst(id, fileList, folderList, totalSize, timestamp, totalDesc)
 
-	if rtkPlatform.GetConfirmDocumentsAccept() {
+	switch decision {
+	case fileDropDecisionReject:
+		logger.Warn("file drop is rejected by rule", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ip), rtkMisc.TransferIDAttr(timestamp), "reason", reason)
+		UpdateFileDropRespDataFromDst(id, rtkCommon.FILE_DROP_REJECT, "")
+		rtkPlatform.GoNotifyFileTransferErrEvent(id, ip, timestamp, rtkMisc.ERR_BIZ_FD_REJECTED_BY_RULE)
+	case fileDropDecisionConfirm:
+		rtkEvent.Publish(rtkEvent.TransferRequestEvent{
+			ID:        id,
+			IPAddr:    ip,
+			Platform:  platform,
+			TotalDesc: totalDesc,
+			FileCnt:   uint32(len(fileList)),
+			FolderCnt: uint32(len(folderList)),
+			TotalSize: totalSize,
+			TimeStamp: timestamp,
+		})
 		rtkPlatform.GoSetupFileListDrop(ip, id, platform, totalDesc, uint32(len(fileList)), uint32(len(folderList)), timestamp) // need pop-up confirmation
-	} else {
+		startConfirmTimer(id, ip, timestamp)
+	default:
 		nFileCount := uint32(len(fileList))
 		firstFileSize := uint64(0)
 		firstFileName := string("")
 		if nFileCount > 0 {
 			firstFileSize = uint64(fileList[0].FileSize_.SizeHigh)<<32 | uint64(fileList[0].FileSize_.SizeLow)
-			firstFileName = filepath.Join(rtkPlatform.GetDownloadPath(), rtkMisc.AdaptationPath(fileList[0].FileName))
+			firstFileName = filepath.Join(downloadPath, rtkMisc.AdaptationPath(fileList[0].FileName))
 		} else {
 			firstFileName = filepath.Join(rtkMisc.AdaptationPath(folderList[0]))
 		}
 
 		firstFileName, _ = rtkUtils.GetTargetDstPathName(firstFileName, "")
-		UpdateFileDropRespDataFromDst(id, rtkCommon.FILE_DROP_ACCEPT, rtkPlatform.GetDownloadPath())
+		UpdateFileDropRespDataFromDst(id, rtkCommon.FILE_DROP_ACCEPT, downloadPath)
 		rtkPlatform.GoFileListReceiveNotify(ip, id, nFileCount, totalSize, timestamp, firstFileName, firstFileSize, getFileDropDataDetails(id, ip)) //No need to confirm
 	}
 }
@@ -288,36 +324,19 @@ func SetupDstFileDropDataDetails(id, ipAddr string, details []byte) rtkMisc.Cros
 	var fileDataInfo FileDropData
 	err := json.Unmarshal(details, &fileDataInfo)
 	if err != nil {
-		log.Printf("[%s] Unmarshal details[%s] err:%+v", rtkMisc.GetFuncInfo(), string(details), err)
+		logger.Error("unmarshal file drop details failed", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), "details", string(details), rtkMisc.ErrAttr(err))
 		return rtkMisc.ERR_BIZ_JSON_UNMARSHAL
 	}
 
 	if len(fileDataInfo.SrcFileList) == 0 && len(fileDataInfo.FolderList) == 0 {
-		log.Printf("[%s] ID[%s] IP:[%s] get file drop data is null", rtkMisc.GetFuncInfo(), id, ipAddr)
+		logger.Warn("file drop data is empty", rtkMisc.PeerIDAttr(id), rtkMisc.IPAttr(ipAddr), rtkMisc.TransferIDAttr(fileDataInfo.TimeStamp), rtkMisc.ErrCodeAttr(rtkMisc.ERR_BIZ_FD_DATA_INVALID))
 		return rtkMisc.ERR_BIZ_FD_DATA_INVALID
 	}
 
-	if len(fileDataInfo.FolderList) > 0 {
-		fileDataInfo.SrcFileList, fileDataInfo.FolderList = rtkUtils.GetTargetFileList(rtkPlatform.GetDownloadPath(), fileDataInfo.SrcFileList, fileDataInfo.FolderList)
-	}
-
-	if !rtkPlatform.GetConfirmDocumentsAccept() { //No need to confirm
-		UpdateFileListDropReqDataFromDst(id, fileDataInfo.SrcFileList, fileDataInfo.FolderList, fileDataInfo.TotalSize, fileDataInfo.TimeStamp, fileDataInfo.TotalDescribe)
-
-		nFileCount := uint32(len(fileDataInfo.SrcFileList))
-		firstFileSize := uint64(0)
-		firstFileName := string("")
-		if nFileCount > 0 {
-			firstFileSize = uint64(fileDataInfo.SrcFileList[0].FileSize_.SizeHigh)<<32 | uint64(fileDataInfo.SrcFileList[0].FileSize_.SizeLow)
-			firstFileName = filepath.Join(rtkPlatform.GetDownloadPath(), rtkMisc.AdaptationPath(fileDataInfo.SrcFileList[0].FileName))
-		} else {
-			firstFileName = filepath.Join(rtkMisc.AdaptationPath(fileDataInfo.FolderList[0]))
-		}
-
-		firstFileName, _ = rtkUtils.GetTargetDstPathName(firstFileName, "")
-		UpdateFileDropRespDataFromDst(id, rtkCommon.FILE_DROP_ACCEPT, rtkPlatform.GetDownloadPath())
-		rtkPlatform.GoFileListReceiveNotify(ipAddr, id, nFileCount, fileDataInfo.TotalSize, fileDataInfo.TimeStamp, firstFileName, firstFileSize, getFileDropDataDetails(id, ipAddr))
+	decision, reason := evaluateFileDropRule(id, fileDataInfo.SrcFileList)
+	if decision == fileDropDecisionConfirm { // keep it to platform as before, only the accept and reject decisions are applied here
+		return rtkMisc.SUCCESS
 	}
-
+	setupDstFileDropRequest(id, ipAddr, "", fileDataInfo.TotalDescribe, fileDataInfo.SrcFileList, fileDataInfo.FolderList, fileDataInfo.TotalSize, fileDataInfo.TimeStamp, decision, reason)
 	return rtkMisc.SUCCESS
 }
@@ -2,8 +2,11 @@ package filedrop
 
 import (
 	"log"
+	"path/filepath"
 	rtkCommon "rtk-cross-share/client/common"
 	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
+	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 )
 
@@ -22,23 +25,31 @@ func CancelFileTransfer(id, ipAddr string, timestamp uint64) {
 			return
 		}
 
-		if cacheData.filesTransferDataQueue[0].TimeStamp == timestamp {
-			if cacheData.cancelFn != nil {
-				if cacheData.filesTransferDataQueue[0].FileTransDirection == FilesTransfer_As_Src {
-					cacheData.cancelFn(rtkCommon.FileTransSrcGuiCancel)
+		if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 && cacheData.filesTransferDataQueue[i].isInProgress {
+			if item := &cacheData.filesTransferDataQueue[i]; item.cancelFn != nil {
+				if item.FileTransDirection == FilesTransfer_As_Src {
+					item.cancelFn(rtkCommon.FileTransSrcGuiCancel)
 				} else {
-					cacheData.cancelFn(rtkCommon.FileTransDstGuiCancel)
+					item.cancelFn(rtkCommon.FileTransDstGuiCancel)
 				}
-				cacheData.cancelFn = nil
+				item.cancelFn = nil
 				filesDataCacheMap[id] = cacheData
 				log.Printf("[%s] ID:[%s],IP:[%s] timestamp:[%d] CancelFileTransfer success by platform GUI!", rtkMisc.GetFuncInfo(), id, ipAddr, timestamp)
 			} else {
 				log.Printf("[%s] ID:[%s] Not fount cancelFn from cache map data\n\n", rtkMisc.GetFuncInfo(), id)
 			}
 		} else {
-			if queue, asSrc, bOk := RemoveItemFromCacheQueue(cacheData.filesTransferDataQueue, timestamp); bOk {
+			if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 {
+				item := cacheData.filesTransferDataQueue[i]
+				queue, asSrc, _ := RemoveItemFromCacheQueue(cacheData.filesTransferDataQueue, timestamp)
 				cacheData.filesTransferDataQueue = queue
 				filesDataCacheMap[id] = cacheData
+				saveTransferJournal()
+				errCode := rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_GUI
+				if asSrc {
+					errCode = rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_GUI
+				}
+				rtkMisc.GoSafe(func() { addTransferHistory(id, &item, errCode) })
 
 				log.Printf("[%s] ID:[%s],IP:[%s] timestamp:[%d] CancelFileTransfer Remove cache data success by platform GUI!", rtkMisc.GetFuncInfo(), id, ipAddr, timestamp)
 				if callbackSendCancelFileTransferMsgToPeer != nil {
@@ -62,22 +73,22 @@ func IsCancelFileTransInProgress(id string, timestamp uint64, errCode rtkMisc.Cr
 
 	if cacheData, ok := filesDataCacheMap[id]; ok {
 		if len(cacheData.filesTransferDataQueue) > 0 {
-			if cacheData.filesTransferDataQueue[0].TimeStamp == timestamp {
-				if cacheData.cancelFn != nil {
-					if cacheData.filesTransferDataQueue[0].FileTransDirection == FilesTransfer_As_Src {
+			if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 && cacheData.filesTransferDataQueue[i].isInProgress {
+				if item := &cacheData.filesTransferDataQueue[i]; item.cancelFn != nil {
+					if item.FileTransDirection == FilesTransfer_As_Src {
 						if errCode == rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_GUI {
-							cacheData.cancelFn(rtkCommon.FileTransSrcGuiCancel)
+							item.cancelFn(rtkCommon.FileTransSrcGuiCancel)
 						} else {
-							cacheData.cancelFn(rtkCommon.FileTransSrcCancel)
+							item.cancelFn(rtkCommon.FileTransSrcCancel)
 						}
 					} else {
 						if errCode == rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_GUI {
-							cacheData.cancelFn(rtkCommon.FileTransDstGuiCancel)
+							item.cancelFn(rtkCommon.FileTransDstGuiCancel)
 						} else {
-							cacheData.cancelFn(rtkCommon.FileTransDstCancel)
+							item.cancelFn(rtkCommon.FileTransDstCancel)
 						}
 					}
-					cacheData.cancelFn = nil
+					item.cancelFn = nil
 					filesDataCacheMap[id] = cacheData
 					return true
 				} else {
@@ -126,7 +137,6 @@ func setFilesDataToCache(id string, isSrc bool) uint64 {
 				InterruptLastErrCode:        rtkMisc.SUCCESS,
 				RecoverFileTransTimerCancel: nil,
 			}},
-			cancelFn: nil,
 		}
 	} else {
 		cacheData.filesTransferDataQueue = append(cacheData.filesTransferDataQueue, FilesTransferDataItem{
@@ -142,6 +152,7 @@ func setFilesDataToCache(id string, isSrc bool) uint64 {
 
 		filesDataCacheMap[id] = cacheData
 	}
+	saveTransferJournal()
 
 	return filesDataItem.TimeStamp
 }
@@ -161,19 +172,7 @@ func GetFilesTransferDataItem(id string, timestamp uint64) *FilesTransferDataIte
 				cacheData.filesTransferDataQueue[i] = itemCacheValue
 				filesDataCacheMap[id] = cacheData
 
-				return &FilesTransferDataItem{
-					FileDropData: FileDropData{
-						SrcFileList:   itemCacheValue.SrcFileList,
-						ActionType:    itemCacheValue.ActionType,
-						TimeStamp:     itemCacheValue.TimeStamp,
-						FolderList:    itemCacheValue.FolderList,
-						TotalDescribe: itemCacheValue.TotalDescribe,
-						TotalSize:     itemCacheValue.TotalSize,
-						DstFilePath:   itemCacheValue.DstFilePath,
-						Cmd:           itemCacheValue.Cmd,
-					},
-					FileTransDirection: itemCacheValue.FileTransDirection,
-				}
+				return copyCacheItem(&itemCacheValue)
 			}
 		}
 	}
@@ -181,29 +180,131 @@ func GetFilesTransferDataItem(id string, timestamp uint64) *FilesTransferDataIte
 	return nil
 }
 
-func SetFilesCacheItemComplete(id string, timestamp uint64) {
+// PeekFilesTransferDataItem get the cache item without taking it to transfer, timestamp 0 is the first item
+func PeekFilesTransferDataItem(id string, timestamp uint64) *FilesTransferDataItem {
+	fileDropDataMutex.RLock()
+	defer fileDropDataMutex.RUnlock()
+	if cacheData, ok := filesDataCacheMap[id]; ok {
+		for _, itemCacheValue := range cacheData.filesTransferDataQueue {
+			if itemCacheValue.TimeStamp == timestamp || timestamp == 0 {
+				return copyCacheItem(&itemCacheValue)
+			}
+		}
+	}
+	return nil
+}
+
+func GetFilesTransferDataList(id string) []FilesTransferDataItem {
+	fileDropDataMutex.RLock()
+	defer fileDropDataMutex.RUnlock()
+	if cacheData, ok := filesDataCacheMap[id]; ok {
+		list := make([]FilesTransferDataItem, 0, len(cacheData.filesTransferDataQueue))
+		for _, itemCacheValue := range cacheData.filesTransferDataQueue {
+			list = append(list, *copyCacheItem(&itemCacheValue))
+		}
+		return list
+	}
+	return nil
+}
+
+func copyCacheItem(item *FilesTransferDataItem) *FilesTransferDataItem {
+	return &FilesTransferDataItem{
+		FileDropData: FileDropData{
+			SrcFileList:   item.SrcFileList,
+			ActionType:    item.ActionType,
+			TimeStamp:     item.TimeStamp,
+			FolderList:    item.FolderList,
+			TotalDescribe: item.TotalDescribe,
+			TotalSize:     item.TotalSize,
+			DstFilePath:   item.DstFilePath,
+			Cmd:           item.Cmd,
+		},
+		FileTransDirection:          item.FileTransDirection,
+		InterruptSrcFileName:        item.InterruptSrcFileName,
+		InterruptDstFileName:        item.InterruptDstFileName,
+		InterruptDstFullPath:        item.InterruptDstFullPath,
+		InterruptFileOffSet:         item.InterruptFileOffSet,
+		InterruptDoneRanges:         item.InterruptDoneRanges,
+		InterruptLastErrCode:        item.InterruptLastErrCode,
+		RecoverFileTransTimerCancel: item.RecoverFileTransTimerCancel,
+	}
+}
+
+func findCacheItemIndex(queue []FilesTransferDataItem, timestamp uint64) int {
+	for i, item := range queue {
+		if item.TimeStamp == timestamp {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetFilesCacheItemComplete removes the ended item from cache and adds it into transfer history with resultCode,
+// the history is written out of the transfer loop so the next queued item is not delayed by database
+func SetFilesCacheItemComplete(id string, timestamp uint64, resultCode rtkMisc.CrossShareErr) {
+	if item := removeFilesCacheItem(id, timestamp); item != nil {
+		rtkMisc.GoSafe(func() { addTransferHistory(id, item, resultCode) })
+	}
+}
+
+func removeFilesCacheItem(id string, timestamp uint64) *FilesTransferDataItem {
 	fileDropDataMutex.Lock()
 	defer fileDropDataMutex.Unlock()
 	if cacheData, ok := filesDataCacheMap[id]; ok {
 		nItemCount := len(cacheData.filesTransferDataQueue)
 		if nItemCount > 0 {
-			if cacheData.filesTransferDataQueue, _, ok = RemoveItemFromCacheQueue(cacheData.filesTransferDataQueue, timestamp); !ok {
+			i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp)
+			if i < 0 {
 				log.Printf("[%s] ID:[%s] Not fount cache map data, Item id:[%d]\n\n", rtkMisc.GetFuncInfo(), id, timestamp)
-				return
+				return nil
 			}
-			if nItemCount == 1 && ok {
+			item := cacheData.filesTransferDataQueue[i]
+			cacheData.filesTransferDataQueue, _, _ = RemoveItemFromCacheQueue(cacheData.filesTransferDataQueue, timestamp)
+			if nItemCount == 1 {
 				log.Printf("[%s] ID:[%s] compelete a files cache item, id:[%d], all files cache data done! \n\n", rtkMisc.GetFuncInfo(), id, timestamp)
 			} else {
 				log.Printf("[%s] ID:[%s] compelete a files cache item, id:[%d], still %d records left", rtkMisc.GetFuncInfo(), id, timestamp, nItemCount-1)
 			}
-			//cacheData.cancelFn = nil
 			filesDataCacheMap[id] = cacheData
+			saveTransferJournal()
+			return &item
 		} else {
 			log.Printf("[%s] ID:[%s] Not fount cache map data\n\n", rtkMisc.GetFuncInfo(), id)
 		}
 	} else {
 		log.Printf("[%s] ID:[%s] Not fount cache map data\n\n", rtkMisc.GetFuncInfo(), id)
 	}
+	return nil
+}
+
+func addTransferHistory(id string, item *FilesTransferDataItem, resultCode rtkMisc.CrossShareErr) {
+	historyItem := rtkTransferHistory.TransferHistoryItem{
+		ID:         id,
+		Direction:  rtkTransferHistory.DirectionReceive,
+		TimeStamp:  item.TimeStamp,
+		FileCnt:    uint32(len(item.SrcFileList)),
+		FolderCnt:  uint32(len(item.FolderList)),
+		FileNames:  make([]string, 0, len(item.SrcFileList)),
+		TotalDesc:  item.TotalDescribe,
+		LocalPath:  item.DstFilePath,
+		TotalSize:  item.TotalSize,
+		ResultCode: resultCode,
+	}
+	for _, fileInfo := range item.SrcFileList {
+		historyItem.FileNames = append(historyItem.FileNames, fileInfo.FileName)
+	}
+	if item.FileTransDirection == FilesTransfer_As_Src {
+		historyItem.Direction = rtkTransferHistory.DirectionSend
+		historyItem.LocalPath = item.SrcRootPath
+		if historyItem.LocalPath == "" && len(item.SrcFileList) > 0 {
+			historyItem.LocalPath = filepath.Dir(item.SrcFileList[0].FilePath)
+		}
+	}
+	if clientInfo, err := rtkUtils.GetClientInfo(id); err == nil {
+		historyItem.ClientName = clientInfo.DeviceName
+		historyItem.Platform = clientInfo.Platform
+	}
+	rtkTransferHistory.AddTransferHistory(historyItem)
 }
 
 func GetFilesTransferDataCacheCount(id string) int {
@@ -231,6 +332,55 @@ func GetFilesTransferDataSendCacheCount(id string) int {
 	return nSendCount
 }
 
+// FilesTransferCacheDump is the state of a file transfer in cache queue, it is for diagnostics
+type FilesTransferCacheDump struct {
+	TimeStamp            uint64
+	Direction            FilesTransferDirectionType
+	Cmd                  rtkCommon.FileDropCmd
+	TotalDescribe        string
+	TotalSize            uint64
+	FileCnt              int
+	FolderCnt            int
+	IsInProgress         bool
+	IsFromJournal        bool
+	InterruptSrcFileName string
+	InterruptDstFullPath string
+	InterruptFileOffSet  int64
+	InterruptDoneRanges  int
+	InterruptLastErrCode rtkMisc.CrossShareErr
+}
+
+// GetFilesTransferCacheDump returns the cache queues of all peers in order, key: ID
+func GetFilesTransferCacheDump() map[string][]FilesTransferCacheDump {
+	fileDropDataMutex.RLock()
+	defer fileDropDataMutex.RUnlock()
+
+	cacheDumpMap := make(map[string][]FilesTransferCacheDump)
+	for id, cacheData := range filesDataCacheMap {
+		dumpList := make([]FilesTransferCacheDump, 0, len(cacheData.filesTransferDataQueue))
+		for _, item := range cacheData.filesTransferDataQueue {
+			dumpList = append(dumpList, FilesTransferCacheDump{
+				TimeStamp:            item.TimeStamp,
+				Direction:            item.FileTransDirection,
+				Cmd:                  item.Cmd,
+				TotalDescribe:        item.TotalDescribe,
+				TotalSize:            item.TotalSize,
+				FileCnt:              len(item.SrcFileList),
+				FolderCnt:            len(item.FolderList),
+				IsInProgress:         item.isInProgress,
+				IsFromJournal:        item.isFromJournal,
+				InterruptSrcFileName: item.InterruptSrcFileName,
+				InterruptDstFullPath: item.InterruptDstFullPath,
+				InterruptFileOffSet:  item.InterruptFileOffSet,
+				InterruptDoneRanges:  len(item.InterruptDoneRanges),
+				InterruptLastErrCode: item.InterruptLastErrCode,
+			})
+		}
+		cacheDumpMap[id] = dumpList
+	}
+	return cacheDumpMap
+}
+
 func SetCancelFileTransferFunc(id string, timeStamp uint64, fn func(rtkCommon.CancelBusinessSource)) {
 	fileDropDataMutex.Lock()
 	defer fileDropDataMutex.Unlock()
@@ -249,7 +399,7 @@ func SetCancelFileTransferFunc(id string, timeStamp uint64, fn func(rtkCommon.Ca
 	}
 }
 
-func SetFilesTransferDataInterrupt(id, srcFileName, dstFileName, dstFullName string, timestamp uint64, offset int64, errCode rtkMisc.CrossShareErr) bool {
+func SetFilesTransferDataInterrupt(id, srcFileName, dstFileName, dstFullName string, timestamp uint64, offset int64, doneRanges []rtkCommon.FileRange, errCode rtkMisc.CrossShareErr) bool {
 	fileDropDataMutex.Lock()
 	defer fileDropDataMutex.Unlock()
 	if cacheData, ok := filesDataCacheMap[id]; ok {
@@ -259,8 +409,12 @@ func SetFilesTransferDataInterrupt(id, srcFileName, dstFileName, dstFullName str
 				cacheData.filesTransferDataQueue[i].InterruptDstFileName = dstFileName
 				cacheData.filesTransferDataQueue[i].InterruptDstFullPath = dstFullName
 				cacheData.filesTransferDataQueue[i].InterruptFileOffSet = offset
+				cacheData.filesTransferDataQueue[i].InterruptDoneRanges = doneRanges
 				cacheData.filesTransferDataQueue[i].InterruptLastErrCode = errCode
+				cacheData.filesTransferDataQueue[i].isInProgress = false // wait to be taken again by recover process
+				cacheData.filesTransferDataQueue[i].cancelFn = nil
 				filesDataCacheMap[id] = cacheData
+				saveTransferJournal()
 				log.Printf("[%s] ID:[%s] timestamp:[%d] Set interrupt info srcfileName:[%s] offset:[%d] success!", rtkMisc.GetFuncInfo(), id, timestamp, srcFileName, offset)
 				return true
 			}
@@ -269,12 +423,25 @@ func SetFilesTransferDataInterrupt(id, srcFileName, dstFileName, dstFullName str
 	return false
 }
 
+func SetFilesTransferRecoverTimerCancel(id string, timestamp uint64, fn func()) {
+	fileDropDataMutex.Lock()
+	defer fileDropDataMutex.Unlock()
+	if cacheData, ok := filesDataCacheMap[id]; ok {
+		if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 {
+			cacheData.filesTransferDataQueue[i].RecoverFileTransTimerCancel = fn
+			filesDataCacheMap[id] = cacheData
+			return
+		}
+	}
+	log.Printf("[%s] ID:[%s] timestamp:[%d] Not fount cache map data", rtkMisc.GetFuncInfo(), id, timestamp)
+}
+
 func IsFileTransInProgress(id string, timestamp uint64) bool {
 	fileDropDataMutex.RLock()
 	defer fileDropDataMutex.RUnlock()
 	if cacheData, ok := filesDataCacheMap[id]; ok {
 		if len(cacheData.filesTransferDataQueue) > 0 {
-			if cacheData.filesTransferDataQueue[0].TimeStamp == timestamp {
+			if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 && cacheData.filesTransferDataQueue[i].isInProgress {
 				return true
 			}
 		} else {
@@ -285,7 +452,7 @@ func IsFileTransInProgress(id string, timestamp uint64) bool {
 	return false
 }
 
-func CancelFileTransFromCacheMap(id string, timestamp uint64) bool {
+func CancelFileTransFromCacheMap(id string, timestamp uint64, errCode rtkMisc.CrossShareErr) bool {
 	fileDropDataMutex.Lock()
 	defer fileDropDataMutex.Unlock()
 	if cacheData, ok := filesDataCacheMap[id]; ok {
@@ -293,6 +460,8 @@ func CancelFileTransFromCacheMap(id string, timestamp uint64) bool {
 			if !fileDataItem.isInProgress && fileDataItem.TimeStamp == timestamp {
 				if cacheData.filesTransferDataQueue, _, ok = RemoveItemFromCacheQueue(cacheData.filesTransferDataQueue, timestamp); ok {
 					filesDataCacheMap[id] = cacheData
+					saveTransferJournal()
+					rtkMisc.GoSafe(func() { addTransferHistory(id, &fileDataItem, errCode) })
 					log.Printf("[%s] ID:[%s] timestamp:[%d] CancelFileTransfer success from cache map data!", rtkMisc.GetFuncInfo(), id, timestamp)
 					return true
 				}
@@ -0,0 +1,195 @@
+package filedrop
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"time"
+)
+
+// Transfer journal keeps filesDataCacheMap on disk, so the queued and interrupted file transfers
+// are recovered by COMM_FILE_TRANSFER_RECOVER_REQ/RSP after the client restart.
+// Dst records the current file and a checkpoint offset which is already synced to disk, the partial file is kept.
+
+const transferJournalExpireTime = 24 * time.Hour
+
+type transferJournalItem struct {
+	FileDropData
+	FileTransDirection   FilesTransferDirectionType
+	InterruptSrcFileName string
+	InterruptDstFileName string
+	InterruptDstFullPath string
+	InterruptFileOffSet  int64
+	InterruptDoneRanges  []rtkCommon.FileRange
+	InterruptLastErrCode rtkMisc.CrossShareErr
+}
+
+type transferJournal struct {
+	SaveTime int64                            // unix milli
+	Items    map[string][]transferJournalItem // key: ID
+}
+
+var isTransferJournalLoaded = false
+
+// saveTransferJournal must be called with fileDropDataMutex locked
+func saveTransferJournal() {
+	if !isTransferJournalLoaded {
+		return
+	}
+	journalPath := rtkPlatform.GetTransferJournalPath()
+	if journalPath == "" {
+		return
+	}
+
+	journal := transferJournal{
+		SaveTime: time.Now().UnixMilli(),
+		Items:    make(map[string][]transferJournalItem),
+	}
+	for id, cacheData := range filesDataCacheMap {
+		if len(cacheData.filesTransferDataQueue) == 0 {
+			continue
+		}
+		items := make([]transferJournalItem, 0, len(cacheData.filesTransferDataQueue))
+		for _, item := range cacheData.filesTransferDataQueue {
+			items = append(items, transferJournalItem{
+				FileDropData:         item.FileDropData,
+				FileTransDirection:   item.FileTransDirection,
+				InterruptSrcFileName: item.InterruptSrcFileName,
+				InterruptDstFileName: item.InterruptDstFileName,
+				InterruptDstFullPath: item.InterruptDstFullPath,
+				InterruptFileOffSet:  item.InterruptFileOffSet,
+				InterruptDoneRanges:  item.InterruptDoneRanges,
+				InterruptLastErrCode: item.InterruptLastErrCode,
+			})
+		}
+		journal.Items[id] = items
+	}
+
+	if len(journal.Items) == 0 {
+		if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("[%s] remove transfer journal:[%s] err:%+v", rtkMisc.GetFuncInfo(), journalPath, err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(journal)
+	if err != nil {
+		log.Printf("[%s] Marshal transfer journal err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	tmpPath := journalPath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Printf("[%s] write transfer journal:[%s] err:%+v", rtkMisc.GetFuncInfo(), tmpPath, err)
+		return
+	}
+	if err = os.Rename(tmpPath, journalPath); err != nil {
+		log.Printf("[%s] rename transfer journal:[%s] err:%+v", rtkMisc.GetFuncInfo(), journalPath, err)
+	}
+}
+
+// LoadTransferJournal restores the file transfer cache saved before restart, it must be called once after platform init
+func LoadTransferJournal() {
+	fileDropDataMutex.Lock()
+	defer fileDropDataMutex.Unlock()
+	isTransferJournalLoaded = true
+
+	journalPath := rtkPlatform.GetTransferJournalPath()
+	if journalPath == "" {
+		return
+	}
+	data, err := os.ReadFile(journalPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[%s] read transfer journal:[%s] err:%+v", rtkMisc.GetFuncInfo(), journalPath, err)
+		}
+		return
+	}
+
+	var journal transferJournal
+	if err = json.Unmarshal(data, &journal); err != nil {
+		log.Printf("[%s] Unmarshal transfer journal:[%s] err:%+v, discard it", rtkMisc.GetFuncInfo(), journalPath, err)
+		os.Remove(journalPath)
+		return
+	}
+	if time.Since(time.UnixMilli(journal.SaveTime)) > transferJournalExpireTime {
+		log.Printf("[%s] transfer journal is saved at [%s] and expired, discard it", rtkMisc.GetFuncInfo(), time.UnixMilli(journal.SaveTime).Format(time.DateTime))
+		os.Remove(journalPath)
+		return
+	}
+
+	nCount := 0
+	for id, items := range journal.Items {
+		cacheData := filesDataTransferCache{filesTransferDataQueue: make([]FilesTransferDataItem, 0, len(items))}
+		for _, item := range items {
+			cacheItem := FilesTransferDataItem{
+				FileDropData:         item.FileDropData,
+				FileTransDirection:   item.FileTransDirection,
+				InterruptSrcFileName: item.InterruptSrcFileName,
+				InterruptDstFileName: item.InterruptDstFileName,
+				InterruptDstFullPath: item.InterruptDstFullPath,
+				InterruptFileOffSet:  item.InterruptFileOffSet,
+				InterruptDoneRanges:  item.InterruptDoneRanges,
+				InterruptLastErrCode: item.InterruptLastErrCode,
+				isFromJournal:        true,
+			}
+			if cacheItem.FileTransDirection == FilesTransfer_As_Dst && cacheItem.InterruptSrcFileName != "" && cacheItem.InterruptLastErrCode == rtkMisc.SUCCESS {
+				cacheItem.InterruptLastErrCode = rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS // checkpoint of a transfer killed by restart
+			}
+			cacheData.filesTransferDataQueue = append(cacheData.filesTransferDataQueue, cacheItem)
+			nCount++
+		}
+		filesDataCacheMap[id] = cacheData
+	}
+	log.Printf("[%s] load transfer journal success, peer count:[%d] item count:[%d]", rtkMisc.GetFuncInfo(), len(journal.Items), nCount)
+}
+
+// TakeJournalRecoverItem returns the first cache item of ID if it is loaded from journal, and only once.
+// If it is a Dst item never started, the first file is set as interrupt file with no dst name, so it starts from beginning.
+func TakeJournalRecoverItem(id string) *FilesTransferDataItem {
+	fileDropDataMutex.Lock()
+	defer fileDropDataMutex.Unlock()
+
+	cacheData, ok := filesDataCacheMap[id]
+	if !ok || len(cacheData.filesTransferDataQueue) == 0 || !cacheData.filesTransferDataQueue[0].isFromJournal {
+		return nil
+	}
+	for i := range cacheData.filesTransferDataQueue {
+		cacheData.filesTransferDataQueue[i].isFromJournal = false
+	}
+
+	item := &cacheData.filesTransferDataQueue[0]
+	if item.FileTransDirection == FilesTransfer_As_Dst && item.InterruptSrcFileName == "" {
+		if len(item.SrcFileList) == 0 {
+			log.Printf("[%s] ID:[%s] timestamp:[%d] journal item has no file, can not recover", rtkMisc.GetFuncInfo(), id, item.TimeStamp)
+			return nil
+		}
+		item.InterruptSrcFileName = item.SrcFileList[0].FileName
+		item.InterruptFileOffSet = 0
+		item.InterruptDoneRanges = nil
+		item.InterruptLastErrCode = rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
+	}
+	filesDataCacheMap[id] = cacheData
+	return copyCacheItem(item)
+}
+
+// SetFilesTransferDataCheckpoint records the Dst file in transfer and the offset (or completed ranges of a chunked file) already synced to disk, only saved in journal
+func SetFilesTransferDataCheckpoint(id, srcFileName, dstFileName, dstFullName string, timestamp uint64, offset int64, doneRanges []rtkCommon.FileRange) {
+	fileDropDataMutex.Lock()
+	defer fileDropDataMutex.Unlock()
+	if cacheData, ok := filesDataCacheMap[id]; ok {
+		if i := findCacheItemIndex(cacheData.filesTransferDataQueue, timestamp); i >= 0 {
+			item := &cacheData.filesTransferDataQueue[i]
+			item.InterruptSrcFileName = srcFileName
+			item.InterruptDstFileName = dstFileName
+			item.InterruptDstFullPath = dstFullName
+			item.InterruptFileOffSet = offset
+			item.InterruptDoneRanges = doneRanges
+			item.InterruptLastErrCode = rtkMisc.SUCCESS
+			filesDataCacheMap[id] = cacheData
+			saveTransferJournal()
+		}
+	}
+}
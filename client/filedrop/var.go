@@ -14,6 +14,8 @@ const (
 	FilesTransfer_Unknown FilesTransferDirectionType = "FilesTransfer_Unknown"
 )
 
+var logger = rtkMisc.NewLogger("filedrop")
+
 type CallbackSendCancelFileTransMsgFunc func(id, ipAddr string, fileTransDataId uint64, asSrc bool)
 
 var (
@@ -76,8 +78,13 @@ type FilesTransferDataItem struct {
 	InterruptDstFileName        string                `json:"-"` // Dst fileName
 	InterruptDstFullPath        string                `json:"-"` // Dst fullPath
 	InterruptFileOffSet         int64                 `json:"-"`
+	InterruptDoneRanges         []rtkCommon.FileRange `json:"-"` // completed ranges of a chunked file
 	InterruptLastErrCode        rtkMisc.CrossShareErr `json:"-"`
 	RecoverFileTransTimerCancel func()                `json:"-"`
+
+	isInProgress  bool
+	isFromJournal bool // loaded from transfer journal and not yet recovered
+	cancelFn      func(rtkCommon.CancelBusinessSource)
 }
 
 type filesDataTransferCache struct {
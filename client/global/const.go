@@ -7,6 +7,7 @@ const (
 	ClientXClipVerSerial          = 46      // the client support XClip since third version(serial number) 46
 	ClientCaptureIndexVerSerial   = 48      // the client build ClientIndex color block on verification dialog
 	ClientQueueFileTransVerSerial = 50      // the client file drop queue transfer since third version(serial number) 50
+	ClientRmFileLimitVerSerial    = 52      // the client remove file drop count limit since third version(serial number) 52
 
 	LanServerMobileDragFileVerSerial = 31 //  the lanserver support mobile drag file since third version(serial number) 31
 	ProtocolID                       = "/libp2p/dcutr"
@@ -19,17 +20,14 @@ const (
 	ProtocolFileTransQueue    = "/ipfs/protocol/cross_share/fileDataTransfer/"
 	DefaultPort               = 0
 
-	// This is the maximum length of messages between clients,    32KB
+	// This is the maximum length of messages between clients without FramedMsg capability (one read is one message),    32KB
 	P2PMsgMaxLength = 32 * 1024
 
-	//This is the length of SrcFileList removed from file drop messages between clients,
-	P2PMsgMagicLength = 400
+	// This is the maximum length of one framed message between clients,    64MB
+	P2PMsgFrameMaxLength = 64 * 1024 * 1024
 
-	//This is the length of FilePath and FileName removed from FileInfo struct
-	FileInfoMagicLength = 80
-
-	//This is the length of content removed from String Array
-	StringArrayMagicLength = 5
+	// Framed message buffers larger than this are not kept in buffer pool,    1MB
+	P2PMsgPoolBufferMaxLength = 1024 * 1024
 
 	//The maximum size for sending documents each time,   10GB
 	SendFilesRequestMaxSize = 10 * 1024 * 1024 * 1024
@@ -39,4 +37,7 @@ const (
 
 	//Concurrent transmission file data max size
 	FilesConcurrentTransferMaxSize = 3
+
+	//The count of QUIC streams used to send one large file in ranges at once
+	FileDropChunkStreamCount = 4
 )
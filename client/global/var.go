@@ -29,4 +29,21 @@ var (
 
 	RTT               map[string]time.Duration = make(map[string]time.Duration)
 	IsSupportFileDrag bool
+
+	// capabilities of this client, advertised to peers in RegistMdnsMessage
+	ClientCapabilities = []string{
+		rtkCommon.CapabilityXClip,
+		rtkCommon.CapabilityQueueFileTrans,
+		rtkCommon.CapabilityRmFileCntLimit,
+		rtkCommon.CapabilityQuicXClip,
+		rtkCommon.CapabilityFramedMsg,
+		rtkCommon.CapabilityFileHash,
+		rtkCommon.CapabilityChunkedFile,
+		rtkCommon.CapabilityCompress,
+		rtkCommon.CapabilityLazyXClip,
+		rtkCommon.CapabilityMimeXClip,
+	}
+
+	// clipboard image formats accepted by this client in preference order, JPEG only until platform declares them
+	ClientImageFormats = []string{rtkCommon.ImageFormatJpeg}
 )
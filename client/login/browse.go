@@ -95,7 +95,7 @@ func browseLanServer(ctx context.Context, serviceType, domain string, resultChan
 				textRecordKeyVersion := txtMap[rtkMisc.TextRecordKeyVersion]
 				log.Printf("Browse get a Service, mName:[%s] instance:[%s] IP:[%s] ver:[%s] timestamp:[%s], use %d ms", textRecordmonitorName, entry.Instance, lanServerIp, textRecordKeyVersion, textRecordTimeStamp, time.Now().UnixMilli()-startTime)
 
-				resultChan <- browseParam{entry.Instance, lanServerIp, textRecordmonitorName, textRecordKeyVersion, 0}
+				resultChan <- browseParam{entry.Instance, lanServerIp, textRecordmonitorName, textRecordKeyVersion, 0, txtMap[rtkMisc.TextRecordKeyServerKey]}
 			}
 		}
 		log.Printf("Stop Browse service instances...")
@@ -163,7 +163,7 @@ func browseLanServerAndroid(ctx context.Context, serviceType, domain string, res
 
 				log.Printf("Found target Service, mName:[%s] instance:[%s] IP:[%s] ver:[%s] timestamp:[%d], use %d ms", textRecordmName, entry.Instance, lanServerIp, textRecordKeyVersion, stamp, time.Now().UnixMilli()-startTime)
 
-				resultChan <- browseParam{entry.Instance, lanServerIp, textRecordmName, textRecordKeyVersion, stamp}
+				resultChan <- browseParam{entry.Instance, lanServerIp, textRecordmName, textRecordKeyVersion, stamp, txtMap[rtkMisc.TextRecordKeyServerKey]}
 			}
 		}
 		log.Printf("Stop Browse service instances")
@@ -192,7 +192,7 @@ func browseLanServeriOS(ctx context.Context, serviceType string, resultChan chan
 			log.Printf("[%s] WARNING: invalid[%s]:%d. err:%s", rtkMisc.GetFuncInfo(), rtkMisc.TextRecordKeyTimestamp, stamp, err)
 		}
 
-		resultChan <- browseParam{instance, lanServerIp, mName, version, int64(stamp)}
+		resultChan <- browseParam{instance, lanServerIp, mName, version, int64(stamp), ""}
 	})
 	rtkPlatform.GoStartBrowseMdns("", serviceType)
 
@@ -258,7 +258,7 @@ func lookupLanServer(ctx context.Context, instance, serviceType, domain string,
 			if err != nil {
 				log.Printf("[%s] WARNING: invalid[%s]:%d. err:%s", rtkMisc.GetFuncInfo(), rtkMisc.TextRecordKeyTimestamp, stamp, err)
 			}
-			param := browseParam{entry.Instance, lanServerIp, textRecordmonitorName, textRecordKeyVersion, int64(stamp)}
+			param := browseParam{entry.Instance, lanServerIp, textRecordmonitorName, textRecordKeyVersion, int64(stamp), txtMap[rtkMisc.TextRecordKeyServerKey]}
 			g_monitorName = textRecordmonitorName
 			serverInstanceMap.Store(param.instance, param)
 			return lanServerIp, rtkMisc.SUCCESS
@@ -279,7 +279,7 @@ func lookupLanServeriOS(ctx context.Context, instance, serviceType string) (stri
 		if err != nil {
 			log.Printf("[%s] WARNING: invalid[%s]:%d. err:%s", rtkMisc.GetFuncInfo(), rtkMisc.TextRecordKeyTimestamp, stamp, err)
 		}
-		lanServerEntry <- browseParam{instance, lanServerIp, mName, version, int64(stamp)}
+		lanServerEntry <- browseParam{instance, lanServerIp, mName, version, int64(stamp), ""}
 	})
 	rtkPlatform.GoStartBrowseMdns(instance, serviceType)
 
@@ -134,6 +134,7 @@ func sendReqMsgToLanServer(MsgType rtkMisc.C2SMsgType, extData ...interface{}) r
 func buildMessageReq(msg *rtkMisc.C2SMessage, extData ...interface{}) rtkMisc.CrossShareErr {
 	msg.TimeStamp = time.Now().UnixMilli()
 	msg.ClientID = rtkGlobal.NodeInfo.ID
+	msg.ProtocolVersion = rtkMisc.C2SProtocolVersion
 
 	switch msg.MsgType {
 	case rtkMisc.C2SMsg_CLIENT_HEARTBEAT:
@@ -151,42 +152,70 @@ func buildMessageReq(msg *rtkMisc.C2SMessage, extData ...interface{}) rtkMisc.Cr
 		}
 		msg.ExtData = reqData
 
-	case rtkMisc.C2SMsg_RESET_CLIENT:
-		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
 	case rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE:
 		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
 		msg.ExtData = rtkMisc.AuthDataIndexMobileReq{mobileAuthData}
-	case rtkMisc.C2SMsg_REQ_CLIENT_LIST:
-		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
-	case rtkMisc.CS2Msg_MESSAGE_EVENT:
-		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
-		if len(extData) < 1 {
-			log.Printf("[%s] msg MESSAGE_EVENT ext data is null!", rtkMisc.GetFuncInfo())
-			return rtkMisc.ERR_BIZ_C2S_EXT_DATA_EMPTY
+	default:
+		schema, ok := rtkMisc.GetC2SMsgSchema(msg.MsgType)
+		if !ok || schema.Request == nil {
+			log.Printf("[%s] Unknown or unsupported MsgType[%s]", rtkMisc.GetFuncInfo(), msg.MsgType)
+			return rtkMisc.ERR_BIZ_C2S_UNKNOWN_MSG_TYPE
 		}
-		msg.ExtData = extData[0]
-	case rtkMisc.CS2Msg_UPDATE_SRCPORT_INFO:
 		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
-		if len(extData) < 1 {
-			log.Printf("[%s] msg UPDATE_SRCPORT_INFO ext data is null!", rtkMisc.GetFuncInfo())
-			return rtkMisc.ERR_BIZ_C2S_EXT_DATA_EMPTY
+		if schema.Request == rtkMisc.C2SNoExtData {
+			break
 		}
-		msg.ExtData = extData[0]
-	case rtkMisc.C2SMsg_DRAG_FILE_START:
-		msg.ClientIndex = rtkGlobal.NodeInfo.ClientIndex
 		if len(extData) < 1 {
-			log.Printf("[%s] msg DRAG_FILE_START ext data is null!", rtkMisc.GetFuncInfo())
+			log.Printf("[%s] msg %s ext data is null!", rtkMisc.GetFuncInfo(), msg.MsgType)
 			return rtkMisc.ERR_BIZ_C2S_EXT_DATA_EMPTY
 		}
 		msg.ExtData = extData[0]
-	default:
-		log.Printf("Unknown MsgType[%s]", msg.MsgType)
-		return rtkMisc.ERR_BIZ_C2S_UNKNOWN_MSG_TYPE
 	}
 
 	return rtkMisc.SUCCESS
 }
 
+// s2cMsgHandlerMap dispatches the message from lan server, its ExtData is decoded by rtkMisc.C2SMsgSchema
+var s2cMsgHandlerMap = map[rtkMisc.C2SMsgType]func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr{
+	rtkMisc.C2SMsg_CLIENT_HEARTBEAT: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		checkPingServerRspTimeStamp(msg.TimeStamp)
+		return rtkMisc.SUCCESS
+	},
+	rtkMisc.C2SMsg_INIT_CLIENT: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgInitClient(msg.ClientID, msg.ExtData.(rtkMisc.InitClientMessageResponse))
+	},
+	rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgMobileAuthDataResp(msg.ClientID, msg.ClientIndex, msg.ExtData.(rtkMisc.AuthDataIndexMobileResponse))
+	},
+	rtkMisc.C2SMsg_REQ_CLIENT_LIST: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgRespClientList(msg.ClientID, msg.ExtData.(rtkMisc.GetClientListResponse))
+	},
+	rtkMisc.C2SMsg_DRAG_FILE_END: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgReqClientDragFiles(msg.ClientID, msg.ExtData.(string))
+	},
+	rtkMisc.CS2Msg_PERIODIC_NOTIFY: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgPeriodicNotify(msg.ClientID, msg.ExtData.(rtkMisc.PeriodicNotifyReq))
+	},
+	rtkMisc.CS2Msg_NOTIFY_CLIENT_VERSION: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgNotifyClientVersion(msg.ClientID, msg.ExtData.(rtkMisc.NotifyClientVersionReq))
+	},
+	rtkMisc.CS2Msg_MESSAGE_EVENT: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgMessageEvent(msg.ClientID, msg.ExtData.(rtkMisc.PlatformMsgEventResponse))
+	},
+	rtkMisc.CS2Msg_UPDATE_SRCPORT_INFO: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgUpdateSrcPortInfo(msg.ClientID, msg.ExtData.(rtkMisc.UpdateClientSrcPortInfoResponse))
+	},
+	rtkMisc.CS2Msg_UPDATE_PLUG_EVENT: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgUpdatePlugEventReq(msg.ClientID, msg.ExtData.(rtkMisc.UpdatePlugEventReq))
+	},
+	rtkMisc.C2SMsg_DRAG_FILE_START: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgDragFileListStartResponse(msg.ClientID, msg.ExtData.(rtkMisc.DragFileStartResponse))
+	},
+	rtkMisc.C2SMsg_ERROR_RESPONSE: func(msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+		return dealS2CMsgErrorResponse(msg.ClientID, msg.ExtData.(rtkMisc.C2SErrorResponse))
+	},
+}
+
 func handleReadMessageFromServer(buffer []byte) rtkMisc.CrossShareErr {
 	buffer = bytes.Trim(buffer, "\x00")
 
@@ -194,62 +223,29 @@ func handleReadMessageFromServer(buffer []byte) rtkMisc.CrossShareErr {
 		return rtkMisc.ERR_BIZ_C2S_READ_EMPTY_DATA
 	}
 
-	type TempMsg struct {
-		ExtData json.RawMessage
-		rtkMisc.C2SMessage
-	}
-	var rspMsg TempMsg
-	err := json.Unmarshal(buffer, &rspMsg)
-	if err != nil {
-		log.Println("Failed to unmarshal C2SMessage data: ", err.Error())
-		log.Printf("Err JSON len[%d] data:[%s] ", len(buffer), string(buffer))
-		return rtkMisc.ERR_BIZ_JSON_UNMARSHAL
+	rspMsg, errCode := rtkMisc.DecodeC2SMessage(buffer, true)
+	if errCode != rtkMisc.SUCCESS {
+		return errCode
 	}
 
 	if rspMsg.MsgType != rtkMisc.C2SMsg_CLIENT_HEARTBEAT && rspMsg.MsgType != rtkMisc.CS2Msg_PERIODIC_NOTIFY {
 		log.Printf("Received a Response msg from Server, clientID:[%s] ClientIndex:[%d] MsgType:[%s] RTT:[%d]ms", rspMsg.ClientID, rspMsg.ClientIndex, rspMsg.MsgType, time.Now().UnixMilli()-rspMsg.TimeStamp)
 	}
 
-	switch rspMsg.MsgType {
-	case rtkMisc.C2SMsg_CLIENT_HEARTBEAT:
-		checkPingServerRspTimeStamp(rspMsg.TimeStamp)
-	case rtkMisc.C2SMsg_INIT_CLIENT:
-		return dealS2CMsgInitClient(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE:
-		return dealS2CMsgMobileAuthDataResp(rspMsg.ClientID, rspMsg.ClientIndex, rspMsg.ExtData)
-	case rtkMisc.C2SMsg_REQ_CLIENT_LIST:
-		return dealS2CMsgRespClientList(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.C2SMsg_DRAG_FILE_END:
-		return dealS2CMsgReqClientDragFiles(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.CS2Msg_PERIODIC_NOTIFY:
-		return dealS2CMsgPeriodicNotify(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.CS2Msg_NOTIFY_CLIENT_VERSION:
-		return dealS2CMsgNotifyClientVersion(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.CS2Msg_MESSAGE_EVENT:
-		return dealS2CMsgMessageEvent(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.CS2Msg_UPDATE_SRCPORT_INFO:
-		return dealS2CMsgUpdateSrcPortInfo(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.CS2Msg_UPDATE_PLUG_EVENT:
-		return dealS2CMsgUpdatePlugEventReq(rspMsg.ClientID, rspMsg.ExtData)
-	case rtkMisc.C2SMsg_DRAG_FILE_START:
-		return dealS2CMsgDragFileListStartResponse(rspMsg.ClientID, rspMsg.ExtData)
-	default:
-		log.Printf("[%s]Unknown MsgType:[%s]", rtkMisc.GetFuncInfo(), rspMsg.MsgType)
-		return rtkMisc.ERR_BIZ_C2S_UNKNOWN_MSG_TYPE
+	handler, ok := s2cMsgHandlerMap[rspMsg.MsgType]
+	if !ok {
+		log.Printf("[%s] MsgType:[%s] has no handler", rtkMisc.GetFuncInfo(), rspMsg.MsgType)
+		return rtkMisc.ERR_BIZ_C2S_UNSUPPORTED_MSG_TYPE
 	}
-
-	return rtkMisc.SUCCESS
+	return handler(&rspMsg)
 }
 
-func dealS2CMsgInitClient(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var initClientRsp rtkMisc.InitClientMessageResponse
-	initClientRsp.Scenario = rtkMisc.ScenarioType_ViewManager
-	err := json.Unmarshal(extData, &initClientRsp)
-	if err != nil {
-		log.Printf("clientID:[%s]decode ExtDataText  Err: %+v", id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
+func dealS2CMsgErrorResponse(id string, errorRsp rtkMisc.C2SErrorResponse) rtkMisc.CrossShareErr {
+	log.Printf("[%s] clientID:[%s] request MsgType:[%s] is refused by LanServer, code:[%d] errMsg:[%s]", rtkMisc.GetFuncInfo(), id, errorRsp.ReqMsgType, errorRsp.Code, errorRsp.Msg)
+	return errorRsp.Code
+}
 
+func dealS2CMsgInitClient(id string, initClientRsp rtkMisc.InitClientMessageResponse) rtkMisc.CrossShareErr {
 	if initClientRsp.Code != rtkMisc.SUCCESS {
 		log.Printf("Request Init Client failed,  code:[%d] errMsg:[%s]", initClientRsp.Code, initClientRsp.Msg)
 		return initClientRsp.Code
@@ -289,14 +285,7 @@ func dealS2CMsgInitClient(id string, extData json.RawMessage) rtkMisc.CrossShare
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgMobileAuthDataResp(id string, index uint32, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var authIndexMobileRsp rtkMisc.AuthDataIndexMobileResponse
-	err := json.Unmarshal(extData, &authIndexMobileRsp)
-	if err != nil {
-		log.Printf("clientID:[%s] Index:[%d] Err: decode ExtDataText:%+v", id, index, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgMobileAuthDataResp(id string, index uint32, authIndexMobileRsp rtkMisc.AuthDataIndexMobileResponse) rtkMisc.CrossShareErr {
 	if authIndexMobileRsp.Code != rtkMisc.SUCCESS {
 		log.Printf("[%s] clientID:[%s] Index[%d] Err: Unauthorized with err[%d]", rtkMisc.GetFuncInfo(), id, index, authIndexMobileRsp.Code)
 		NotifyDIASStatus(DIAS_Status_Authorization_Failed)
@@ -314,17 +303,11 @@ func dealS2CMsgMobileAuthDataResp(id string, index uint32, extData json.RawMessa
 	return SendReqClientListToLanServer()
 }
 
-func dealS2CMsgRespClientList(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var getClientListRsp rtkMisc.GetClientListResponse
-	err := json.Unmarshal(extData, &getClientListRsp)
-	if err != nil {
-		log.Printf("clientID:[%s] Err: decode ExtDataText:%+v", id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgRespClientList(id string, getClientListRsp rtkMisc.GetClientListResponse) rtkMisc.CrossShareErr {
 	if getClientListRsp.Code != rtkMisc.SUCCESS {
 		return getClientListRsp.Code
 	}
+	pinLanServerKey() // client list is requested after the authorization
 	clientList := make([]rtkMisc.ClientInfo, 0)
 	for _, client := range getClientListRsp.ClientList {
 		if client.ID != rtkGlobal.NodeInfo.ID {
@@ -350,33 +333,18 @@ func dealS2CMsgRespClientList(id string, extData json.RawMessage) rtkMisc.CrossS
 		GetClientListFlag <- clientList
 	}
 
-	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac {
+	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux {
 		SendReqUpdateSrcPortMapInfo()
 	}
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgReqClientDragFiles(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var targetID string
-	err := json.Unmarshal(extData, &targetID)
-	if err != nil {
-		log.Printf("clientID:[%s]  Err: decode ExtDataText:%+v", id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgReqClientDragFiles(id string, targetID string) rtkMisc.CrossShareErr {
 	log.Printf("Call Client Drag file by LanServer success, get target Client id:[%s]", targetID)
 	return rtkFileDrop.UpdateDragFileReqDataFromLocal(targetID)
 }
 
-func dealS2CMsgPeriodicNotify(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var periodicNotify rtkMisc.PeriodicNotifyReq
-	periodicNotify.Scenario = rtkMisc.ScenarioType_ViewManager // default value
-	err := json.Unmarshal(extData, &periodicNotify)
-	if err != nil {
-		log.Printf("clientID:[%s] Err: decode ExtDataText:%+v", id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgPeriodicNotify(id string, periodicNotify rtkMisc.PeriodicNotifyReq) rtkMisc.CrossShareErr {
 	notifyVerValue := rtkMisc.GetVersionValue(periodicNotify.ClientVersion)
 	if notifyVerValue < 0 {
 		log.Printf("[%s] ClientVersion:[%s]  GetVersionValue failed!", rtkMisc.GetFuncInfo(), periodicNotify.ClientVersion)
@@ -396,13 +364,7 @@ func dealS2CMsgPeriodicNotify(id string, extData json.RawMessage) rtkMisc.CrossS
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgNotifyClientVersion(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var notifyVersion rtkMisc.NotifyClientVersionReq
-	err := json.Unmarshal(extData, &notifyVersion)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s]  Err: decode ExtDataText:%+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
+func dealS2CMsgNotifyClientVersion(id string, notifyVersion rtkMisc.NotifyClientVersionReq) rtkMisc.CrossShareErr {
 	notifyVerValue := rtkMisc.GetVersionValue(notifyVersion.ClientVersion)
 	if notifyVerValue < 0 {
 		log.Printf("[%s] ClientVersion:[%s]  GetVersionValue failed!", rtkMisc.GetFuncInfo(), notifyVersion.ClientVersion)
@@ -412,14 +374,7 @@ func dealS2CMsgNotifyClientVersion(id string, extData json.RawMessage) rtkMisc.C
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgMessageEvent(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var messageEventRsp rtkMisc.PlatformMsgEventResponse
-	err := json.Unmarshal(extData, &messageEventRsp)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s]  Err: decode ExtDataText:%+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgMessageEvent(id string, messageEventRsp rtkMisc.PlatformMsgEventResponse) rtkMisc.CrossShareErr {
 	if messageEventRsp.Code != rtkMisc.SUCCESS {
 		log.Printf("[%s] Message Event Response failed, errCode:[%d]  errMsg:[%s]!", rtkMisc.GetFuncInfo(), messageEventRsp.Code, messageEventRsp.Msg)
 		return messageEventRsp.Code
@@ -427,14 +382,7 @@ func dealS2CMsgMessageEvent(id string, extData json.RawMessage) rtkMisc.CrossSha
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgUpdateSrcPortInfo(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var updateSrcPortInfoRsp rtkMisc.UpdateClientSrcPortInfoResponse
-	err := json.Unmarshal(extData, &updateSrcPortInfoRsp)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s]  Err: decode ExtDataText:%+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgUpdateSrcPortInfo(id string, updateSrcPortInfoRsp rtkMisc.UpdateClientSrcPortInfoResponse) rtkMisc.CrossShareErr {
 	if updateSrcPortInfoRsp.Code != rtkMisc.SUCCESS {
 		log.Printf("[%s] UpdateSrcPortInfo Response failed, errCode:[%d]  errMsg:[%s]!", rtkMisc.GetFuncInfo(), updateSrcPortInfoRsp.Code, updateSrcPortInfoRsp.Msg)
 		return updateSrcPortInfoRsp.Code
@@ -443,14 +391,7 @@ func dealS2CMsgUpdateSrcPortInfo(id string, extData json.RawMessage) rtkMisc.Cro
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgUpdatePlugEventReq(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var updatePlugEventReq rtkMisc.UpdatePlugEventReq
-	err := json.Unmarshal(extData, &updatePlugEventReq)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s]  Err: decode ExtDataText:%+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgUpdatePlugEventReq(id string, updatePlugEventReq rtkMisc.UpdatePlugEventReq) rtkMisc.CrossShareErr {
 	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformiOS {
 		log.Printf("[%s] UpdatePlugEvent request success, plug:[%v] !", rtkMisc.GetFuncInfo(), updatePlugEventReq.PlugEvent)
 		if !updatePlugEventReq.PlugEvent { // only cable out event need Call ios Platfrom
@@ -461,14 +402,7 @@ func dealS2CMsgUpdatePlugEventReq(id string, extData json.RawMessage) rtkMisc.Cr
 	return rtkMisc.SUCCESS
 }
 
-func dealS2CMsgDragFileListStartResponse(id string, extData json.RawMessage) rtkMisc.CrossShareErr {
-	var dragFileListStartRsp rtkMisc.DragFileStartResponse
-	err := json.Unmarshal(extData, &dragFileListStartRsp)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s]  Err: decode ExtDataText:%+v", rtkMisc.GetFuncInfo(), id, err)
-		return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
-	}
-
+func dealS2CMsgDragFileListStartResponse(id string, dragFileListStartRsp rtkMisc.DragFileStartResponse) rtkMisc.CrossShareErr {
 	if dragFileListStartRsp.Code != rtkMisc.SUCCESS {
 		log.Printf("[%s] DragFileListStart  Response error, Code:[%d] msg:[%s] !", rtkMisc.GetFuncInfo(), int(dragFileListStartRsp.Code), dragFileListStartRsp.Msg)
 	}
@@ -207,7 +207,7 @@ func ConnectLanServerRun(ctx context.Context) {
 		}
 	}()
 
-	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac {
+	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux {
 		g_lookupByUnicast = true
 		computerInitLanServer(ctx)
 	} else {
@@ -322,7 +322,7 @@ func getLanServerAddr(ctx context.Context, bPrintErr bool) (string, rtkMisc.Cros
 		return "", rtkMisc.ERR_BIZ_C2S_GET_NO_SERVER_NAME
 	}
 
-	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac {
+	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux {
 		mapValue, ok := serverInstanceMap.Load(lanServerInstance)
 		if ok {
 			lanServerIp := mapValue.(browseParam).ip
@@ -383,7 +383,13 @@ func connectToLanServer(ctx context.Context, bPrintErr bool) rtkMisc.CrossShareE
 			return rtkMisc.ERR_NETWORK_C2S_DIAL
 		}
 
-		pSafeConnect.Reset(pConnectLanServer)
+		secureConn, errCode := secureLanServerConn(tOctx, pConnectLanServer, lanServerInstance)
+		if errCode != rtkMisc.SUCCESS {
+			pConnectLanServer.Close()
+			return errCode
+		}
+
+		pSafeConnect.Reset(secureConn)
 		log.Printf("Connect LanServerAddr:[%s] success! LocalAddr:[%s]", serverAddr, pConnectLanServer.LocalAddr().String())
 
 		stopBrowseInstance() // mobile need stop Browse
@@ -399,7 +405,7 @@ func initLanServer(ctx context.Context, bPrintErr bool) rtkMisc.CrossShareErr {
 
 	rtkPlatform.GoMonitorNameNotify(g_monitorName)
 	if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac ||
-		rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMnt {
+		rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMnt {
 		NotifyDIASStatus(DIAS_Status_Checking_Authorization)
 	} else {
 		NotifyDIASStatus(DIAS_Status_Wait_screenCasting)
@@ -431,7 +437,7 @@ func reConnectLanServer(ctx context.Context) {
 			NotifyDIASStatus(DIAS_Status_Connectting_DiasService)
 			rtkPlatform.GoMonitorNameNotify("")
 
-			if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac {
+			if rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformWindows || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformMac || rtkGlobal.NodeInfo.Platform == rtkMisc.PlatformLinux {
 				serverInstanceMap.Delete(lanServerInstance)
 				log.Printf("initLanServer %d times failed, errCode:%d ! try to Lookup Service over again...", retryCnt, errCode)
 			} else {
@@ -496,6 +502,10 @@ func NotifyDIASStatus(status CrossShareDiasStatus) {
 	}
 }
 
+func GetDIASStatus() CrossShareDiasStatus {
+	return currentDiasStatus
+}
+
 func IsGetClientList() bool {
 	if currentDiasStatus == DIAS_Status_Wait_Other_Clients || currentDiasStatus == DIAS_Status_Get_Clients_Success {
 		return true
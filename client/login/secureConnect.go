@@ -0,0 +1,190 @@
+package login
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+
+	libp2pCrypto "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+// C2S TLS: client verifies lan server by the key pinned for its instance, or the key in mDNS text record before pinning.
+// The key is pinned after the first authorization, so the mobile browse result without text record (TOFU) is also verified
+// by authorization. The legacy lan server which has neither key nor C2S TLS version is refused, unless platform
+// calls SetAllowPlaintextLanServer(true) to connect it in plaintext.
+// The pinned key must be removed from lanServerKeyStore manually if the lan server key is reset.
+var (
+	allowPlaintextLanServer bool                      // compatibility switch for the legacy lan server without C2S TLS, default is off
+	pinnedServerKeyMap      = make(map[string]string) // KEY: instance
+	isServerKeyStoreLoaded  = false
+	serverKeyStoreMutex     sync.Mutex
+	connectedServerKey      string // the key of current connected lan server, empty in plaintext
+	connectedServerInstance string
+	clientCert              *tls.Certificate
+)
+
+// loadServerKeyStore must be called with serverKeyStoreMutex locked
+func loadServerKeyStore() {
+	if isServerKeyStoreLoaded {
+		return
+	}
+	isServerKeyStoreLoaded = true
+
+	storePath := rtkPlatform.GetLanServerKeyStorePath()
+	if storePath == "" {
+		return
+	}
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[%s] read lan server key store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+		}
+		return
+	}
+	if err = json.Unmarshal(data, &pinnedServerKeyMap); err != nil {
+		log.Printf("[%s] Unmarshal lan server key store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+		pinnedServerKeyMap = make(map[string]string)
+	}
+}
+
+// saveServerKeyStore must be called with serverKeyStoreMutex locked
+func saveServerKeyStore() {
+	storePath := rtkPlatform.GetLanServerKeyStorePath()
+	if storePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(pinnedServerKeyMap)
+	if err != nil {
+		log.Printf("[%s] Marshal lan server key store err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	tmpPath := storePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		log.Printf("[%s] write lan server key store:[%s] err:%+v", rtkMisc.GetFuncInfo(), tmpPath, err)
+		return
+	}
+	if err = os.Rename(tmpPath, storePath); err != nil {
+		log.Printf("[%s] rename lan server key store:[%s] err:%+v", rtkMisc.GetFuncInfo(), storePath, err)
+	}
+}
+
+func getPinnedServerKey(instance string) string {
+	serverKeyStoreMutex.Lock()
+	defer serverKeyStoreMutex.Unlock()
+	loadServerKeyStore()
+	return pinnedServerKeyMap[instance]
+}
+
+// pinLanServerKey is called after the authorization of current connected lan server
+func pinLanServerKey() {
+	serverKeyStoreMutex.Lock()
+	defer serverKeyStoreMutex.Unlock()
+	if connectedServerKey == "" || connectedServerInstance == "" {
+		return
+	}
+	loadServerKeyStore()
+	if pinnedServerKeyMap[connectedServerInstance] == connectedServerKey {
+		return
+	}
+	pinnedServerKeyMap[connectedServerInstance] = connectedServerKey
+	saveServerKeyStore()
+	log.Printf("[%s] instance:[%s] server key:[%s] is pinned", rtkMisc.GetFuncInfo(), connectedServerInstance, connectedServerKey)
+}
+
+// SetAllowPlaintextLanServer allows the plaintext control channel to the legacy lan server without C2S TLS
+func SetAllowPlaintextLanServer(allow bool) {
+	allowPlaintextLanServer = allow
+	log.Printf("[%s] AllowPlaintextLanServer:[%+v]", rtkMisc.GetFuncInfo(), allow)
+}
+
+func setConnectedServerKey(instance, key string) {
+	serverKeyStoreMutex.Lock()
+	defer serverKeyStoreMutex.Unlock()
+	connectedServerInstance = instance
+	connectedServerKey = key
+}
+
+// getClientCertificate builds the client certificate of libp2p key, so lan server can verify the client ID
+func getClientCertificate() (tls.Certificate, error) {
+	serverKeyStoreMutex.Lock()
+	defer serverKeyStoreMutex.Unlock()
+	if clientCert != nil {
+		return *clientCert, nil
+	}
+
+	stdKey, err := libp2pCrypto.PrivKeyToStdKey(rtkPlatform.GenKey())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	signer, ok := stdKey.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("invalid key type %T", stdKey)
+	}
+	cert, err := rtkMisc.GenC2SCertificate(signer)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	clientCert = &cert
+	return cert, nil
+}
+
+// secureLanServerConn does TLS handshake on conn with lan server instance, it returns conn itself to the legacy lan server
+// only if AllowPlaintextLanServer is on
+func secureLanServerConn(ctx context.Context, conn net.Conn, instance string) (net.Conn, rtkMisc.CrossShareErr) {
+	var param browseParam
+	if mapValue, ok := serverInstanceMap.Load(instance); ok {
+		param = mapValue.(browseParam)
+	}
+
+	serverKey := getPinnedServerKey(instance)
+	if serverKey != "" && param.serverKey != "" && param.serverKey != serverKey {
+		log.Printf("[%s] instance:[%s] server key:[%s] mismatch with the pinned key:[%s]", rtkMisc.GetFuncInfo(), instance, param.serverKey, serverKey)
+		return nil, rtkMisc.ERR_BIZ_C2S_SERVER_KEY_MISMATCH
+	}
+	if serverKey == "" {
+		serverKey = param.serverKey
+	}
+
+	if serverKey == "" && !rtkMisc.IsLanServerSupportC2STls(param.ver) {
+		if !allowPlaintextLanServer {
+			log.Printf("[%s] instance:[%s] ver:[%s] legacy lan server without C2S TLS is refused", rtkMisc.GetFuncInfo(), instance, param.ver)
+			return nil, rtkMisc.ERR_BIZ_C2S_PLAINTEXT_REFUSED
+		}
+		log.Printf("[%s] instance:[%s] ver:[%s] WARNING: legacy lan server, use plaintext", rtkMisc.GetFuncInfo(), instance, param.ver)
+		setConnectedServerKey(instance, "")
+		return conn, rtkMisc.SUCCESS
+	}
+
+	cert, err := getClientCertificate()
+	if err != nil {
+		log.Printf("[%s] get client certificate err:%+v", rtkMisc.GetFuncInfo(), err)
+		return nil, rtkMisc.ERR_NETWORK_C2S_TLS_HANDSHAKE
+	}
+	tlsConn := tls.Client(conn, rtkMisc.NewC2SClientTlsConfig(cert, serverKey))
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		log.Printf("[%s] instance:[%s] TLS handshake err:%+v", rtkMisc.GetFuncInfo(), instance, err)
+		return nil, rtkMisc.ERR_NETWORK_C2S_TLS_HANDSHAKE
+	}
+
+	pub, err := rtkMisc.GetC2SPeerPublicKey(tlsConn.ConnectionState())
+	if err == nil {
+		serverKey, err = rtkMisc.GetC2SPublicKey(pub)
+	}
+	if err != nil {
+		log.Printf("[%s] instance:[%s] get server key err:%+v", rtkMisc.GetFuncInfo(), instance, err)
+		tlsConn.Close()
+		return nil, rtkMisc.ERR_NETWORK_C2S_TLS_HANDSHAKE
+	}
+	setConnectedServerKey(instance, serverKey)
+	log.Printf("[%s] instance:[%s] TLS handshake success, server key:[%s]", rtkMisc.GetFuncInfo(), instance, serverKey)
+	return tlsConn, rtkMisc.SUCCESS
+}
@@ -36,6 +36,7 @@ type browseParam struct {
 	monitorName string
 	ver         string
 	timeStamp   int64
+	serverKey   string // C2S TLS key in text record, mobile browse result has no key
 }
 
 var (
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkMisc "rtk-cross-share/misc"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The metrics are always counted, they are only exported after StartServer is called
+const (
+	namespace = "crossshare"
+	subsystem = "client"
+
+	DirectionSend    = "send"
+	DirectionReceive = "receive"
+
+	KindFile      = "file"
+	KindClipboard = "clipboard"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	transferBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "transfer_bytes_total",
+		Help:      "Bytes of file and clipboard data sent to or received from peer.",
+	}, []string{"peer", "direction", "kind"})
+
+	fileTransferDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "file_transfer_duration_seconds",
+		Help:      "Duration of file transfers from the first progress to the end, by result code.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"direction", "code"})
+
+	fileTransferTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "file_transfers_total",
+		Help:      "Ended file transfers by result code, the code of success is " + strconv.Itoa(int(rtkMisc.SUCCESS)) + ".",
+	}, []string{"direction", "code"})
+
+	pingRtt = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ping_rtt_seconds",
+		Help:      "RTT of the stream alive ping to peer.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"peer"})
+
+	pingFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ping_failures_total",
+		Help:      "Failed or timeout stream alive pings to peer.",
+	}, []string{"peer"})
+)
+
+type transferKey struct {
+	id        string
+	timestamp uint64
+}
+
+type transferStart struct {
+	startTime time.Time
+	direction string
+}
+
+var (
+	transferStartMap   = make(map[transferKey]transferStart)
+	transferStartMutex sync.Mutex
+
+	server      *http.Server
+	serverMutex sync.Mutex
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		transferBytes,
+		fileTransferDuration,
+		fileTransferTotal,
+		pingRtt,
+		pingFailures,
+	)
+
+	rtkEvent.Subscribe(rtkEvent.Filter{Types: []rtkEvent.EventType{
+		rtkEvent.EventTransferProgress,
+		rtkEvent.EventTransferDone,
+		rtkEvent.EventTransferFailed,
+	}}, observeTransferEvent)
+}
+
+func AddTransferBytes(id, direction, kind string, n int) {
+	if n <= 0 {
+		return
+	}
+	transferBytes.WithLabelValues(id, direction, kind).Add(float64(n))
+}
+
+func ObservePingRtt(id string, rtt time.Duration) {
+	pingRtt.WithLabelValues(id).Observe(rtt.Seconds())
+}
+
+func IncPingFailure(id string) {
+	pingFailures.WithLabelValues(id).Inc()
+}
+
+func getDirection(isSender bool) string {
+	if isSender {
+		return DirectionSend
+	}
+	return DirectionReceive
+}
+
+func observeTransferEvent(ev rtkEvent.Event) {
+	transferStartMutex.Lock()
+	defer transferStartMutex.Unlock()
+
+	switch e := ev.(type) {
+	case rtkEvent.TransferProgressEvent:
+		key := transferKey{e.ID, e.TimeStamp}
+		if _, ok := transferStartMap[key]; !ok {
+			transferStartMap[key] = transferStart{startTime: time.Now(), direction: getDirection(e.IsSender)}
+		}
+	case rtkEvent.TransferDoneEvent:
+		observeTransferEnd(transferKey{e.ID, e.TimeStamp}, getDirection(e.IsSender), rtkMisc.SUCCESS)
+	case rtkEvent.TransferFailedEvent:
+		observeTransferEnd(transferKey{e.ID, e.TimeStamp}, "", e.Code)
+	}
+}
+
+// observeTransferEnd the transfer which fails before any progress has no duration
+func observeTransferEnd(key transferKey, direction string, code rtkMisc.CrossShareErr) {
+	start, ok := transferStartMap[key]
+	delete(transferStartMap, key)
+	if direction == "" {
+		direction = start.direction
+	}
+	if direction == "" {
+		direction = "unknown"
+	}
+
+	codeLabel := strconv.Itoa(int(code))
+	fileTransferTotal.WithLabelValues(direction, codeLabel).Inc()
+	if ok {
+		fileTransferDuration.WithLabelValues(direction, codeLabel).Observe(time.Since(start.startTime).Seconds())
+	}
+}
+
+// StartServer exports the metrics on http://addr/metrics, addr must be a loopback address
+func StartServer(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("metrics address [%s] is not a loopback address", addr)
+	}
+
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+	if server != nil {
+		return errors.New("metrics server is already started")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	srv := server
+	rtkMisc.GoSafe(func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[%s] metrics server err:%+v", rtkMisc.GetFuncInfo(), err)
+		}
+	})
+	log.Printf("[%s] metrics is exported on [http://%s/metrics]", rtkMisc.GetFuncInfo(), listener.Addr().String())
+	return nil
+}
+
+func StopServer() {
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+	if server == nil {
+		return
+	}
+	server.Close()
+	server = nil
+}
@@ -0,0 +1,128 @@
+package peer2peer
+
+import (
+	"context"
+	"log"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+// Bandwidth limit of file transfer, the data of every file stream is limited by the global bucket and the bucket of its peer.
+// A bucket holds at most one second of tokens, and it allows a debt, the next caller waits until the debt is paid back.
+const bandwidthLimitPieceSize = 32 << 10 // 32KB, large buffer is written in pieces so the progress is smooth
+
+type tokenBucket struct {
+	mutex    sync.Mutex
+	rate     int64 // bytes per second, <= 0 means no limit
+	tokens   float64
+	lastTime time.Time
+}
+
+var (
+	globalBandwidthBucket  = &tokenBucket{}
+	peerBandwidthBucketMap = make(map[string]*tokenBucket) // key: ID
+	peerBandwidthMutex     sync.Mutex
+)
+
+func init() {
+	rtkPlatform.SetGoBandwidthLimitCallback(SetBandwidthLimit)
+}
+
+func (b *tokenBucket) setRate(rate int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rate = rate
+	b.tokens = float64(rate)
+	b.lastTime = time.Now()
+}
+
+func (b *tokenBucket) getRate() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.rate
+}
+
+// reserve takes n tokens and returns the time to wait before using them
+func (b *tokenBucket) reserve(n int) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.lastTime).Seconds()*float64(b.rate), float64(b.rate))
+	b.lastTime = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+}
+
+// SetBandwidthLimit sets the file transfer speed limit with the peer ID, empty ID is the global limit, bytesPerSec <= 0 means no limit
+func SetBandwidthLimit(id string, bytesPerSec int64) {
+	if id == "" {
+		globalBandwidthBucket.setRate(bytesPerSec)
+		log.Printf("[%s] set global bandwidth limit:[%d] bytes/s", rtkMisc.GetFuncInfo(), bytesPerSec)
+		return
+	}
+
+	peerBandwidthMutex.Lock()
+	defer peerBandwidthMutex.Unlock()
+	if bytesPerSec <= 0 {
+		if bucket, ok := peerBandwidthBucketMap[id]; ok {
+			bucket.setRate(0) // the bucket maybe used by the streams in transfer
+			delete(peerBandwidthBucketMap, id)
+		}
+	} else {
+		bucket, ok := peerBandwidthBucketMap[id]
+		if !ok {
+			bucket = &tokenBucket{}
+			peerBandwidthBucketMap[id] = bucket
+		}
+		bucket.setRate(bytesPerSec)
+	}
+	log.Printf("[%s] ID:[%s] set bandwidth limit:[%d] bytes/s", rtkMisc.GetFuncInfo(), id, bytesPerSec)
+}
+
+// bandwidthLimiter is used by the file streams of one peer
+type bandwidthLimiter struct {
+	id string
+}
+
+func newBandwidthLimiter(id string) *bandwidthLimiter {
+	return &bandwidthLimiter{id: id}
+}
+
+func (l *bandwidthLimiter) getPeerBucket() *tokenBucket {
+	peerBandwidthMutex.Lock()
+	defer peerBandwidthMutex.Unlock()
+	return peerBandwidthBucketMap[l.id]
+}
+
+func (l *bandwidthLimiter) isLimited() bool {
+	return globalBandwidthBucket.getRate() > 0 || l.getPeerBucket() != nil
+}
+
+// wait blocks until n bytes are allowed by both global and peer limit, or ctx is done
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	delay := globalBandwidthBucket.reserve(n)
+	if peerBucket := l.getPeerBucket(); peerBucket != nil {
+		delay = max(delay, peerBucket.reserve(n))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -3,7 +3,9 @@ package peer2peer
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-yamux/v5"
 	"io"
 	"log"
@@ -11,6 +13,8 @@ import (
 	rtkClipboard "rtk-cross-share/client/clipboard"
 	rtkCommon "rtk-cross-share/client/common"
 	rtkConnection "rtk-cross-share/client/connection"
+	rtkMetrics "rtk-cross-share/client/metrics"
+	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"time"
@@ -26,31 +30,52 @@ func writeXClipDataToSocket(id, ipAddr string) rtkMisc.CrossShareErr {
 	}
 	defer rtkConnection.CloseFmtTypeStream(id, rtkCommon.XCLIP_CB)
 
-	cbData := rtkClipboard.GetLastClipboardData()
+	cbData, isAllowed := rtkClipboard.GetLastClipboardDataForPeer(id)
 	if cbData.FmtType != rtkCommon.XCLIP_CB {
 		log.Printf("[%s] GetLastClipboardData Unknown ext data, fmtType: %s", rtkMisc.GetFuncInfo(), cbData.FmtType)
 		return rtkMisc.ERR_BIZ_CB_GET_DATA_TYPE_ERR
 	}
 
 	extData, ok := cbData.ExtData.(rtkCommon.ExtDataXClip)
-	if !ok {
-		log.Printf("[%s] GetLastClipboardData Unknown ext data, fmtType: %s", rtkMisc.GetFuncInfo(), cbData.FmtType)
+	if !ok || !isAllowed {
+		log.Printf("[%s] GetLastClipboardData Unknown ext data or rejected by policy, fmtType: %s", rtkMisc.GetFuncInfo(), cbData.FmtType)
 		return rtkMisc.ERR_BIZ_CB_INVALID_DATA
 	}
+	return writeXClipExtDataToStream(id, ipAddr, sXClip, extData, startTime)
+}
 
-	log.Printf("(SRC) IP[%s] Start to copy XClip data, text:[%d] image:[%d] html:[%d] rtf:[%d]...", ipAddr, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen)
-	var readers []io.Reader
+func writeXClipExtDataToStream(id, ipAddr string, sXClip network.Stream, extData rtkCommon.ExtDataXClip, startTime int64) rtkMisc.CrossShareErr {
+	log.Printf("(SRC) IP[%s] Start to copy XClip data, text:[%d] image:[%d] html:[%d] rtf:[%d] items:[%d]...", ipAddr, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen, len(extData.Items))
+	var parts [][]byte
 	if !rtkUtils.GetPeerClientIsSupportXClip(id) {
-		readers = make([]io.Reader, 1)
-		readers[0] = bytes.NewReader(extData.Image)
+		parts = [][]byte{extData.Image}
 	} else {
-		readers = make([]io.Reader, 4)
-		readers[0] = bytes.NewReader(extData.Text)
-		readers[1] = bytes.NewReader(extData.Image)
-		readers[2] = bytes.NewReader(extData.Html)
-		readers[3] = bytes.NewReader(extData.Rtf)
+		parts = [][]byte{extData.Text, extData.Image, extData.Html, extData.Rtf}
+		for _, item := range extData.Items {
+			parts = append(parts, item.Data)
+		}
 	}
-	nWrite, err := io.Copy(sXClip, io.MultiReader(readers...))
+	var nWrite int64
+	var err error
+	if isCompressSupported(id) {
+		compressWriter := newCompressBlockWriter(sXClip, false)
+		for _, part := range parts {
+			compressWriter.isCompress = isCompressibleData(part)
+			var n int
+			n, err = compressWriter.Write(part)
+			nWrite += int64(n)
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		readers := make([]io.Reader, 0, len(parts))
+		for _, part := range parts {
+			readers = append(readers, bytes.NewReader(part))
+		}
+		nWrite, err = io.Copy(sXClip, io.MultiReader(readers...))
+	}
+	rtkMetrics.AddTransferBytes(id, rtkMetrics.DirectionSend, rtkMetrics.KindClipboard, int(nWrite))
 	if err != nil {
 		log.Printf("(SRC) IP:[%s] Copy XClip data err:%+v", ipAddr, err)
 		if errors.Is(err, yamux.ErrStreamReset) {
@@ -88,11 +113,20 @@ func handleXClipDataFromSocket(id, ipAddr string) rtkMisc.CrossShareErr {
 
 	var xClipBuffer bytes.Buffer
 	xClipBuffer.Reset()
-	nXClipLen := extData.TextLen + extData.ImageLen + extData.HtmlLen + extData.RtfLen
+	nRtfEnd := extData.TextLen + extData.ImageLen + extData.HtmlLen + extData.RtfLen
+	nXClipLen := nRtfEnd
+	for _, item := range extData.Items {
+		nXClipLen += item.Len
+	}
 	xClipBuffer.Grow(int(nXClipLen))
 
 	log.Printf("(DST) IP[%s] Start to Copy XClip data, Total size:[%d]...", ipAddr, nXClipLen)
-	nDstWrite, err := io.Copy(&xClipBuffer, sXClip)
+	var xClipReader io.Reader = sXClip
+	if isCompressSupported(id) {
+		xClipReader = newCompressBlockReader(sXClip)
+	}
+	nDstWrite, err := io.Copy(&xClipBuffer, xClipReader)
+	rtkMetrics.AddTransferBytes(id, rtkMetrics.DirectionReceive, rtkMetrics.KindClipboard, int(nDstWrite))
 	if err != nil {
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			log.Printf("[%s] IP:[%s] (DST) Read XClip data timeout:%+v", rtkMisc.GetFuncInfo(), ipAddr, netErr)
@@ -125,11 +159,15 @@ func handleXClipDataFromSocket(id, ipAddr string) rtkMisc.CrossShareErr {
 		rtfData := []byte(nil)
 		if extData.RtfLen > 0 {
 			rtfData = make([]byte, extData.RtfLen)
-			io.ReadFull(bytes.NewReader(xClipBuffer.Bytes()[(extData.TextLen+extData.ImageLen+extData.HtmlLen):]), rtfData)
+			io.ReadFull(bytes.NewReader(xClipBuffer.Bytes()[(extData.TextLen+extData.ImageLen+extData.HtmlLen):nRtfEnd]), rtfData)
 		}
 
+		items := rtkClipboard.SplitXClipItemsData(extData.Items, xClipBuffer.Bytes()[nRtfEnd:])
+
 		log.Printf("(DST) IP[%s] End to Copy XClip data success, Total size:[%d] use [%d] ms", ipAddr, nDstWrite, time.Now().UnixMilli()-startTime)
-		rtkMisc.GoSafe(func() { rtkClipboard.SetupDstPasteXClipData(id, textData, imageData, htmlData, rtfData) })
+		rtkMisc.GoSafe(func() {
+			rtkClipboard.SetupDstPasteXClipData(id, cbData.OriginID, cbData.Clock, textData, imageData, htmlData, rtfData, items)
+		})
 		xClipBuffer.Reset()
 		return rtkMisc.SUCCESS
 	} else {
@@ -137,3 +175,43 @@ func handleXClipDataFromSocket(id, ipAddr string) rtkMisc.CrossShareErr {
 		return rtkMisc.ERR_BIZ_CB_DST_COPY_LOSS
 	}
 }
+
+// lazyXClipPullProcessAsSrc sends the XClip data of lazy head with hash when dst pastes it
+func lazyXClipPullProcessAsSrc(id, ipAddr, hash string) {
+	cbData, errCode := rtkClipboard.GetLazyXClipPullData(id, hash)
+	if sendLazyXClipPullResponseToDst(id, hash, cbData, errCode) != rtkMisc.SUCCESS || errCode != rtkMisc.SUCCESS {
+		return
+	}
+	extData := cbData.ExtData.(rtkCommon.ExtDataXClip)
+
+	startTime := time.Now().UnixMilli()
+	rtkConnection.HandleFmtTypeStreamReady(id, rtkCommon.XCLIP_CB) // wait for fmtType stream Ready
+	sXClip, ok := rtkConnection.GetFmtTypeStream(id, rtkCommon.XCLIP_CB)
+	if !ok {
+		log.Printf("[%s] Err: Not found  stream by ID:[%s]", rtkMisc.GetFuncInfo(), id)
+		sendCmdMsgToPeer(id, COMM_CB_TRANSFER_SRC_INTERRUPT, rtkCommon.XCLIP_CB, rtkMisc.ERR_BIZ_CB_GET_STREAM_EMPTY)
+		return
+	}
+	defer rtkConnection.CloseFmtTypeStream(id, rtkCommon.XCLIP_CB)
+
+	if errCode = writeXClipExtDataToStream(id, ipAddr, sXClip, extData, startTime); errCode != rtkMisc.SUCCESS {
+		log.Printf("(SRC) ID[%s] IP[%s] Copy lazy XClip data To Socket failed, ERR code:[%d]", id, ipAddr, errCode)
+		sendCmdMsgToPeer(id, COMM_CB_TRANSFER_SRC_INTERRUPT, rtkCommon.XCLIP_CB, errCode)
+	}
+}
+
+// lazyXClipPullProcessAsDst receives the XClip data pulled from src, and it is pasted as the data in normal mode
+func lazyXClipPullProcessAsDst(ctx context.Context, id, ipAddr string, pullRsp rtkCommon.ExtDataXClipLazyPullRsp) {
+	if pullRsp.ReqResultCode != rtkMisc.SUCCESS {
+		log.Printf("[%s](DST) ID:[%s] hash:[%s] pull lazy XClip data failed, errCode:[%d]", rtkMisc.GetFuncInfo(), id, pullRsp.Hash, pullRsp.ReqResultCode)
+		rtkPlatform.GoNotifyErrEvent(id, pullRsp.ReqResultCode, ipAddr, pullRsp.Hash, "", "")
+		return
+	}
+
+	rtkClipboard.SetupDstPasteXClipHead(id, pullRsp.OriginID, pullRsp.Clock, pullRsp.TextLen, pullRsp.ImageLen, pullRsp.HtmlLen, pullRsp.RtfLen, pullRsp.Items)
+	if errCode := rtkConnection.BuildFmtTypeTalker(ctx, id, rtkCommon.XCLIP_CB); errCode != rtkMisc.SUCCESS {
+		log.Printf("[%s]BuildFmtTypeTalker errCode:%+v ", rtkMisc.GetFuncInfo(), errCode)
+		return
+	}
+	processIoRead(ctx, id, ipAddr, rtkCommon.XCLIP_CB, 0)
+}
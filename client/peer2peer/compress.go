@@ -0,0 +1,213 @@
+package peer2peer
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkUtils "rtk-cross-share/client/utils"
+	"strings"
+)
+
+// Compress, only used with the peer which negotiated CapabilityCompress:
+// the file data on item stream and XClip data are sent in blocks of [4 bytes raw size][4 bytes payload size][payload], big endian,
+// the payload is DEFLATE data if payload size is less than raw size, otherwise it is raw data.
+// Chunked file is not compressed. ProgressBar, resume offsets and bandwidth limit of dst count the raw bytes.
+const (
+	compressBlockMaxSize    = copyBufSize
+	compressBlockHeaderSize = 8
+	compressSampleSize      = 64 << 10 // 64KB
+	compressEntropyMax      = 7.5      // bits per byte, the sampled data with higher entropy is taken as compressed
+)
+
+var compressedFileExtMap = map[string]bool{
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".7z": true, ".rar": true, ".zst": true, ".lz4": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true, ".avif": true,
+	".mp3": true, ".aac": true, ".m4a": true, ".ogg": true, ".opus": true, ".flac": true,
+	".mp4": true, ".mkv": true, ".mov": true, ".avi": true, ".webm": true, ".m4v": true,
+	".docx": true, ".xlsx": true, ".pptx": true, ".apk": true, ".ipa": true, ".jar": true, ".dmg": true, ".msi": true,
+}
+
+func isCompressSupported(id string) bool {
+	return rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityCompress)
+}
+
+// getSampleEntropy returns the Shannon entropy of data in bits per byte
+func getSampleEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+func isCompressibleData(data []byte) bool {
+	return getSampleEntropy(data[:min(len(data), compressSampleSize)]) < compressEntropyMax
+}
+
+// isCompressibleFile skips the files already compressed, which are detected by extension or sampled entropy from offset
+func isCompressibleFile(filePath string, offset int64) bool {
+	if compressedFileExtMap[strings.ToLower(filepath.Ext(filePath))] {
+		return false
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	sample := make([]byte, compressSampleSize)
+	n, err := file.ReadAt(sample, offset)
+	if n == 0 && err != nil {
+		return false
+	}
+	return isCompressibleData(sample[:n])
+}
+
+// compressBlockWriter writes p in blocks and returns the raw size, so the writer after it in io.MultiWriter counts raw bytes
+type compressBlockWriter struct {
+	w          io.Writer
+	isCompress bool
+	flateW     *flate.Writer
+	payload    bytes.Buffer
+	header     [compressBlockHeaderSize]byte
+}
+
+func newCompressBlockWriter(w io.Writer, isCompress bool) *compressBlockWriter {
+	return &compressBlockWriter{w: w, isCompress: isCompress}
+}
+
+func (c *compressBlockWriter) Write(p []byte) (int, error) {
+	nTotal := 0
+	for len(p) > 0 {
+		block := p[:min(len(p), compressBlockMaxSize)]
+		if err := c.writeBlock(block); err != nil {
+			return nTotal, err
+		}
+		nTotal += len(block)
+		p = p[len(block):]
+	}
+	return nTotal, nil
+}
+
+func (c *compressBlockWriter) writeBlock(block []byte) error {
+	payload := block
+	if c.isCompress {
+		c.payload.Reset()
+		if c.flateW == nil {
+			c.flateW, _ = flate.NewWriter(&c.payload, flate.BestSpeed)
+		} else {
+			c.flateW.Reset(&c.payload)
+		}
+		c.flateW.Write(block)
+		c.flateW.Close()
+		if c.payload.Len() < len(block) {
+			payload = c.payload.Bytes()
+		}
+	}
+
+	binary.BigEndian.PutUint32(c.header[:4], uint32(len(block)))
+	binary.BigEndian.PutUint32(c.header[4:], uint32(len(payload)))
+	if _, err := c.w.Write(c.header[:]); err != nil {
+		return err
+	}
+	_, err := c.w.Write(payload)
+	return err
+}
+
+// compressBlockReader reads the blocks written by compressBlockWriter, it never reads the data after the last block
+type compressBlockReader struct {
+	r       io.Reader
+	flateR  io.ReadCloser
+	payload []byte
+	raw     []byte
+	pending []byte
+	header  [compressBlockHeaderSize]byte
+}
+
+func newCompressBlockReader(r io.Reader) *compressBlockReader {
+	return &compressBlockReader{r: r}
+}
+
+func (c *compressBlockReader) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		if err := c.readBlock(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *compressBlockReader) readBlock() error {
+	if _, err := io.ReadFull(c.r, c.header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("read compress block header: %w", err)
+		}
+		return err // io.EOF is the end of data
+	}
+	rawSize := binary.BigEndian.Uint32(c.header[:4])
+	payloadSize := binary.BigEndian.Uint32(c.header[4:])
+	if rawSize == 0 || rawSize > compressBlockMaxSize || payloadSize > rawSize {
+		return fmt.Errorf("invalid compress block, raw size:[%d] payload size:[%d]", rawSize, payloadSize)
+	}
+
+	if cap(c.payload) < int(payloadSize) {
+		c.payload = make([]byte, compressBlockMaxSize)
+	}
+	payload := c.payload[:payloadSize]
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return fmt.Errorf("read compress block payload: %w", err)
+	}
+	if payloadSize == rawSize {
+		c.pending = payload
+		return nil
+	}
+
+	if c.flateR == nil {
+		c.flateR = flate.NewReader(bytes.NewReader(payload))
+	} else {
+		c.flateR.(flate.Resetter).Reset(bytes.NewReader(payload), nil)
+	}
+	if cap(c.raw) < int(rawSize) {
+		c.raw = make([]byte, compressBlockMaxSize)
+	}
+	raw := c.raw[:rawSize]
+	if _, err := io.ReadFull(c.flateR, raw); err != nil {
+		return fmt.Errorf("decompress block: %w", err)
+	}
+	c.pending = raw
+	return nil
+}
+
+// newFileDataWriter returns the writer of one file on item stream
+func newFileDataWriter(id string, w io.Writer, filePath string, offset int64) io.Writer {
+	if !isCompressSupported(id) {
+		return w
+	}
+	return newCompressBlockWriter(w, isCompressibleFile(filePath, offset))
+}
+
+// newFileDataReader returns the reader of one file with fileSize raw bytes on item stream
+func newFileDataReader(id string, r io.Reader, fileSize int64) io.Reader {
+	if !isCompressSupported(id) {
+		return io.LimitReader(r, fileSize)
+	}
+	return io.LimitReader(newCompressBlockReader(r), fileSize)
+}
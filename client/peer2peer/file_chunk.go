@@ -0,0 +1,291 @@
+package peer2peer
+
+import (
+	"context"
+	"github.com/libp2p/go-libp2p/core/network"
+	"io"
+	"log"
+	"os"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Chunked file, only used with the peer which negotiated CapabilityChunkedFile:
+// the file which is not transferred is split into chunks of fileChunkSize, chunk k is sent on chunk stream k%FileDropChunkStreamCount,
+// both sides build the same chunk list from the completed ranges, so no chunk header is needed.
+// dst writes every chunk at its offset, and records the completed ranges for resume instead of one offset.
+const (
+	chunkedFileThreshold   = 256 << 20 // 256MB
+	fileChunkSize          = 16 << 20  // 16MB
+	chunkStreamWaitTimeout = 2 * time.Second
+)
+
+func isChunkedFile(id string, fileSize uint64) bool {
+	return fileSize >= chunkedFileThreshold &&
+		rtkUtils.GetPeerClientIsSupportQueueTrans(id) &&
+		rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityChunkedFile)
+}
+
+// mergeFileRanges returns a new sorted list, the overlapped and adjacent ranges are merged
+func mergeFileRanges(ranges []rtkCommon.FileRange) []rtkCommon.FileRange {
+	sorted := make([]rtkCommon.FileRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Size > 0 {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	merged := make([]rtkCommon.FileRange, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && merged[n-1].Offset+merged[n-1].Size >= r.Offset {
+			if end := r.Offset + r.Size; end > merged[n-1].Offset+merged[n-1].Size {
+				merged[n-1].Size = end - merged[n-1].Offset
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func getFileRangesSize(ranges []rtkCommon.FileRange) int64 {
+	size := int64(0)
+	for _, r := range ranges {
+		size += r.Size
+	}
+	return size
+}
+
+// getInterruptDoneRanges checks the completed ranges of the interrupt file, an offset of the interrupt file is taken as range [0, offset)
+func getInterruptDoneRanges(fileSize, offset int64, doneRanges []rtkCommon.FileRange) ([]rtkCommon.FileRange, bool) {
+	if len(doneRanges) == 0 && offset > 0 {
+		doneRanges = []rtkCommon.FileRange{{Offset: 0, Size: offset}}
+	}
+	for _, r := range doneRanges {
+		if r.Offset < 0 || r.Size <= 0 || r.Offset+r.Size > fileSize {
+			return nil, false
+		}
+	}
+	return mergeFileRanges(doneRanges), true
+}
+
+// getPendingFileChunks splits the ranges not in doneRanges into chunks, doneRanges must be merged
+func getPendingFileChunks(fileSize int64, doneRanges []rtkCommon.FileRange) []rtkCommon.FileRange {
+	chunks := make([]rtkCommon.FileRange, 0)
+	addGap := func(start, end int64) {
+		for ; start < end; start += fileChunkSize {
+			chunks = append(chunks, rtkCommon.FileRange{Offset: start, Size: min(fileChunkSize, end-start)})
+		}
+	}
+
+	start := int64(0)
+	for _, r := range doneRanges {
+		addGap(start, r.Offset)
+		start = r.Offset + r.Size
+	}
+	addGap(start, fileSize)
+	return chunks
+}
+
+// runFileChunkTasks runs task for every chunk on the chunk streams at once, and returns the first error
+func runFileChunkTasks(ctx context.Context, ipAddr string, timeStamp uint64, chunkStreams []network.Stream, chunks []rtkCommon.FileRange, isSrc bool, task func(stream network.Stream, chunk rtkCommon.FileRange, buf []byte) rtkMisc.CrossShareErr) rtkMisc.CrossShareErr {
+	setDeadline := func(stream network.Stream, t time.Time) {
+		if isSrc {
+			stream.SetWriteDeadline(t)
+		} else {
+			stream.SetReadDeadline(t)
+		}
+	}
+
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	firstErrCode := rtkMisc.SUCCESS
+	for k, stream := range chunkStreams {
+		setDeadline(stream, time.Time{}) // the streams are reused by the next chunked file of this item
+		wg.Add(1)
+		rtkMisc.GoSafe(func() {
+			defer wg.Done()
+			buf := make([]byte, copyBufSize)
+			for j := k; j < len(chunks); j += len(chunkStreams) {
+				if chunkCtx.Err() != nil {
+					return
+				}
+				if errCode := task(stream, chunks[j], buf); errCode != rtkMisc.SUCCESS {
+					errMutex.Lock()
+					if firstErrCode == rtkMisc.SUCCESS {
+						firstErrCode = errCode
+					}
+					errMutex.Unlock()
+					cancel()
+					return
+				}
+			}
+		})
+	}
+
+	done := make(chan struct{})
+	rtkMisc.GoSafe(func() {
+		select {
+		case <-chunkCtx.Done():
+			select {
+			case <-done:
+				return
+			default:
+			}
+			//cancel io.Copy maybe block at stream Read/Write, so need interrupt by set deadline
+			for _, stream := range chunkStreams {
+				setDeadline(stream, time.Now().Add(10*time.Millisecond))
+			}
+		case <-done:
+		}
+	})
+
+	wg.Wait()
+	close(done)
+	if firstErrCode == rtkMisc.SUCCESS && ctx.Err() != nil {
+		return getFileTransferCancelErrCode(ctx, ipAddr, timeStamp, isSrc)
+	}
+	return firstErrCode
+}
+
+func writeChunkedFileToSocket(id, ipAddr string, write *cancelableWriter, read *cancelableReader, totalBar *ProgressBar, fileName, filePath string, fileSize, timeStamp uint64, doneRanges []rtkCommon.FileRange, isFileHash bool) rtkMisc.CrossShareErr {
+	startTime := time.Now().UnixMilli()
+	chunkStreams, ok := rtkConnection.GetFileDropChunkStreams(id, timeStamp, chunkStreamWaitTimeout)
+	if !ok {
+		log.Printf("[%s] Err: Not found file chunk streams by ID:[%s] timestamp:[%d]", rtkMisc.GetFuncInfo(), id, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_GET_STREAM_EMPTY
+	}
+
+	var srcFile *os.File
+	errCode := OpenSrcFile(&srcFile, filePath, 0)
+	if errCode != rtkMisc.SUCCESS {
+		log.Printf("[%s] OpenSrcFile err code:[%d]", rtkMisc.GetFuncInfo(), errCode)
+		return errCode
+	}
+	defer CloseFile(&srcFile)
+
+	chunks := getPendingFileChunks(int64(fileSize), doneRanges)
+	log.Printf("(SRC) IP[%s] Start copy chunked file:[%s], size:[%d], chunk count:[%d], already done:[%d] ...", ipAddr, fileName, fileSize, len(chunks), getFileRangesSize(doneRanges))
+
+	errCode = runFileChunkTasks(read.ctx, ipAddr, timeStamp, chunkStreams, chunks, true, func(stream network.Stream, chunk rtkCommon.FileRange, buf []byte) rtkMisc.CrossShareErr {
+		chunkRead := cancelableReader{realReader: io.NewSectionReader(srcFile, chunk.Offset, chunk.Size), ctx: read.ctx}
+		chunkWrite := cancelableWriter{realWriter: stream, ctx: read.ctx, limiter: write.limiter}
+		nCopy, err := io.CopyBuffer(io.MultiWriter(&chunkWrite, totalBar), &chunkRead, chunkWrite.getCopyBuffer(buf))
+		if err != nil {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] Copy file chunk offset:[%d] Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, chunk.Offset, err)
+			return getFileDataSendErrCode(err, &chunkRead, ipAddr, timeStamp)
+		}
+		if nCopy != chunk.Size {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] file chunk offset:[%d] only read:[%d], chunk size:[%d]", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, chunk.Offset, nCopy, chunk.Size)
+			return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE
+		}
+		return rtkMisc.SUCCESS
+	})
+	if errCode != rtkMisc.SUCCESS {
+		return errCode
+	}
+
+	if isFileHash {
+		fileHash, err := newFileHashWithPrefix(filePath, int64(fileSize))
+		if err != nil {
+			log.Printf("[%s] IP:[%s] id:[%d] hash file:[%s] err:%+v", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, filePath, err)
+			return rtkMisc.ERR_BIZ_FD_SRC_OPEN_FILE
+		}
+		if _, err = write.Write(fileHash.Sum(nil)); err != nil {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] write file:[%s] hash Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, fileName, err)
+			return getFileDataSendErrCode(err, read, ipAddr, timeStamp)
+		}
+	}
+	log.Printf("(SRC) IP[%s] End to copy chunked file:[%s] success, use [%d] ms", ipAddr, fileName, time.Now().UnixMilli()-startTime)
+	return rtkMisc.SUCCESS
+}
+
+// fileChunkTracker records the completed ranges of dst file, and syncs the file and records them to transfer journal every checkpointSize bytes
+type fileChunkTracker struct {
+	mutex          sync.Mutex
+	file           *os.File
+	doneRanges     []rtkCommon.FileRange
+	uncheckedBytes int64
+	onCheckpoint   func(offset int64, doneRanges []rtkCommon.FileRange)
+}
+
+func (t *fileChunkTracker) addDoneRange(chunk rtkCommon.FileRange) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.doneRanges = mergeFileRanges(append(t.doneRanges, chunk))
+	t.uncheckedBytes += chunk.Size
+	if t.onCheckpoint == nil || t.uncheckedBytes < checkpointSize {
+		return
+	}
+	if err := t.file.Sync(); err != nil {
+		log.Printf("[%s] Sync file err:%+v, skip checkpoint", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	t.onCheckpoint(0, t.doneRanges)
+	t.uncheckedBytes = 0
+}
+
+func (t *fileChunkTracker) getDoneRanges() []rtkCommon.FileRange {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.doneRanges
+}
+
+// readChunkedFileFromSocket receives the chunks not in doneRanges, and doneRanges is updated with the received chunks even if failed
+func readChunkedFileFromSocket(id, ipAddr string, read *cancelableReader, totalBar *ProgressBar, fileSize, timeStamp uint64, dstFileName, dstFullPath string, doneRanges *[]rtkCommon.FileRange, isRetry bool, onCheckpoint func(offset int64, doneRanges []rtkCommon.FileRange)) rtkMisc.CrossShareErr {
+	startTime := time.Now().UnixMilli()
+	chunkStreams, ok := rtkConnection.GetFileDropChunkStreams(id, timeStamp, chunkStreamWaitTimeout)
+	if !ok {
+		log.Printf("[%s] Err: Not found file chunk streams by ID:[%s] timestamp:[%d]", rtkMisc.GetFuncInfo(), id, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_GET_STREAM_EMPTY
+	}
+
+	var dstFile *os.File
+	if err := OpenDstFile(&dstFile, dstFullPath); err != nil {
+		return rtkMisc.ERR_BIZ_FD_DST_OPEN_FILE
+	}
+	defer CloseFile(&dstFile)
+	if !isRetry {
+		dstFile.Truncate(int64(fileSize))
+	}
+
+	chunks := getPendingFileChunks(int64(fileSize), *doneRanges)
+	if isRetry {
+		log.Printf("(DST) IP[%s] Retry copy chunked file:[%s], still has chunk count:[%d] left ...", ipAddr, dstFileName, len(chunks))
+	} else {
+		log.Printf("(DST) IP[%s] Start copy chunked file:[%s], size:[%d], chunk count:[%d] ...", ipAddr, dstFileName, fileSize, len(chunks))
+	}
+
+	tracker := fileChunkTracker{file: dstFile, doneRanges: *doneRanges, onCheckpoint: onCheckpoint}
+	errCode := runFileChunkTasks(read.ctx, ipAddr, timeStamp, chunkStreams, chunks, false, func(stream network.Stream, chunk rtkCommon.FileRange, buf []byte) rtkMisc.CrossShareErr {
+		chunkRead := cancelableReader{realReader: io.LimitReader(stream, chunk.Size), ctx: read.ctx, limiter: read.limiter}
+		nDstWrite, err := io.CopyBuffer(io.MultiWriter(io.NewOffsetWriter(dstFile, chunk.Offset), totalBar), &chunkRead, buf)
+		if err != nil {
+			log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] Copy file chunk offset:[%d] Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, chunk.Offset, err)
+			return getFileDataReceiveErrCode(err, &chunkRead, ipAddr, timeStamp)
+		}
+		if nDstWrite < chunk.Size {
+			log.Printf("(DST) IP[%s] file chunk offset:[%d] total:[%d], it less then chunk size:[%d]...", ipAddr, chunk.Offset, nDstWrite, chunk.Size)
+			return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_LOSS
+		}
+		tracker.addDoneRange(chunk)
+		return rtkMisc.SUCCESS
+	})
+	*doneRanges = tracker.getDoneRanges()
+	if errCode != rtkMisc.SUCCESS {
+		return errCode
+	}
+
+	dstFile.Sync()
+	log.Printf("(DST) IP[%s] End to Copy chunked file:[%s] success, use [%d] ms", ipAddr, dstFileName, time.Now().UnixMilli()-startTime)
+	return rtkMisc.SUCCESS
+}
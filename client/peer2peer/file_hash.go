@@ -0,0 +1,191 @@
+package peer2peer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/libp2p/go-libp2p/core/network"
+	"hash"
+	"io"
+	"log"
+	"os"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkMisc "rtk-cross-share/misc"
+	"time"
+)
+
+// File hash verify, only used with the peer which negotiated CapabilityFileHash:
+// src writes [file data][32 bytes SHA-256 of whole file] for every file on the item stream,
+// after the last file dst replies [4 bytes mismatch count][4 bytes file index]..., big endian,
+// then src resends the mismatched files in this order and dst replies again, until no mismatch or out of retry count
+const (
+	fileHashRetryMaxCnt   = 2
+	fileHashResultTimeout = 30 * time.Second
+)
+
+// newFileHashWithPrefix returns a SHA-256 which has already written the first n bytes of file, used when resume from an offset
+func newFileHashWithPrefix(filePath string, n int64) (hash.Hash, error) {
+	fileHash := sha256.New()
+	if n <= 0 {
+		return fileHash, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	nCopy, err := io.CopyN(fileHash, file, n)
+	if err != nil {
+		return nil, fmt.Errorf("hash file:[%s] prefix:[%d] only read:[%d], err:%+v", filePath, n, nCopy, err)
+	}
+	return fileHash, nil
+}
+
+// checkFileHashTrailer reads the hash written by src after the file data, and compares it with the hash of received data
+func checkFileHashTrailer(read *cancelableReader, s io.Reader, fileHash hash.Hash, ipAddr string, timeStamp uint64, fileName string) (bool, rtkMisc.CrossShareErr) {
+	read.realReader = io.LimitReader(s, sha256.Size)
+	trailer := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(read, trailer); err != nil {
+		log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] read file:[%s] hash Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, fileName, err)
+		return false, getFileDataReceiveErrCode(err, read, ipAddr, timeStamp)
+	}
+
+	if !bytes.Equal(trailer, fileHash.Sum(nil)) {
+		log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] file:[%s] hash mismatch!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, fileName)
+		return false, rtkMisc.SUCCESS
+	}
+	return true, rtkMisc.SUCCESS
+}
+
+func writeFileHashResult(s network.Stream, mismatchList []uint32) error {
+	buf := make([]byte, 4*(len(mismatchList)+1))
+	binary.BigEndian.PutUint32(buf, uint32(len(mismatchList)))
+	for i, index := range mismatchList {
+		binary.BigEndian.PutUint32(buf[4*(i+1):], index)
+	}
+
+	s.SetWriteDeadline(time.Now().Add(fileHashResultTimeout))
+	defer s.SetWriteDeadline(time.Time{})
+	_, err := s.Write(buf)
+	return err
+}
+
+func readFileHashResult(s network.Stream, nTotalFileCnt uint32) ([]uint32, error) {
+	s.SetReadDeadline(time.Now().Add(fileHashResultTimeout))
+	defer s.SetReadDeadline(time.Time{})
+
+	var header [4]byte
+	if _, err := io.ReadFull(s, header[:]); err != nil {
+		return nil, err
+	}
+	nCount := binary.BigEndian.Uint32(header[:])
+	if nCount > nTotalFileCnt {
+		return nil, fmt.Errorf("invalid mismatch count:[%d], total file count:[%d]", nCount, nTotalFileCnt)
+	}
+
+	buf := make([]byte, 4*nCount)
+	if _, err := io.ReadFull(s, buf); err != nil {
+		return nil, err
+	}
+	mismatchList := make([]uint32, 0, nCount)
+	for i := uint32(0); i < nCount; i++ {
+		index := binary.BigEndian.Uint32(buf[4*i:])
+		if index >= nTotalFileCnt {
+			return nil, fmt.Errorf("invalid mismatch file index:[%d], total file count:[%d]", index, nTotalFileCnt)
+		}
+		mismatchList = append(mismatchList, index)
+	}
+	return mismatchList, nil
+}
+
+func resendFileHashMismatchFilesAsSrc(id, ipAddr string, sFileDrop network.Stream, write *cancelableWriter, read *cancelableReader, fileDropReqData *rtkFileDrop.FilesTransferDataItem, buf *[]byte) rtkMisc.CrossShareErr {
+	nTotalFileCnt := uint32(len(fileDropReqData.SrcFileList))
+	for retryCnt := 0; ; retryCnt++ {
+		mismatchList, err := readFileHashResult(sFileDrop, nTotalFileCnt)
+		if err != nil {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] read file hash result err:%+v", rtkMisc.GetFuncInfo(), ipAddr, fileDropReqData.TimeStamp, err)
+			return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE
+		}
+		if len(mismatchList) == 0 {
+			return rtkMisc.SUCCESS
+		}
+		if retryCnt >= fileHashRetryMaxCnt {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] still [%d] files hash mismatch after retry [%d] times!", rtkMisc.GetFuncInfo(), ipAddr, fileDropReqData.TimeStamp, len(mismatchList), retryCnt)
+			return rtkMisc.ERR_BIZ_FD_FILE_HASH_MISMATCH
+		}
+
+		log.Printf("(SRC) IP:[%s] timestamp:[%d] [%d] files hash mismatch, resend them, retry:[%d]", ipAddr, fileDropReqData.TimeStamp, len(mismatchList), retryCnt+1)
+		for _, index := range mismatchList {
+			fileInfo := fileDropReqData.SrcFileList[index]
+			fileSize := uint64(fileInfo.FileSize_.SizeHigh)<<32 | uint64(fileInfo.FileSize_.SizeLow)
+			retryBar := New64(int64(fileSize)) // resend bytes are not counted in the total progress
+			errCode := writeFileToSocket(id, ipAddr, write, read, &retryBar, fileInfo.FileName, fileInfo.FilePath, fileSize, fileDropReqData.TimeStamp, 0, buf, sha256.New())
+			if errCode != rtkMisc.SUCCESS {
+				if errCode == rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS { // no interrupt info to recover a resend
+					errCode = rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL
+				}
+				return errCode
+			}
+		}
+	}
+}
+
+func resendFileHashMismatchFilesAsDst(id, ipAddr string, sFileDrop network.Stream, write *cancelableWriter, read *cancelableReader, fileDropData *rtkFileDrop.FilesTransferDataItem, buf *[]byte, mismatchList []uint32, mismatchPathMap map[uint32]string) rtkMisc.CrossShareErr {
+	deleteMismatchFiles := func() {
+		for _, index := range mismatchList {
+			DeleteFile(mismatchPathMap[index])
+		}
+	}
+
+	for retryCnt := 0; ; retryCnt++ {
+		if err := writeFileHashResult(sFileDrop, mismatchList); err != nil {
+			log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] write file hash result err:%+v", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, err)
+			if len(mismatchList) == 0 { // all files are verified, only src miss the result
+				return rtkMisc.SUCCESS
+			}
+			deleteMismatchFiles()
+			return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE
+		}
+		if len(mismatchList) == 0 {
+			return rtkMisc.SUCCESS
+		}
+		if retryCnt >= fileHashRetryMaxCnt {
+			log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] still [%d] files hash mismatch after retry [%d] times!", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, len(mismatchList), retryCnt)
+			deleteMismatchFiles()
+			return rtkMisc.ERR_BIZ_FD_FILE_HASH_MISMATCH
+		}
+
+		log.Printf("(DST) IP:[%s] timestamp:[%d] [%d] files hash mismatch, wait for resend, retry:[%d]", ipAddr, fileDropData.TimeStamp, len(mismatchList), retryCnt+1)
+		nextMismatchList := make([]uint32, 0)
+		for _, index := range mismatchList {
+			fileInfo := fileDropData.SrcFileList[index]
+			fileSize := uint64(fileInfo.FileSize_.SizeHigh)<<32 | uint64(fileInfo.FileSize_.SizeLow)
+			dstFullPath := mismatchPathMap[index]
+			DeleteFile(dstFullPath)
+
+			read.realReader = newFileDataReader(id, sFileDrop, int64(fileSize))
+			retryBar := New64(int64(fileSize)) // resend bytes are not counted in the total progress
+			offset := int64(0)
+			fileHash := sha256.New()
+			errCode := readFileFromSocket(id, ipAddr, write, read, &retryBar, fileSize, fileDropData.TimeStamp, fileInfo.FileName, dstFullPath, buf, &offset, false, fileHash, nil)
+			isMatch := false
+			if errCode == rtkMisc.SUCCESS {
+				isMatch, errCode = checkFileHashTrailer(read, sFileDrop, fileHash, ipAddr, fileDropData.TimeStamp, fileInfo.FileName)
+			}
+			if errCode != rtkMisc.SUCCESS {
+				if errCode == rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS { // no interrupt info to recover a resend
+					errCode = rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL
+				}
+				deleteMismatchFiles()
+				return errCode
+			}
+			if !isMatch {
+				nextMismatchList = append(nextMismatchList, index)
+			}
+		}
+		mismatchList = nextMismatchList
+	}
+}
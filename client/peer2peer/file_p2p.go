@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-yamux/v5"
+	"hash"
 	"io"
 	"log"
 	"net"
@@ -16,17 +17,17 @@ import (
 	rtkCommon "rtk-cross-share/client/common"
 	rtkConnection "rtk-cross-share/client/connection"
 	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkMetrics "rtk-cross-share/client/metrics"
 	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
-	"strconv"
-	"sync"
 	"time"
 )
 
 const (
 	copyBufSize       = 256 << 10 // 256KB
 	truncateThreshold = 32 << 20  // 32MB
+	checkpointSize    = 64 << 20  // 64MB, dst records the received offset to transfer journal
 
 	interruptFailureInterval = 60 //seconds, Interrupt file data transfer time out: 60s
 )
@@ -34,11 +35,34 @@ const (
 type cancelableReader struct {
 	realReader io.Reader
 	ctx        context.Context
+	limiter    *bandwidthLimiter // only set when realReader is a file stream
 }
 
 type cancelableWriter struct {
 	realWriter io.Writer
 	ctx        context.Context
+	limiter    *bandwidthLimiter // only set when realWriter is a file stream
+}
+
+// journalCheckpointWriter syncs dst file and records the offset to transfer journal every checkpointSize bytes
+type journalCheckpointWriter struct {
+	file           *os.File
+	offset         int64
+	lastCheckpoint int64
+	onCheckpoint   func(offset int64, doneRanges []rtkCommon.FileRange)
+}
+
+func (w *journalCheckpointWriter) Write(p []byte) (int, error) {
+	w.offset += int64(len(p))
+	if w.offset-w.lastCheckpoint >= checkpointSize {
+		if err := w.file.Sync(); err != nil {
+			log.Printf("[%s] Sync file err:%+v, skip checkpoint", rtkMisc.GetFuncInfo(), err)
+		} else {
+			w.onCheckpoint(w.offset, nil)
+			w.lastCheckpoint = w.offset
+		}
+	}
+	return len(p), nil
 }
 
 func (cRead *cancelableReader) Read(p []byte) (int, error) {
@@ -47,7 +71,17 @@ func (cRead *cancelableReader) Read(p []byte) (int, error) {
 		log.Printf("[%s] cancel by cancelableReader!", rtkMisc.GetFuncInfo())
 		return 0, cRead.ctx.Err()
 	default:
-		return cRead.realReader.Read(p) //maybe block here
+		if cRead.limiter == nil {
+			return cRead.realReader.Read(p) //maybe block here
+		}
+		n, err := cRead.realReader.Read(p[:min(len(p), bandwidthLimitPieceSize)])
+		if n > 0 {
+			rtkMetrics.AddTransferBytes(cRead.limiter.id, rtkMetrics.DirectionReceive, rtkMetrics.KindFile, n)
+			if waitErr := cRead.limiter.wait(cRead.ctx, n); waitErr != nil {
+				return n, waitErr
+			}
+		}
+		return n, err
 	}
 }
 
@@ -57,8 +91,33 @@ func (cWrite *cancelableWriter) Write(p []byte) (int, error) {
 		log.Printf("[%s] cancel by cancelableWriter!", rtkMisc.GetFuncInfo())
 		return 0, cWrite.ctx.Err()
 	default:
-		return cWrite.realWriter.Write(p) //maybe block here
+		if cWrite.limiter == nil {
+			return cWrite.realWriter.Write(p) //maybe block here
+		}
+		nTotal := 0
+		for len(p) > 0 {
+			piece := p[:min(len(p), bandwidthLimitPieceSize)]
+			if err := cWrite.limiter.wait(cWrite.ctx, len(piece)); err != nil {
+				return nTotal, err
+			}
+			n, err := cWrite.realWriter.Write(piece)
+			nTotal += n
+			rtkMetrics.AddTransferBytes(cWrite.limiter.id, rtkMetrics.DirectionSend, rtkMetrics.KindFile, n)
+			if err != nil {
+				return nTotal, err
+			}
+			p = p[n:]
+		}
+		return nTotal, nil
+	}
+}
+
+// getCopyBuffer returns a small buffer when the bandwidth is limited, so the progress is updated smoothly
+func (cWrite *cancelableWriter) getCopyBuffer(buf []byte) []byte {
+	if cWrite.limiter != nil && cWrite.limiter.isLimited() {
+		return buf[:min(len(buf), bandwidthLimitPieceSize)]
 	}
+	return buf
 }
 
 func OpenSrcFile(file **os.File, filePath string, offset int64) rtkMisc.CrossShareErr {
@@ -105,7 +164,7 @@ func OpenDstFile(file **os.File, filePath string) error {
 	}
 
 	var err error
-	*file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	*file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		log.Printf("Error opening Dst file path: %s err: %v ", filePath, err)
 		*file = nil
@@ -130,14 +189,14 @@ func CancelSrcFileTransfer(id, ipAddr string, timestamp uint64, errCode rtkMisc.
 	} else {
 		log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] Copy file operation was canceled by dst errCode:%d!", rtkMisc.GetFuncInfo(), ipAddr, timestamp, errCode)
 		if errCode == rtkMisc.ERR_BIZ_FT_DST_COPY_DETAILS {
-			rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp)
-			rtkPlatform.GoNotifyErrEvent(id, errCode, ipAddr, strconv.Itoa(int(timestamp)), "", "")
+			rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp, errCode)
+			rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timestamp, errCode)
 			rtkConnection.CloseFileDropItemStream(id, timestamp)
 			rtkConnection.HandleFmtTypeStreamReady(id, rtkCommon.FILE_DROP)
 			return
 		} else if errCode == rtkMisc.ERR_BIZ_FT_DST_OPEN_STREAM {
-			rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp)
-			rtkPlatform.GoNotifyErrEvent(id, errCode, ipAddr, strconv.Itoa(int(timestamp)), "", "")
+			rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp, errCode)
+			rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timestamp, errCode)
 			return
 		}
 	}
@@ -145,8 +204,8 @@ func CancelSrcFileTransfer(id, ipAddr string, timestamp uint64, errCode rtkMisc.
 	if rtkFileDrop.IsCancelFileTransInProgress(id, timestamp, errCode) {
 		log.Printf("(SRC) [%s] ID:[%s] Cancel FileTransfer success, timestamp:%d", rtkMisc.GetFuncInfo(), id, timestamp)
 	} else {
-		if rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp) {
-			rtkPlatform.GoNotifyErrEvent(id, errCode, ipAddr, strconv.Itoa(int(timestamp)), "", "") // notice  errCode to platform
+		if rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp, errCode) {
+			rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timestamp, errCode) // notice  errCode to platform
 			rtkConnection.HandleFmtTypeStreamReady(id, rtkCommon.FILE_DROP)
 			rtkConnection.CloseFileDropItemStream(id, timestamp)
 		}
@@ -162,8 +221,8 @@ func CancelDstFileTransfer(id, ipAddr string, timestamp uint64, errCode rtkMisc.
 	if rtkFileDrop.IsCancelFileTransInProgress(id, timestamp, errCode) {
 		log.Printf("(DST) [%s] ID:[%s] Cancel FileTransfer success, timestamp:%d", rtkMisc.GetFuncInfo(), id, timestamp)
 	} else {
-		if rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp) {
-			rtkPlatform.GoNotifyErrEvent(id, errCode, ipAddr, strconv.Itoa(int(timestamp)), "", "") // notice  errCode to platform
+		if rtkFileDrop.CancelFileTransFromCacheMap(id, timestamp, errCode) {
+			rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timestamp, errCode) // notice  errCode to platform
 			rtkConnection.CloseFileDropItemStream(id, timestamp)
 		}
 	}
@@ -212,6 +271,50 @@ func getFileTransferCancelErrCode(ctx context.Context, ipAddr string, timeStamp
 	return errCode
 }
 
+func getFileDataSendErrCode(err error, read *cancelableReader, ipAddr string, timeStamp uint64) rtkMisc.CrossShareErr {
+	if read.ctx.Err() != nil {
+		return getFileDataSendCancelErrCode(read.ctx, ipAddr, timeStamp)
+	}
+	if rtkConnection.IsQuicEOF(err) { // close by remote, need retry  TODO: check it
+		log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] quic remote Close!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
+	} else if rtkConnection.IsQuicClose(err) { // close by local, need retry  TODO: check it
+		log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] quic local Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		log.Printf("(SRC) [%s] IP:[%s] Error sending file timeout:%v", rtkMisc.GetFuncInfo(), ipAddr, netErr)
+		return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
+	} else if errors.Is(err, yamux.ErrStreamClosed) || errors.Is(err, yamux.ErrStreamReset) { // old  version client use tcp stream trigger this case
+		log.Printf("(SRC) IP[%s] IP:[%s]  Copy operation was canceled by close stream!", rtkMisc.GetFuncInfo(), ipAddr)
+		return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL
+	} else {
+		log.Printf("(SRC) [%s] IP:[%s] timeStamp:[%d] Copy file Error:%+v", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
+		return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE
+	}
+}
+
+func getFileDataReceiveErrCode(err error, read *cancelableReader, ipAddr string, timeStamp uint64) rtkMisc.CrossShareErr {
+	if read.ctx.Err() != nil {
+		return getFileDataReceiveCancelErrCode(read.ctx, ipAddr, timeStamp)
+	}
+	if rtkConnection.IsQuicEOF(err) { // close by remote, need retry  TODO: check it
+		log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] quic remote Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
+	} else if rtkConnection.IsQuicClose(err) { // close by local, need retry  TODO: check it
+		log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] quic local Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
+		return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		log.Printf("(DST) [%s] IP:[%s] Error Read file timeout:%v", rtkMisc.GetFuncInfo(), ipAddr, netErr)
+		return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
+	} else if errors.Is(err, yamux.ErrStreamClosed) || errors.Is(err, yamux.ErrStreamReset) { // old  version client trigger this case
+		log.Printf("(DST) IP[%s] Copy operation was canceled by close stream!", ipAddr)
+		return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL
+	} else {
+		log.Printf("(DST) [%s] IP:[%s] timeStamp:[%d] Copy file Error:%+v", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
+		return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE
+	}
+}
+
 func writeItemFileDataDetailsToSocket(ctx context.Context, id, ipAddr string, timeStamp uint64) {
 	startTime := time.Now().UnixMilli()
 	rtkConnection.HandleFmtTypeStreamReady(id, rtkCommon.FILE_DROP) // wait for file drop stream Ready
@@ -274,7 +377,9 @@ func dealFilesCacheDataProcess(p2pCtx context.Context, id, ipAddr string, timeSt
 		if cacheData == nil {
 			break
 		}
+		timeStamp = 0 // then take the next waiting item in queue
 
+		var resultCode rtkMisc.CrossShareErr
 		if cacheData.FileTransDirection == rtkFileDrop.FilesTransfer_As_Src {
 			resultCode = writeItemFileDataToSocket(p2pCtx, id, ipAddr, cacheData)
 			if resultCode != rtkMisc.SUCCESS {
@@ -285,8 +390,10 @@ func dealFilesCacheDataProcess(p2pCtx context.Context, id, ipAddr string, timeSt
 					return
 				} else {
 					log.Printf("(SRC) ID[%s] IP[%s] Copy file data To Socket failed, timestamp:%d, ERR code:[%d],  and not resend!", id, ipAddr, cacheData.TimeStamp, resultCode)
-					sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_SRC_INTERRUPT, resultCode, cacheData.TimeStamp)
-					rtkPlatform.GoNotifyErrEvent(id, resultCode, ipAddr, strconv.Itoa(int(cacheData.TimeStamp)), "", "")
+					if resultCode != rtkMisc.ERR_BIZ_FD_FILE_HASH_MISMATCH { // dst has the same result
+						sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_SRC_INTERRUPT, resultCode, cacheData.TimeStamp)
+					}
+					rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, cacheData.TimeStamp, resultCode)
 				}
 			}
 			rtkConnection.CloseFileDropItemStream(id, cacheData.TimeStamp)
@@ -302,17 +409,20 @@ func dealFilesCacheDataProcess(p2pCtx context.Context, id, ipAddr string, timeSt
 					return
 				} else {
 					log.Printf("(DST) ID[%s] IP[%s] Copy file data To Socket failed, timestamp:%d, ERR code:[%d]  and not retry", id, ipAddr, cacheData.TimeStamp, resultCode)
-					sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_DST_INTERRUPT, resultCode, cacheData.TimeStamp)
-					rtkPlatform.GoNotifyErrEvent(id, resultCode, ipAddr, strconv.Itoa(int(cacheData.TimeStamp)), "", "")
+					if resultCode != rtkMisc.ERR_BIZ_FD_FILE_HASH_MISMATCH { // src has the same result
+						sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_DST_INTERRUPT, resultCode, cacheData.TimeStamp)
+					}
+					rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, cacheData.TimeStamp, resultCode)
 				}
 			}
 			rtkConnection.CloseFileDropItemStream(id, cacheData.TimeStamp)
 			rtkConnection.CloseFmtTypeStream(id, rtkCommon.FILE_DROP) //  keep for support old version
 		} else {
 			log.Printf("[%s] ID:[%s] Invalid direction type:[%s], skit it!", rtkMisc.GetFuncInfo(), id, cacheData.FileTransDirection)
+			resultCode = rtkMisc.ERR_BIZ_FD_DIRECTION_TYPE_INVALID
 		}
 
-		rtkFileDrop.SetFilesCacheItemComplete(id, cacheData.TimeStamp)
+		rtkFileDrop.SetFilesCacheItemComplete(id, cacheData.TimeStamp, resultCode)
 	}
 }
 
@@ -353,7 +463,7 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 
 	ctx, cancel := rtkUtils.WithCancelSource(p2pCtx)
 	defer cancel(rtkCommon.FileTransDone)
-	rtkFileDrop.SetCancelFileTransferFunc(id, cancel)
+	rtkFileDrop.SetCancelFileTransferFunc(id, fileDropReqData.TimeStamp, cancel)
 
 	if isResend {
 		log.Printf("(SRC) Retry Copy file data to IP:[%s], id:[%d] file count:[%d] folder count:[%d] totalSize:[%d] TotalDescribe:[%s]...", ipAddr, fileDropReqData.TimeStamp, nTotalFileCnt, nTotalFolderCnt, fileDropReqData.TotalSize, fileDropReqData.TotalDescribe)
@@ -407,11 +517,13 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 	cancelableWrite := cancelableWriter{
 		realWriter: sFileDrop,
 		ctx:        ctx,
+		limiter:    newBandwidthLimiter(id),
 	}
 
 	copyBuffer := make([]byte, copyBufSize)
 	getInterruptFile := false
 	offSet := int64(0)
+	isFileHash := rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityFileHash)
 	for i, fileInfo := range fileDropReqData.SrcFileList {
 		curFileSize = uint64(fileInfo.FileSize_.SizeHigh)<<32 | uint64(fileInfo.FileSize_.SizeLow)
 		curFilePath = fileInfo.FilePath
@@ -422,6 +534,8 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 		}
 
 		fileSize := curFileSize
+		isChunked := isChunkedFile(id, curFileSize)
+		var doneRanges []rtkCommon.FileRange
 		if isResend && fileInfo.FileName == fileDropReqData.InterruptSrcFileName {
 			getInterruptFile = true
 			offSet = fileDropReqData.InterruptFileOffSet
@@ -429,7 +543,16 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 				log.Printf("[%s] Retry Copy file data to IP:[%s], id:[%d], get invalid interrupt offset:[%d]!", rtkMisc.GetFuncInfo(), ipAddr, fileDropReqData.TimeStamp, offSet)
 				return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
 			}
-			progressBar.Add64(fileDropReqData.InterruptFileOffSet)
+			if isChunked {
+				var ok bool
+				if doneRanges, ok = getInterruptDoneRanges(int64(curFileSize), offSet, fileDropReqData.InterruptDoneRanges); !ok {
+					log.Printf("[%s] Retry Copy file data to IP:[%s], id:[%d], get invalid interrupt done ranges:%+v!", rtkMisc.GetFuncInfo(), ipAddr, fileDropReqData.TimeStamp, fileDropReqData.InterruptDoneRanges)
+					return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
+				}
+				progressBar.Add64(getFileRangesSize(doneRanges))
+			} else {
+				progressBar.Add64(fileDropReqData.InterruptFileOffSet)
+			}
 			sendBytes := progressBar.GetCurrentBytes()
 			rounded := float64(sendBytes) / float64(fileDropReqData.TotalSize) * 100
 			fileSize = curFileSize - uint64(fileDropReqData.InterruptFileOffSet)
@@ -438,7 +561,20 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 			offSet = int64(0)
 		}
 
-		errCode := writeFileToSocket(id, ipAddr, &cancelableWrite, &cancelableRead, &progressBar, fileInfo.FileName, fileInfo.FilePath, fileSize, fileDropReqData.TimeStamp, offSet, &copyBuffer)
+		var errCode rtkMisc.CrossShareErr
+		if isChunked {
+			errCode = writeChunkedFileToSocket(id, ipAddr, &cancelableWrite, &cancelableRead, progressBar, fileInfo.FileName, fileInfo.FilePath, curFileSize, fileDropReqData.TimeStamp, doneRanges, isFileHash)
+		} else {
+			var fileHash hash.Hash
+			if isFileHash {
+				var err error
+				if fileHash, err = newFileHashWithPrefix(fileInfo.FilePath, offSet); err != nil {
+					log.Printf("[%s] IP:[%s] id:[%d] hash file:[%s] err:%+v", rtkMisc.GetFuncInfo(), ipAddr, fileDropReqData.TimeStamp, fileInfo.FilePath, err)
+					return rtkMisc.ERR_BIZ_FD_SRC_OPEN_FILE
+				}
+			}
+			errCode = writeFileToSocket(id, ipAddr, &cancelableWrite, &cancelableRead, &progressBar, fileInfo.FileName, fileInfo.FilePath, fileSize, fileDropReqData.TimeStamp, offSet, &copyBuffer, fileHash)
+		}
 		if errCode != rtkMisc.SUCCESS {
 			return errCode
 		}
@@ -453,13 +589,19 @@ func writeItemFileDataToSocket(p2pCtx context.Context, id, ipAddr string, fileDr
 		return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
 	}
 
+	if isFileHash {
+		if errCode := resendFileHashMismatchFilesAsSrc(id, ipAddr, sFileDrop, &cancelableWrite, &cancelableRead, fileDropReqData, &copyBuffer); errCode != rtkMisc.SUCCESS {
+			return errCode
+		}
+	}
+
 	rtkPlatform.GoUpdateSendProgressBar(ipAddr, id, curFilePath, fileDoneCnt, nTotalFileCnt, curFileSize, fileDropReqData.TotalSize, fileDropReqData.TotalSize, fileDropReqData.TimeStamp)
 	log.Printf("(SRC) End Copy all file data to IP:[%s] success, id:[%d] file count:[%d] folder count:[%d] TotalDescribe:[%s], total use [%d] ms", ipAddr, fileDropReqData.TimeStamp, nTotalFileCnt, nTotalFolderCnt, fileDropReqData.TotalDescribe, time.Now().UnixMilli()-startTime)
 	ShowNotiMessageSendFileTransferDone(fileDropReqData, id)
 	return rtkMisc.SUCCESS
 }
 
-func writeFileToSocket(id, ipAddr string, write *cancelableWriter, read *cancelableReader, totalBar **ProgressBar, fileName, filePath string, fileSize, timeStamp uint64, offset int64, buf *[]byte) rtkMisc.CrossShareErr {
+func writeFileToSocket(id, ipAddr string, write *cancelableWriter, read *cancelableReader, totalBar **ProgressBar, fileName, filePath string, fileSize, timeStamp uint64, offset int64, buf *[]byte, fileHash hash.Hash) rtkMisc.CrossShareErr {
 	startTime := time.Now().UnixMilli()
 
 	var srcFile *os.File
@@ -480,33 +622,25 @@ func writeFileToSocket(id, ipAddr string, write *cancelableWriter, read *cancela
 	nCopy := int64(0)
 	var err error
 	if fileSize > 0 {
-		nCopy, err = io.CopyBuffer(io.MultiWriter(*totalBar, write), read, *buf)
+		writers := []io.Writer{newFileDataWriter(id, write, filePath, offset), *totalBar} // progress is counted after the data is sent
+		if fileHash != nil {
+			writers = append(writers, fileHash)
+		}
+		nCopy, err = io.CopyBuffer(io.MultiWriter(writers...), read, write.getCopyBuffer(*buf))
 		if err != nil {
 			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] Copy file Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
-			if read.ctx.Err() != nil {
-				return getFileDataSendCancelErrCode(read.ctx, ipAddr, timeStamp)
-			}
-			if rtkConnection.IsQuicEOF(err) { // close by remote, need retry  TODO: check it
-				log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] quic remote Close!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
-				return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
-			} else if rtkConnection.IsQuicClose(err) { // close by local, need retry  TODO: check it
-				log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] quic local Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
-				return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
-			} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				log.Printf("(SRC) [%s] IP:[%s] Error sending file timeout:%v", rtkMisc.GetFuncInfo(), ipAddr, netErr)
-				return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS
-			} else if errors.Is(err, yamux.ErrStreamClosed) || errors.Is(err, yamux.ErrStreamReset) { // old  version client use tcp stream trigger this case
-				log.Printf("(SRC) IP[%s] IP:[%s]  Copy operation was canceled by close stream!", rtkMisc.GetFuncInfo(), ipAddr)
-				return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL
-			} else {
-				log.Printf("(SRC) [%s] IP:[%s] timeStamp:[%d] Copy file Error:%+v", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
-				return rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE
-			}
+			return getFileDataSendErrCode(err, read, ipAddr, timeStamp)
 		}
 		if !rtkUtils.GetPeerClientIsSupportQueueTrans(id) { //quic no need flush
 			bufio.NewWriter(write).Flush()
 		}
 	}
+	if fileHash != nil {
+		if _, err = write.Write(fileHash.Sum(nil)); err != nil {
+			log.Printf("(SRC) [%s] IP:[%s] timestamp:[%d] write file:[%s] hash Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, fileName, err)
+			return getFileDataSendErrCode(err, read, ipAddr, timeStamp)
+		}
+	}
 	log.Printf("(SRC) IP[%s] End to copy file:[%s] success, total:[%d] use [%d] ms", ipAddr, fileName, nCopy, time.Now().UnixMilli()-startTime)
 	return rtkMisc.SUCCESS
 }
@@ -547,7 +681,7 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 
 	ctx, cancel := rtkUtils.WithCancelSource(p2pCtx)
 	defer cancel(rtkCommon.FileTransDone)
-	rtkFileDrop.SetCancelFileTransferFunc(id, cancel)
+	rtkFileDrop.SetCancelFileTransferFunc(id, fileDropData.TimeStamp, cancel)
 
 	isRetry := false                                                                                                                                //interrupt and retry transmission flag
 	if fileDropData.InterruptSrcFileName != "" && fileDropData.InterruptDstFileName != "" && fileDropData.InterruptLastErrCode != rtkMisc.SUCCESS { //InterruptFileOffSet maybe is 0
@@ -614,6 +748,7 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 	cancelableRead := cancelableReader{
 		realReader: nil,
 		ctx:        ctx,
+		limiter:    newBandwidthLimiter(id),
 	}
 	cancelableWrite := cancelableWriter{
 		realWriter: nil,
@@ -624,6 +759,9 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 	getInterruptFile := false
 	isInterruptFile := false
 	offset := int64(0)
+	isFileHash := rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityFileHash)
+	mismatchList := make([]uint32, 0)
+	mismatchPathMap := make(map[uint32]string)
 	for i, fileInfo := range fileDropData.SrcFileList {
 		curFileSize = uint64(fileInfo.FileSize_.SizeHigh)<<32 | uint64(fileInfo.FileSize_.SizeLow)
 		if isRetry && fileInfo.FileName != fileDropData.InterruptSrcFileName && !getInterruptFile {
@@ -633,13 +771,24 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 		}
 
 		fileSize := curFileSize
+		isChunked := isChunkedFile(id, curFileSize)
+		var doneRanges []rtkCommon.FileRange
 		if isRetry && fileInfo.FileName == fileDropData.InterruptSrcFileName {
 			getInterruptFile = true
 			if fileDropData.InterruptFileOffSet < 0 || fileDropData.InterruptFileOffSet > int64(curFileSize) {
 				log.Printf("[%d] Retry Copy file data from IP:[%s], id:[%d], get invalid interrupt offset:[%d]!", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, fileDropData.InterruptFileOffSet)
 				return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
 			}
-			progressBar.Add64(fileDropData.InterruptFileOffSet)
+			if isChunked {
+				var ok bool
+				if doneRanges, ok = getInterruptDoneRanges(int64(curFileSize), fileDropData.InterruptFileOffSet, fileDropData.InterruptDoneRanges); !ok {
+					log.Printf("[%s] Retry Copy file data from IP:[%s], id:[%d], get invalid interrupt done ranges:%+v!", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, fileDropData.InterruptDoneRanges)
+					return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
+				}
+				progressBar.Add64(getFileRangesSize(doneRanges))
+			} else {
+				progressBar.Add64(fileDropData.InterruptFileOffSet)
+			}
 			receivedBytes := progressBar.GetCurrentBytes()
 			rounded := float64(receivedBytes) / float64(fileDropData.TotalSize) * 100
 			log.Printf("(DST) Retry Copy file data from IP:[%s], id:[%d], already received:[%d], percentage:[%.2f%%], Starting from this file:[%s], offset:[%d]...", ipAddr, fileDropData.TimeStamp, receivedBytes, rounded, fileInfo.FileName, fileDropData.InterruptFileOffSet)
@@ -648,18 +797,57 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 			dstFilePath = filepath.Join(fileDropData.DstFilePath, curFileName)
 			isInterruptFile = true
 			fileSize = curFileSize - uint64(fileDropData.InterruptFileOffSet)
+			offset = fileDropData.InterruptFileOffSet
 		} else {
 			isInterruptFile = false
+			offset = int64(0)
 			curFileName = rtkMisc.AdaptationPath(fileInfo.FileName)
 			dstFilePath, curFileName = rtkUtils.GetTargetDstPathName(filepath.Join(fileDropData.DstFilePath, curFileName), curFileName)
 		}
 
-		cancelableRead.realReader = io.LimitReader(sFileDrop, int64(fileSize))
+		var fileHash hash.Hash
+		if isFileHash && !isChunked {
+			var err error
+			if fileHash, err = newFileHashWithPrefix(dstFilePath, offset); err != nil {
+				log.Printf("[%s] IP:[%s] id:[%d] hash file:[%s] err:%+v", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, dstFilePath, err)
+				return rtkMisc.ERR_BIZ_FD_DST_OPEN_FILE
+			}
+		}
+
+		srcFileName, dstFileName, dstFullPath := fileInfo.FileName, curFileName, dstFilePath
+		onCheckpoint := func(checkpointOffset int64, checkpointRanges []rtkCommon.FileRange) {
+			rtkFileDrop.SetFilesTransferDataCheckpoint(id, srcFileName, dstFileName, dstFullPath, fileDropData.TimeStamp, checkpointOffset, checkpointRanges)
+		}
 
-		errCode := readFileFromSocket(id, ipAddr, &cancelableWrite, &cancelableRead, &progressBar, fileSize, fileDropData.TimeStamp, curFileName, dstFilePath, &copyBuffer, &offset, isInterruptFile)
+		var errCode rtkMisc.CrossShareErr
+		if isChunked {
+			offset = 0 // the chunked file is resumed by doneRanges
+			onCheckpoint(offset, doneRanges)
+			errCode = readChunkedFileFromSocket(id, ipAddr, &cancelableRead, progressBar, curFileSize, fileDropData.TimeStamp, curFileName, dstFilePath, &doneRanges, isInterruptFile, onCheckpoint)
+			if errCode == rtkMisc.SUCCESS && isFileHash {
+				var err error
+				if fileHash, err = newFileHashWithPrefix(dstFilePath, int64(curFileSize)); err != nil {
+					log.Printf("[%s] IP:[%s] id:[%d] hash file:[%s] err:%+v", rtkMisc.GetFuncInfo(), ipAddr, fileDropData.TimeStamp, dstFilePath, err)
+					errCode = rtkMisc.ERR_BIZ_FD_DST_OPEN_FILE
+				}
+			}
+		} else {
+			onCheckpoint(offset, nil)
+			cancelableRead.realReader = newFileDataReader(id, sFileDrop, int64(fileSize))
+			errCode = readFileFromSocket(id, ipAddr, &cancelableWrite, &cancelableRead, &progressBar, fileSize, fileDropData.TimeStamp, curFileName, dstFilePath, &copyBuffer, &offset, isInterruptFile, fileHash, onCheckpoint)
+		}
+		if errCode == rtkMisc.SUCCESS && fileHash != nil {
+			offset = int64(curFileSize) // all data is received, resume only need the hash
+			var isMatch bool
+			isMatch, errCode = checkFileHashTrailer(&cancelableRead, sFileDrop, fileHash, ipAddr, fileDropData.TimeStamp, curFileName)
+			if errCode == rtkMisc.SUCCESS && !isMatch {
+				mismatchList = append(mismatchList, uint32(i))
+				mismatchPathMap[uint32(i)] = dstFilePath
+			}
+		}
 		if errCode != rtkMisc.SUCCESS {
 			if errCode == rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS {
-				rtkFileDrop.SetFilesTransferDataInterrupt(id, fileInfo.FileName, curFileName, dstFilePath, fileDropData.TimeStamp, offset, errCode)
+				rtkFileDrop.SetFilesTransferDataInterrupt(id, fileInfo.FileName, curFileName, dstFilePath, fileDropData.TimeStamp, offset, doneRanges, errCode)
 			} else {
 				DeleteFile(dstFilePath)
 			}
@@ -677,13 +865,19 @@ func readItemFileDataFromSocket(p2pCtx context.Context, id, ipAddr string, fileD
 		return rtkMisc.ERR_BIZ_FT_INTERRUPT_INFO_INVALID
 	}
 
+	if isFileHash {
+		if errCode := resendFileHashMismatchFilesAsDst(id, ipAddr, sFileDrop, &cancelableWrite, &cancelableRead, fileDropData, &copyBuffer, mismatchList, mismatchPathMap); errCode != rtkMisc.SUCCESS {
+			return errCode
+		}
+	}
+
 	rtkPlatform.GoUpdateReceiveProgressBar(ipAddr, id, dstFilePath, fileDoneCnt, nTotalFileCnt, curFileSize, fileDropData.TotalSize, fileDropData.TotalSize, fileDropData.TimeStamp)
 	log.Printf("(DST) End Copy file data from IP:[%s] success, id:[%d] file count:[%d] folder count:[%d] totalSize:[%d] totalDescribe:[%s] total use:[%d]ms", ipAddr, fileDropData.TimeStamp, nTotalFileCnt, nTotalFolderCnt, fileDropData.TotalSize, fileDropData.TotalDescribe, time.Now().UnixMilli()-startTime)
 	ShowNotiMessageRecvFileTransferDone(fileDropData, id)
 	return rtkMisc.SUCCESS
 }
 
-func readFileFromSocket(id, ipAddr string, write *cancelableWriter, read *cancelableReader, totalBar **ProgressBar, fileSize, timeStamp uint64, dstFileName, dstFullPath string, buf *[]byte, offset *int64, isRetry bool) rtkMisc.CrossShareErr {
+func readFileFromSocket(id, ipAddr string, write *cancelableWriter, read *cancelableReader, totalBar **ProgressBar, fileSize, timeStamp uint64, dstFileName, dstFullPath string, buf *[]byte, offset *int64, isRetry bool, fileHash hash.Hash, onCheckpoint func(offset int64, doneRanges []rtkCommon.FileRange)) rtkMisc.CrossShareErr {
 	startTime := time.Now().UnixMilli()
 	var dstFile *os.File
 	err := OpenDstFile(&dstFile, dstFullPath)
@@ -691,6 +885,10 @@ func readFileFromSocket(id, ipAddr string, write *cancelableWriter, read *cancel
 		return rtkMisc.ERR_BIZ_FD_DST_OPEN_FILE
 	}
 	defer CloseFile(&dstFile)
+	if _, err = dstFile.Seek(*offset, io.SeekStart); err != nil {
+		log.Printf("(DST) [%s] IP:[%s] seek file:[%s] offset:[%d] err:%+v", rtkMisc.GetFuncInfo(), ipAddr, dstFullPath, *offset, err)
+		return rtkMisc.ERR_BIZ_FD_DST_OPEN_FILE
+	}
 	write.realWriter = dstFile
 
 	if isRetry {
@@ -704,29 +902,18 @@ func readFileFromSocket(id, ipAddr string, write *cancelableWriter, read *cancel
 		if fileSize > uint64(truncateThreshold) && !isRetry {
 			dstFile.Truncate(int64(fileSize))
 		}
-		nDstWrite, err = io.CopyBuffer(io.MultiWriter(write, *totalBar), read, *buf)
+		writers := []io.Writer{write, *totalBar}
+		if fileHash != nil {
+			writers = append(writers, fileHash)
+		}
+		if onCheckpoint != nil {
+			writers = append(writers, &journalCheckpointWriter{file: dstFile, offset: *offset, lastCheckpoint: *offset, onCheckpoint: onCheckpoint})
+		}
+		nDstWrite, err = io.CopyBuffer(io.MultiWriter(writers...), read, *buf)
 		if err != nil {
-			*offset = nDstWrite
+			*offset += nDstWrite
 			log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] Copy file Error:%+v!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
-			if read.ctx.Err() != nil {
-				return getFileDataReceiveCancelErrCode(read.ctx, ipAddr, timeStamp)
-			}
-			if rtkConnection.IsQuicEOF(err) { // close by remote, need retry  TODO: check it
-				log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] quic remote Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
-				return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
-			} else if rtkConnection.IsQuicClose(err) { // close by local, need retry  TODO: check it
-				log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] quic local Closed!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
-				return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
-			} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				log.Printf("(DST) [%s] IP:[%s] Error Read file timeout:%v", rtkMisc.GetFuncInfo(), ipAddr, netErr)
-				return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_BUSINESS
-			} else if errors.Is(err, yamux.ErrStreamClosed) || errors.Is(err, yamux.ErrStreamReset) { // old  version client trigger this case
-				log.Printf("(DST) IP[%s] Copy operation was canceled by close stream!", ipAddr)
-				return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL
-			} else {
-				log.Printf("(DST) [%s] IP:[%s] timeStamp:[%d] Copy file Error:%+v", rtkMisc.GetFuncInfo(), ipAddr, timeStamp, err)
-				return rtkMisc.ERR_BIZ_FD_DST_COPY_FILE
-			}
+			return getFileDataReceiveErrCode(err, read, ipAddr, timeStamp)
 		}
 		dstFile.Sync()
 	}
@@ -753,7 +940,7 @@ func ShowNotiMessageSendFileTransferDone(fileDropData *rtkFileDrop.FilesTransfer
 		fileUnit = "file"
 	}
 	filename := fmt.Sprintf("%d %s", fileCnt, fileUnit)
-	rtkPlatform.GoNotiMessageFileTransfer(filename, clientInfo.DeviceName, clientInfo.Platform, fileDropData.TimeStamp, true)
+	rtkPlatform.GoNotiMessageFileTransfer(id, filename, clientInfo.DeviceName, clientInfo.Platform, fileDropData.TimeStamp, true)
 }
 
 func ShowNotiMessageRecvFileTransferDone(fileDropData *rtkFileDrop.FilesTransferDataItem, id string) {
@@ -769,7 +956,7 @@ func ShowNotiMessageRecvFileTransferDone(fileDropData *rtkFileDrop.FilesTransfer
 		fileUnit = "file"
 	}
 	filename := fmt.Sprintf("%d %s", fileCnt, fileUnit)
-	rtkPlatform.GoNotiMessageFileTransfer(filename, clientInfo.DeviceName, clientInfo.Platform, fileDropData.TimeStamp, false)
+	rtkPlatform.GoNotiMessageFileTransfer(id, filename, clientInfo.DeviceName, clientInfo.Platform, fileDropData.TimeStamp, false)
 }
 
 func watchRecoverFileTransferCacheTimeout(id, ipAddr string, timestamp uint64, code rtkMisc.CrossShareErr, isSrc bool) {
@@ -820,22 +1007,39 @@ func watchRecoverFileTransferCacheTimeoutAsDst(id, ipAddr string, timestamp uint
 		nCount++
 	}
 
-	cacheData := rtkFileDrop.GetFilesTransferDataItem(id)
+	cacheData := rtkFileDrop.PeekFilesTransferDataItem(id, timestamp)
+	if cacheData == nil {
+		return
+	}
+
+	sendFileTransRecoverRequestToSrc(id, cacheData.InterruptSrcFileName, cacheData.TimeStamp, cacheData.InterruptFileOffSet, cacheData.InterruptDoneRanges, cacheData.InterruptLastErrCode)
+}
+
+// recoverFileTransferFromJournal continues the transfers saved in journal before restart when the peer is online again
+func recoverFileTransferFromJournal(id, ipAddr string) {
+	cacheData := rtkFileDrop.TakeJournalRecoverItem(id)
 	if cacheData == nil {
 		return
 	}
 
-	sendFileTransRecoverRequestToSrc(id, cacheData.InterruptSrcFileName, cacheData.TimeStamp, cacheData.InterruptFileOffSet, cacheData.InterruptLastErrCode)
+	if cacheData.FileTransDirection == rtkFileDrop.FilesTransfer_As_Src {
+		log.Printf("(SRC) [%s] ID:[%s] IP:[%s] timestamp:[%d] file transfer is loaded from journal, wait for dst recover request...", rtkMisc.GetFuncInfo(), id, ipAddr, cacheData.TimeStamp)
+		rtkConnection.BuildFileDropItemStreamListener(cacheData.TimeStamp) // the listener is lost after restart, it's removed by clearFilesTransferCacheList if time out
+		watchRecoverFileTransferCacheTimeoutAsSrc(id, ipAddr, cacheData.TimeStamp, rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_BUSINESS)
+	} else {
+		log.Printf("(DST) [%s] ID:[%s] IP:[%s] timestamp:[%d] file transfer is loaded from journal, file:[%s] offset:[%d], request src to recover...", rtkMisc.GetFuncInfo(), id, ipAddr, cacheData.TimeStamp, cacheData.InterruptSrcFileName, cacheData.InterruptFileOffSet)
+		watchRecoverFileTransferCacheTimeoutAsDst(id, ipAddr, cacheData.TimeStamp, cacheData.InterruptLastErrCode)
+	}
 }
 
-func recoverFileTransferProcessAsDst(ctx context.Context, id, ipAddr string) {
-	cacheData := rtkFileDrop.GetFilesTransferDataItem(id)
+func recoverFileTransferProcessAsDst(ctx context.Context, id, ipAddr string, timestamp uint64) {
+	cacheData := rtkFileDrop.PeekFilesTransferDataItem(id, timestamp)
 	if cacheData == nil {
 		return
 	}
 
 	if cacheData.InterruptSrcFileName == "" ||
-		cacheData.InterruptDstFileName == "" ||
+		(cacheData.InterruptDstFileName == "" && cacheData.InterruptFileOffSet != 0) || // no dst file name means start from beginning, see TakeJournalRecoverItem
 		cacheData.InterruptLastErrCode == rtkMisc.SUCCESS ||
 		cacheData.RecoverFileTransTimerCancel == nil {
 		log.Printf("[%s] ID:[%s] IP:[%s] Invalid Interrupt info!", rtkMisc.GetFuncInfo(), id, ipAddr)
@@ -853,13 +1057,13 @@ func recoverFileTransferProcessAsDst(ctx context.Context, id, ipAddr string) {
 	}
 
 	cacheData.RecoverFileTransTimerCancel()
-	dealFilesCacheDataProcess(ctx, id, ipAddr)
+	dealFilesCacheDataProcess(ctx, id, ipAddr, timestamp)
 }
 
-func recoverFileTransferProcessAsSrc(ctx context.Context, id, ipAddr string) {
+func recoverFileTransferProcessAsSrc(ctx context.Context, id, ipAddr string, timestamp uint64) {
 	errCode := buildFileDropItemStream(ctx, id)
 
-	cacheData := rtkFileDrop.GetFilesTransferDataItem(id)
+	cacheData := rtkFileDrop.PeekFilesTransferDataItem(id, timestamp)
 	if cacheData == nil {
 		errCode = rtkMisc.ERR_BIZ_FD_DATA_EMPTY
 	} else {
@@ -874,11 +1078,12 @@ func recoverFileTransferProcessAsSrc(ctx context.Context, id, ipAddr string) {
 		}
 	}
 
-	if sendFileTransRecoverResponseToDst(id, errCode) != rtkMisc.SUCCESS || errCode != rtkMisc.SUCCESS {
+	if sendFileTransRecoverResponseToDst(id, timestamp, errCode) != rtkMisc.SUCCESS || errCode != rtkMisc.SUCCESS {
+		rtkConnection.RemoveFileDropItemStreamListener(timestamp)
 		return
 	}
 	cacheData.RecoverFileTransTimerCancel()
-	dealFilesCacheDataProcess(ctx, id, ipAddr)
+	dealFilesCacheDataProcess(ctx, id, ipAddr, timestamp)
 }
 
 func buildFileDropItemStream(ctx context.Context, id string) rtkMisc.CrossShareErr {
@@ -901,13 +1106,7 @@ func buildFileDropItemStream(ctx context.Context, id string) rtkMisc.CrossShareE
 func clearFilesTransferCacheList(id, ipAddr string, code rtkMisc.CrossShareErr) {
 	rtkConnection.CloseAllFileDropStream(id) // close all file data transfer stream
 
-	i := 0
-	for rtkFileDrop.GetFilesTransferDataCacheCount(id) > 0 {
-		cacheData := rtkFileDrop.GetFilesTransferDataItem(id)
-		if cacheData == nil {
-			break
-		}
-
+	for i, cacheData := range rtkFileDrop.GetFilesTransferDataList(id) {
 		if cacheData.FileTransDirection == rtkFileDrop.FilesTransfer_As_Src {
 			log.Printf("(SRC) ID[%s] IP[%s] (cache) Copy file data To Socket failed, timestamp:%d, ERR code:[%d]!", id, ipAddr, cacheData.TimeStamp, code)
 			rtkConnection.RemoveFileDropItemStreamListener(cacheData.TimeStamp)
@@ -920,8 +1119,7 @@ func clearFilesTransferCacheList(id, ipAddr string, code rtkMisc.CrossShareErr)
 			log.Printf("[%s] ID:[%s] Invalid direction type:[%s]!", rtkMisc.GetFuncInfo(), id, cacheData.FileTransDirection)
 		}
 
-		i++
-		rtkPlatform.GoNotifyErrEvent(id, code, ipAddr, strconv.Itoa(int(cacheData.TimeStamp)), "", "")
-		rtkFileDrop.SetFilesCacheItemComplete(id, cacheData.TimeStamp)
+		rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, cacheData.TimeStamp, code)
+		rtkFileDrop.SetFilesCacheItemComplete(id, cacheData.TimeStamp, code)
 	}
 }
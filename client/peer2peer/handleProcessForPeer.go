@@ -3,6 +3,7 @@ package peer2peer
 import (
 	"context"
 	"log"
+	rtkClipboard "rtk-cross-share/client/clipboard"
 	rtkCommon "rtk-cross-share/client/common"
 	rtkConnection "rtk-cross-share/client/connection"
 	rtkFileDrop "rtk-cross-share/client/filedrop"
@@ -10,17 +11,18 @@ import (
 	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
-	"strconv"
 	"time"
 )
 
 func init() {
 	rtkFileDrop.SetSendFileTransferCancelMsgToPeerCallback(SendFileTransCancelByGuiMsgToPeer)
+	rtkClipboard.SetSendLazyXClipPullReqCallback(sendLazyXClipPullRequestToSrc)
 }
 
 func StartProcessForPeer(ctx context.Context, id, ipAddr string) func(source rtkCommon.CancelBusinessSource) {
 	sonCtx, cancel := rtkUtils.WithCancelSource(ctx)
 	rtkMisc.GoSafe(func() { ProcessEventsForPeer(sonCtx, id, ipAddr) })
+	rtkMisc.GoSafe(func() { recoverFileTransferFromJournal(id, ipAddr) })
 	log.Printf("[%s] ID:[%s] IP:[%s] ProcessEventsForPeer is Start !", rtkMisc.GetFuncInfo(), id, ipAddr)
 	return cancel
 }
@@ -33,12 +35,12 @@ func SendFileTransCancelByGuiMsgToPeer(id, ipAddr string, fileTransDataId uint64
 	if asSrc {
 		log.Printf("(SRC) [%s] IP:[%s] send cancel filesCachedata msg to dst, id:%d", rtkMisc.GetFuncInfo(), ipAddr, fileTransDataId)
 		sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_SRC_INTERRUPT, rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_GUI, fileTransDataId)
-		rtkPlatform.GoNotifyErrEvent(id, rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_GUI, ipAddr, strconv.Itoa(int(fileTransDataId)), "", "")
+		rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, fileTransDataId, rtkMisc.ERR_BIZ_FD_SRC_COPY_FILE_CANCEL_GUI)
 		rtkConnection.HandleFmtTypeStreamReady(id, rtkCommon.FILE_DROP)
 	} else {
 		log.Printf("(DST) [%s] IP:[%s] send cancel filesCachedata msg to src, id:%d", rtkMisc.GetFuncInfo(), ipAddr, fileTransDataId)
 		sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_DST_INTERRUPT, rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_GUI, fileTransDataId)
-		rtkPlatform.GoNotifyErrEvent(id, rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_GUI, ipAddr, strconv.Itoa(int(fileTransDataId)), "", "")
+		rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, fileTransDataId, rtkMisc.ERR_BIZ_FD_DST_COPY_FILE_CANCEL_GUI)
 	}
 	rtkConnection.CloseFileDropItemStream(id, fileTransDataId)
 }
@@ -46,13 +48,13 @@ func SendFileTransCancelByGuiMsgToPeer(id, ipAddr string, fileTransDataId uint64
 func SendFileTransOpenStreamErrToSrc(id, ipAddr string, timeStamp uint64) {
 	log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] open file drop Item stream error!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
 	sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_DST_INTERRUPT, rtkMisc.ERR_BIZ_FT_DST_OPEN_STREAM, timeStamp)
-	rtkPlatform.GoNotifyErrEvent(id, rtkMisc.ERR_BIZ_FT_DST_OPEN_STREAM, ipAddr, strconv.Itoa(int(timeStamp)), "", "")
+	rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timeStamp, rtkMisc.ERR_BIZ_FT_DST_OPEN_STREAM)
 }
 
 func SendFileTransCopyDetailsErrToSrc(id, ipAddr string, timeStamp uint64) {
 	log.Printf("(DST) [%s] IP:[%s] timestamp:[%d] Copy file data details error!", rtkMisc.GetFuncInfo(), ipAddr, timeStamp)
 	sendFileTransInterruptMsgToPeer(id, COMM_FILE_TRANSFER_DST_INTERRUPT, rtkMisc.ERR_BIZ_FT_DST_COPY_DETAILS, timeStamp)
-	rtkPlatform.GoNotifyErrEvent(id, rtkMisc.ERR_BIZ_FT_DST_COPY_DETAILS, ipAddr, strconv.Itoa(int(timeStamp)), "", "")
+	rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, timeStamp, rtkMisc.ERR_BIZ_FT_DST_COPY_DETAILS)
 	rtkConnection.CloseFileDropItemStream(id, timeStamp)
 }
 
@@ -82,10 +84,11 @@ func sendCmdMsgToPeer(id string, cmd CommandType, fmtType rtkCommon.TransFmtType
 	writeToSocket(&msg, id)
 }
 
-func sendFileTransRecoverRequestToSrc(id, srcFileName string, timestamp uint64, offset int64, errCode rtkMisc.CrossShareErr) rtkMisc.CrossShareErr {
+func sendFileTransRecoverRequestToSrc(id, srcFileName string, timestamp uint64, offset int64, doneRanges []rtkCommon.FileRange, errCode rtkMisc.CrossShareErr) rtkMisc.CrossShareErr {
 	extData := rtkCommon.ExtDataFilesTransferRecoverReq{
 		InterruptSrcFileName: srcFileName,
 		InterruptFileOffSet:  offset,
+		InterruptDoneRanges:  doneRanges,
 		TimeStamp:            timestamp,
 		InterruptErrCode:     errCode,
 	}
@@ -112,3 +115,42 @@ func sendFileTransRecoverResponseToDst(id string, timestamp uint64, errCode rtkM
 	}
 	return writeToSocket(&msg, id)
 }
+
+func isLazyXClipSupported(id string) bool {
+	return rtkClipboard.IsClipboardLazyMode() && rtkUtils.GetPeerClientIsSupportCapability(id, rtkCommon.CapabilityLazyXClip)
+}
+
+func sendLazyXClipPullRequestToSrc(id, hash string) rtkMisc.CrossShareErr {
+	var msg Peer2PeerMessage
+	msg.SourceID = rtkGlobal.NodeInfo.ID
+	msg.SourcePlatform = rtkGlobal.NodeInfo.Platform
+	msg.FmtType = rtkCommon.XCLIP_CB
+	msg.TimeStamp = uint64(time.Now().UnixMilli())
+	msg.Command = COMM_CB_LAZY_PULL_REQ
+	msg.ExtData = rtkCommon.ExtDataXClipLazyPullReq{
+		Hash: hash,
+	}
+	return writeToSocket(&msg, id)
+}
+
+func sendLazyXClipPullResponseToDst(id, hash string, cbData rtkCommon.ClipBoardData, errCode rtkMisc.CrossShareErr) rtkMisc.CrossShareErr {
+	extData, _ := cbData.ExtData.(rtkCommon.ExtDataXClip)
+	var msg Peer2PeerMessage
+	msg.SourceID = rtkGlobal.NodeInfo.ID
+	msg.SourcePlatform = rtkGlobal.NodeInfo.Platform
+	msg.FmtType = rtkCommon.XCLIP_CB
+	msg.TimeStamp = uint64(time.Now().UnixMilli())
+	msg.Command = COMM_CB_LAZY_PULL_RSP
+	msg.ExtData = rtkCommon.ExtDataXClipLazyPullRsp{
+		Hash:          hash,
+		ReqResultCode: errCode,
+		OriginID:      cbData.OriginID,
+		Clock:         cbData.Clock,
+		TextLen:       extData.TextLen,
+		ImageLen:      extData.ImageLen,
+		HtmlLen:       extData.HtmlLen,
+		RtfLen:        extData.RtfLen,
+		Items:         rtkClipboard.GetXClipItemHeads(extData.Items),
+	}
+	return writeToSocket(&msg, id)
+}
@@ -10,6 +10,7 @@ import (
 	rtkConnection "rtk-cross-share/client/connection"
 	rtkFileDrop "rtk-cross-share/client/filedrop"
 	rtkGlobal "rtk-cross-share/client/global"
+	rtkPlatform "rtk-cross-share/client/platform"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"time"
@@ -102,7 +103,6 @@ func HandleFileDropEvent(ctxMain context.Context, resultChan chan<- EventResult,
 							},
 							Data: "",
 						}
-						rtkFileDrop.ResetFileDropData(id)
 					} else {
 						log.Printf("[%s %d] Invalid fileDrop response data:%s with ID: %s", rtkMisc.GetFuncName(), rtkMisc.GetLine(), data.Cmd, id)
 					}
@@ -258,6 +258,22 @@ func processInbandRead(buffer []byte, len int, msg *Peer2PeerMessage) rtkMisc.Cr
 				return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
 			}
 			msg.ExtData = resultCode
+		} else if msg.Command == COMM_CB_LAZY_PULL_REQ {
+			var extData rtkCommon.ExtDataXClipLazyPullReq
+			err = json.Unmarshal(temp.ExtData, &extData)
+			if err != nil {
+				log.Printf("[%s] Err: decode ExtDataXClipLazyPullReq:%+v", rtkMisc.GetFuncInfo(), err)
+				return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
+			}
+			msg.ExtData = extData
+		} else if msg.Command == COMM_CB_LAZY_PULL_RSP {
+			var extData rtkCommon.ExtDataXClipLazyPullRsp
+			err = json.Unmarshal(temp.ExtData, &extData)
+			if err != nil {
+				log.Printf("[%s] Err: decode ExtDataXClipLazyPullRsp:%+v", rtkMisc.GetFuncInfo(), err)
+				return rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL
+			}
+			msg.ExtData = extData
 		} else {
 			var extDataXClip rtkCommon.ExtDataXClip
 			err = json.Unmarshal(temp.ExtData, &extDataXClip)
@@ -279,8 +295,7 @@ func HandleReadInbandFromSocket(ctxMain context.Context, resultChan chan<- Event
 			log.Printf("[%s][Socket] ID:[%s] Err: Read operation is done by context", rtkMisc.GetFuncInfo(), id)
 			return
 		default:
-			buffer := make([]byte, rtkGlobal.P2PMsgMaxLength) // 32KB
-			nLen, errCode := rtkConnection.ReadSocket(id, buffer)
+			buffer, errCode := rtkConnection.ReadSocketMsg(id)
 			if errCode == rtkMisc.ERR_BIZ_GET_STREAM_RESET {
 				continue
 			} else if errCode == rtkMisc.ERR_BIZ_GET_STREAM_EMPTY {
@@ -295,7 +310,8 @@ func HandleReadInbandFromSocket(ctxMain context.Context, resultChan chan<- Event
 			}
 
 			var msg Peer2PeerMessage
-			errCode = processInbandRead(buffer, nLen, &msg)
+			errCode = processInbandRead(*buffer, len(*buffer), &msg)
+			rtkConnection.PutMsgBuffer(buffer)
 			if errCode != rtkMisc.SUCCESS {
 				log.Printf("[%s] handle Read message error, errCode:%d, retrying...", rtkMisc.GetFuncInfo(), errCode)
 				continue
@@ -333,8 +349,8 @@ func HandleReadInbandFromSocket(ctxMain context.Context, resultChan chan<- Event
 				continue
 			} else if msg.Command == COMM_FILE_TRANSFER_RECOVER_REQ { // Src
 				if recoverInfo, ok := msg.ExtData.(rtkCommon.ExtDataFilesTransferRecoverReq); ok {
-					if rtkFileDrop.SetFilesTransferDataInterrupt(id, recoverInfo.InterruptSrcFileName, "", "", recoverInfo.TimeStamp, recoverInfo.InterruptFileOffSet, recoverInfo.InterruptErrCode) {
-						rtkMisc.GoSafe(func() { recoverFileTransferProcessAsSrc(ctxMain, id, ipAddr) })
+					if rtkFileDrop.SetFilesTransferDataInterrupt(id, recoverInfo.InterruptSrcFileName, "", "", recoverInfo.TimeStamp, recoverInfo.InterruptFileOffSet, recoverInfo.InterruptDoneRanges, recoverInfo.InterruptErrCode) {
+						rtkMisc.GoSafe(func() { recoverFileTransferProcessAsSrc(ctxMain, id, ipAddr, recoverInfo.TimeStamp) })
 					}
 				}
 				continue
@@ -348,6 +364,16 @@ func HandleReadInbandFromSocket(ctxMain context.Context, resultChan chan<- Event
 					}
 				}
 				continue
+			} else if msg.Command == COMM_CB_LAZY_PULL_REQ { // Src
+				if pullReq, ok := msg.ExtData.(rtkCommon.ExtDataXClipLazyPullReq); ok {
+					rtkMisc.GoSafe(func() { lazyXClipPullProcessAsSrc(id, ipAddr, pullReq.Hash) })
+				}
+				continue
+			} else if msg.Command == COMM_CB_LAZY_PULL_RSP { // Dst
+				if pullRsp, ok := msg.ExtData.(rtkCommon.ExtDataXClipLazyPullRsp); ok {
+					rtkMisc.GoSafe(func() { lazyXClipPullProcessAsDst(ctxMain, id, ipAddr, pullRsp) })
+				}
+				continue
 			} else if msg.Command == COMM_CB_TRANSFER_SRC_INTERRUPT {
 				log.Printf("[%s] (DST) Copy image operation was canceled by src !", rtkMisc.GetFuncInfo())
 				continue
@@ -423,9 +449,18 @@ func buildMessage(msg *Peer2PeerMessage, id string, event EventResult) bool {
 	msg.Command = event.Cmd.Command
 	msg.TimeStamp = uint64(time.Now().UnixMilli())
 
+	var lastCbData rtkCommon.ClipBoardData
+	if event.Cmd.Command == COMM_SRC && event.Cmd.FmtType != rtkCommon.FILE_DROP {
+		isAllowed := false
+		if lastCbData, isAllowed = rtkClipboard.GetLastClipboardDataForPeer(id); !isAllowed {
+			log.Printf("[%s] ID:[%s] clipboard data is rejected by clipboard policy", rtkMisc.GetFuncInfo(), id)
+			return false
+		}
+	}
+
 	if !rtkUtils.GetPeerClientIsSupportXClip(id) && event.Cmd.FmtType == rtkCommon.XCLIP_CB {
 		if event.Cmd.Command == COMM_SRC {
-			if extData, ok := rtkClipboard.GetLastClipboardData().ExtData.(rtkCommon.ExtDataXClip); ok {
+			if extData, ok := lastCbData.ExtData.(rtkCommon.ExtDataXClip); ok {
 				if extData.ImageLen > 0 {
 					msg.FmtType = rtkCommon.IMAGE_CB
 				} else if extData.TextLen > 0 {
@@ -442,7 +477,7 @@ func buildMessage(msg *Peer2PeerMessage, id string, event EventResult) bool {
 
 	switch msg.FmtType {
 	case rtkCommon.TEXT_CB:
-		if extData, ok := rtkClipboard.GetLastClipboardData().ExtData.(rtkCommon.ExtDataXClip); ok {
+		if extData, ok := lastCbData.ExtData.(rtkCommon.ExtDataXClip); ok {
 			msg.ExtData = rtkCommon.ExtDataText{
 				Text: string(extData.Text),
 			}
@@ -458,16 +493,15 @@ func buildMessage(msg *Peer2PeerMessage, id string, event EventResult) bool {
 			msg.Command = COMM_DST
 			return true
 		} else if event.Cmd.Command == COMM_SRC {
-			if extData, ok := rtkClipboard.GetLastClipboardData().ExtData.(rtkCommon.ExtDataXClip); ok {
-				_, width, height := rtkUtils.GetByteImageInfo(extData.Image)
+			if extData, ok := lastCbData.ExtData.(rtkCommon.ExtDataXClip); ok {
 				msg.ExtData = rtkCommon.ExtDataImg{
 					Size: rtkCommon.FileSize{
 						SizeHigh: uint32(0),
 						SizeLow:  uint32(extData.ImageLen),
 					},
 					Header: rtkCommon.ImgHeader{
-						Width:       int32(width),
-						Height:      int32(height),
+						Width:       extData.ImageWidth,
+						Height:      extData.ImageHeight,
 						Planes:      1,
 						BitCount:    32,
 						Compression: 0,
@@ -528,7 +562,7 @@ func buildMessage(msg *Peer2PeerMessage, id string, event EventResult) bool {
 			msg.Command = COMM_DST
 			return true
 		} else if event.Cmd.Command == COMM_SRC {
-			if extData, ok := rtkClipboard.GetLastClipboardData().ExtData.(rtkCommon.ExtDataXClip); ok {
+			if extData, ok := lastCbData.ExtData.(rtkCommon.ExtDataXClip); ok {
 				msg.ExtData = rtkCommon.ExtDataXClip{
 					Text:     nil,
 					Image:    nil,
@@ -538,6 +572,14 @@ func buildMessage(msg *Peer2PeerMessage, id string, event EventResult) bool {
 					ImageLen: extData.ImageLen,
 					HtmlLen:  extData.HtmlLen,
 					RtfLen:   extData.RtfLen,
+
+					ImageFormat: extData.ImageFormat,
+					ImageWidth:  extData.ImageWidth,
+					ImageHeight: extData.ImageHeight,
+					Hash:        lastCbData.Hash,
+					OriginID:    lastCbData.OriginID,
+					Clock:       lastCbData.Clock,
+					Items:       rtkClipboard.GetXClipItemHeads(extData.Items),
 				}
 				return true
 			} else {
@@ -563,9 +605,9 @@ func writeToSocket(msg *Peer2PeerMessage, id string) rtkMisc.CrossShareErr {
 	encodedData = bytes.Trim(encodedData, "\x00")
 	encodedData = bytes.Trim(encodedData, "\x13")
 
-	errCode := rtkConnection.WriteSocket(id, encodedData)
+	errCode := rtkConnection.WriteSocketMsg(id, encodedData)
 	if errCode == rtkMisc.ERR_BIZ_GET_STREAM_RESET {
-		return rtkConnection.WriteSocket(id, encodedData)
+		return rtkConnection.WriteSocketMsg(id, encodedData)
 	}
 	return errCode
 }
@@ -688,6 +730,8 @@ func processFileDrop(ctx context.Context, id, ipAddr string, event EventResult)
 					return false
 				}
 			}
+		} else if nextState == STATE_IO && nextCommand == COMM_DST && rtkFileDrop.IsFileDropRejected(id) {
+			defer rtkFileDrop.ResetFileDropData(id) // [Dst]: only send the reject response, reset the data after the msg is built
 		} else if nextState == STATE_IO && nextCommand == COMM_DST {
 			buildItemFileDropStream := func() (uint64, rtkMisc.CrossShareErr) { // [Dst]: every FileDropData need build a new stream
 				fileDropInfo, ok := rtkFileDrop.GetFileDropData(id)
@@ -727,7 +771,15 @@ func processFileDrop(ctx context.Context, id, ipAddr string, event EventResult)
 		}
 		var msg Peer2PeerMessage
 		if buildMessage(&msg, id, event) {
-			writeToSocket(&msg, id)
+			errCode := writeToSocket(&msg, id)
+			if errCode == rtkMisc.ERR_BIZ_P2P_MSG_OVER_RANGE && nextState == STATE_INFO && nextCommand == COMM_SRC {
+				// file list is too long for the peer without FramedMsg capability
+				if fileDropData, ok := rtkFileDrop.GetFileDropData(id); ok {
+					rtkPlatform.GoNotifyFileTransferErrEvent(id, ipAddr, fileDropData.TimeStamp, errCode)
+				}
+				rtkFileDrop.ResetFileDropData(id)
+				return false
+			}
 		} else {
 			log.Printf("[%s %d] Build message failed", rtkMisc.GetFuncName(), rtkMisc.GetLine())
 			return false
@@ -744,16 +796,29 @@ func processXClip(ctx context.Context, id, ipAddr string, event EventResult) boo
 		// [Src]: Start to trans XClip
 		rtkMisc.GoSafe(func() { processIoWrite(ctx, id, ipAddr, event.Cmd.FmtType, 0) })
 	} else {
+		if nextState == STATE_INFO && nextCommand == COMM_DST {
+			if extData, ok := event.Data.(rtkCommon.ExtDataXClip); ok && !rtkClipboard.ReceiveXClipVersion(id, extData.OriginID, extData.Clock) {
+				// [Dst]: drop the older XClip data and DO NOT send msg
+				return true
+			}
+		}
+
 		if nextState == STATE_INFO && nextCommand == COMM_DST && !rtkUtils.GetPeerClientIsSupportXClip(id) { // not support XClip
 			if extData, ok := event.Data.(rtkCommon.ExtDataXClip); ok {
 				if extData.TextLen > 0 {
-					rtkClipboard.SetupDstPasteXClipData(id, extData.Text, nil, nil, nil)
+					rtkClipboard.SetupDstPasteXClipData(id, "", 0, extData.Text, nil, nil, nil, nil)
 					return true
 				}
 			}
+		} else if nextState == STATE_INFO && nextCommand == COMM_DST && isLazyXClipSupported(id) {
+			if extData, ok := event.Data.(rtkCommon.ExtDataXClip); ok && extData.Hash != "" {
+				// [Dst]: Setup lazy XClip head and DO NOT send msg, the data is pulled when platform pastes it
+				rtkClipboard.SetupDstLazyXClipHead(id, extData)
+				return true
+			}
 		} else if nextState == STATE_TRANS && nextCommand == COMM_DST {
 			if extData, ok := event.Data.(rtkCommon.ExtDataXClip); ok {
-				rtkClipboard.SetupDstPasteXClipHead(id, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen)
+				rtkClipboard.SetupDstPasteXClipHead(id, extData.OriginID, extData.Clock, extData.TextLen, extData.ImageLen, extData.HtmlLen, extData.RtfLen, extData.Items)
 			} else {
 				log.Printf("[%s] Err: Setup past XClip failed", rtkMisc.GetFuncInfo())
 				return false
@@ -879,6 +944,7 @@ func ProcessEventsForPeer(ctx context.Context, id, ipAddr string) {
 			if rtkClipboard.GetLastClipboardData().SourceID == id {
 				rtkClipboard.ResetLastClipboardData()
 			}
+			rtkClipboard.ResetLazyXClipHead(id)
 			return
 		case event, ok := <-eventResultClipboard:
 			if !ok {
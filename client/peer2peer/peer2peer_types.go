@@ -39,6 +39,8 @@ const (
 	COMM_FILE_TRANSFER_DST_INTERRUPT CommandType = "COMM_FILE_TRANSFER_DST_INTERRUPT" // cancel by  dst
 	COMM_FILE_TRANSFER_RECOVER_REQ   CommandType = "COMM_FILE_TRANSFER_RECOVER_REQ"   //dst request src to recover file strans, It will automatically recover file data transfer
 	COMM_FILE_TRANSFER_RECOVER_RSP   CommandType = "COMM_FILE_TRANSFER_RECOVER_RSP"   //src response dst to recover file strans
+	COMM_CB_LAZY_PULL_REQ            CommandType = "COMM_CB_LAZY_PULL_REQ"            //dst request src to send the XClip data of lazy head
+	COMM_CB_LAZY_PULL_RSP            CommandType = "COMM_CB_LAZY_PULL_RSP"            //src response dst, then XClip data is sent if success
 )
 
 type DispatchCmd struct {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
 	rtkGlobal "rtk-cross-share/client/global"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
@@ -33,6 +34,11 @@ var (
 	hostID                   string
 	nodeID                   string
 	lockFile                 string
+	transferJournal          string
+	clipboardHistory         string
+	trustStore               string
+	lanServerKeyStore        string
+	transferHistory          string
 	lockFd                   *os.File
 	logFile                  string
 	crashLogFile             string
@@ -59,6 +65,8 @@ type Callback interface {
 	CallbackUpdateMonitorName(monitorName string)
 	CallbackRequestUpdateClientVersion(clienVersion string)
 	CallbackNotifyBrowseResult(monitorName, instance, ipAddr, version string, timestamp int64)
+	CallbackLazyXClipHead(id, head string)
+	CallbackPairingRequest(id, request string)
 }
 
 var CallbackInstance Callback = nil
@@ -72,6 +80,11 @@ func initFilePath() {
 	hostID = ".HostID"
 	nodeID = ".ID"
 	lockFile = "singleton.lock"
+	transferJournal = "transferJournal.json"
+	clipboardHistory = "clipboardHistory"
+	trustStore = "trustStore.json"
+	lanServerKeyStore = "lanServerKeyStore.json"
+	transferHistory = "transferHistory.db"
 	logFile = "p2p.log"
 	crashLogFile = "crash.log"
 	downloadPath = ""
@@ -106,6 +119,7 @@ type (
 	CallbackDragFileListRequestFunc    func([]rtkCommon.FileInfo, []string, uint64, uint64, string, string)
 	CallbackGetMacAddressFunc          func(string)
 	CallbackCancelFileTransFunc        func(string, string, uint64)
+	CallbackSetBandwidthLimitFunc      func(id string, bytesPerSec int64)
 	CallbackPluginEventFunc            func(isPlugin bool, productName string)
 	CallbackDisplayEventFunc           func(rtkCommon.DisplayEventInfo)
 	CallbackDIASSourceAndPortFunc      func(uint8, uint8)
@@ -129,6 +143,7 @@ var (
 	callbackDragFileListRequestCB      CallbackDragFileListRequestFunc    = nil
 	callbackGetMacAddressCB            CallbackGetMacAddressFunc          = nil
 	callbackCancelFileTransDragCB      CallbackCancelFileTransFunc        = nil
+	callbackSetBandwidthLimit          CallbackSetBandwidthLimitFunc      = nil
 	callbackPluginEventCB              CallbackPluginEventFunc            = nil
 	callbackDIASSourceAndPortCB        CallbackDIASSourceAndPortFunc      = nil
 	callbackAuthStatusCodeCB           CallbackAuthStatusCodeFunc         = nil
@@ -195,6 +210,10 @@ func SetGoCancelFileTransCallback(cb CallbackCancelFileTransFunc) {
 	callbackCancelFileTransDragCB = cb
 }
 
+func SetGoBandwidthLimitCallback(cb CallbackSetBandwidthLimitFunc) {
+	callbackSetBandwidthLimit = cb
+}
+
 func SetGetFilesTransCodeCallback(cb CallbackGetFilesTransCodeFunc) {
 	callbackGetFilesTransCode = cb
 }
@@ -265,6 +284,11 @@ func SetupRootPath(path string) {
 	hostID = getPath(settingsPath, hostID)
 	nodeID = getPath(settingsPath, nodeID)
 	lockFile = getPath(settingsPath, lockFile)
+	transferJournal = getPath(settingsPath, transferJournal)
+	clipboardHistory = getPath(settingsPath, clipboardHistory)
+	trustStore = getPath(settingsPath, trustStore)
+	lanServerKeyStore = getPath(settingsPath, lanServerKeyStore)
+	transferHistory = getPath(settingsPath, transferHistory)
 
 	logFile = getPath(logPath, logFile)
 	crashLogFile = getPath(logPath, crashLogFile)
@@ -324,17 +348,17 @@ func GoCopyXClipData(text, image, html, rtf string) {
 		}
 
 		format, width, height := rtkUtils.GetByteImageInfo(data)
-		jpegData, err := rtkUtils.ImageToJpeg(format, data)
+		clipData, err := rtkUtils.ImageToClipboardFormat(format, data)
 		if err != nil {
 			return
 		}
-		if len(jpegData) == 0 {
-			log.Printf("[CopyXClip] Error: jpeg data is empty")
+		if len(clipData) == 0 {
+			log.Printf("[CopyXClip] Error: image data is empty")
 			return
 		}
 
-		imgData = jpegData
-		log.Printf("image get jpg size:[%d](%d,%d),decode use:[%d]ms", len(imgData), width, height, time.Now().UnixMilli()-startTime)
+		imgData = clipData
+		log.Printf("image get %s size:[%d](%d,%d),decode use:[%d]ms", format, len(imgData), width, height, time.Now().UnixMilli()-startTime)
 	}
 
 	callbackCopyXClipCB([]byte(text), imgData, []byte(html), []byte(rtf))
@@ -437,21 +461,6 @@ func GoMultiFilesDropRequest(filesDataInfoJson string) rtkCommon.SendFilesReques
 		return rtkCommon.SendFilesRequestSizeOverRange
 	}*/
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength)
-
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] ID[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), fileDataInfo.Id, len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
-
 	callbackFileListDropRequestCB(fileDataInfo.Id, fileList, folderList, totalSize, uint64(fileDataInfo.TimeStamp), totalDesc, "")
 	return rtkCommon.SendFilesRequestSuccess
 }
@@ -523,19 +532,6 @@ func GoDragFileListRequest(dragFileInfoJson string) rtkCommon.SendFilesRequestEr
 		return rtkCommon.SendFilesRequestParameterErr
 	}
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength) //p2p null msg length
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
-
 	callbackDragFileListRequestCB(fileList, folderList, totalSize, timestamp, totalDesc, srcRootPath)
 
 	return callbackSendDragFileStart(&dragFileInfo.DragFileStartInfo)
@@ -562,6 +558,15 @@ func GoCancelFileTrans(ip, id string, timestamp int64) {
 	callbackCancelFileTransDragCB(id, ip, uint64(timestamp))
 }
 
+// GoSetBandwidthLimit limits the file transfer speed with the peer ID, empty ID is the global limit of all peers, bytesPerSec <= 0 means no limit
+func GoSetBandwidthLimit(id string, bytesPerSec int64) {
+	if callbackSetBandwidthLimit == nil {
+		log.Println("callbackSetBandwidthLimit is null!")
+		return
+	}
+	callbackSetBandwidthLimit(id, bytesPerSec)
+}
+
 func SetConfirmDocumentsAccept(ifConfirm bool) {
 	ifConfirmDocumentsAccept = ifConfirm
 }
@@ -627,114 +632,82 @@ func GoDragFileListFolderNotify(ip, id, folderName string, timestamp uint64) {
 	CallbackInstance.CallbackFileListDragFolderNotify(ip, id, folderName, int64(timestamp))
 }
 
-func GoUpdateClientStatusEx(id string, status uint8) {
-	if CallbackInstance == nil {
-		log.Println(" CallbackInstance is null !")
-		return
-	}
-
-	var clientInfo rtkCommon.ClientStatusInfo
-	if status == 1 { // online
-		info, err := rtkUtils.GetClientInfo(id)
-		if err != nil {
-			log.Printf("[%s] err:%+v", rtkMisc.GetFuncInfo(), err)
-			return
-		}
-		clientInfo.ClientInfo = info.ClientInfo
-	} else {
-		clientInfo.ID = id
-	}
-
-	clientInfo.TimeStamp = time.Now().UnixMilli()
-	clientInfo.Status = status
-
-	encodedData, err := json.Marshal(clientInfo)
-	if err != nil {
-		log.Println("Failed to Marshal ClientStatusInfo data, err:", err)
-		return
-	}
-
-	log.Printf("[%s] json Str:%s", rtkMisc.GetFuncInfo(), string(encodedData))
-	CallbackInstance.CallbackUpdateClientStatus(string(encodedData))
-}
-
-func GoSetupDstPasteXClipData(cbText, cbImage, cbHtml, cbRtf []byte) {
-	if CallbackInstance == nil {
-		log.Println("GoSetupDstPasteXClipData failed - callbackInstance is nil")
-		return
-	}
-	log.Printf("[%s] text:%d , image:%d, html:%d, rtf:%d \n\n", rtkMisc.GetFuncInfo(), len(cbText), len(cbImage), len(cbHtml), len(cbRtf))
-
-	imageStr := ""
-	if len(cbImage) > 0 {
-		imageBase64 := rtkUtils.Base64Encode(cbImage)
-		imageStr = imageBase64
-	}
-
-	CallbackInstance.CallbackPasteXClipData(string(cbText), imageStr, string(cbHtml), string(cbRtf))
-}
-
-func GoUpdateSendProgressBar(ip, id, currentFileName string, sendFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, sendSize, timestamp uint64) {
+func GoNotifyLazyXClipHead(id, head string) {
 	if CallbackInstance == nil {
-		log.Println("CallbackUpdateSendProgressBar CallbackInstance is null !")
+		log.Println("GoNotifyLazyXClipHead failed - callbackInstance is nil")
 		return
 	}
-	//log.Printf("GoUpdateSendProgressBar ip:[%s] [%s] currentFileName:[%s] sendSize:[%d] total:[%d] timestamp:[%d]", ip, deviceName, currentFileName, sendSize, totalFileSize, timestamp)
-	CallbackInstance.CallbackUpdateSendProgressBar(ip, id, currentFileName, int(sendFileCnt), int(totalFileCnt), int64(currentFileSize), int64(totalFileSize), int64(sendSize), int64(timestamp))
+	CallbackInstance.CallbackLazyXClipHead(id, head)
 }
 
-func GoUpdateReceiveProgressBar(ip, id, currentFileName string, recvFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, recvSize, timestamp uint64) {
+// GoNotifyPairingRequest returns false if the pairing request is not delivered to any handler
+func GoNotifyPairingRequest(id, request string) bool {
 	if CallbackInstance == nil {
-		log.Println("CallbackUpdateReceiveProgressBar CallbackInstance is null !")
-		return
+		log.Println("GoNotifyPairingRequest failed - callbackInstance is nil")
+		return false
 	}
-	//log.Printf("GoUpdateReceiveProgressBar ip:[%s] [%s] currentFileName:[%s] recvSize:[%d] total:[%d] timestamp:[%d]", ip, deviceName, currentFileName, sentSize, totalSize, timestamp)
-	CallbackInstance.CallbackUpdateReceiveProgressBar(ip, id, currentFileName, int(recvFileCnt), int(totalFileCnt), int64(currentFileSize), int64(totalFileSize), int64(recvSize), int64(timestamp))
+	CallbackInstance.CallbackPairingRequest(id, request)
+	return true
 }
 
 func GoUpdateSystemInfo(ip, serviceVer string) {
 
 }
 
-func GoNotiMessageFileTransfer(fileInfo, clientName, platform string, timestamp uint64, isSender bool) {
-	log.Printf("[%s]: fileInfo:[%s], clientName:%s, timestamp:%d ", rtkMisc.GetFuncInfo(), fileInfo, clientName, timestamp)
+func GoRequestUpdateClientVersion(ver string) {
 	if CallbackInstance == nil {
-		log.Println(" CallbackInstance is null !")
+		log.Println("GoRequestUpdateClientVersion CallbackInstance is null !")
 		return
 	}
 
-	var code NOTI_MSG_CODE
-	if isSender {
-		code = NOTI_MSG_CODE_FILE_TRANS_DONE_SENDER
-		CallbackInstance.CallbackSendFilesDone(fileInfo, platform, clientName, int64(timestamp))
-	} else {
-		code = NOTI_MSG_CODE_FILE_TRANS_DONE_RECEIVER
-	}
-
-	CallbackInstance.CallbackNotiMessage(fileInfo, platform, clientName, int(code), 0, int64(timestamp))
+	log.Printf("[%s] client version:%s \n\n", rtkMisc.GetFuncInfo(), ver)
+	CallbackInstance.CallbackRequestUpdateClientVersion(ver)
 }
 
-func GoNotifyErrEvent(id string, errCode rtkMisc.CrossShareErr, arg1, arg2, arg3, arg4 string) {
-	if CallbackInstance == nil {
-		log.Println("GoNotifyErrEvent CallbackInstance is null !")
-		return
-	}
-
-	log.Printf("[%s] id:[%s] errCode:%d arg1:%s, arg2:%s, arg3:%s, arg4:%s", rtkMisc.GetFuncInfo(), id, errCode, arg1, arg2, arg3, arg4)
-	CallbackInstance.CallbackNotifyErrEvent(id, int(errCode), arg1, arg2, arg3, arg4)
+func GoCleanClipboard() {
 }
 
-func GoRequestUpdateClientVersion(ver string) {
+// notifyPlatformEvent delivers rtkEvent to the CallbackInstance set by android UI
+func notifyPlatformEvent(ev rtkEvent.Event) {
 	if CallbackInstance == nil {
-		log.Println("GoRequestUpdateClientVersion CallbackInstance is null !")
+		log.Printf("[%s] event:[%s] CallbackInstance is null !", rtkMisc.GetFuncInfo(), ev.Type())
 		return
 	}
 
-	log.Printf("[%s] client version:%s \n\n", rtkMisc.GetFuncInfo(), ver)
-	CallbackInstance.CallbackRequestUpdateClientVersion(ver)
-}
-
-func GoCleanClipboard() {
+	switch e := ev.(type) {
+	case rtkEvent.TransferProgressEvent:
+		if e.IsSender {
+			CallbackInstance.CallbackUpdateSendProgressBar(e.IPAddr, e.ID, e.CurrentFileName, int(e.FileCnt), int(e.TotalFileCnt), int64(e.CurrentFileSize), int64(e.TotalSize), int64(e.TransSize), int64(e.TimeStamp))
+		} else {
+			CallbackInstance.CallbackUpdateReceiveProgressBar(e.IPAddr, e.ID, e.CurrentFileName, int(e.FileCnt), int(e.TotalFileCnt), int64(e.CurrentFileSize), int64(e.TotalSize), int64(e.TransSize), int64(e.TimeStamp))
+		}
+	case rtkEvent.TransferDoneEvent:
+		log.Printf("[%s]: fileInfo:[%s], clientName:%s, timestamp:%d ", rtkMisc.GetFuncInfo(), e.FileName, e.ClientName, e.TimeStamp)
+		var code NOTI_MSG_CODE
+		if e.IsSender {
+			code = NOTI_MSG_CODE_FILE_TRANS_DONE_SENDER
+			CallbackInstance.CallbackSendFilesDone(e.FileName, e.Platform, e.ClientName, int64(e.TimeStamp))
+		} else {
+			code = NOTI_MSG_CODE_FILE_TRANS_DONE_RECEIVER
+		}
+		CallbackInstance.CallbackNotiMessage(e.FileName, e.Platform, e.ClientName, int(code), 0, int64(e.TimeStamp))
+	case rtkEvent.TransferFailedEvent:
+		notifyPlatformEvent(getTransferFailedErrEvent(e))
+	case rtkEvent.ErrorEvent:
+		log.Printf("[%s] id:[%s] errCode:%d arg1:%s, arg2:%s, arg3:%s, arg4:%s", rtkMisc.GetFuncInfo(), e.ID, e.Code, e.Arg1, e.Arg2, e.Arg3, e.Arg4)
+		CallbackInstance.CallbackNotifyErrEvent(e.ID, int(e.Code), e.Arg1, e.Arg2, e.Arg3, e.Arg4)
+	case rtkEvent.PeerStatusEvent:
+		if clientInfo, ok := getClientStatusInfoJson(e); ok {
+			log.Printf("[%s] json Str:%s", rtkMisc.GetFuncInfo(), clientInfo)
+			CallbackInstance.CallbackUpdateClientStatus(clientInfo)
+		}
+	case rtkEvent.ClipboardReceivedEvent:
+		log.Printf("[%s] text:%d , image:%d, html:%d, rtf:%d \n\n", rtkMisc.GetFuncInfo(), len(e.Text), len(e.Image), len(e.Html), len(e.Rtf))
+		CallbackInstance.CallbackPasteXClipData(string(e.Text), getXClipImageBase64(e.Image), string(e.Html), string(e.Rtf))
+	case rtkEvent.LanServerStatusEvent:
+		log.Printf("[%s] diasStatus:%d", rtkMisc.GetFuncInfo(), e.Status)
+		CallbackInstance.CallbackUpdateDiasStatus(int(e.Status))
+	}
 }
 
 func GenKey() crypto.PrivKey {
@@ -771,6 +744,26 @@ func GetHostIDPath() string {
 	return hostID
 }
 
+func GetTransferJournalPath() string {
+	return transferJournal
+}
+
+func GetClipboardHistoryPath() string {
+	return clipboardHistory
+}
+
+func GetTrustStorePath() string {
+	return trustStore
+}
+
+func GetLanServerKeyStorePath() string {
+	return lanServerKeyStore
+}
+
+func GetTransferHistoryPath() string {
+	return transferHistory
+}
+
 func GetPlatform() string {
 	return rtkGlobal.NodeInfo.Platform
 }
@@ -834,15 +827,6 @@ func GoMonitorNameNotify(name string) {
 	CallbackInstance.CallbackUpdateMonitorName(name)
 }
 
-func GoDIASStatusNotify(diasStatus uint32) {
-	if CallbackInstance == nil {
-		log.Println("GoDIASStatusNotify - failed - callbackInstance is nil")
-		return
-	}
-	log.Printf("[%s] diasStatus:%d", rtkMisc.GetFuncInfo(), diasStatus)
-	CallbackInstance.CallbackUpdateDiasStatus(int(diasStatus))
-}
-
 func GoTriggerDetectPluginEvent(isPlugin bool) {
 }
 
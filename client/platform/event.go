@@ -0,0 +1,121 @@
+package platform
+
+import (
+	"encoding/json"
+	"log"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"strconv"
+	"time"
+)
+
+// The notifies from client business to UI are published to rtkEvent bus, and every platform delivers them
+// to its callbacks in notifyPlatformEvent, so Go code can subscribe the same events without cgo.
+func init() {
+	rtkEvent.Subscribe(rtkEvent.Filter{}, notifyPlatformEvent)
+}
+
+func GoUpdateSendProgressBar(ip, id, currentFileName string, sendFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, sendSize, timestamp uint64) {
+	rtkEvent.Publish(rtkEvent.TransferProgressEvent{
+		ID:              id,
+		IPAddr:          ip,
+		CurrentFileName: currentFileName,
+		FileCnt:         sendFileCnt,
+		TotalFileCnt:    totalFileCnt,
+		CurrentFileSize: currentFileSize,
+		TotalSize:       totalFileSize,
+		TransSize:       sendSize,
+		TimeStamp:       timestamp,
+		IsSender:        true,
+	})
+}
+
+func GoUpdateReceiveProgressBar(ip, id, currentFileName string, recvFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, recvSize, timestamp uint64) {
+	rtkEvent.Publish(rtkEvent.TransferProgressEvent{
+		ID:              id,
+		IPAddr:          ip,
+		CurrentFileName: currentFileName,
+		FileCnt:         recvFileCnt,
+		TotalFileCnt:    totalFileCnt,
+		CurrentFileSize: currentFileSize,
+		TotalSize:       totalFileSize,
+		TransSize:       recvSize,
+		TimeStamp:       timestamp,
+		IsSender:        false,
+	})
+}
+
+func GoNotiMessageFileTransfer(id, fileName, clientName, platform string, timestamp uint64, isSender bool) {
+	rtkEvent.Publish(rtkEvent.TransferDoneEvent{
+		ID:         id,
+		FileName:   fileName,
+		ClientName: clientName,
+		Platform:   platform,
+		TimeStamp:  timestamp,
+		IsSender:   isSender,
+	})
+}
+
+func GoNotifyErrEvent(id string, errCode rtkMisc.CrossShareErr, arg1, arg2, arg3, arg4 string) {
+	rtkEvent.Publish(rtkEvent.ErrorEvent{ID: id, Code: errCode, Arg1: arg1, Arg2: arg2, Arg3: arg3, Arg4: arg4})
+}
+
+// GoNotifyFileTransferErrEvent the failed file transfer is published once, and platform delivers it to its error callback
+func GoNotifyFileTransferErrEvent(id, ipAddr string, timestamp uint64, errCode rtkMisc.CrossShareErr) {
+	rtkEvent.Publish(rtkEvent.TransferFailedEvent{ID: id, IPAddr: ipAddr, TimeStamp: timestamp, Code: errCode})
+}
+
+// getTransferFailedErrEvent is the failed file transfer notified by the error callback of platform, with ipAddr and timestamp args
+func getTransferFailedErrEvent(e rtkEvent.TransferFailedEvent) rtkEvent.ErrorEvent {
+	return rtkEvent.ErrorEvent{ID: e.ID, Code: e.Code, Arg1: e.IPAddr, Arg2: strconv.FormatUint(e.TimeStamp, 10)}
+}
+
+func GoUpdateClientStatusEx(id string, status uint8) {
+	statusEvent := rtkEvent.PeerStatusEvent{IsOnline: status == 1, TimeStamp: time.Now().UnixMilli()}
+	if statusEvent.IsOnline {
+		info, err := rtkUtils.GetClientInfo(id)
+		if err != nil {
+			log.Printf("[%s] err:%+v", rtkMisc.GetFuncInfo(), err)
+			return
+		}
+		statusEvent.ClientInfo = info.ClientInfo
+	} else {
+		statusEvent.ID = id
+	}
+	rtkEvent.Publish(statusEvent)
+}
+
+func GoSetupDstPasteXClipData(id string, cbText, cbImage, cbHtml, cbRtf []byte) {
+	rtkEvent.Publish(rtkEvent.ClipboardReceivedEvent{ID: id, Text: cbText, Image: cbImage, Html: cbHtml, Rtf: cbRtf})
+}
+
+func GoDIASStatusNotify(diasStatus uint32) {
+	rtkEvent.Publish(rtkEvent.LanServerStatusEvent{Status: diasStatus})
+}
+
+func getClientStatusInfo(statusEvent rtkEvent.PeerStatusEvent) rtkCommon.ClientStatusInfo {
+	clientInfo := rtkCommon.ClientStatusInfo{TimeStamp: statusEvent.TimeStamp, ClientInfo: statusEvent.ClientInfo}
+	if statusEvent.IsOnline {
+		clientInfo.Status = 1
+	}
+	return clientInfo
+}
+
+// getClientStatusInfoJson is the clientInfo arg of UpdateClientStatusEx callback
+func getClientStatusInfoJson(statusEvent rtkEvent.PeerStatusEvent) (string, bool) {
+	encodedData, err := json.Marshal(getClientStatusInfo(statusEvent))
+	if err != nil {
+		log.Println("Failed to Marshal ClientStatusInfo data, err:", err)
+		return "", false
+	}
+	return string(encodedData), true
+}
+
+func getXClipImageBase64(cbImage []byte) string {
+	if len(cbImage) == 0 {
+		return ""
+	}
+	return rtkUtils.Base64Encode(cbImage)
+}
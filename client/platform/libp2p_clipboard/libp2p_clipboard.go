@@ -5,8 +5,14 @@ package libp2p_clipboard
 import (
 	"log"
 	rtkBuildConfig "rtk-cross-share/client/buildConfig"
+	rtkClipboard "rtk-cross-share/client/clipboard"
 	rtkCmd "rtk-cross-share/client/cmd"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkDiagnostics "rtk-cross-share/client/diagnostics"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkLogin "rtk-cross-share/client/login"
 	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"strings"
@@ -115,6 +121,140 @@ func CancelFileTrans(ip, id string, timestamp int64) {
 	rtkPlatform.GoCancelFileTrans(ip, id, timestamp)
 }
 
+func SetBandwidthLimit(id string, bytesPerSec int64) {
+	log.Printf("[%s] ID:[%s] bytesPerSec:[%d]", rtkMisc.GetFuncInfo(), id, bytesPerSec)
+	rtkPlatform.GoSetBandwidthLimit(id, bytesPerSec)
+}
+
+func GetClipboardHistoryList() string {
+	return rtkClipboard.GetClipboardHistoryList()
+}
+
+func GetClipboardHistoryData(index int64) string {
+	return rtkClipboard.GetClipboardHistoryData(uint64(index))
+}
+
+func ApplyClipboardHistory(index int64) bool {
+	return rtkClipboard.ApplyClipboardHistory(uint64(index))
+}
+
+func DeleteClipboardHistory(index int64) bool {
+	return rtkClipboard.DeleteClipboardHistory(uint64(index))
+}
+
+func ClearClipboardHistory() {
+	rtkClipboard.ClearClipboardHistory()
+}
+
+func SetClipboardHistoryMaxCount(count int) {
+	rtkClipboard.SetClipboardHistoryMaxCount(count)
+}
+
+func SetClipboardPolicy(policyJson string) bool {
+	return rtkClipboard.SetClipboardPolicy(policyJson)
+}
+
+func GetClipboardPolicy() string {
+	return rtkClipboard.GetClipboardPolicy()
+}
+
+// SetLogLevel levelSpec is like "info,peer2peer=debug"
+func SetLogLevel(levelSpec string) bool {
+	return rtkMisc.SetLogLevelSpec(levelSpec)
+}
+
+func GetLogLevels() string {
+	return rtkMisc.GetLogLevels()
+}
+
+func SetLogFormat(format string) bool {
+	return rtkMisc.SetLogFormat(format)
+}
+
+// CollectDiagnostics returns the path of diagnostics archive, empty if failed
+func CollectDiagnostics(outputDir string) string {
+	path, _ := rtkDiagnostics.Collect(outputDir)
+	return path
+}
+
+// GetTransferHistoryList returns JSON of transfer history, filterJson is empty or JSON of TransferHistoryFilter
+func GetTransferHistoryList(filterJson string) string {
+	return rtkTransferHistory.GetTransferHistoryList(filterJson)
+}
+
+func DeleteTransferHistory(index int64) bool {
+	return rtkTransferHistory.DeleteTransferHistory(index)
+}
+
+func ClearTransferHistory() {
+	rtkTransferHistory.ClearTransferHistory()
+}
+
+func SetTransferHistoryLimit(maxCount, maxDays int) {
+	rtkTransferHistory.SetTransferHistoryLimit(maxCount, maxDays)
+}
+
+// RevealTransferHistory returns the local path of transfer history, empty if it's not exist
+func RevealTransferHistory(index int64) string {
+	return rtkTransferHistory.RevealTransferHistory(index)
+}
+
+func MarkNextCopyConcealed() {
+	rtkClipboard.MarkNextCopyConcealed()
+}
+
+func SetClipboardImageFormats(formatsJson string) bool {
+	return rtkClipboard.SetClipboardImageFormats(formatsJson)
+}
+
+func SetClipboardImageBudget(maxPixels, maxBytes int64) {
+	rtkClipboard.SetClipboardImageBudget(maxPixels, maxBytes)
+}
+
+func GetLastClipboardImageInfo() string {
+	return rtkClipboard.GetLastClipboardImageInfo()
+}
+
+func SetClipboardLazyMode(enable bool) {
+	rtkClipboard.SetClipboardLazyMode(enable)
+}
+
+func GetLazyXClipHead() string {
+	return rtkClipboard.GetLazyXClipHead()
+}
+
+func RequestLazyXClipData() bool {
+	return rtkClipboard.RequestLazyXClipData()
+}
+
+func CopyXClipItems(itemsJson string) bool {
+	return rtkClipboard.CopyXClipItems(itemsJson)
+}
+
+func GetLastXClipItems() string {
+	return rtkClipboard.GetLastXClipItems()
+}
+
+func ConfirmPairing(id string, isAccepted bool) bool {
+	return rtkConnection.ConfirmPairing(id, isAccepted)
+}
+
+func GetTrustedPeerList() string {
+	return rtkConnection.GetTrustedPeerList()
+}
+
+func RenameTrustedPeer(id, name string) bool {
+	return rtkConnection.RenameTrustedPeer(id, name)
+}
+
+func RevokeTrustedPeer(id string) bool {
+	return rtkConnection.RevokeTrustedPeer(id)
+}
+
+func DeleteTrustedPeer(id string) bool {
+	return rtkConnection.DeleteTrustedPeer(id)
+}
+
 // Deprecated: unused
 func SetNetWorkConnected(isConnect bool) {
 	log.Printf("[%s] SetNetWorkConnected:[%v]", rtkMisc.GetFuncInfo(), isConnect)
@@ -140,6 +280,18 @@ func SetConfirmDocumentsAccept(ifConfirm bool) {
 	rtkPlatform.SetConfirmDocumentsAccept(ifConfirm)
 }
 
+func SetFileDropRule(ruleJson string) bool {
+	return rtkFileDrop.SetFileDropRule(ruleJson)
+}
+
+func GetFileDropRule() string {
+	return rtkFileDrop.GetFileDropRule()
+}
+
+func SetAllowPlaintextLanServer(allow bool) {
+	rtkLogin.SetAllowPlaintextLanServer(allow)
+}
+
 func GetVersion() string {
 	return rtkPlatform.GoGetClientVersion()
 }
@@ -0,0 +1,1001 @@
+//go:build linux && !android
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	rtkBuildConfig "rtk-cross-share/client/buildConfig"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkGlobal "rtk-cross-share/client/global"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+var (
+	privKeyFile              = ".priv.pem"
+	hostID                   = ".HostID"
+	nodeID                   = ".ID"
+	lockFile                 = "singleton.lock"
+	transferJournal          = "transferJournal.json"
+	clipboardHistory         = "clipboardHistory"
+	trustStore               = "trustStore.json"
+	lanServerKeyStore        = "lanServerKeyStore.json"
+	transferHistory          = "transferHistory.db"
+	lockFd                   *os.File
+	logFile                  = "p2p.log"
+	crashLogFile             = "crash.log"
+	downloadPath             = ""
+	ifConfirmDocumentsAccept bool
+
+	eventSink      io.Writer
+	eventSinkMutex sync.Mutex
+)
+
+// InitPlatform Linux has no UI process in front of the client, so every notify that other platforms
+// hand over to the UI is written to the event sink (or the log) and the clipboard is served by a ClipboardProvider
+func InitPlatform(rootPath, downLoadPath, deviceName string) {
+	downloadPath = downLoadPath
+	if deviceName == "" {
+		hostName, err := os.Hostname()
+		if err != nil || hostName == "" {
+			hostName = "UnknownDeviceName"
+		}
+		rtkGlobal.NodeInfo.DeviceName = hostName
+	} else {
+		rtkGlobal.NodeInfo.DeviceName = deviceName
+	}
+	rtkGlobal.NodeInfo.Platform = rtkMisc.PlatformLinux
+	rtkGlobal.ClientImageFormats = []string{rtkCommon.ImageFormatPng, rtkCommon.ImageFormatJpeg, rtkCommon.ImageFormatBmp}
+
+	getPath := func(dirPath, filePath string) string {
+		return filepath.Join(dirPath, filePath)
+	}
+
+	settingsDir := ".Settings"
+	logDir := "Log"
+	settingsPath := getPath(rootPath, settingsDir)
+	logPath := getPath(rootPath, logDir)
+
+	if !rtkMisc.FolderExists(settingsPath) {
+		rtkMisc.CreateDir(settingsPath)
+	}
+
+	if !rtkMisc.FolderExists(logPath) {
+		rtkMisc.CreateDir(logPath)
+	}
+
+	if !rtkMisc.FolderExists(downloadPath) {
+		rtkMisc.CreateDir(downloadPath)
+	}
+
+	privKeyFile = getPath(settingsPath, privKeyFile)
+	hostID = getPath(settingsPath, hostID)
+	nodeID = getPath(settingsPath, nodeID)
+	lockFile = getPath(settingsPath, lockFile)
+	transferJournal = getPath(settingsPath, transferJournal)
+	clipboardHistory = getPath(settingsPath, clipboardHistory)
+	trustStore = getPath(settingsPath, trustStore)
+	lanServerKeyStore = getPath(settingsPath, lanServerKeyStore)
+	transferHistory = getPath(settingsPath, transferHistory)
+
+	logFile = getPath(logPath, logFile)
+	crashLogFile = getPath(logPath, crashLogFile)
+
+	rtkMisc.InitLog(logFile, crashLogFile, 0)
+	if isEventSinkStdout() { // stdout is the JSON event stream, the log and the console probe would corrupt it
+		rtkMisc.SetupLogFile()
+	} else if n, fErr := fmt.Fprintln(os.Stdout, "CheckStatus"); fErr == nil && n == 12 {
+		rtkMisc.SetupLogConsoleFile()
+	} else {
+		rtkMisc.SetupLogFile()
+	}
+
+	if GetClipboardProvider() == nil {
+		SetClipboardProvider(NewMemoryClipboardProvider())
+	}
+	rtkMisc.GoSafe(func() { watchClipboardProvider() })
+
+	log.Printf("[%s] init rootPath:[%s] downLoadPath:[%s], deviceName:[%s] success!", rtkMisc.GetFuncInfo(), rootPath, downLoadPath, rtkGlobal.NodeInfo.DeviceName)
+}
+
+type (
+	CallbackNetworkSwitchFunc          func()
+	CallbackCopyXClipFunc              func(cbText, cbImage, cbHtml, cbRtf []byte)
+	CallbackPasteXClipFunc             func(text, image, html, rtf string)
+	CallbackLazyXClipHeadFunc          func(id, head string)
+	CallbackPairingRequestFunc         func(id, request string) bool // returns false if platform has no pairing handler
+	CallbackCleanClipboardFunc         func()
+	CallbackFileListDropRequestFunc    func(string, []rtkCommon.FileInfo, []string, uint64, uint64, string, string)
+	CallbackDragFileListRequestFunc    func([]rtkCommon.FileInfo, []string, uint64, uint64, string, string)
+	CallbackFileListNotifyFunc         func(ip, id string, fileCnt uint32, totalSize, timestamp uint64, firstFileName string, firstFileSize uint64, fileDetails string)
+	CallbackProgressBarFunc            func(ip, id, currentFileName string, sendFileCnt, totalFileCnt uint32, currentFileSize, totalSize, sendSize, timestamp uint64)
+	CallbackNotiMessageFileTransFunc   func(fileName, clientName, platform string, timestamp uint64, isSender bool)
+	CallbackFileDropResponseFunc       func(string, rtkCommon.FileDropCmd, string)
+	CallbackCancelFileTransFunc        func(string, string, uint64)
+	CallbackSetBandwidthLimitFunc      func(id string, bytesPerSec int64)
+	CallbackExtractDIASFunc            func()
+	CallbackGetMacAddressFunc          func(string)
+	CallbackDisplayEventFunc           func(rtkCommon.DisplayEventInfo)
+	CallbackAuthStatusCodeFunc         func(uint8)
+	CallbackDIASSourceAndPortFunc      func(uint8, uint8)
+	CallbackMethodBrowseMdnsResultFunc func(string, string, int, string, string, string, string)
+	CallbackAuthViaIndexCallbackFunc   func(uint32)
+	CallbackDIASStatusFunc             func(uint32)
+	CallbackReqSourceAndPortFunc       func()
+	CallbackUpdateSystemInfoFunc       func(ipAddr string, verInfo string)
+	CallbackUpdateClientStatusFunc     func(status uint32, ip, id, deviceName, deviceType string)
+	CallbackUpdateClientStatusExFunc   func(clientInfo string)
+	CallbackPluginEventFunc            func(isPlugin bool, productName string)
+	CallbackGetFilesTransCodeFunc      func(id string) rtkCommon.SendFilesRequestErrCode
+	CallbackGetFilesCacheSendCountFunc func(id string) int
+	CallbackReqClientUpdateVerFunc     func(clientVer string)
+	CallbackNotifyErrEventFunc         func(id string, errCode uint32, arg1, arg2, arg3, arg4 string)
+	CallbackConnectLanServerFunc       func(instance string)
+	CallbackBrowseLanServerFunc        func()
+	CallbackSetMsgEventFunc            func(event uint32, arg1, arg2, arg3, arg4 string)
+	CallbackSendDragFileStartFunc      func(*rtkMisc.DragFileStartInfo) rtkCommon.SendFilesRequestErrCode
+	CallbackGetShareFeatAvailableFunc  func() int
+)
+
+var (
+	// Go business Callback
+	callbackNetworkSwitchCB            CallbackNetworkSwitchFunc          = nil
+	callbackCopyXClipDataCB            CallbackCopyXClipFunc              = nil
+	callbackPasteXClipDataCB           CallbackPasteXClipFunc             = nil
+	callbackLazyXClipHead              CallbackLazyXClipHeadFunc          = nil
+	callbackPairingRequest             CallbackPairingRequestFunc         = nil
+	callbackFileListDropRequestCB      CallbackFileListDropRequestFunc    = nil
+	callbackDragFileListRequestCB      CallbackDragFileListRequestFunc    = nil
+	callbackFileListSendNotify         CallbackFileListNotifyFunc         = nil
+	callbackFileListReceiveNotify      CallbackFileListNotifyFunc         = nil
+	callbackSendProgressBar            CallbackProgressBarFunc            = nil
+	callbackReceiveProgressBar         CallbackProgressBarFunc            = nil
+	callbackNotiMessageFileTransCB     CallbackNotiMessageFileTransFunc   = nil
+	callbackInstanceFileDropResponseCB CallbackFileDropResponseFunc       = nil
+	callbackCancelFileTransDragCB      CallbackCancelFileTransFunc        = nil
+	callbackSetBandwidthLimit          CallbackSetBandwidthLimitFunc      = nil
+	callbackExtractDIASCB              CallbackExtractDIASFunc            = nil
+	callbackGetMacAddressCB            CallbackGetMacAddressFunc          = nil
+	callbackDisplayEvent               CallbackDisplayEventFunc           = nil
+	callbackAuthStatusCodeCB           CallbackAuthStatusCodeFunc         = nil
+	callbackDIASSourceAndPortCB        CallbackDIASSourceAndPortFunc      = nil
+	callbackMethodBrowseMdnsResult     CallbackMethodBrowseMdnsResultFunc = nil
+	callbackReqClientUpdateVer         CallbackReqClientUpdateVerFunc     = nil
+	callbackNotifyErrEvent             CallbackNotifyErrEventFunc         = nil
+	callbackSetMsgEvent                CallbackSetMsgEventFunc            = nil
+	callbackGetShareFeatAvailable      CallbackGetShareFeatAvailableFunc  = nil
+
+	// main.go Callback
+	callbackAuthViaIndex           CallbackAuthViaIndexCallbackFunc   = nil
+	callbackDIASStatus             CallbackDIASStatusFunc             = nil
+	callbackReqSourceAndPort       CallbackReqSourceAndPortFunc       = nil
+	callbackUpdateSystemInfo       CallbackUpdateSystemInfoFunc       = nil
+	callbackUpdateClientStatus     CallbackUpdateClientStatusFunc     = nil
+	callbackUpdateClientStatusEx   CallbackUpdateClientStatusExFunc   = nil
+	callbackCleanClipboard         CallbackCleanClipboardFunc         = nil
+	callbackGetFilesTransCode      CallbackGetFilesTransCodeFunc      = nil
+	callbackGetFilesCacheSendCount CallbackGetFilesCacheSendCountFunc = nil
+)
+
+/*======================================= Used  by GO set Callback =======================================*/
+
+func SetGoNetworkSwitchCallback(cb CallbackNetworkSwitchFunc) {
+	callbackNetworkSwitchCB = cb
+}
+
+func SetCopyXClipCallback(cb CallbackCopyXClipFunc) {
+	callbackCopyXClipDataCB = cb
+}
+
+func SetPasteXClipCallback(cb CallbackPasteXClipFunc) {
+	callbackPasteXClipDataCB = cb
+}
+
+func SetLazyXClipHeadCallback(cb CallbackLazyXClipHeadFunc) {
+	callbackLazyXClipHead = cb
+}
+
+func SetPairingRequestCallback(cb CallbackPairingRequestFunc) {
+	callbackPairingRequest = cb
+}
+
+func SetGoFileListDropRequestCallback(cb CallbackFileListDropRequestFunc) {
+	callbackFileListDropRequestCB = cb
+}
+
+func SetGoDragFileListRequestCallback(cb CallbackDragFileListRequestFunc) {
+	callbackDragFileListRequestCB = cb
+}
+
+func SetGoFileDropResponseCallback(cb CallbackFileDropResponseFunc) {
+	callbackInstanceFileDropResponseCB = cb
+}
+
+func SetGoCancelFileTransCallback(cb CallbackCancelFileTransFunc) {
+	callbackCancelFileTransDragCB = cb
+}
+
+func SetGoBandwidthLimitCallback(cb CallbackSetBandwidthLimitFunc) {
+	callbackSetBandwidthLimit = cb
+}
+
+func SetGoExtractDIASCallback(cb CallbackExtractDIASFunc) {
+	callbackExtractDIASCB = cb
+}
+
+func SetGoGetMacAddressCallback(cb CallbackGetMacAddressFunc) {
+	callbackGetMacAddressCB = cb
+}
+
+func SetGoGetDisplayEventCallback(cb CallbackDisplayEventFunc) {
+	callbackDisplayEvent = cb
+}
+
+func SetPluginEventCallback(cb CallbackPluginEventFunc) {
+}
+
+func SetGoAuthStatusCodeCallback(cb CallbackAuthStatusCodeFunc) {
+	callbackAuthStatusCodeCB = cb
+}
+
+func SetGoDIASSourceAndPortCallback(cb CallbackDIASSourceAndPortFunc) {
+	callbackDIASSourceAndPortCB = cb
+}
+
+func SetGoBrowseMdnsResultCallback(cb CallbackMethodBrowseMdnsResultFunc) {
+	callbackMethodBrowseMdnsResult = cb
+}
+
+func SetGetFilesTransCodeCallback(cb CallbackGetFilesTransCodeFunc) {
+	callbackGetFilesTransCode = cb
+}
+
+func SetGetFilesCacheSendCountCallback(cb CallbackGetFilesCacheSendCountFunc) {
+	callbackGetFilesCacheSendCount = cb
+}
+
+func SetGoConnectLanServerCallback(cb CallbackConnectLanServerFunc) {
+}
+
+func SetGoBrowseLanServerCallback(cb CallbackBrowseLanServerFunc) {
+}
+
+func SetGoSetMsgEventCallback(cb CallbackSetMsgEventFunc) {
+	callbackSetMsgEvent = cb
+}
+
+func SetGoSendDragFileStartCallback(cb CallbackSendDragFileStartFunc) {
+}
+
+func SetGoGetShareFeatAvailableCallback(cb CallbackGetShareFeatAvailableFunc) {
+	callbackGetShareFeatAvailable = cb
+}
+
+/*======================================= Used by main.go, set Callback =======================================*/
+
+func SetAuthViaIndexCallback(cb CallbackAuthViaIndexCallbackFunc) {
+	callbackAuthViaIndex = cb
+}
+
+func SetDIASStatusCallback(cb CallbackDIASStatusFunc) {
+	callbackDIASStatus = cb
+}
+
+func SetRequestSourceAndPortCallback(cb CallbackReqSourceAndPortFunc) {
+	callbackReqSourceAndPort = cb
+}
+
+func SetUpdateSystemInfoCallback(cb CallbackUpdateSystemInfoFunc) {
+	callbackUpdateSystemInfo = cb
+}
+
+func SetUpdateClientStatusExCallback(cb CallbackUpdateClientStatusExFunc) {
+	callbackUpdateClientStatusEx = cb
+}
+
+func SetUpdateClientStatusCallback(cb CallbackUpdateClientStatusFunc) {
+	callbackUpdateClientStatus = cb
+}
+
+func SetCleanClipboardCallback(cb CallbackCleanClipboardFunc) {
+	callbackCleanClipboard = cb
+}
+
+func SetSendProgressBarCallback(cb CallbackProgressBarFunc) {
+	callbackSendProgressBar = cb
+}
+
+func SetReceiveProgressBarCallback(cb CallbackProgressBarFunc) {
+	callbackReceiveProgressBar = cb
+}
+
+func SetFileListSendNotifyCallback(cb CallbackFileListNotifyFunc) {
+	callbackFileListSendNotify = cb
+}
+
+func SetFileListReceiveNotifyCallback(cb CallbackFileListNotifyFunc) {
+	callbackFileListReceiveNotify = cb
+}
+
+func SetNotiMessageFileTransCallback(cb CallbackNotiMessageFileTransFunc) {
+	callbackNotiMessageFileTransCB = cb
+}
+
+func SetReqClientUpdateVerCallback(cb CallbackReqClientUpdateVerFunc) {
+	callbackReqClientUpdateVer = cb
+}
+
+func SetNotifyErrEventCallback(cb CallbackNotifyErrEventFunc) {
+	callbackNotifyErrEvent = cb
+}
+
+/*======================================= Event sink =======================================*/
+
+// PlatformEvent is one JSON line written to the event sink
+type PlatformEvent struct {
+	Event     string      `json:"event"`
+	TimeStamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// SetEventSink all notifies are written to w as JSON lines, nil means only write them to the log.
+// It is set before InitPlatform, the log is not printed to the console if w is os.Stdout
+func SetEventSink(w io.Writer) {
+	eventSinkMutex.Lock()
+	defer eventSinkMutex.Unlock()
+	eventSink = w
+}
+
+func isEventSinkStdout() bool {
+	eventSinkMutex.Lock()
+	defer eventSinkMutex.Unlock()
+	return eventSink == os.Stdout
+}
+
+func notifyEvent(event string, data interface{}) {
+	eventSinkMutex.Lock()
+	defer eventSinkMutex.Unlock()
+
+	if eventSink == nil {
+		log.Printf("[Event] %s: %+v", event, data)
+		return
+	}
+
+	encodedData, err := json.Marshal(PlatformEvent{Event: event, TimeStamp: time.Now().UnixMilli(), Data: data})
+	if err != nil {
+		log.Printf("[%s] event:[%s] Marshal err:%+v", rtkMisc.GetFuncInfo(), event, err)
+		return
+	}
+	encodedData = append(encodedData, '\n')
+	if _, err = eventSink.Write(encodedData); err != nil {
+		log.Printf("[%s] event:[%s] write event sink err:%+v", rtkMisc.GetFuncInfo(), event, err)
+	}
+}
+
+/*======================================= Used by main.go =======================================*/
+func GoSetMsgEventFunc(event uint32, arg1, arg2, arg3, arg4 string) {
+	if callbackSetMsgEvent == nil {
+		log.Println("callbackSetMsgEvent is null!")
+		return
+	}
+	callbackSetMsgEvent(event, arg1, arg2, arg3, arg4)
+}
+
+func GoCopyXClipData(text, image, html, rtf string) {
+	imgData := []byte(nil)
+	if image != "" {
+		data := rtkUtils.Base64Decode(image)
+		if data == nil {
+			return
+		}
+
+		imgData = imageToClipboardFormat(data)
+		if imgData == nil {
+			return
+		}
+	}
+
+	writeClipboardProvider([]byte(text), imgData, []byte(html), []byte(rtf))
+	copyXClipData([]byte(text), imgData, []byte(html), []byte(rtf))
+}
+
+func copyXClipData(cbText, cbImage, cbHtml, cbRtf []byte) {
+	if callbackCopyXClipDataCB == nil {
+		log.Println("callbackCopyXClipDataCB is null!")
+		return
+	}
+
+	callbackCopyXClipDataCB(cbText, cbImage, cbHtml, cbRtf)
+}
+
+func imageToClipboardFormat(data []byte) []byte {
+	startTime := time.Now().UnixMilli()
+	format, width, height := rtkUtils.GetByteImageInfo(data)
+	clipData, err := rtkUtils.ImageToClipboardFormat(format, data)
+	if err != nil {
+		return nil
+	}
+	if len(clipData) == 0 {
+		log.Printf("[CopyXClip] Error: image data is empty")
+		return nil
+	}
+
+	log.Printf("image get %s size:[%d](%d,%d),decode use:[%d]ms", format, len(clipData), width, height, time.Now().UnixMilli()-startTime)
+	return clipData
+}
+
+func GoGetShareFeatAvailable() int {
+	if callbackGetShareFeatAvailable == nil {
+		log.Println("callbackGetShareFeatAvailable is null!")
+		return 0
+	}
+
+	return callbackGetShareFeatAvailable()
+}
+
+func GoSetAuthStatusCode(status uint8) {
+	if callbackAuthStatusCodeCB == nil {
+		log.Println("callbackAuthStatusCodeCB is null!")
+		return
+	}
+	callbackAuthStatusCodeCB(status)
+}
+
+func GoSetDIASSourceAndPort(src, port uint8) {
+	if callbackDIASSourceAndPortCB == nil {
+		log.Println("callbackDIASSourceAndPortCB is null!")
+		return
+	}
+	callbackDIASSourceAndPortCB(src, port)
+}
+
+func GoExtractDIASCallback() {
+	if callbackExtractDIASCB == nil {
+		log.Println("callbackExtractDIASCB is null!")
+		return
+	}
+	callbackExtractDIASCB()
+}
+
+func GoSetMacAddress(macAddr string) {
+	if callbackGetMacAddressCB == nil {
+		log.Println("callbackGetMacAddressCB is null!")
+		return
+	}
+	callbackGetMacAddressCB(macAddr)
+}
+
+func GoSetDisplayEvent(displayEventInfo *rtkCommon.DisplayEventInfo) {
+	if callbackDisplayEvent == nil {
+		log.Println("callbackDisplayEvent is null!")
+		return
+	}
+
+	callbackDisplayEvent(*displayEventInfo)
+}
+
+func GoMultiFilesDropRequest(id, ipAddr string, fileStrList *[]string, timeStamp uint64) rtkCommon.SendFilesRequestErrCode {
+	if callbackFileListDropRequestCB == nil {
+		log.Println("callbackFileListDropRequestCB is null!")
+		return rtkCommon.SendFilesRequestCallbackNotSet
+	}
+
+	if callbackGetFilesCacheSendCount == nil {
+		log.Println("callbackGetFilesCacheSendCount is null!")
+		return rtkCommon.SendFilesRequestCallbackNotSet
+	}
+
+	fileList := make([]rtkCommon.FileInfo, 0)
+	folderList := make([]string, 0)
+	totalSize := uint64(0)
+	nFileCnt := 0
+	nFolderCnt := 0
+	nPathSize := uint64(0)
+	srcRootPath := ""
+
+	for _, file := range *fileStrList {
+		if rtkMisc.FolderExists(file) {
+			nFileCnt = len(fileList)
+			nFolderCnt = len(folderList)
+			nPathSize = totalSize
+			srcRootPath = filepath.Dir(file)
+			rtkUtils.WalkPath(file, &folderList, &fileList, &totalSize)
+			log.Printf("[%s] walk a path:[%s], get [%d] files and [%d] folders , total size:[%d] ", rtkMisc.GetFuncInfo(), file, len(fileList)-nFileCnt, len(folderList)-nFolderCnt, totalSize-nPathSize)
+		} else if rtkMisc.FileExists(file) {
+			fileSize, err := rtkMisc.FileSize(file)
+			if err != nil {
+				log.Printf("[%s] get file:[%s] size error, skit it!", rtkMisc.GetFuncInfo(), file)
+				continue
+			}
+			fileList = append(fileList, rtkCommon.FileInfo{
+				FileSize_: rtkCommon.FileSize{
+					SizeHigh: uint32(fileSize >> 32),
+					SizeLow:  uint32(fileSize & 0xFFFFFFFF),
+				},
+				FilePath: file,
+				FileName: filepath.Base(file),
+			})
+			totalSize += fileSize
+			log.Printf("[%s] get a file:[%s], size:[%d] ", rtkMisc.GetFuncInfo(), file, fileSize)
+		} else {
+			log.Printf("[%s] get file or path:[%s] is invalid, skit it!", rtkMisc.GetFuncInfo(), file)
+		}
+	}
+
+	totalDesc := rtkMisc.FileSizeDesc(totalSize)
+
+	log.Printf("[%s] ID[%s] IP:[%s] get file count:[%d] folder count:[%d], totalSize:[%d] totalDesc:[%s] timestamp:[%d]", rtkMisc.GetFuncInfo(), id, ipAddr, len(fileList), len(folderList), totalSize, totalDesc, timeStamp)
+
+	if len(fileList) == 0 && len(folderList) == 0 {
+		log.Println("file content is null!")
+		return rtkCommon.SendFilesRequestParameterErr
+	}
+
+	if !rtkUtils.GetPeerClientIsSupportQueueTrans(id) {
+		if callbackGetFilesTransCode == nil {
+			log.Println("callbackGetFilesTransCode is null!")
+			return rtkCommon.SendFilesRequestCallbackNotSet
+		}
+
+		filesTransCode := callbackGetFilesTransCode(id)
+		if filesTransCode != rtkCommon.SendFilesRequestSuccess {
+			return filesTransCode
+		}
+	}
+
+	nCacheCount := callbackGetFilesCacheSendCount(id)
+	if nCacheCount >= rtkGlobal.SendFilesRequestMaxQueueSize {
+		log.Printf("[%s] ID[%s] this user file drop cache count:[%d] is too large and over range !", rtkMisc.GetFuncInfo(), id, nCacheCount)
+		return rtkCommon.SendFilesRequestCacheOverRange
+	}
+
+	if totalSize > uint64(rtkGlobal.SendFilesRequestMaxSize) {
+		log.Printf("[%s] ID[%s] this file drop total size:[%d] [%s] is too large and over range !", rtkMisc.GetFuncInfo(), id, totalSize, totalDesc)
+		return rtkCommon.SendFilesRequestSizeOverRange
+	}
+
+	callbackFileListDropRequestCB(id, fileList, folderList, totalSize, timeStamp, totalDesc, srcRootPath)
+	return rtkCommon.SendFilesRequestSuccess
+}
+
+func GoDragFileListRequest(fileStrList *[]string, timeStamp uint64) rtkCommon.SendFilesRequestErrCode {
+	if callbackDragFileListRequestCB == nil {
+		log.Println("callbackDragFileListRequestCB is null!")
+		return rtkCommon.SendFilesRequestCallbackNotSet
+	}
+
+	if !rtkGlobal.IsSupportFileDrag {
+		log.Printf("[%s] Mnt unsupport drag file, skit it!", rtkMisc.GetFuncInfo())
+		return rtkCommon.MntUnsupportDragFile
+	}
+
+	fileList := make([]rtkCommon.FileInfo, 0)
+	folderList := make([]string, 0)
+	totalSize := uint64(0)
+	nFileCnt := 0
+	nFolderCnt := 0
+	nPathSize := uint64(0)
+	srcRootPath := ""
+
+	for _, file := range *fileStrList {
+		if rtkMisc.FolderExists(file) {
+			nFileCnt = len(fileList)
+			nFolderCnt = len(folderList)
+			nPathSize = totalSize
+			srcRootPath = filepath.Dir(file)
+			rtkUtils.WalkPath(file, &folderList, &fileList, &totalSize)
+			log.Printf("[%s] walk a path:[%s], get [%d] files and [%d] folders, total size:[%d]", rtkMisc.GetFuncInfo(), file, len(fileList)-nFileCnt, len(folderList)-nFolderCnt, totalSize-nPathSize)
+		} else if rtkMisc.FileExists(file) {
+			fileSize, err := rtkMisc.FileSize(file)
+			if err != nil {
+				log.Printf("[%s] get file:[%s] size error, skit it!", rtkMisc.GetFuncInfo(), file)
+				continue
+			}
+			fileList = append(fileList, rtkCommon.FileInfo{
+				FileSize_: rtkCommon.FileSize{
+					SizeHigh: uint32(fileSize >> 32),
+					SizeLow:  uint32(fileSize & 0xFFFFFFFF),
+				},
+				FilePath: file,
+				FileName: filepath.Base(file),
+			})
+			totalSize += fileSize
+			log.Printf("[%s] get a file:[%s], size:[%d] ", rtkMisc.GetFuncInfo(), file, fileSize)
+		} else {
+			log.Printf("[%s] get file or path:[%s] is invalid, skit it!", rtkMisc.GetFuncInfo(), file)
+		}
+	}
+	totalDesc := rtkMisc.FileSizeDesc(totalSize)
+
+	log.Printf("[%s] get file count:[%d] folder count:[%d], totalSize:[%d] totalDesc:[%s] timestamp:[%d]", rtkMisc.GetFuncInfo(), len(fileList), len(folderList), totalSize, totalDesc, timeStamp)
+
+	if len(fileList) == 0 && len(folderList) == 0 {
+		log.Println("file content is null!")
+		return rtkCommon.SendFilesRequestParameterErr
+	}
+
+	if totalSize > uint64(rtkGlobal.SendFilesRequestMaxSize) {
+		log.Printf("[%s] this file drop total size:[%d] [%s] is too large and over range !", rtkMisc.GetFuncInfo(), totalSize, totalDesc)
+		return rtkCommon.SendFilesRequestSizeOverRange
+	}
+
+	callbackDragFileListRequestCB(fileList, folderList, totalSize, timeStamp, totalDesc, srcRootPath)
+	return rtkCommon.SendFilesRequestSuccess
+}
+
+// GoFileDropResponse accepts or rejects the received file drop which needs confirmation, filePath is the folder to save files
+func GoFileDropResponse(id string, fileCmd rtkCommon.FileDropCmd, filePath string) {
+	if callbackInstanceFileDropResponseCB == nil {
+		log.Println("callbackInstanceFileDropResponseCB is null!")
+		return
+	}
+	callbackInstanceFileDropResponseCB(id, fileCmd, filePath)
+}
+
+func GoCancelFileTrans(ip, id string, timestamp int64) {
+	if callbackCancelFileTransDragCB == nil {
+		log.Println("callbackCancelFileTransDragCB is null!")
+		return
+	}
+	callbackCancelFileTransDragCB(id, ip, uint64(timestamp))
+}
+
+// GoSetBandwidthLimit limits the file transfer speed with the peer ID, empty ID is the global limit of all peers, bytesPerSec <= 0 means no limit
+func GoSetBandwidthLimit(id string, bytesPerSec int64) {
+	if callbackSetBandwidthLimit == nil {
+		log.Println("callbackSetBandwidthLimit is null!")
+		return
+	}
+	callbackSetBandwidthLimit(id, bytesPerSec)
+}
+
+func GoUpdateDownloadPath(path string) {
+	downloadPath = path
+	log.Printf("[%s] update downloadPath:[%s] success!", rtkMisc.GetFuncInfo(), downloadPath)
+}
+
+func GoGetIsSupportFileDrag() bool {
+	return rtkGlobal.IsSupportFileDrag
+}
+
+func GetIsDebugMode() bool {
+	log.Printf("[%s] Debug:%s", rtkMisc.GetFuncInfo(), rtkBuildConfig.Debug)
+	if rtkBuildConfig.Debug == "1" {
+		return true
+	}
+	return false
+}
+
+/*======================================= Used by GO business =======================================*/
+
+func GetDownloadPath() string {
+	return downloadPath
+}
+
+func GetLogFilePath() string {
+	return logFile
+}
+
+func GetCrashLogFilePath() string {
+	return crashLogFile
+}
+
+func GoAuthViaIndex(clientIndex uint32) {
+	notifyEvent("AuthViaIndex", clientIndex)
+	if callbackAuthViaIndex == nil {
+		log.Printf("[%s] callbackAuthViaIndex is nil, GoAuthViaIndex failed!", rtkMisc.GetFuncInfo())
+		return
+	}
+	callbackAuthViaIndex(clientIndex)
+}
+
+func GoReqSourceAndPort() {
+	if callbackReqSourceAndPort == nil {
+		log.Printf("[%s] callbackReqSourceAndPort is nil, use the source and port from ini!", rtkMisc.GetFuncInfo())
+		srcPort := GoGetSrcAndPortFromIni()
+		GoSetDIASSourceAndPort(uint8(srcPort.Source), uint8(srcPort.Port))
+		return
+	}
+	callbackReqSourceAndPort()
+}
+
+func GoMonitorNameNotify(name string) {
+	notifyEvent("MonitorName", name)
+}
+
+func GetAuthData(clientIndex uint32) (rtkMisc.CrossShareErr, rtkMisc.AuthDataInfo) {
+	return rtkMisc.ERR_BIZ_GET_CALLBACK_INSTANCE_NULL, rtkMisc.AuthDataInfo{}
+}
+
+func GoSetupDstPasteFile(desc, fileName, platform string, fileSizeHigh uint32, fileSizeLow uint32) {
+
+}
+
+func GoSetupFileListDrop(ip, id, platform, totalDesc string, fileCount, folderCount uint32, timestamp uint64) {
+	log.Printf("[%s] fileCnt:[%d] folderCnt:[%d] totalDesc:[%s]", rtkMisc.GetFuncInfo(), fileCount, folderCount, totalDesc)
+}
+
+type fileListNotifyEvent struct {
+	Ip            string `json:"ip"`
+	Id            string `json:"id"`
+	FileCnt       uint32 `json:"fileCnt"`
+	TotalSize     uint64 `json:"totalSize"`
+	TimeStamp     uint64 `json:"timestamp"`
+	FirstFileName string `json:"firstFileName"`
+	FirstFileSize uint64 `json:"firstFileSize"`
+	FileDetails   string `json:"fileDetails"`
+}
+
+func GoFileListSendNotify(ip, id string, fileCnt uint32, totalSize, timestamp uint64, firstFileName string, firstFileSize uint64, fileDetails string) {
+	notifyEvent("FileListSend", fileListNotifyEvent{ip, id, fileCnt, totalSize, timestamp, firstFileName, firstFileSize, fileDetails})
+	if callbackFileListSendNotify == nil {
+		return
+	}
+	callbackFileListSendNotify(ip, id, fileCnt, totalSize, timestamp, firstFileName, firstFileSize, fileDetails)
+}
+
+func GoFileListReceiveNotify(ip, id string, fileCnt uint32, totalSize uint64, timestamp uint64, firstFileName string, firstFileSize uint64, fileDetails string) {
+	notifyEvent("FileListReceive", fileListNotifyEvent{ip, id, fileCnt, totalSize, timestamp, firstFileName, firstFileSize, fileDetails})
+	if callbackFileListReceiveNotify == nil {
+		return
+	}
+	callbackFileListReceiveNotify(ip, id, fileCnt, totalSize, timestamp, firstFileName, firstFileSize, fileDetails)
+}
+
+func GoDragFileListFolderNotify(ip, id, folderName string, timestamp uint64) {
+}
+
+func GoNotifyLazyXClipHead(id, head string) {
+	if callbackLazyXClipHead == nil {
+		log.Println("callbackLazyXClipHead is null!")
+		return
+	}
+	callbackLazyXClipHead(id, head)
+}
+
+// GoNotifyPairingRequest returns false if the pairing request is not delivered to any handler
+func GoNotifyPairingRequest(id, request string) bool {
+	notifyEvent("PairingRequest", json.RawMessage(request))
+	if callbackPairingRequest == nil {
+		log.Println("callbackPairingRequest is null!")
+		return false
+	}
+	return callbackPairingRequest(id, request)
+}
+
+type progressBarEvent struct {
+	Ip              string `json:"ip"`
+	Id              string `json:"id"`
+	CurrentFileName string `json:"currentFileName"`
+	FileCnt         uint32 `json:"fileCnt"`
+	TotalFileCnt    uint32 `json:"totalFileCnt"`
+	CurrentFileSize uint64 `json:"currentFileSize"`
+	TotalSize       uint64 `json:"totalSize"`
+	TransSize       uint64 `json:"transSize"`
+	TimeStamp       uint64 `json:"timestamp"`
+}
+
+func GoUpdateSystemInfo(ipAddr, serviceVer string) {
+	log.Printf("[%s] Ip:[%s]  version[%s]", rtkMisc.GetFuncInfo(), ipAddr, serviceVer)
+	notifyEvent("SystemInfo", map[string]string{"ipAddr": ipAddr, "version": serviceVer})
+	if callbackUpdateSystemInfo == nil {
+		return
+	}
+	callbackUpdateSystemInfo(ipAddr, serviceVer)
+}
+
+func GoUpdateClientStatus(status uint32, ip, id, name, deviceType string) {
+	if callbackUpdateClientStatus == nil {
+		return
+	}
+	callbackUpdateClientStatus(status, ip, id, name, deviceType)
+}
+
+func GoRequestUpdateClientVersion(ver string) {
+	notifyEvent("RequestUpdateClientVersion", ver)
+	if callbackReqClientUpdateVer == nil {
+		return
+	}
+
+	callbackReqClientUpdateVer(ver)
+}
+
+func GoCleanClipboard() {
+	cleanClipboardProvider()
+	if callbackCleanClipboard == nil {
+		return
+	}
+	callbackCleanClipboard()
+}
+
+// notifyPlatformEvent writes rtkEvent to the event sink, and delivers it to the callbacks set by the embedding application
+func notifyPlatformEvent(ev rtkEvent.Event) {
+	switch e := ev.(type) {
+	case rtkEvent.TransferRequestEvent:
+		notifyEvent("FileListDropRequest", map[string]interface{}{"id": e.ID, "ip": e.IPAddr, "platform": e.Platform, "totalDesc": e.TotalDesc, "fileCnt": e.FileCnt, "folderCnt": e.FolderCnt, "totalSize": e.TotalSize, "timestamp": e.TimeStamp})
+	case rtkEvent.TransferProgressEvent:
+		callbackProgressBar := callbackReceiveProgressBar
+		eventName := "ReceiveProgress"
+		if e.IsSender {
+			callbackProgressBar = callbackSendProgressBar
+			eventName = "SendProgress"
+		}
+		notifyEvent(eventName, progressBarEvent{e.IPAddr, e.ID, e.CurrentFileName, e.FileCnt, e.TotalFileCnt, e.CurrentFileSize, e.TotalSize, e.TransSize, e.TimeStamp})
+		if callbackProgressBar == nil {
+			return
+		}
+		callbackProgressBar(e.IPAddr, e.ID, e.CurrentFileName, e.FileCnt, e.TotalFileCnt, e.CurrentFileSize, e.TotalSize, e.TransSize, e.TimeStamp)
+	case rtkEvent.TransferDoneEvent:
+		notifyEvent("FileTransferDone", map[string]interface{}{"id": e.ID, "fileName": e.FileName, "clientName": e.ClientName, "platform": e.Platform, "timestamp": e.TimeStamp, "isSender": e.IsSender})
+		if callbackNotiMessageFileTransCB == nil {
+			return
+		}
+		callbackNotiMessageFileTransCB(e.FileName, e.ClientName, e.Platform, e.TimeStamp, e.IsSender)
+	case rtkEvent.TransferFailedEvent:
+		notifyEvent("FileTransferFailed", map[string]interface{}{"id": e.ID, "ipAddr": e.IPAddr, "timestamp": e.TimeStamp, "errCode": uint32(e.Code)})
+		if callbackNotifyErrEvent == nil {
+			return
+		}
+		errEvent := getTransferFailedErrEvent(e)
+		callbackNotifyErrEvent(errEvent.ID, uint32(errEvent.Code), errEvent.Arg1, errEvent.Arg2, errEvent.Arg3, errEvent.Arg4)
+	case rtkEvent.ErrorEvent:
+		notifyEvent("ErrEvent", map[string]interface{}{"id": e.ID, "errCode": uint32(e.Code), "args": []string{e.Arg1, e.Arg2, e.Arg3, e.Arg4}})
+		if callbackNotifyErrEvent == nil {
+			return
+		}
+		callbackNotifyErrEvent(e.ID, uint32(e.Code), e.Arg1, e.Arg2, e.Arg3, e.Arg4)
+	case rtkEvent.PeerStatusEvent:
+		notifyEvent("ClientStatus", getClientStatusInfo(e))
+		if callbackUpdateClientStatusEx == nil {
+			return
+		}
+		if clientInfo, ok := getClientStatusInfoJson(e); ok {
+			callbackUpdateClientStatusEx(clientInfo)
+		}
+	case rtkEvent.ClipboardReceivedEvent:
+		writeClipboardProvider(e.Text, e.Image, e.Html, e.Rtf)
+		notifyEvent("PasteXClip", map[string]interface{}{"id": e.ID, "text": len(e.Text), "image": len(e.Image), "html": len(e.Html), "rtf": len(e.Rtf)})
+		if callbackPasteXClipDataCB == nil {
+			return
+		}
+		callbackPasteXClipDataCB(string(e.Text), getXClipImageBase64(e.Image), string(e.Html), string(e.Rtf))
+	case rtkEvent.LanServerStatusEvent:
+		notifyEvent("DIASStatus", e.Status)
+		if callbackDIASStatus == nil {
+			return
+		}
+		callbackDIASStatus(e.Status)
+	}
+}
+
+func GenKey() crypto.PrivKey {
+	return rtkUtils.GenKey(privKeyFile)
+}
+
+func IsHost() bool {
+	return rtkMisc.FileExists(hostID)
+}
+
+func GetHostID() string {
+	file, err := os.Open(hostID)
+	if err != nil {
+		log.Println(err)
+		return rtkGlobal.HOST_ID
+	}
+	defer file.Close()
+
+	data := make([]byte, 1024)
+	_, err = file.Read(data)
+	if err != nil {
+		log.Println(err)
+		return rtkGlobal.HOST_ID
+	}
+
+	return string(data)
+}
+
+func GetIDPath() string {
+	return nodeID
+}
+
+func GetHostIDPath() string {
+	return hostID
+}
+
+func GetTransferJournalPath() string {
+	return transferJournal
+}
+
+func GetClipboardHistoryPath() string {
+	return clipboardHistory
+}
+
+func GetTrustStorePath() string {
+	return trustStore
+}
+
+func GetLanServerKeyStorePath() string {
+	return lanServerKeyStore
+}
+
+func GetTransferHistoryPath() string {
+	return transferHistory
+}
+
+func GetPlatform() string {
+	return rtkMisc.PlatformLinux
+}
+
+func LockFile() error {
+	var err error
+	lockFd, err = os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		log.Printf("Failed to open or create lock file:[%s] err:%+v", lockFile, err)
+		return err
+	}
+
+	err = syscall.Flock(int(lockFd.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		log.Printf("Failed to lock file[%s] err:%+v", lockFile, err) //err:  resource temporarily unavailable
+	}
+	return err
+}
+
+func UnlockFile() error {
+	err := syscall.Flock(int(lockFd.Fd()), syscall.LOCK_UN|syscall.LOCK_NB)
+	if err != nil {
+		log.Printf("Failed to unlock file[%s] err:%+v", lockFile, err)
+	}
+
+	lockFd.Close()
+	return err
+}
+
+func GoTriggerNetworkSwitch() {
+	if callbackNetworkSwitchCB == nil {
+		log.Println("callbackNetworkSwitchCB is null!")
+		return
+	}
+	callbackNetworkSwitchCB()
+}
+
+func GoTriggerDetectPluginEvent(isPlug bool) {
+}
+
+func GoGetSrcAndPortFromIni() rtkMisc.SourcePort {
+	return rtkUtils.GetDeviceSrcPort()
+}
+
+func SetConfirmDocumentsAccept(ifConfirm bool) {
+	ifConfirmDocumentsAccept = ifConfirm
+}
+
+func GetConfirmDocumentsAccept() bool {
+	return ifConfirmDocumentsAccept
+}
+
+func GoNotifyBrowseResult(monitorName, instance, ipAddr, version string, timestamp int64) {
+
+}
+
+// Specific Platform: iOS. Browse and lookup MDNS from iOS
+func GoStartBrowseMdns(instance, serviceType string) {
+}
+
+func GoStopBrowseMdns() {
+}
+
+func GoSetupAppLink(link string) {
+	rtkMisc.AppLink = link
+}
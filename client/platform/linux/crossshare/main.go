@@ -0,0 +1,735 @@
+//go:build linux && !android
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	rtkControlApi "rtk-cross-share/client/controlApi"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// crossshare is the command line tool of linux client daemon, all commands are done by the control API
+const usage = `Usage: crossshare [-socket path] [-json] <command> [args]
+
+Commands:
+  status                         show the client and LanServer status
+  peers                          list online peers
+  send [-nowait] <peer> <paths>  send files or folders to peer and show the progress
+  accept <peer> [folder]         accept the received files, default folder is the download path
+  reject <peer>                  reject the received files
+  cancel [-peer p] <transferId>  cancel the file transfer, transferId is its timestamp
+  transfers [-watch] [peer]      list the file transfers, -watch keeps showing their progress
+  clip push [text]               push text to clipboard and peers, read stdin if no text
+  clip pull                      print the text of clipboard
+  events [-types a,b] [peer]     print the events of client
+  diag [folder]                  collect the diagnostics archive, default folder is the log folder
+  history [flags] [keyword]      list the ended file transfers, -peer p -dir send|receive -failed -since 24h -limit n
+  history reveal|rm <index>      print the local path of the history, or remove the history
+  history clear                  remove all of history
+  pairing watch                  print the pairing requests of unknown peers with SAS to compare
+  pairing accept|reject <id>     confirm the pairing request, id is the exact ID in the request
+
+<peer> is the peer ID, its unique prefix or device name.
+`
+
+const progressBarWidth = 30
+
+var (
+	socketPath string
+	jsonOutput bool
+)
+
+func defaultSocketPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return rtkControlApi.SocketName
+	}
+	return filepath.Join(homeDir, ".crossShare", rtkControlApi.SocketName)
+}
+
+// newFlagSet the common flags are also accepted after the command
+func newFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ExitOnError)
+	flagSet.StringVar(&socketPath, "socket", socketPath, "unix socket of client control API")
+	flagSet.BoolVar(&jsonOutput, "json", jsonOutput, "print the result as JSON")
+	return flagSet
+}
+
+type command func(client *rtkControlApi.Client, args []string) error
+
+var commandMap = map[string]command{
+	"status":    cmdStatus,
+	"peers":     cmdPeers,
+	"send":      cmdSend,
+	"accept":    cmdAccept,
+	"reject":    cmdReject,
+	"cancel":    cmdCancel,
+	"transfers": cmdTransfers,
+	"clip":      cmdClip,
+	"events":    cmdEvents,
+	"diag":      cmdDiag,
+	"history":   cmdHistory,
+	"pairing":   cmdPairing,
+}
+
+func main() {
+	flag.StringVar(&socketPath, "socket", defaultSocketPath(), "unix socket of client control API")
+	flag.BoolVar(&jsonOutput, "json", false, "print the result as JSON")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commandMap[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "crossshare: unknown command [%s]\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := rtkControlApi.Dial(socketPath)
+	if err != nil {
+		fatal(fmt.Errorf("connect client daemon [%s] err: %w", socketPath, err))
+	}
+	defer client.Close()
+
+	if err = cmd(client, flag.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "crossshare: %v\n", err)
+	os.Exit(1)
+}
+
+func printJson(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func printJsonLine(v interface{}) error {
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
+
+func sizeDesc(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func progressBar(transSize, totalSize uint64) string {
+	percent := 100
+	if totalSize > 0 {
+		percent = int(transSize * 100 / totalSize)
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := percent * progressBarWidth / 100
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), percent)
+}
+
+func getPeerList(client *rtkControlApi.Client) ([]rtkControlApi.PeerInfo, error) {
+	var peerList []rtkControlApi.PeerInfo
+	err := client.Call(rtkControlApi.MethodPeerList, nil, &peerList)
+	return peerList, err
+}
+
+// resolvePeer matches the peer ID, then the unique ID prefix or device name
+func resolvePeer(client *rtkControlApi.Client, name string) (string, error) {
+	peerList, err := getPeerList(client)
+	if err != nil {
+		return "", err
+	}
+
+	matchList := make([]string, 0)
+	for _, peer := range peerList {
+		if peer.ID == name {
+			return peer.ID, nil
+		}
+		if strings.HasPrefix(peer.ID, name) || strings.EqualFold(peer.DeviceName, name) {
+			matchList = append(matchList, peer.ID)
+		}
+	}
+
+	switch len(matchList) {
+	case 0:
+		return "", fmt.Errorf("peer [%s] is not online", name)
+	case 1:
+		return matchList[0], nil
+	default:
+		return "", fmt.Errorf("peer [%s] is ambiguous: %s", name, strings.Join(matchList, ", "))
+	}
+}
+
+func cmdStatus(client *rtkControlApi.Client, args []string) error {
+	newFlagSet("status").Parse(args)
+
+	var status rtkControlApi.StatusInfo
+	if err := client.Call(rtkControlApi.MethodStatus, nil, &status); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(status)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", status.ID)
+	fmt.Fprintf(w, "Device:\t%s (%s)\n", status.DeviceName, status.Platform)
+	fmt.Fprintf(w, "Version:\t%s\n", status.Version)
+	fmt.Fprintf(w, "Download:\t%s\n", status.DownloadPath)
+	fmt.Fprintf(w, "LanServer:\t%s\n", status.LanServerStatusDesc)
+	fmt.Fprintf(w, "Peers:\t%d\n", status.PeerCount)
+	fmt.Fprintf(w, "Transfers:\t%d\n", status.TransferCount)
+	return w.Flush()
+}
+
+func cmdPeers(client *rtkControlApi.Client, args []string) error {
+	newFlagSet("peers").Parse(args)
+
+	peerList, err := getPeerList(client)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(peerList)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPLATFORM\tADDRESS\tVERSION")
+	for _, peer := range peerList {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", peer.ID, peer.DeviceName, peer.Platform, peer.IpAddr, peer.Version)
+	}
+	return w.Flush()
+}
+
+// the fields of client/event structs used by this tool
+type transferEvent struct {
+	ID              string
+	CurrentFileName string
+	FileCnt         uint32
+	TotalFileCnt    uint32
+	TotalSize       uint64
+	TransSize       uint64
+	TimeStamp       uint64
+	IsSender        bool
+	FileName        string
+	TotalDesc       string
+	Code            uint32
+}
+
+const (
+	eventTransferRequest  = "TransferRequest"
+	eventTransferProgress = "TransferProgress"
+	eventTransferDone     = "TransferDone"
+	eventTransferFailed   = "TransferFailed"
+	eventPairingRequest   = "PairingRequest"
+)
+
+func subscribe(client *rtkControlApi.Client, id string, types ...string) error {
+	return client.Call(rtkControlApi.MethodEventSubscribe, rtkControlApi.EventSubscribeParams{Types: types, ID: id}, nil)
+}
+
+// nextEvent returns io.EOF if the connection is closed
+func nextEvent(client *rtkControlApi.Client) (rtkControlApi.EventParams, error) {
+	for notification := range client.Notifications() {
+		if notification.Method != rtkControlApi.MethodEventNotification {
+			continue
+		}
+		var params rtkControlApi.EventParams
+		if err := json.Unmarshal(notification.Params, &params); err != nil {
+			return params, err
+		}
+		return params, nil
+	}
+	return rtkControlApi.EventParams{}, io.EOF
+}
+
+func cmdSend(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("send")
+	noWait := flagSet.Bool("nowait", false, "return after the request is sent")
+	flagSet.Parse(args)
+	if flagSet.NArg() < 2 {
+		return errors.New("usage: send [-nowait] <peer> <paths...>")
+	}
+
+	id, err := resolvePeer(client, flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, flagSet.NArg()-1)
+	for _, path := range flagSet.Args()[1:] {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, absPath)
+	}
+
+	if !*noWait {
+		if err = subscribe(client, id, eventTransferProgress, eventTransferDone, eventTransferFailed); err != nil {
+			return err
+		}
+	}
+
+	var result rtkControlApi.TransferResult
+	if err = client.Call(rtkControlApi.MethodTransferSend, rtkControlApi.TransferSendParams{ID: id, Paths: paths}, &result); err != nil {
+		return err
+	}
+	if jsonOutput {
+		printJsonLine(result)
+	} else {
+		fmt.Printf("transfer %d is sent to %s\n", result.TimeStamp, id)
+	}
+	if *noWait {
+		return nil
+	}
+
+	for {
+		params, err := nextEvent(client)
+		if err != nil {
+			return err
+		}
+		var ev transferEvent
+		if err = json.Unmarshal(params.Event, &ev); err != nil || ev.TimeStamp != result.TimeStamp {
+			continue
+		}
+		if jsonOutput {
+			printJsonLine(params)
+		}
+
+		switch params.Type {
+		case eventTransferProgress:
+			if !jsonOutput {
+				fmt.Printf("\r%s %s/%s (%d/%d) %s\033[K", progressBar(ev.TransSize, ev.TotalSize), sizeDesc(ev.TransSize), sizeDesc(ev.TotalSize), ev.FileCnt, ev.TotalFileCnt, ev.CurrentFileName)
+			}
+		case eventTransferDone:
+			if !jsonOutput {
+				fmt.Printf("\ntransfer %d is done: %s\n", result.TimeStamp, ev.FileName)
+			}
+			return nil
+		case eventTransferFailed:
+			if !jsonOutput {
+				fmt.Println()
+			}
+			return fmt.Errorf("transfer %d is failed, code:%d", result.TimeStamp, ev.Code)
+		}
+	}
+}
+
+func cmdAccept(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("accept")
+	flagSet.Parse(args)
+	if flagSet.NArg() < 1 {
+		return errors.New("usage: accept <peer> [folder]")
+	}
+
+	id, err := resolvePeer(client, flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	params := rtkControlApi.TransferAcceptParams{ID: id}
+	if flagSet.NArg() > 1 {
+		if params.Path, err = filepath.Abs(flagSet.Arg(1)); err != nil {
+			return err
+		}
+	}
+
+	var result rtkControlApi.TransferResult
+	if err = client.Call(rtkControlApi.MethodTransferAccept, params, &result); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(result)
+	}
+	fmt.Printf("transfer %d is accepted\n", result.TimeStamp)
+	return nil
+}
+
+func cmdReject(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("reject")
+	flagSet.Parse(args)
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: reject <peer>")
+	}
+
+	id, err := resolvePeer(client, flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	var result rtkControlApi.TransferResult
+	if err = client.Call(rtkControlApi.MethodTransferReject, rtkControlApi.PeerParams{ID: id}, &result); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(result)
+	}
+	fmt.Printf("transfer %d is rejected\n", result.TimeStamp)
+	return nil
+}
+
+func cmdCancel(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("cancel")
+	peer := flagSet.String("peer", "", "peer of the transfer, default is found by transferId")
+	flagSet.Parse(args)
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: cancel [-peer p] <transferId>")
+	}
+	timestamp, err := strconv.ParseUint(flagSet.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("transferId [%s] is invalid", flagSet.Arg(0))
+	}
+
+	id := ""
+	if *peer != "" {
+		if id, err = resolvePeer(client, *peer); err != nil {
+			return err
+		}
+	} else {
+		var stateList []rtkControlApi.TransferState
+		if err = client.Call(rtkControlApi.MethodTransferList, nil, &stateList); err != nil {
+			return err
+		}
+		for _, state := range stateList {
+			if state.TimeStamp == timestamp {
+				id = state.ID
+				break
+			}
+		}
+		if id == "" {
+			return fmt.Errorf("transfer %d is not found", timestamp)
+		}
+	}
+
+	params := rtkControlApi.TransferCancelParams{ID: id, TimeStamp: timestamp}
+	if err = client.Call(rtkControlApi.MethodTransferCancel, params, nil); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(params)
+	}
+	fmt.Printf("transfer %d is canceled\n", timestamp)
+	return nil
+}
+
+func printTransferList(stateList []rtkControlApi.TransferState) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRANSFER\tPEER\tDIRECTION\tSTATE\tFILES\tSIZE\tPROGRESS")
+	for _, state := range stateList {
+		progress := ""
+		if state.Progress != nil {
+			progress = progressBar(state.Progress.TransSize, state.Progress.TotalSize)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", state.TimeStamp, state.ID, state.Direction, state.State, state.FileCnt, sizeDesc(state.TotalSize), progress)
+	}
+	return w.Flush()
+}
+
+func cmdTransfers(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("transfers")
+	watch := flagSet.Bool("watch", false, "keep showing the progress of transfers")
+	flagSet.Parse(args)
+
+	id := ""
+	if flagSet.NArg() > 0 {
+		var err error
+		if id, err = resolvePeer(client, flagSet.Arg(0)); err != nil {
+			return err
+		}
+	}
+
+	if *watch {
+		if err := subscribe(client, id, eventTransferRequest, eventTransferProgress, eventTransferDone, eventTransferFailed); err != nil {
+			return err
+		}
+	}
+
+	var stateList []rtkControlApi.TransferState
+	if err := client.Call(rtkControlApi.MethodTransferList, rtkControlApi.PeerParams{ID: id}, &stateList); err != nil {
+		return err
+	}
+	if jsonOutput {
+		if *watch {
+			printJsonLine(stateList)
+		} else {
+			return printJson(stateList)
+		}
+	} else if err := printTransferList(stateList); err != nil {
+		return err
+	}
+	if !*watch {
+		return nil
+	}
+
+	lastProgress := uint64(0) // the transfer of progress line which is not ended
+	for {
+		params, err := nextEvent(client)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			printJsonLine(params)
+			continue
+		}
+
+		var ev transferEvent
+		if err = json.Unmarshal(params.Event, &ev); err != nil {
+			continue
+		}
+		if lastProgress != 0 && (params.Type != eventTransferProgress || lastProgress != ev.TimeStamp) {
+			fmt.Println()
+			lastProgress = 0
+		}
+
+		switch params.Type {
+		case eventTransferRequest:
+			fmt.Printf("%d %s request: %s, accept or reject it\n", ev.TimeStamp, ev.ID, ev.TotalDesc)
+		case eventTransferProgress:
+			direction := rtkControlApi.TransferDirectionReceive
+			if ev.IsSender {
+				direction = rtkControlApi.TransferDirectionSend
+			}
+			fmt.Printf("\r%d %s %s %s %s/%s (%d/%d) %s\033[K", ev.TimeStamp, ev.ID, direction, progressBar(ev.TransSize, ev.TotalSize),
+				sizeDesc(ev.TransSize), sizeDesc(ev.TotalSize), ev.FileCnt, ev.TotalFileCnt, ev.CurrentFileName)
+			lastProgress = ev.TimeStamp
+		case eventTransferDone:
+			fmt.Printf("%d %s done: %s\n", ev.TimeStamp, ev.ID, ev.FileName)
+		case eventTransferFailed:
+			fmt.Printf("%d %s failed, code:%d\n", ev.TimeStamp, ev.ID, ev.Code)
+		}
+	}
+}
+
+func cmdClip(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("clip")
+	flagSet.Parse(args)
+
+	switch flagSet.Arg(0) {
+	case "push":
+		text := strings.Join(flagSet.Args()[1:], " ")
+		if flagSet.NArg() == 1 {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+		return client.Call(rtkControlApi.MethodClipboardSetText, rtkControlApi.ClipboardTextParams{Text: text}, nil)
+	case "pull":
+		var result rtkControlApi.ClipboardTextParams
+		if err := client.Call(rtkControlApi.MethodClipboardGetText, nil, &result); err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJson(result)
+		}
+		fmt.Print(result.Text)
+		return nil
+	default:
+		return errors.New("usage: clip push [text] | clip pull")
+	}
+}
+
+func cmdEvents(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("events")
+	types := flagSet.String("types", "", "comma separated event types, default is all events")
+	flagSet.Parse(args)
+
+	id := ""
+	if flagSet.NArg() > 0 {
+		var err error
+		if id, err = resolvePeer(client, flagSet.Arg(0)); err != nil {
+			return err
+		}
+	}
+	typeList := make([]string, 0)
+	if *types != "" {
+		typeList = strings.Split(*types, ",")
+	}
+	if err := subscribe(client, id, typeList...); err != nil {
+		return err
+	}
+
+	for {
+		params, err := nextEvent(client)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			printJsonLine(params)
+		} else {
+			fmt.Printf("%s %s\n", params.Type, string(params.Event))
+		}
+	}
+}
+
+func cmdDiag(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("diag")
+	flagSet.Parse(args)
+
+	var params rtkControlApi.DiagnosticsParams
+	if flagSet.NArg() > 0 {
+		var err error
+		if params.Path, err = filepath.Abs(flagSet.Arg(0)); err != nil {
+			return err
+		}
+	}
+	var result rtkControlApi.DiagnosticsParams
+	if err := client.Call(rtkControlApi.MethodDiagCollect, params, &result); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(result)
+	}
+	fmt.Println(result.Path)
+	return nil
+}
+
+func cmdHistory(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("history")
+	peer := flagSet.String("peer", "", "only the history of peer")
+	direction := flagSet.String("dir", "", "only the direction send or receive")
+	failed := flagSet.Bool("failed", false, "only the failed transfers")
+	since := flagSet.Duration("since", 0, "only the transfers ended in the duration, e.g. 24h")
+	limit := flagSet.Int("limit", 0, "the max count of history, default is 100")
+	flagSet.Parse(args)
+
+	switch flagSet.Arg(0) {
+	case "reveal", "rm":
+		index, err := strconv.ParseInt(flagSet.Arg(1), 10, 64)
+		if err != nil || flagSet.NArg() != 2 {
+			return errors.New("usage: history reveal|rm <index>")
+		}
+		if flagSet.Arg(0) == "rm" {
+			return client.Call(rtkControlApi.MethodHistoryDelete, rtkControlApi.HistoryIndexParams{Index: index}, nil)
+		}
+		var result rtkControlApi.HistoryRevealResult
+		if err = client.Call(rtkControlApi.MethodHistoryReveal, rtkControlApi.HistoryIndexParams{Index: index}, &result); err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJson(result)
+		}
+		fmt.Println(result.Path)
+		return nil
+	case "clear":
+		return client.Call(rtkControlApi.MethodHistoryClear, nil, nil)
+	}
+
+	params := rtkControlApi.HistoryListParams{
+		Direction: *direction,
+		Keyword:   strings.Join(flagSet.Args(), " "),
+		Limit:     *limit,
+	}
+	if *peer != "" {
+		var err error
+		if params.ID, err = resolvePeer(client, *peer); err != nil {
+			return err
+		}
+	}
+	if *failed {
+		params.Result = rtkControlApi.HistoryResultFailed
+	}
+	if *since > 0 {
+		params.StartTime = time.Now().Add(-*since).UnixMilli()
+	}
+
+	var historyList []rtkControlApi.HistoryItem
+	if err := client.Call(rtkControlApi.MethodHistoryList, params, &historyList); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJson(historyList)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tEND TIME\tPEER\tDIRECTION\tFILES\tSIZE\tSPEED\tRESULT\tPATH")
+	for _, item := range historyList {
+		result := "ok"
+		if !item.Success {
+			result = strconv.Itoa(item.ResultCode)
+		}
+		name := item.ID
+		if item.DeviceName != "" {
+			name = item.DeviceName
+		}
+		files := item.TotalDesc
+		if len(item.FileNames) > 0 {
+			files = item.FileNames[0]
+			if item.FileCnt > 1 {
+				files = fmt.Sprintf("%s (+%d)", files, item.FileCnt-1)
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s/s\t%s\t%s\n", item.Index, time.UnixMilli(item.EndTime).Format(time.DateTime), name, item.Direction,
+			files, sizeDesc(item.TotalSize), sizeDesc(item.AvgSpeed), result, item.LocalPath)
+	}
+	return w.Flush()
+}
+
+type pairingRequest struct {
+	ID         string
+	IPAddr     string
+	DeviceName string
+	Platform   string
+	SAS        string
+}
+
+// cmdPairing the unknown peer is refused at once if no one watches its pairing request
+func cmdPairing(client *rtkControlApi.Client, args []string) error {
+	flagSet := newFlagSet("pairing")
+	flagSet.Parse(args)
+
+	switch flagSet.Arg(0) {
+	case "accept", "reject":
+		if flagSet.NArg() != 2 {
+			return errors.New("usage: pairing accept|reject <id>")
+		}
+		method := rtkControlApi.MethodPairingAccept
+		if flagSet.Arg(0) == "reject" {
+			method = rtkControlApi.MethodPairingReject
+		}
+		return client.Call(method, rtkControlApi.PeerParams{ID: flagSet.Arg(1)}, nil)
+	case "watch":
+		if err := subscribe(client, "", eventPairingRequest); err != nil {
+			return err
+		}
+		for {
+			params, err := nextEvent(client)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				printJsonLine(params)
+				continue
+			}
+			var request pairingRequest
+			if err = json.Unmarshal(params.Event, &request); err != nil {
+				return err
+			}
+			fmt.Printf("pairing request from %s (%s, %s) id:%s\n", request.DeviceName, request.Platform, request.IPAddr, request.ID)
+			fmt.Printf("  SAS:%s, compare it with the peer, then run: crossshare pairing accept|reject %s\n", request.SAS, request.ID)
+		}
+	default:
+		return errors.New("usage: pairing watch | pairing accept|reject <id>")
+	}
+}
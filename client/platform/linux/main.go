@@ -0,0 +1,168 @@
+//go:build linux && !android
+
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	rtkClipboard "rtk-cross-share/client/clipboard"
+	rtkCmd "rtk-cross-share/client/cmd"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkControl "rtk-cross-share/client/control"
+	rtkControlApi "rtk-cross-share/client/controlApi"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkLogin "rtk-cross-share/client/login"
+	rtkMetrics "rtk-cross-share/client/metrics"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
+	rtkMisc "rtk-cross-share/misc"
+)
+
+var (
+	rootPath     = flag.String("root", "", "client data folder, default is $HOME/.crossShare")
+	downloadPath = flag.String("download", "", "folder to save received files, default is {root}/Download")
+	deviceName   = flag.String("name", "", "device name shown to peers, default is host name")
+	clipboardDir = flag.String("clipboard", "", "use files in this folder as clipboard, default is in-memory clipboard")
+	eventFile    = flag.String("event", "", "write notify events as JSON lines to this file, '-' is stdout, default only write log")
+	instance     = flag.String("instance", "", "LanServer instance (DIAS MAC address) to connect after start")
+	allowPlain   = flag.Bool("allowPlaintextServer", false, "connect the legacy LanServer before 2.2.38 without TLS on the control channel, default is refused")
+	authorized   = flag.Bool("authorized", false, "reply authorized when LanServer asks for DDC/CI auth, for hosts without DDC/CI link")
+	pairAccept   = flag.Bool("acceptPairing", false, "INSECURE: accept the pairing of every unknown peer without comparing SAS, default is confirmed by pairing.accept of control API")
+	bandwidth    = flag.Int64("bandwidth", 0, "global file transfer speed limit in bytes per second, default is no limit")
+	cbPolicy     = flag.String("clipboardPolicy", "", "JSON file of clipboard sharing policy, default is the built-in policy")
+	imgMaxPixels = flag.Int64("imageMaxPixels", 0, "downscale clipboard image sent to peers above this pixel count, default is no limit")
+	imgMaxBytes  = flag.Int64("imageMaxBytes", 0, "downscale clipboard image sent to peers above this byte size, default is no limit")
+	controlSock  = flag.String("control", "", "unix socket of local JSON-RPC control API, default is {root}/crossShare.sock, 'none' to disable")
+	metricsAddr  = flag.String("metrics", "", "export Prometheus metrics on this loopback address, e.g. 127.0.0.1:9464, default is disabled")
+	logLevel     = flag.String("logLevel", "", "log levels like 'info,peer2peer=debug', a level without subsystem is for all, default is info")
+	logFormat    = flag.String("logFormat", "", "log format 'text' or 'json', default is text")
+	historyMax   = flag.Int("historyMax", rtkTransferHistory.TransferHistoryDefaultMaxCount, "max count of file transfer history, 0 disables the history")
+	historyDays  = flag.Int("historyDays", rtkTransferHistory.TransferHistoryDefaultMaxDays, "max days of file transfer history, 0 is no time limit")
+	confirmFiles = flag.Bool("confirmFiles", false, "wait for accepting or rejecting received files by control API, default is accept automatically")
+	dropRule     = flag.String("fileDropRule", "", "JSON file of rule for received files (trusted peers, size, extensions, peer folders, confirm timeout)")
+)
+
+func main() {
+	flag.Parse()
+
+	if *rootPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatalf("[%s] get home dir err:%+v", rtkMisc.GetFuncInfo(), err)
+		}
+		*rootPath = filepath.Join(homeDir, ".crossShare")
+	}
+	if err := rtkMisc.CreateDir(*rootPath); err != nil {
+		log.Fatalf("[%s] get rootPath [%s] is invalid! err:%+v", rtkMisc.GetFuncInfo(), *rootPath, err)
+	}
+
+	if *downloadPath == "" {
+		*downloadPath = filepath.Join(*rootPath, "Download")
+	}
+
+	if *clipboardDir != "" {
+		provider, err := rtkPlatform.NewFileClipboardProvider(*clipboardDir)
+		if err != nil {
+			log.Fatalf("[%s] create clipboard folder [%s] err:%+v", rtkMisc.GetFuncInfo(), *clipboardDir, err)
+		}
+		rtkPlatform.SetClipboardProvider(provider)
+	}
+
+	if *eventFile == "-" {
+		rtkPlatform.SetEventSink(os.Stdout)
+	} else if *eventFile != "" {
+		file, err := os.OpenFile(*eventFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("[%s] open event file [%s] err:%+v", rtkMisc.GetFuncInfo(), *eventFile, err)
+		}
+		defer file.Close()
+		rtkPlatform.SetEventSink(file)
+	}
+
+	if *authorized {
+		rtkPlatform.SetAuthViaIndexCallback(func(index uint32) {
+			log.Printf("[%s] index:[%d] reply authorized without DDC/CI", rtkMisc.GetFuncInfo(), index)
+			rtkMisc.GoSafe(func() { rtkPlatform.GoSetAuthStatusCode(1) })
+		})
+	}
+
+	if *pairAccept {
+		log.Printf("[%s] INSECURE: -acceptPairing is set, every unknown peer is paired without comparing SAS", rtkMisc.GetFuncInfo())
+		rtkPlatform.SetPairingRequestCallback(func(id, request string) bool {
+			log.Printf("[%s] INSECURE: ID:[%s] accept pairing request without comparing SAS:%s", rtkMisc.GetFuncInfo(), id, request)
+			rtkMisc.GoSafe(func() { rtkConnection.ConfirmPairing(id, true) })
+			return true
+		})
+	}
+
+	if *bandwidth > 0 {
+		rtkPlatform.GoSetBandwidthLimit("", *bandwidth)
+	}
+
+	if *imgMaxPixels > 0 || *imgMaxBytes > 0 {
+		rtkClipboard.SetClipboardImageBudget(*imgMaxPixels, *imgMaxBytes)
+	}
+
+	if *cbPolicy != "" {
+		policyJson, err := os.ReadFile(*cbPolicy)
+		if err != nil {
+			log.Fatalf("[%s] read clipboard policy [%s] err:%+v", rtkMisc.GetFuncInfo(), *cbPolicy, err)
+		}
+		if !rtkClipboard.SetClipboardPolicy(string(policyJson)) {
+			log.Fatalf("[%s] clipboard policy [%s] is invalid", rtkMisc.GetFuncInfo(), *cbPolicy)
+		}
+	}
+
+	if *dropRule != "" {
+		ruleJson, err := os.ReadFile(*dropRule)
+		if err != nil {
+			log.Fatalf("[%s] read file drop rule [%s] err:%+v", rtkMisc.GetFuncInfo(), *dropRule, err)
+		}
+		if !rtkFileDrop.SetFileDropRule(string(ruleJson)) {
+			log.Fatalf("[%s] file drop rule [%s] is invalid", rtkMisc.GetFuncInfo(), *dropRule)
+		}
+	}
+
+	rtkPlatform.SetConfirmDocumentsAccept(*confirmFiles)
+	rtkPlatform.InitPlatform(*rootPath, *downloadPath, *deviceName)
+
+	if *logFormat != "" && !rtkMisc.SetLogFormat(*logFormat) {
+		log.Fatalf("[%s] log format [%s] is invalid", rtkMisc.GetFuncInfo(), *logFormat)
+	}
+	if *logLevel != "" && !rtkMisc.SetLogLevelSpec(*logLevel) {
+		log.Fatalf("[%s] log level [%s] is invalid", rtkMisc.GetFuncInfo(), *logLevel)
+	}
+
+	rtkTransferHistory.SetTransferHistoryLimit(*historyMax, *historyDays)
+
+	if *controlSock == "" {
+		*controlSock = filepath.Join(*rootPath, rtkControlApi.SocketName)
+	}
+	if *controlSock != "none" {
+		if err := rtkControl.Start(*controlSock); err != nil {
+			log.Fatalf("[%s] start control API on [%s] err:%+v", rtkMisc.GetFuncInfo(), *controlSock, err)
+		}
+		defer rtkControl.Stop()
+		if !*pairAccept {
+			rtkPlatform.SetPairingRequestCallback(func(id, request string) bool {
+				return rtkControl.IsPairingWatched(id)
+			})
+		}
+	}
+
+	if *metricsAddr != "" {
+		if err := rtkMetrics.StartServer(*metricsAddr); err != nil {
+			log.Fatalf("[%s] start metrics on [%s] err:%+v", rtkMisc.GetFuncInfo(), *metricsAddr, err)
+		}
+		defer rtkMetrics.StopServer()
+	}
+
+	rtkLogin.SetAllowPlaintextLanServer(*allowPlain)
+	if *instance != "" {
+		rtkPlatform.GoSetMacAddress(*instance)
+	}
+
+	rtkCmd.Run()
+}
@@ -0,0 +1,237 @@
+//go:build linux && !android
+
+package platform
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	rtkUtils "rtk-cross-share/client/utils"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+)
+
+const clipboardProviderPollInterval = 500 * time.Millisecond
+
+// ClipboardContent is the XClip data exchanged with a ClipboardProvider, Image can be any format decoded by image.Decode
+type ClipboardContent struct {
+	Text  []byte
+	Image []byte
+	Html  []byte
+	Rtf   []byte
+}
+
+func (c ClipboardContent) isEmpty() bool {
+	return len(c.Text) == 0 && len(c.Image) == 0 && len(c.Html) == 0 && len(c.Rtf) == 0
+}
+
+func (c ClipboardContent) hash() string {
+	hash, err := rtkUtils.CreateMD5Hash(c.Text, c.Image, c.Html, c.Rtf)
+	if err != nil {
+		return ""
+	}
+	return hash.B58String()
+}
+
+// ClipboardProvider is the local clipboard of a headless Linux client.
+// Read is polled, a changed content is treated as a local copy and shared with peers.
+type ClipboardProvider interface {
+	Read() (ClipboardContent, error)
+	Write(content ClipboardContent) error
+	Clear() error
+}
+
+var (
+	clipboardProvider      ClipboardProvider
+	clipboardProviderMutex sync.RWMutex
+	lastClipboardHash      string
+)
+
+func SetClipboardProvider(provider ClipboardProvider) {
+	clipboardProviderMutex.Lock()
+	defer clipboardProviderMutex.Unlock()
+	clipboardProvider = provider
+	lastClipboardHash = ""
+	if provider != nil {
+		if content, err := provider.Read(); err == nil && !content.isEmpty() {
+			lastClipboardHash = content.hash() // content already there before start is not a new copy
+		}
+	}
+}
+
+func GetClipboardProvider() ClipboardProvider {
+	clipboardProviderMutex.RLock()
+	defer clipboardProviderMutex.RUnlock()
+	return clipboardProvider
+}
+
+func watchClipboardProvider() {
+	ticker := time.NewTicker(clipboardProviderPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		clipboardProviderMutex.Lock()
+		if clipboardProvider == nil {
+			clipboardProviderMutex.Unlock()
+			continue
+		}
+
+		content, err := clipboardProvider.Read()
+		if err != nil || content.isEmpty() {
+			clipboardProviderMutex.Unlock()
+			continue
+		}
+
+		hash := content.hash()
+		if hash == lastClipboardHash {
+			clipboardProviderMutex.Unlock()
+			continue
+		}
+		lastClipboardHash = hash
+		clipboardProviderMutex.Unlock()
+
+		log.Printf("[%s] clipboard provider content changed, Text:%d Image:%d Html:%d Rtf:%d", rtkMisc.GetFuncInfo(), len(content.Text), len(content.Image), len(content.Html), len(content.Rtf))
+		imgData := []byte(nil)
+		if len(content.Image) > 0 {
+			imgData = imageToClipboardFormat(content.Image)
+			if imgData == nil {
+				continue
+			}
+		}
+		copyXClipData(content.Text, imgData, content.Html, content.Rtf)
+	}
+}
+
+func writeClipboardProvider(cbText, cbImage, cbHtml, cbRtf []byte) {
+	clipboardProviderMutex.Lock()
+	defer clipboardProviderMutex.Unlock()
+	if clipboardProvider == nil {
+		log.Println("clipboardProvider is null!")
+		return
+	}
+
+	content := ClipboardContent{Text: cbText, Image: cbImage, Html: cbHtml, Rtf: cbRtf}
+	if err := clipboardProvider.Write(content); err != nil {
+		log.Printf("[%s] write clipboard provider err:%+v", rtkMisc.GetFuncInfo(), err)
+		return
+	}
+	lastClipboardHash = content.hash() // content written by ourselves is not a new local copy
+}
+
+func cleanClipboardProvider() {
+	clipboardProviderMutex.Lock()
+	defer clipboardProviderMutex.Unlock()
+	if clipboardProvider == nil {
+		return
+	}
+
+	if err := clipboardProvider.Clear(); err != nil {
+		log.Printf("[%s] clear clipboard provider err:%+v", rtkMisc.GetFuncInfo(), err)
+	}
+	lastClipboardHash = ""
+}
+
+/*======================================= Memory clipboard =======================================*/
+
+type memoryClipboardProvider struct {
+	mutex   sync.Mutex
+	content ClipboardContent
+}
+
+// NewMemoryClipboardProvider keeps the clipboard in process, local copy is set by GoCopyXClipData
+func NewMemoryClipboardProvider() ClipboardProvider {
+	return &memoryClipboardProvider{}
+}
+
+func (m *memoryClipboardProvider) Read() (ClipboardContent, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.content, nil
+}
+
+func (m *memoryClipboardProvider) Write(content ClipboardContent) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.content = content
+	return nil
+}
+
+func (m *memoryClipboardProvider) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.content = ClipboardContent{}
+	return nil
+}
+
+/*======================================= File clipboard =======================================*/
+
+const (
+	clipboardFileText  = "text.txt"
+	clipboardFileImage = "image"
+	clipboardFileHtml  = "html.html"
+	clipboardFileRtf   = "rtf.rtf"
+)
+
+type fileClipboardProvider struct {
+	dir string
+}
+
+// NewFileClipboardProvider keeps every clipboard format as a file in dir,
+// other tools copy by writing these files and read what peers pasted from them
+func NewFileClipboardProvider(dir string) (ClipboardProvider, error) {
+	if err := rtkMisc.CreateDir(dir); err != nil {
+		return nil, err
+	}
+	return &fileClipboardProvider{dir: dir}, nil
+}
+
+func (f *fileClipboardProvider) readFile(name string) []byte {
+	data, err := os.ReadFile(filepath.Join(f.dir, name))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (f *fileClipboardProvider) writeFile(name string, data []byte) error {
+	path := filepath.Join(f.dir, name)
+	if len(data) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (f *fileClipboardProvider) Read() (ClipboardContent, error) {
+	return ClipboardContent{
+		Text:  f.readFile(clipboardFileText),
+		Image: f.readFile(clipboardFileImage),
+		Html:  f.readFile(clipboardFileHtml),
+		Rtf:   f.readFile(clipboardFileRtf),
+	}, nil
+}
+
+func (f *fileClipboardProvider) Write(content ClipboardContent) error {
+	if err := f.writeFile(clipboardFileText, content.Text); err != nil {
+		return err
+	}
+	if err := f.writeFile(clipboardFileImage, content.Image); err != nil {
+		return err
+	}
+	if err := f.writeFile(clipboardFileHtml, content.Html); err != nil {
+		return err
+	}
+	return f.writeFile(clipboardFileRtf, content.Rtf)
+}
+
+func (f *fileClipboardProvider) Clear() error {
+	return f.Write(ClipboardContent{})
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
 	rtkGlobal "rtk-cross-share/client/global"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
@@ -25,6 +26,11 @@ var (
 	hostID                   string
 	nodeID                   string
 	lockFile                 string
+	transferJournal          string
+	clipboardHistory         string
+	trustStore               string
+	lanServerKeyStore        string
+	transferHistory          string
 	lockFd                   *os.File
 	logFile                  string
 	crashLogFile             string
@@ -38,6 +44,11 @@ func init() {
 	nodeID = ".ID"
 	logFile = "p2p.log"
 	lockFile = "singleton.lock"
+	transferJournal = "transferJournal.json"
+	clipboardHistory = "clipboardHistory"
+	trustStore = "trustStore.json"
+	lanServerKeyStore = "lanServerKeyStore.json"
+	transferHistory = "transferHistory.db"
 	crashLogFile = "crash.log"
 	downloadPath = ""
 	rtkGlobal.NodeInfo.Platform = rtkMisc.PlatformMac
@@ -64,6 +75,8 @@ type (
 	CallbackNetworkSwitchFunc              func()
 	CallbackCopyXClipFunc                  func(cbText, cbImage, cbHtml, cbRtf []byte)
 	CallbackPasteXClipFunc                 func(text, image, html, rtf string)
+	CallbackLazyXClipHeadFunc              func(id, head string)
+	CallbackPairingRequestFunc             func(id, request string) bool // returns false if platform has no pairing handler
 	CallbackFileDropResponseFunc           func(string, rtkCommon.FileDropCmd, string)
 	CallbackDragFileListRequestFunc        func([]rtkCommon.FileInfo, []string, uint64, uint64, string, string)
 	CallbackFileListNotify                 func(string, string, uint32, uint64, uint64, string, uint64, string)
@@ -73,6 +86,7 @@ type (
 	CallbackUpdateProgressBar              func(string, string, string, uint32, uint32, uint64, uint64, uint64, uint64)
 	CallbackNotiMessageFileTransFunc       func(fileName, clientName, platform string, timestamp uint64, isSender bool)
 	CallbackCancelFileTransFunc            func(string, string, uint64)
+	CallbackSetBandwidthLimitFunc          func(id string, bytesPerSec int64)
 	CallbackNotifyErrEventFunc             func(id string, errCode uint32, arg1, arg2, arg3, arg4 string)
 	CallbackGetMacAddressFunc              func(string)
 	CallbackDisplayEventFunc               func(rtkCommon.DisplayEventInfo)
@@ -103,6 +117,8 @@ var (
 	callbackNetworkSwitch              CallbackNetworkSwitchFunc              = nil
 	callbackCopyXClipData              CallbackCopyXClipFunc                  = nil
 	callbackPasteXClipData             CallbackPasteXClipFunc                 = nil
+	callbackLazyXClipHead              CallbackLazyXClipHeadFunc              = nil
+	callbackPairingRequest             CallbackPairingRequestFunc             = nil
 	callbackInstanceFileDropResponseCB CallbackFileDropResponseFunc           = nil
 	callbackDragFileListRequestCB      CallbackDragFileListRequestFunc        = nil
 	callbackFileListSendNotify         CallbackFileListNotify                 = nil
@@ -114,6 +130,7 @@ var (
 	callbackUpdateReceiveProgressBar   CallbackUpdateProgressBar              = nil
 	callbackNotiMessageFileTransCB     CallbackNotiMessageFileTransFunc       = nil
 	callbackCancelFileTrans            CallbackCancelFileTransFunc            = nil
+	callbackSetBandwidthLimit          CallbackSetBandwidthLimitFunc          = nil
 	callbackNotifyErrEvent             CallbackNotifyErrEventFunc             = nil
 	callbackGetMacAddress              CallbackGetMacAddressFunc              = nil
 	callbackDisplayEvent               CallbackDisplayEventFunc               = nil
@@ -197,6 +214,14 @@ func SetCallbackPasteXClipData(cb CallbackPasteXClipFunc) {
 	callbackPasteXClipData = cb
 }
 
+func SetCallbackLazyXClipHead(cb CallbackLazyXClipHeadFunc) {
+	callbackLazyXClipHead = cb
+}
+
+func SetCallbackPairingRequest(cb CallbackPairingRequestFunc) {
+	callbackPairingRequest = cb
+}
+
 func SetCallbackRequestUpdateClientVersion(cb CallbackRequestUpdateClientVersionFunc) {
 	callbackRequestUpdateClientVersion = cb
 }
@@ -232,6 +257,10 @@ func SetGoCancelFileTransCallback(cb CallbackCancelFileTransFunc) {
 	callbackCancelFileTrans = cb
 }
 
+func SetGoBandwidthLimitCallback(cb CallbackSetBandwidthLimitFunc) {
+	callbackSetBandwidthLimit = cb
+}
+
 func SetGoExtractDIASCallback(cb CallbackExtractDIASFunc) {
 	callbackExtractDIAS = cb
 }
@@ -313,6 +342,11 @@ func SetupRootPath(path string) {
 	hostID = getPath(settingsPath, hostID)
 	nodeID = getPath(settingsPath, nodeID)
 	lockFile = getPath(settingsPath, lockFile)
+	transferJournal = getPath(settingsPath, transferJournal)
+	clipboardHistory = getPath(settingsPath, clipboardHistory)
+	trustStore = getPath(settingsPath, trustStore)
+	lanServerKeyStore = getPath(settingsPath, lanServerKeyStore)
+	transferHistory = getPath(settingsPath, transferHistory)
 
 	logFile = getPath(logPath, logFile)
 	crashLogFile = getPath(logPath, crashLogFile)
@@ -405,17 +439,17 @@ func GoCopyXClipData(text, image, html, rtf string) {
 		}
 
 		format, width, height := rtkUtils.GetByteImageInfo(data)
-		jpegData, err := rtkUtils.ImageToJpeg(format, data)
+		clipData, err := rtkUtils.ImageToClipboardFormat(format, data)
 		if err != nil {
 			return
 		}
-		if len(jpegData) == 0 {
-			log.Printf("[CopyXClip] Error: jpeg data is empty")
+		if len(clipData) == 0 {
+			log.Printf("[CopyXClip] Error: image data is empty")
 			return
 		}
 
-		imgData = jpegData
-		log.Printf("image get jpg size:[%d](%d,%d),decode use:[%d]ms", len(imgData), width, height, time.Now().UnixMilli()-startTime)
+		imgData = clipData
+		log.Printf("image get %s size:[%d](%d,%d),decode use:[%d]ms", format, len(imgData), width, height, time.Now().UnixMilli()-startTime)
 	}
 
 	callbackCopyXClipData([]byte(text), imgData, []byte(html), []byte(rtf))
@@ -540,21 +574,6 @@ func GoMultiFilesDropRequest(filesDataInfoJson string) rtkCommon.SendFilesReques
 		return rtkCommon.SendFilesRequestSizeOverRange
 	}
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength) //p2p null msg length
-
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] ID[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), filesDataInfo.Id, len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
-
 	callbackFileListDropRequest(filesDataInfo.Id, fileList, folderList, totalSize, timestamp, totalDesc, srcRootPath)
 	return rtkCommon.SendFilesRequestSuccess
 }
@@ -623,19 +642,6 @@ func GoDragFileListRequest(multiFilesData string, timeStamp uint64) rtkCommon.Se
 		return rtkCommon.SendFilesRequestSizeOverRange
 	}
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength) //p2p null msg length
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
 	callbackDragFileListRequestCB(fileList, folderList, totalSize, timeStamp, totalDesc, srcRootPath)
 	return rtkCommon.SendFilesRequestSuccess
 }
@@ -648,6 +654,15 @@ func GoCancelFileTrans(ip, id string, timestamp uint64) {
 	callbackCancelFileTrans(id, ip, timestamp)
 }
 
+// GoSetBandwidthLimit limits the file transfer speed with the peer ID, empty ID is the global limit of all peers, bytesPerSec <= 0 means no limit
+func GoSetBandwidthLimit(id string, bytesPerSec int64) {
+	if callbackSetBandwidthLimit == nil {
+		log.Println("callbackSetBandwidthLimit is null!")
+		return
+	}
+	callbackSetBandwidthLimit(id, bytesPerSec)
+}
+
 func GoUpdateDownloadPath(path string) {
 	downloadPath = path
 	log.Printf("[%s] update downloadPath:[%s] success!", rtkMisc.GetFuncInfo(), downloadPath)
@@ -689,66 +704,21 @@ func GoDragFileListFolderNotify(ip, id, folderName string, timestamp uint64) {
 	callbackFileListDragFolderNotify(ip, id, folderName, timestamp)
 }
 
-func GoUpdateClientStatusEx(id string, status uint8) {
-	if callbackUpdateClientStatus == nil {
-		log.Printf("callbackUpdateClientStatus is null!\n\n")
-		return
-	}
-
-	var clientInfo rtkCommon.ClientStatusInfo
-	if status == 1 { // online
-		info, err := rtkUtils.GetClientInfo(id)
-		if err != nil {
-			log.Printf("[%s] err:%+v", rtkMisc.GetFuncInfo(), err)
-			return
-		}
-		clientInfo.ClientInfo = info.ClientInfo
-	} else {
-		clientInfo.ID = id
-	}
-
-	clientInfo.TimeStamp = time.Now().UnixMilli()
-	clientInfo.Status = status
-	encodedData, err := json.Marshal(clientInfo)
-	if err != nil {
-		log.Println("Failed to Marshal ClientStatusInfo data, err:", err)
-		return
-	}
-
-	callbackUpdateClientStatus(string(encodedData))
-}
-
-func GoSetupDstPasteXClipData(cbText, cbImage, cbHtml, cbRtf []byte) {
-	if callbackPasteXClipData == nil {
-		log.Printf("callbackPasteXClipData is null!\n\n")
-		return
-	}
-
-	imageStr := ""
-	if len(cbImage) > 0 {
-		imageBase64 := rtkUtils.Base64Encode(cbImage)
-		imageStr = imageBase64
-	}
-
-	callbackPasteXClipData(string(cbText), imageStr, string(cbHtml), string(cbRtf))
-}
-
-func GoUpdateSendProgressBar(ip, id, currentFileName string, sendFileCnt, totalFileCnt uint32, currentFileSize, totalSize, sendSize, timestamp uint64) {
-	if callbackUpdateSendProgressBar == nil {
-		log.Println("callbackUpdateSendProgressBar is null !")
+func GoNotifyLazyXClipHead(id, head string) {
+	if callbackLazyXClipHead == nil {
+		log.Println("callbackLazyXClipHead is null!")
 		return
 	}
-	//log.Printf("GoUpdateSendProgressBar ip:[%s] currentFileName:[%s] sendSize:[%d] total:[%d]", ip, currentFileName, sendSize, totalSize)
-	callbackUpdateSendProgressBar(ip, id, currentFileName, sendFileCnt, totalFileCnt, currentFileSize, totalSize, sendSize, timestamp)
+	callbackLazyXClipHead(id, head)
 }
 
-func GoUpdateReceiveProgressBar(ip, id, currentFileName string, recvFileCnt, totalFileCnt uint32, currentFileSize, totalSize, recvSize, timestamp uint64) {
-	if callbackUpdateReceiveProgressBar == nil {
-		log.Println("callbackUpdateReceiveProgressBar is null !")
-		return
+// GoNotifyPairingRequest returns false if the pairing request is not delivered to any handler
+func GoNotifyPairingRequest(id, request string) bool {
+	if callbackPairingRequest == nil {
+		log.Println("callbackPairingRequest is null!")
+		return false
 	}
-	//log.Printf("GoUpdateReceiveProgressBar ip:[%s] currentFileName:[%s] recvSize:[%d] total:[%d]", ip, currentFileName, recvSize, totalSize)
-	callbackUpdateReceiveProgressBar(ip, id, currentFileName, recvFileCnt, totalFileCnt, currentFileSize, totalSize, recvSize, timestamp)
+	return callbackPairingRequest(id, request)
 }
 
 func GoUpdateSystemInfo(ipAddr, serviceVer string) {
@@ -756,23 +726,6 @@ func GoUpdateSystemInfo(ipAddr, serviceVer string) {
 	callbackUpdateSystemInfo(ipAddr, serviceVer)
 }
 
-func GoNotiMessageFileTransfer(fileName, clientName, platform string, timestamp uint64, isSender bool) {
-	if callbackNotiMessageFileTransCB == nil {
-		log.Println("CallbackNotiMessageFileTransCB is null !")
-		return
-	}
-	callbackNotiMessageFileTransCB(fileName, clientName, platform, timestamp, isSender)
-}
-
-func GoNotifyErrEvent(id string, errCode rtkMisc.CrossShareErr, arg1, arg2, arg3, arg4 string) {
-	if callbackNotifyErrEvent == nil {
-		log.Printf("callbackNotifyErrEvent is null!\n")
-		return
-	}
-
-	callbackNotifyErrEvent(id, uint32(errCode), arg1, arg2, arg3, arg4)
-}
-
 func GoRequestUpdateClientVersion(ver string) {
 	if callbackRequestUpdateClientVersion == nil {
 		log.Println("callbackRequestUpdateClientVersion is null!")
@@ -786,6 +739,56 @@ func GoCleanClipboard() {
 
 }
 
+// notifyPlatformEvent delivers rtkEvent to the callbacks set by macOS and iOS UI
+func notifyPlatformEvent(ev rtkEvent.Event) {
+	switch e := ev.(type) {
+	case rtkEvent.TransferProgressEvent:
+		callbackProgressBar := callbackUpdateReceiveProgressBar
+		if e.IsSender {
+			callbackProgressBar = callbackUpdateSendProgressBar
+		}
+		if callbackProgressBar == nil {
+			log.Printf("progress bar callback is null, isSender:[%+v] !", e.IsSender)
+			return
+		}
+		callbackProgressBar(e.IPAddr, e.ID, e.CurrentFileName, e.FileCnt, e.TotalFileCnt, e.CurrentFileSize, e.TotalSize, e.TransSize, e.TimeStamp)
+	case rtkEvent.TransferDoneEvent:
+		if callbackNotiMessageFileTransCB == nil {
+			log.Println("callbackNotiMessageFileTransCB is null !")
+			return
+		}
+		callbackNotiMessageFileTransCB(e.FileName, e.ClientName, e.Platform, e.TimeStamp, e.IsSender)
+	case rtkEvent.TransferFailedEvent:
+		notifyPlatformEvent(getTransferFailedErrEvent(e))
+	case rtkEvent.ErrorEvent:
+		if callbackNotifyErrEvent == nil {
+			log.Printf("callbackNotifyErrEvent is null!\n")
+			return
+		}
+		callbackNotifyErrEvent(e.ID, uint32(e.Code), e.Arg1, e.Arg2, e.Arg3, e.Arg4)
+	case rtkEvent.PeerStatusEvent:
+		if callbackUpdateClientStatus == nil {
+			log.Printf("callbackUpdateClientStatus is null!\n\n")
+			return
+		}
+		if clientInfo, ok := getClientStatusInfoJson(e); ok {
+			callbackUpdateClientStatus(clientInfo)
+		}
+	case rtkEvent.ClipboardReceivedEvent:
+		if callbackPasteXClipData == nil {
+			log.Printf("callbackPasteXClipData is null!\n\n")
+			return
+		}
+		callbackPasteXClipData(string(e.Text), getXClipImageBase64(e.Image), string(e.Html), string(e.Rtf))
+	case rtkEvent.LanServerStatusEvent:
+		if callbackDIASStatus == nil {
+			log.Printf("[%s] callbackDIASStatus is nil, DIASStatusNotify failed!", rtkMisc.GetFuncInfo())
+			return
+		}
+		callbackDIASStatus(e.Status)
+	}
+}
+
 func GenKey() crypto.PrivKey {
 	return rtkUtils.GenKey(privKeyFile)
 }
@@ -820,6 +823,26 @@ func GetHostIDPath() string {
 	return hostID
 }
 
+func GetTransferJournalPath() string {
+	return transferJournal
+}
+
+func GetClipboardHistoryPath() string {
+	return clipboardHistory
+}
+
+func GetTrustStorePath() string {
+	return trustStore
+}
+
+func GetLanServerKeyStorePath() string {
+	return lanServerKeyStore
+}
+
+func GetTransferHistoryPath() string {
+	return transferHistory
+}
+
 func LockFile() error {
 	var err error
 	lockFd, err = os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0666)
@@ -879,16 +902,6 @@ func GoMonitorNameNotify(name string) {
 	callbackMonitorName(name)
 }
 
-func GoDIASStatusNotify(diasStatus uint32) {
-	log.Printf("[%s] diasStatus:%d", rtkMisc.GetFuncInfo(), diasStatus)
-	if callbackDIASStatus == nil {
-		log.Printf("[%s] callbackDIASStatus is nil, DIASStatusNotify failed!", rtkMisc.GetFuncInfo())
-		return
-	}
-	callbackDIASStatus(diasStatus)
-
-}
-
 func GetAuthData(clientIndex uint32) (rtkMisc.CrossShareErr, rtkMisc.AuthDataInfo) {
 	return rtkMisc.ERR_BIZ_GET_CALLBACK_INSTANCE_NULL, rtkMisc.AuthDataInfo{}
 }
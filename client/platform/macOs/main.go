@@ -27,6 +27,8 @@ typedef void (*CallbackAuthViaIndex)(unsigned int index);
 typedef void (*CallbackSetDIASStatus)(unsigned int status);
 typedef void (*CallbackSetMonitorName)(char* monitorName);
 typedef void (*CallbackPasteXClipData)(char *text, char *image, char *html, char* rtf);
+typedef void (*CallbackLazyXClipHead)(char* id, char* head);
+typedef void (*CallbackPairingRequest)(char* id, char* request);
 typedef void (*CallbackRequestUpdateClientVersion)(char* clientVer);
 typedef void (*CallbackNotifyErrEvent)(char* id, unsigned int errCode, char* arg1, char* arg2, char* arg3, char* arg4);
 typedef void (*CallbackNotifyBrowseResult)(char* monitorName, char* instance, char* ip, char* version, unsigned long long timestamp);
@@ -45,6 +47,8 @@ static CallbackAuthViaIndex  gCallbackAuthViaIndex = 0;
 static CallbackSetDIASStatus gCallbackSetDIASStatus = 0;
 static CallbackSetMonitorName gCallbackSetMonitorName = 0;
 static CallbackPasteXClipData gCallbackPasteXClipData = 0;
+static CallbackLazyXClipHead gCallbackLazyXClipHead = 0;
+static CallbackPairingRequest gCallbackPairingRequest = 0;
 static CallbackRequestUpdateClientVersion gCallbackRequestUpdateClientVersion = 0;
 static CallbackNotifyErrEvent gCallbackNotifyErrEvent = 0;
 static CallbackNotifyBrowseResult gCallbackNotifyBrowseResult = 0;
@@ -106,6 +110,15 @@ static void setCallbackPasteXClipData(CallbackPasteXClipData cb) {gCallbackPaste
 static void invokeCallbackPasteXClipData(char *text, char *image, char *html, char* rtf) {
 	if (gCallbackPasteXClipData) { gCallbackPasteXClipData(text, image, html, rtf);}
 }
+static void setCallbackLazyXClipHead(CallbackLazyXClipHead cb) {gCallbackLazyXClipHead = cb;}
+static void invokeCallbackLazyXClipHead(char* id, char* head) {
+	if (gCallbackLazyXClipHead) { gCallbackLazyXClipHead(id, head);}
+}
+static void setCallbackPairingRequest(CallbackPairingRequest cb) {gCallbackPairingRequest = cb;}
+static int hasCallbackPairingRequest() {return gCallbackPairingRequest != 0;}
+static void invokeCallbackPairingRequest(char* id, char* request) {
+	if (gCallbackPairingRequest) { gCallbackPairingRequest(id, request);}
+}
 static void setCallbackRequestUpdateClientVersion(CallbackRequestUpdateClientVersion cb) {gCallbackRequestUpdateClientVersion = cb;}
 static void invokeCallbackRequestUpdateClientVersion(char* clientVer) {
 	if (gCallbackRequestUpdateClientVersion) { gCallbackRequestUpdateClientVersion(clientVer);}
@@ -126,9 +139,15 @@ import (
 	"fmt"
 	"log"
 	rtkBuildConfig "rtk-cross-share/client/buildConfig"
+	rtkClipboard "rtk-cross-share/client/clipboard"
 	rtkCmd "rtk-cross-share/client/cmd"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkDiagnostics "rtk-cross-share/client/diagnostics"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkLogin "rtk-cross-share/client/login"
 	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"strings"
@@ -190,6 +209,8 @@ func init() {
 	rtkPlatform.SetCallbackRequestSourceAndPort(GoTriggerCallbackRequestSourceAndPort)
 	rtkPlatform.SetCallbackMonitorName(GoTriggerCallbackSetMonitorName)
 	rtkPlatform.SetCallbackPasteXClipData(GoTriggerCallbackPasteXClipData)
+	rtkPlatform.SetCallbackLazyXClipHead(GoTriggerCallbackLazyXClipHead)
+	rtkPlatform.SetCallbackPairingRequest(GoTriggerCallbackPairingRequest)
 	rtkPlatform.SetCallbackRequestUpdateClientVersion(GoTriggerCallbackReqClientUpdateVer)
 	rtkPlatform.SetCallbackNotifyErrEvent(GoTriggerCallbackNotifyErrEvent)
 
@@ -379,6 +400,31 @@ func GoTriggerCallbackPasteXClipData(text, image, html, rtf string) {
 	C.invokeCallbackPasteXClipData(cText, cImage, cHtml, cRtf)
 }
 
+func GoTriggerCallbackLazyXClipHead(id, head string) {
+	cId := C.CString(id)
+	cHead := C.CString(head)
+	defer C.free(unsafe.Pointer(cId))
+	defer C.free(unsafe.Pointer(cHead))
+
+	log.Printf("[%s] ID:[%s] head:%s", rtkMisc.GetFuncInfo(), id, head)
+	C.invokeCallbackLazyXClipHead(cId, cHead)
+}
+
+func GoTriggerCallbackPairingRequest(id, request string) bool {
+	if C.hasCallbackPairingRequest() == 0 {
+		log.Printf("[%s] CallbackPairingRequest is not set!", rtkMisc.GetFuncInfo())
+		return false
+	}
+	cId := C.CString(id)
+	cRequest := C.CString(request)
+	defer C.free(unsafe.Pointer(cId))
+	defer C.free(unsafe.Pointer(cRequest))
+
+	log.Printf("[%s] ID:[%s] request:%s", rtkMisc.GetFuncInfo(), id, request)
+	C.invokeCallbackPairingRequest(cId, cRequest)
+	return true
+}
+
 func GoTriggerCallbackReqClientUpdateVer(ver string) {
 	cVer := C.CString(ver)
 	defer C.free(unsafe.Pointer(cVer))
@@ -501,6 +547,18 @@ func SetCallbackPasteXClipData(cb C.CallbackPasteXClipData) {
 	C.setCallbackPasteXClipData(cb)
 }
 
+//export SetCallbackLazyXClipHead
+func SetCallbackLazyXClipHead(cb C.CallbackLazyXClipHead) {
+	log.Printf("[%s] SetCallbackLazyXClipHead", rtkMisc.GetFuncInfo())
+	C.setCallbackLazyXClipHead(cb)
+}
+
+//export SetCallbackPairingRequest
+func SetCallbackPairingRequest(cb C.CallbackPairingRequest) {
+	log.Printf("[%s] SetCallbackPairingRequest", rtkMisc.GetFuncInfo())
+	C.setCallbackPairingRequest(cb)
+}
+
 //export SetCallbackRequestUpdateClientVersion
 func SetCallbackRequestUpdateClientVersion(cb C.CallbackRequestUpdateClientVersion) {
 	log.Printf("[%s] SetCallbackRequestUpdateClientVersion", rtkMisc.GetFuncInfo())
@@ -603,6 +661,168 @@ func SetCancelFileTransfer(ipPort, clientID string, timeStamp uint64) {
 	rtkPlatform.GoCancelFileTrans(ipPort, clientID, timeStamp)
 }
 
+//export SetBandwidthLimit
+func SetBandwidthLimit(clientID string, bytesPerSec int64) {
+	log.Printf("[%s] ID:[%s] bytesPerSec:[%d]", rtkMisc.GetFuncInfo(), clientID, bytesPerSec)
+	rtkPlatform.GoSetBandwidthLimit(clientID, bytesPerSec)
+}
+
+//export GetClipboardHistoryList
+func GetClipboardHistoryList() *C.char {
+	return C.CString(rtkClipboard.GetClipboardHistoryList())
+}
+
+//export GetClipboardHistoryData
+func GetClipboardHistoryData(index uint64) *C.char {
+	return C.CString(rtkClipboard.GetClipboardHistoryData(index))
+}
+
+//export ApplyClipboardHistory
+func ApplyClipboardHistory(index uint64) bool {
+	return rtkClipboard.ApplyClipboardHistory(index)
+}
+
+//export DeleteClipboardHistory
+func DeleteClipboardHistory(index uint64) bool {
+	return rtkClipboard.DeleteClipboardHistory(index)
+}
+
+//export ClearClipboardHistory
+func ClearClipboardHistory() {
+	rtkClipboard.ClearClipboardHistory()
+}
+
+//export SetClipboardHistoryMaxCount
+func SetClipboardHistoryMaxCount(count int) {
+	rtkClipboard.SetClipboardHistoryMaxCount(count)
+}
+
+//export SetClipboardPolicy
+func SetClipboardPolicy(policyJson string) bool {
+	return rtkClipboard.SetClipboardPolicy(policyJson)
+}
+
+//export GetClipboardPolicy
+func GetClipboardPolicy() *C.char {
+	return C.CString(rtkClipboard.GetClipboardPolicy())
+}
+
+//export SetLogLevel
+func SetLogLevel(levelSpec string) bool {
+	return rtkMisc.SetLogLevelSpec(levelSpec)
+}
+
+//export GetLogLevels
+func GetLogLevels() *C.char {
+	return C.CString(rtkMisc.GetLogLevels())
+}
+
+//export SetLogFormat
+func SetLogFormat(format string) bool {
+	return rtkMisc.SetLogFormat(format)
+}
+
+//export CollectDiagnostics
+func CollectDiagnostics(outputDir string) *C.char {
+	path, _ := rtkDiagnostics.Collect(outputDir)
+	return C.CString(path)
+}
+
+//export GetTransferHistoryList
+func GetTransferHistoryList(filterJson string) *C.char {
+	return C.CString(rtkTransferHistory.GetTransferHistoryList(filterJson))
+}
+
+//export DeleteTransferHistory
+func DeleteTransferHistory(index int64) bool {
+	return rtkTransferHistory.DeleteTransferHistory(index)
+}
+
+//export ClearTransferHistory
+func ClearTransferHistory() {
+	rtkTransferHistory.ClearTransferHistory()
+}
+
+//export SetTransferHistoryLimit
+func SetTransferHistoryLimit(maxCount, maxDays int) {
+	rtkTransferHistory.SetTransferHistoryLimit(maxCount, maxDays)
+}
+
+//export RevealTransferHistory
+func RevealTransferHistory(index int64) *C.char {
+	return C.CString(rtkTransferHistory.RevealTransferHistory(index))
+}
+
+//export MarkNextCopyConcealed
+func MarkNextCopyConcealed() {
+	rtkClipboard.MarkNextCopyConcealed()
+}
+
+//export SetClipboardImageFormats
+func SetClipboardImageFormats(formatsJson string) bool {
+	return rtkClipboard.SetClipboardImageFormats(formatsJson)
+}
+
+//export SetClipboardImageBudget
+func SetClipboardImageBudget(maxPixels, maxBytes int64) {
+	rtkClipboard.SetClipboardImageBudget(maxPixels, maxBytes)
+}
+
+//export GetLastClipboardImageInfo
+func GetLastClipboardImageInfo() *C.char {
+	return C.CString(rtkClipboard.GetLastClipboardImageInfo())
+}
+
+//export SetClipboardLazyMode
+func SetClipboardLazyMode(enable bool) {
+	rtkClipboard.SetClipboardLazyMode(enable)
+}
+
+//export GetLazyXClipHead
+func GetLazyXClipHead() *C.char {
+	return C.CString(rtkClipboard.GetLazyXClipHead())
+}
+
+//export RequestLazyXClipData
+func RequestLazyXClipData() bool {
+	return rtkClipboard.RequestLazyXClipData()
+}
+
+//export CopyXClipItems
+func CopyXClipItems(itemsJson string) bool {
+	return rtkClipboard.CopyXClipItems(itemsJson)
+}
+
+//export GetLastXClipItems
+func GetLastXClipItems() *C.char {
+	return C.CString(rtkClipboard.GetLastXClipItems())
+}
+
+//export ConfirmPairing
+func ConfirmPairing(id string, isAccepted bool) bool {
+	return rtkConnection.ConfirmPairing(id, isAccepted)
+}
+
+//export GetTrustedPeerList
+func GetTrustedPeerList() *C.char {
+	return C.CString(rtkConnection.GetTrustedPeerList())
+}
+
+//export RenameTrustedPeer
+func RenameTrustedPeer(id, name string) bool {
+	return rtkConnection.RenameTrustedPeer(id, name)
+}
+
+//export RevokeTrustedPeer
+func RevokeTrustedPeer(id string) bool {
+	return rtkConnection.RevokeTrustedPeer(id)
+}
+
+//export DeleteTrustedPeer
+func DeleteTrustedPeer(id string) bool {
+	return rtkConnection.DeleteTrustedPeer(id)
+}
+
 //export RequestUpdateDownloadPath
 func RequestUpdateDownloadPath(downloadPath string) {
 	if downloadPath == "" || !rtkMisc.FolderExists(downloadPath) {
@@ -704,6 +924,21 @@ func SetConfirmDocumentsAccept(ifConfirm bool) {
 	rtkPlatform.SetConfirmDocumentsAccept(ifConfirm)
 }
 
+//export SetFileDropRule
+func SetFileDropRule(ruleJson string) bool {
+	return rtkFileDrop.SetFileDropRule(ruleJson)
+}
+
+//export GetFileDropRule
+func GetFileDropRule() *C.char {
+	return C.CString(rtkFileDrop.GetFileDropRule())
+}
+
+//export SetAllowPlaintextLanServer
+func SetAllowPlaintextLanServer(allow bool) {
+	rtkLogin.SetAllowPlaintextLanServer(allow)
+}
+
 //export SetDragFileListRequest
 func SetDragFileListRequest(multiFilesData string, timeStamp uint64) C.uint {
 	return C.uint(rtkPlatform.GoDragFileListRequest(multiFilesData, timeStamp))
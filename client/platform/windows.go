@@ -3,7 +3,6 @@
 package platform
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"golang.org/x/sys/windows"
@@ -11,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkEvent "rtk-cross-share/client/event"
 	rtkGlobal "rtk-cross-share/client/global"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
@@ -22,6 +22,11 @@ var (
 	hostID                   = ".HostID"
 	nodeID                   = ".ID"
 	lockFile                 = "singleton.lock"
+	transferJournal          = "transferJournal.json"
+	clipboardHistory         = "clipboardHistory"
+	trustStore               = "trustStore.json"
+	lanServerKeyStore        = "lanServerKeyStore.json"
+	transferHistory          = "transferHistory.db"
 	lockFd                   *os.File
 	logFile                  = "p2p.log"
 	crashLogFile             = "crash.log"
@@ -59,6 +64,11 @@ func InitPlatform(rootPath, downLoadPath, deviceName string) {
 	hostID = getPath(settingsPath, hostID)
 	nodeID = getPath(settingsPath, nodeID)
 	lockFile = getPath(settingsPath, lockFile)
+	transferJournal = getPath(settingsPath, transferJournal)
+	clipboardHistory = getPath(settingsPath, clipboardHistory)
+	trustStore = getPath(settingsPath, trustStore)
+	lanServerKeyStore = getPath(settingsPath, lanServerKeyStore)
+	transferHistory = getPath(settingsPath, transferHistory)
 
 	logFile = getPath(logPath, logFile)
 	crashLogFile = getPath(logPath, crashLogFile)
@@ -78,6 +88,8 @@ type (
 	CallbackNetworkSwitchFunc          func()
 	CallbackCopyXClipFunc              func(cbText, cbImage, cbHtml, cbRtf []byte)
 	CallbackPasteXClipFunc             func(text, image, html, rtf string)
+	CallbackLazyXClipHeadFunc          func(id, head string)
+	CallbackPairingRequestFunc         func(id, request string) bool // returns false if platform has no pairing handler
 	CallbackCleanClipboardFunc         func()
 	CallbackFileListDropRequestFunc    func(string, []rtkCommon.FileInfo, []string, uint64, uint64, string, string)
 	CallbackDragFileListRequestFunc    func([]rtkCommon.FileInfo, []string, uint64, uint64, string, string)
@@ -86,6 +98,7 @@ type (
 	CallbackNotiMessageFileTransFunc   func(fileName, clientName, platform string, timestamp uint64, isSender bool)
 	CallbackFileDropResponseFunc       func(string, rtkCommon.FileDropCmd, string)
 	CallbackCancelFileTransFunc        func(string, string, uint64)
+	CallbackSetBandwidthLimitFunc      func(id string, bytesPerSec int64)
 	CallbackExtractDIASFunc            func()
 	CallbackGetMacAddressFunc          func(string)
 	CallbackDisplayEventFunc           func(rtkCommon.DisplayEventInfo)
@@ -115,6 +128,8 @@ var (
 	callbackNetworkSwitchCB            CallbackNetworkSwitchFunc          = nil
 	callbackCopyXClipDataCB            CallbackCopyXClipFunc              = nil
 	callbackPasteXClipDataCB           CallbackPasteXClipFunc             = nil
+	callbackLazyXClipHead              CallbackLazyXClipHeadFunc          = nil
+	callbackPairingRequest             CallbackPairingRequestFunc         = nil
 	callbackFileListDropRequestCB      CallbackFileListDropRequestFunc    = nil
 	callbackDragFileListRequestCB      CallbackDragFileListRequestFunc    = nil
 	callbackFileListSendNotify         CallbackFileListNotifyFunc         = nil
@@ -124,6 +139,7 @@ var (
 	callbackNotiMessageFileTransCB     CallbackNotiMessageFileTransFunc   = nil
 	callbackInstanceFileDropResponseCB CallbackFileDropResponseFunc       = nil
 	callbackCancelFileTransDragCB      CallbackCancelFileTransFunc        = nil
+	callbackSetBandwidthLimit          CallbackSetBandwidthLimitFunc      = nil
 	callbackExtractDIASCB              CallbackExtractDIASFunc            = nil
 	callbackGetMacAddressCB            CallbackGetMacAddressFunc          = nil
 	callbackDisplayEvent               CallbackDisplayEventFunc           = nil
@@ -161,6 +177,14 @@ func SetPasteXClipCallback(cb CallbackPasteXClipFunc) {
 	callbackPasteXClipDataCB = cb
 }
 
+func SetLazyXClipHeadCallback(cb CallbackLazyXClipHeadFunc) {
+	callbackLazyXClipHead = cb
+}
+
+func SetPairingRequestCallback(cb CallbackPairingRequestFunc) {
+	callbackPairingRequest = cb
+}
+
 func SetGoFileListDropRequestCallback(cb CallbackFileListDropRequestFunc) {
 	callbackFileListDropRequestCB = cb
 }
@@ -177,6 +201,10 @@ func SetGoCancelFileTransCallback(cb CallbackCancelFileTransFunc) {
 	callbackCancelFileTransDragCB = cb
 }
 
+func SetGoBandwidthLimitCallback(cb CallbackSetBandwidthLimitFunc) {
+	callbackSetBandwidthLimit = cb
+}
+
 func SetGoExtractDIASCallback(cb CallbackExtractDIASFunc) {
 	callbackExtractDIASCB = cb
 }
@@ -314,17 +342,17 @@ func GoCopyXClipData(text, image, html, rtf string) {
 		}
 
 		format, width, height := rtkUtils.GetByteImageInfo(data)
-		jpegData, err := rtkUtils.ImageToJpeg(format, data)
+		clipData, err := rtkUtils.ImageToClipboardFormat(format, data)
 		if err != nil {
 			return
 		}
-		if len(jpegData) == 0 {
-			log.Printf("[CopyXClip] Error: jpeg data is empty")
+		if len(clipData) == 0 {
+			log.Printf("[CopyXClip] Error: image data is empty")
 			return
 		}
 
-		imgData = jpegData
-		log.Printf("image get jpg size:[%d](%d,%d),decode use:[%d]ms", len(imgData), width, height, time.Now().UnixMilli()-startTime)
+		imgData = clipData
+		log.Printf("image get %s size:[%d](%d,%d),decode use:[%d]ms", format, len(imgData), width, height, time.Now().UnixMilli()-startTime)
 	}
 
 	callbackCopyXClipDataCB([]byte(text), imgData, []byte(html), []byte(rtf))
@@ -444,20 +472,6 @@ func GoMultiFilesDropRequest(id, ipAddr string, fileStrList *[]string, timeStamp
 		return rtkCommon.SendFilesRequestSizeOverRange
 	}
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength) //p2p null msg length
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] ID[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), id, len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
-
 	callbackFileListDropRequestCB(id, fileList, folderList, totalSize, timeStamp, totalDesc, srcRootPath)
 	return rtkCommon.SendFilesRequestSuccess
 }
@@ -523,20 +537,6 @@ func GoDragFileListRequest(fileStrList *[]string, timeStamp uint64) rtkCommon.Se
 		return rtkCommon.SendFilesRequestSizeOverRange
 	}
 
-	nMsgLength := int(rtkGlobal.P2PMsgMagicLength) //p2p null msg length
-	for _, file := range fileList {
-		nMsgLength = nMsgLength + len(file.FileName) + rtkGlobal.FileInfoMagicLength
-	}
-
-	for _, folder := range folderList {
-		nMsgLength = nMsgLength + len(folder) + rtkGlobal.StringArrayMagicLength
-	}
-
-	if nMsgLength >= rtkGlobal.P2PMsgMaxLength {
-		log.Printf("[%s] file count:[%d] folder count:[%d], the p2p message is too long and over range!", rtkMisc.GetFuncInfo(), len(fileList), len(folderList))
-		return rtkCommon.SendFilesRequestLengthOverRange
-	}
-
 	callbackDragFileListRequestCB(fileList, folderList, totalSize, timeStamp, totalDesc, srcRootPath)
 	return rtkCommon.SendFilesRequestSuccess
 }
@@ -545,6 +545,15 @@ func GoCancelFileTrans(ip, id string, timestamp int64) {
 	callbackCancelFileTransDragCB(id, ip, uint64(timestamp))
 }
 
+// GoSetBandwidthLimit limits the file transfer speed with the peer ID, empty ID is the global limit of all peers, bytesPerSec <= 0 means no limit
+func GoSetBandwidthLimit(id string, bytesPerSec int64) {
+	if callbackSetBandwidthLimit == nil {
+		log.Println("callbackSetBandwidthLimit is null!")
+		return
+	}
+	callbackSetBandwidthLimit(id, bytesPerSec)
+}
+
 func GoUpdateDownloadPath(path string) {
 	downloadPath = path
 }
@@ -586,10 +595,6 @@ func GoReqSourceAndPort() {
 func GoMonitorNameNotify(name string) {
 }
 
-func GoDIASStatusNotify(diasStatus uint32) {
-	callbackDIASStatus(diasStatus)
-}
-
 func GetAuthData(clientIndex uint32) (rtkMisc.CrossShareErr, rtkMisc.AuthDataInfo) {
 	return rtkMisc.ERR_BIZ_GET_CALLBACK_INSTANCE_NULL, rtkMisc.AuthDataInfo{}
 }
@@ -621,35 +626,21 @@ func GoFileListReceiveNotify(ip, id string, fileCnt uint32, totalSize uint64, ti
 func GoDragFileListFolderNotify(ip, id, folderName string, timestamp uint64) {
 }
 
-func GoSetupDstPasteXClipData(cbText, cbImage, cbHtml, cbRtf []byte) {
-	if callbackPasteXClipDataCB == nil {
-		log.Printf("callbackPasteXClipDataCB is null!\n\n")
-		return
-	}
-
-	imageStr := ""
-	if len(cbImage) > 0 {
-		imageBase64 := rtkUtils.Base64Encode(cbImage)
-		imageStr = imageBase64
-	}
-
-	callbackPasteXClipDataCB(string(cbText), imageStr, string(cbHtml), string(cbRtf))
-}
-
-func GoUpdateSendProgressBar(ip, id, currentFileName string, sendFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, sendSize, timestamp uint64) {
-	if callbackSendProgressBar == nil {
-		log.Println("callbackSendProgressBar is null !")
+func GoNotifyLazyXClipHead(id, head string) {
+	if callbackLazyXClipHead == nil {
+		log.Println("callbackLazyXClipHead is null!")
 		return
 	}
-	callbackSendProgressBar(ip, id, currentFileName, sendFileCnt, totalFileCnt, currentFileSize, totalFileSize, sendSize, timestamp)
+	callbackLazyXClipHead(id, head)
 }
 
-func GoUpdateReceiveProgressBar(ip, id, currentFileName string, recvFileCnt, totalFileCnt uint32, currentFileSize, totalFileSize, recvSize, timestamp uint64) {
-	if callbackReceiveProgressBar == nil {
-		log.Println("callbackReceiveProgressBar is null !")
-		return
+// GoNotifyPairingRequest returns false if the pairing request is not delivered to any handler
+func GoNotifyPairingRequest(id, request string) bool {
+	if callbackPairingRequest == nil {
+		log.Println("callbackPairingRequest is null!")
+		return false
 	}
-	callbackReceiveProgressBar(ip, id, currentFileName, recvFileCnt, totalFileCnt, currentFileSize, totalFileSize, recvSize, timestamp)
+	return callbackPairingRequest(id, request)
 }
 
 func GoUpdateSystemInfo(ipAddr, serviceVer string) {
@@ -657,52 +648,10 @@ func GoUpdateSystemInfo(ipAddr, serviceVer string) {
 	callbackUpdateSystemInfo(ipAddr, serviceVer)
 }
 
-func GoUpdateClientStatusEx(id string, status uint8) {
-	if callbackUpdateClientStatusEx == nil {
-		log.Printf("callbackUpdateClientStatusEx is null!\n\n")
-		return
-	}
-
-	var clientInfo rtkCommon.ClientStatusInfo
-	if status == 1 { // online
-		info, err := rtkUtils.GetClientInfo(id)
-		if err != nil {
-			log.Printf("[%s] err:%+v", rtkMisc.GetFuncInfo(), err)
-			return
-		}
-		clientInfo.ClientInfo = info.ClientInfo
-	} else {
-		clientInfo.ID = id
-	}
-
-	clientInfo.TimeStamp = time.Now().UnixMilli()
-	clientInfo.Status = status
-	encodedData, err := json.Marshal(clientInfo)
-	if err != nil {
-		log.Println("Failed to Marshal ClientStatusInfo data, err:", err)
-		return
-	}
-
-	callbackUpdateClientStatusEx(string(encodedData))
-}
-
 func GoUpdateClientStatus(status uint32, ip, id, name, deviceType string) {
 	callbackUpdateClientStatus(status, ip, id, name, deviceType)
 }
 
-func GoNotiMessageFileTransfer(fileName, clientName, platform string, timestamp uint64, isSender bool) {
-	callbackNotiMessageFileTransCB(fileName, clientName, platform, timestamp, isSender)
-}
-
-func GoNotifyErrEvent(id string, errCode rtkMisc.CrossShareErr, arg1, arg2, arg3, arg4 string) {
-	if callbackNotifyErrEvent == nil {
-		log.Printf("callbackNotifyErrEvent is null!\n")
-		return
-	}
-
-	callbackNotifyErrEvent(id, uint32(errCode), arg1, arg2, arg3, arg4)
-}
-
 func GoRequestUpdateClientVersion(ver string) {
 	if callbackReqClientUpdateVer == nil {
 		log.Printf("callbackReqClientUpdateVer is null!\n")
@@ -716,6 +665,56 @@ func GoCleanClipboard() {
 	callbackCleanClipboard()
 }
 
+// notifyPlatformEvent delivers rtkEvent to the callbacks set by windows UI
+func notifyPlatformEvent(ev rtkEvent.Event) {
+	switch e := ev.(type) {
+	case rtkEvent.TransferProgressEvent:
+		callbackProgressBar := callbackReceiveProgressBar
+		if e.IsSender {
+			callbackProgressBar = callbackSendProgressBar
+		}
+		if callbackProgressBar == nil {
+			log.Printf("progress bar callback is null, isSender:[%+v] !", e.IsSender)
+			return
+		}
+		callbackProgressBar(e.IPAddr, e.ID, e.CurrentFileName, e.FileCnt, e.TotalFileCnt, e.CurrentFileSize, e.TotalSize, e.TransSize, e.TimeStamp)
+	case rtkEvent.TransferDoneEvent:
+		if callbackNotiMessageFileTransCB == nil {
+			log.Println("callbackNotiMessageFileTransCB is null !")
+			return
+		}
+		callbackNotiMessageFileTransCB(e.FileName, e.ClientName, e.Platform, e.TimeStamp, e.IsSender)
+	case rtkEvent.TransferFailedEvent:
+		notifyPlatformEvent(getTransferFailedErrEvent(e))
+	case rtkEvent.ErrorEvent:
+		if callbackNotifyErrEvent == nil {
+			log.Printf("callbackNotifyErrEvent is null!\n")
+			return
+		}
+		callbackNotifyErrEvent(e.ID, uint32(e.Code), e.Arg1, e.Arg2, e.Arg3, e.Arg4)
+	case rtkEvent.PeerStatusEvent:
+		if callbackUpdateClientStatusEx == nil {
+			log.Printf("callbackUpdateClientStatusEx is null!\n\n")
+			return
+		}
+		if clientInfo, ok := getClientStatusInfoJson(e); ok {
+			callbackUpdateClientStatusEx(clientInfo)
+		}
+	case rtkEvent.ClipboardReceivedEvent:
+		if callbackPasteXClipDataCB == nil {
+			log.Printf("callbackPasteXClipDataCB is null!\n\n")
+			return
+		}
+		callbackPasteXClipDataCB(string(e.Text), getXClipImageBase64(e.Image), string(e.Html), string(e.Rtf))
+	case rtkEvent.LanServerStatusEvent:
+		if callbackDIASStatus == nil {
+			log.Printf("[%s] callbackDIASStatus is nil, DIASStatusNotify failed!", rtkMisc.GetFuncInfo())
+			return
+		}
+		callbackDIASStatus(e.Status)
+	}
+}
+
 func GenKey() crypto.PrivKey {
 	return rtkUtils.GenKey(privKeyFile)
 }
@@ -750,6 +749,26 @@ func GetHostIDPath() string {
 	return hostID
 }
 
+func GetTransferJournalPath() string {
+	return transferJournal
+}
+
+func GetClipboardHistoryPath() string {
+	return clipboardHistory
+}
+
+func GetTrustStorePath() string {
+	return trustStore
+}
+
+func GetLanServerKeyStorePath() string {
+	return lanServerKeyStore
+}
+
+func GetTransferHistoryPath() string {
+	return transferHistory
+}
+
 func GetPlatform() string {
 	return rtkMisc.PlatformWindows
 }
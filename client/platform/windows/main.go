@@ -89,6 +89,16 @@ static void SetupDstPasteXClipDataCallbackFunc(SetupDstPasteXClipDataCallback cb
     if (cb) cb(text, image, html,rtf);
 }
 
+typedef void (*LazyXClipHeadCallback)(const char *id,const char *head);
+static void LazyXClipHeadCallbackFunc(LazyXClipHeadCallback cb, const char *id,const char *head) {
+    if (cb) cb(id, head);
+}
+
+typedef void (*PairingRequestCallback)(const char *id,const char *request);
+static void PairingRequestCallbackFunc(PairingRequestCallback cb, const char *id,const char *request) {
+    if (cb) cb(id, request);
+}
+
 typedef void (*RequestUpdateClientVersionCallback)(const char *clienVersion);
 static void RequestUpdateClientVersionCallbackFunc(RequestUpdateClientVersionCallback cb, const char *clienVersion) {
     if (cb) cb(clienVersion);
@@ -105,9 +115,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	rtkClipboard "rtk-cross-share/client/clipboard"
 	rtkCmd "rtk-cross-share/client/cmd"
 	rtkCommon "rtk-cross-share/client/common"
+	rtkConnection "rtk-cross-share/client/connection"
+	rtkDiagnostics "rtk-cross-share/client/diagnostics"
+	rtkFileDrop "rtk-cross-share/client/filedrop"
+	rtkLogin "rtk-cross-share/client/login"
 	rtkPlatform "rtk-cross-share/client/platform"
+	rtkTransferHistory "rtk-cross-share/client/transferHistory"
 	rtkUtils "rtk-cross-share/client/utils"
 	rtkMisc "rtk-cross-share/misc"
 	"strings"
@@ -196,6 +212,8 @@ var (
 	g_DIASStatusCallback                 C.DIASStatusCallback                 = nil
 	g_RequestSourceAndPortCallback       C.RequestSourceAndPortCallback       = nil
 	g_SetupDstPasteXClipDataCallback     C.SetupDstPasteXClipDataCallback     = nil
+	g_LazyXClipHeadCallback              C.LazyXClipHeadCallback              = nil
+	g_PairingRequestCallback             C.PairingRequestCallback             = nil
 	g_RequestUpdateClientVersionCallback C.RequestUpdateClientVersionCallback = nil
 	g_NotifyErrEventCallback             C.NotifyErrEventCallback             = nil
 )
@@ -210,6 +228,8 @@ func init() {
 	rtkPlatform.SetUpdateClientStatusCallback(GoTriggerCallbackUpdateClientStatus)
 	rtkPlatform.SetUpdateClientStatusExCallback(GoTriggerCallbackUpdateClientStatusEx)
 	rtkPlatform.SetPasteXClipCallback(GoTriggerCallbackSetupDstPasteXClipData)
+	rtkPlatform.SetLazyXClipHeadCallback(GoTriggerCallbackLazyXClipHead)
+	rtkPlatform.SetPairingRequestCallback(GoTriggerCallbackPairingRequest)
 	rtkPlatform.SetCleanClipboardCallback(GoTriggerCallbackCleanClipboard)
 	rtkPlatform.SetFileListSendNotifyCallback(GoTriggerCallbackFileListSendNotify)
 	rtkPlatform.SetFileListReceiveNotifyCallback(GoTriggerCallbackFileListReceiveNotify)
@@ -323,6 +343,41 @@ func GoTriggerCallbackSetupDstPasteXClipData(text, image, html, rtf string) {
 	C.SetupDstPasteXClipDataCallbackFunc(g_SetupDstPasteXClipDataCallback, cText, cImage, cHtml, cRtf)
 }
 
+func GoTriggerCallbackLazyXClipHead(id, head string) {
+	if g_LazyXClipHeadCallback == nil {
+		log.Printf("%s g_LazyXClipHeadCallback is not set!", rtkMisc.GetFuncInfo())
+		return
+	}
+	cId := C.CString(id)
+	cHead := C.CString(head)
+
+	defer func() {
+		C.free(unsafe.Pointer(cId))
+		C.free(unsafe.Pointer(cHead))
+	}()
+
+	log.Printf("[%s] ID:[%s] head:%s", rtkMisc.GetFuncInfo(), id, head)
+	C.LazyXClipHeadCallbackFunc(g_LazyXClipHeadCallback, cId, cHead)
+}
+
+func GoTriggerCallbackPairingRequest(id, request string) bool {
+	if g_PairingRequestCallback == nil {
+		log.Printf("%s g_PairingRequestCallback is not set!", rtkMisc.GetFuncInfo())
+		return false
+	}
+	cId := C.CString(id)
+	cRequest := C.CString(request)
+
+	defer func() {
+		C.free(unsafe.Pointer(cId))
+		C.free(unsafe.Pointer(cRequest))
+	}()
+
+	log.Printf("[%s] ID:[%s] request:%s", rtkMisc.GetFuncInfo(), id, request)
+	C.PairingRequestCallbackFunc(g_PairingRequestCallback, cId, cRequest)
+	return true
+}
+
 func GoTriggerCallbackCleanClipboard() {
 	if g_CleanClipboardCallback == nil {
 		log.Printf("%s g_CleanClipboardCallback is not set!", rtkMisc.GetFuncInfo())
@@ -617,6 +672,24 @@ func SetConfirmDocumentsAccept(ifConfirm bool) {
 	//rtkPlatform.SetConfirmDocumentsAccept(ifConfirm)
 }
 
+//export SetFileDropRule
+func SetFileDropRule(cRuleJson *C.char) C.int {
+	if rtkFileDrop.SetFileDropRule(C.GoString(cRuleJson)) {
+		return 1
+	}
+	return 0
+}
+
+//export GetFileDropRule
+func GetFileDropRule() *C.char {
+	return C.CString(rtkFileDrop.GetFileDropRule())
+}
+
+//export SetAllowPlaintextLanServer
+func SetAllowPlaintextLanServer(cAllow C.int) {
+	rtkLogin.SetAllowPlaintextLanServer(int(cAllow) != 0)
+}
+
 //export SetDragFileListRequest
 func SetDragFileListRequest(filePathArry **C.wchar_t, arryLength C.uint32_t, timeStamp C.uint64_t) C.uint {
 	timestamp := uint64(timeStamp)
@@ -647,6 +720,209 @@ func SetCancelFileTransfer(ipPort *C.char, clientID *C.char, timeStamp C.uint64_
 	rtkPlatform.GoCancelFileTrans(cIpAddr, cId, cTimestamp)
 }
 
+//export SetBandwidthLimit
+func SetBandwidthLimit(clientID *C.char, bytesPerSec C.int64_t) {
+	id := C.GoString(clientID)
+	limit := int64(bytesPerSec)
+	log.Printf("[%s] ID:[%s] bytesPerSec:[%d]", rtkMisc.GetFuncInfo(), id, limit)
+	rtkPlatform.GoSetBandwidthLimit(id, limit)
+}
+
+//export GetClipboardHistoryList
+func GetClipboardHistoryList() *C.char {
+	return C.CString(rtkClipboard.GetClipboardHistoryList())
+}
+
+//export GetClipboardHistoryData
+func GetClipboardHistoryData(index C.uint64_t) *C.char {
+	return C.CString(rtkClipboard.GetClipboardHistoryData(uint64(index)))
+}
+
+//export ApplyClipboardHistory
+func ApplyClipboardHistory(index C.uint64_t) C.int {
+	if rtkClipboard.ApplyClipboardHistory(uint64(index)) {
+		return 1
+	}
+	return 0
+}
+
+//export DeleteClipboardHistory
+func DeleteClipboardHistory(index C.uint64_t) C.int {
+	if rtkClipboard.DeleteClipboardHistory(uint64(index)) {
+		return 1
+	}
+	return 0
+}
+
+//export ClearClipboardHistory
+func ClearClipboardHistory() {
+	rtkClipboard.ClearClipboardHistory()
+}
+
+//export SetClipboardHistoryMaxCount
+func SetClipboardHistoryMaxCount(count C.int) {
+	rtkClipboard.SetClipboardHistoryMaxCount(int(count))
+}
+
+//export SetClipboardPolicy
+func SetClipboardPolicy(cPolicyJson *C.char) C.int {
+	if rtkClipboard.SetClipboardPolicy(C.GoString(cPolicyJson)) {
+		return 1
+	}
+	return 0
+}
+
+//export GetClipboardPolicy
+func GetClipboardPolicy() *C.char {
+	return C.CString(rtkClipboard.GetClipboardPolicy())
+}
+
+//export SetLogLevel
+func SetLogLevel(cLevelSpec *C.char) C.int {
+	if rtkMisc.SetLogLevelSpec(C.GoString(cLevelSpec)) {
+		return 1
+	}
+	return 0
+}
+
+//export GetLogLevels
+func GetLogLevels() *C.char {
+	return C.CString(rtkMisc.GetLogLevels())
+}
+
+//export SetLogFormat
+func SetLogFormat(cFormat *C.char) C.int {
+	if rtkMisc.SetLogFormat(C.GoString(cFormat)) {
+		return 1
+	}
+	return 0
+}
+
+//export CollectDiagnostics
+func CollectDiagnostics(cOutputDir *C.char) *C.char {
+	path, _ := rtkDiagnostics.Collect(C.GoString(cOutputDir))
+	return C.CString(path)
+}
+
+//export GetTransferHistoryList
+func GetTransferHistoryList(cFilterJson *C.char) *C.char {
+	return C.CString(rtkTransferHistory.GetTransferHistoryList(C.GoString(cFilterJson)))
+}
+
+//export DeleteTransferHistory
+func DeleteTransferHistory(index C.int64_t) C.int {
+	if rtkTransferHistory.DeleteTransferHistory(int64(index)) {
+		return 1
+	}
+	return 0
+}
+
+//export ClearTransferHistory
+func ClearTransferHistory() {
+	rtkTransferHistory.ClearTransferHistory()
+}
+
+//export SetTransferHistoryLimit
+func SetTransferHistoryLimit(maxCount, maxDays C.int) {
+	rtkTransferHistory.SetTransferHistoryLimit(int(maxCount), int(maxDays))
+}
+
+//export RevealTransferHistory
+func RevealTransferHistory(index C.int64_t) *C.char {
+	return C.CString(rtkTransferHistory.RevealTransferHistory(int64(index)))
+}
+
+//export MarkNextCopyConcealed
+func MarkNextCopyConcealed() {
+	rtkClipboard.MarkNextCopyConcealed()
+}
+
+//export SetClipboardImageFormats
+func SetClipboardImageFormats(cFormatsJson *C.char) C.int {
+	if rtkClipboard.SetClipboardImageFormats(C.GoString(cFormatsJson)) {
+		return 1
+	}
+	return 0
+}
+
+//export SetClipboardImageBudget
+func SetClipboardImageBudget(maxPixels, maxBytes C.int64_t) {
+	rtkClipboard.SetClipboardImageBudget(int64(maxPixels), int64(maxBytes))
+}
+
+//export GetLastClipboardImageInfo
+func GetLastClipboardImageInfo() *C.char {
+	return C.CString(rtkClipboard.GetLastClipboardImageInfo())
+}
+
+//export SetClipboardLazyMode
+func SetClipboardLazyMode(enable C.int) {
+	rtkClipboard.SetClipboardLazyMode(enable != 0)
+}
+
+//export GetLazyXClipHead
+func GetLazyXClipHead() *C.char {
+	return C.CString(rtkClipboard.GetLazyXClipHead())
+}
+
+//export RequestLazyXClipData
+func RequestLazyXClipData() C.int {
+	if rtkClipboard.RequestLazyXClipData() {
+		return 1
+	}
+	return 0
+}
+
+//export CopyXClipItems
+func CopyXClipItems(itemsJson *C.char) C.int {
+	if rtkClipboard.CopyXClipItems(C.GoString(itemsJson)) {
+		return 1
+	}
+	return 0
+}
+
+//export GetLastXClipItems
+func GetLastXClipItems() *C.char {
+	return C.CString(rtkClipboard.GetLastXClipItems())
+}
+
+//export ConfirmPairing
+func ConfirmPairing(id *C.char, isAccepted C.int) C.int {
+	if rtkConnection.ConfirmPairing(C.GoString(id), isAccepted != 0) {
+		return 1
+	}
+	return 0
+}
+
+//export GetTrustedPeerList
+func GetTrustedPeerList() *C.char {
+	return C.CString(rtkConnection.GetTrustedPeerList())
+}
+
+//export RenameTrustedPeer
+func RenameTrustedPeer(id, name *C.char) C.int {
+	if rtkConnection.RenameTrustedPeer(C.GoString(id), C.GoString(name)) {
+		return 1
+	}
+	return 0
+}
+
+//export RevokeTrustedPeer
+func RevokeTrustedPeer(id *C.char) C.int {
+	if rtkConnection.RevokeTrustedPeer(C.GoString(id)) {
+		return 1
+	}
+	return 0
+}
+
+//export DeleteTrustedPeer
+func DeleteTrustedPeer(id *C.char) C.int {
+	if rtkConnection.DeleteTrustedPeer(C.GoString(id)) {
+		return 1
+	}
+	return 0
+}
+
 //export SetMultiFilesDropRequest
 func SetMultiFilesDropRequest(ipPort *C.char, clientID *C.char, timeStamp C.uint64_t, filePathArry **C.wchar_t, arryLength C.uint32_t) C.uint {
 	id := C.GoString(clientID)
@@ -791,6 +1067,18 @@ func SetSetupDstPasteXClipDataCallback(cb C.SetupDstPasteXClipDataCallback) {
 	g_SetupDstPasteXClipDataCallback = cb
 }
 
+//export SetLazyXClipHeadCallback
+func SetLazyXClipHeadCallback(cb C.LazyXClipHeadCallback) {
+	log.Println("SetLazyXClipHeadCallback")
+	g_LazyXClipHeadCallback = cb
+}
+
+//export SetPairingRequestCallback
+func SetPairingRequestCallback(cb C.PairingRequestCallback) {
+	log.Println("SetPairingRequestCallback")
+	g_PairingRequestCallback = cb
+}
+
 //export SetRequestUpdateClientVersionCallback
 func SetRequestUpdateClientVersionCallback(cb C.RequestUpdateClientVersionCallback) {
 	log.Println("SetRequestUpdateClientVersionCallback")
@@ -0,0 +1,236 @@
+package transferHistory
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	rtkEvent "rtk-cross-share/client/event"
+	rtkMisc "rtk-cross-share/misc"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transfer history keeps the ended file transfers in the sqlite database at GetTransferHistoryPath, the newest is the first.
+// It is limited by count and days, the oldest are removed after each transfer is added.
+const (
+	TransferHistoryDefaultMaxCount = 1000
+	TransferHistoryDefaultMaxDays  = 90
+	transferHistoryFileNameMax     = 50 // the file names kept for summary and search
+	transferHistoryDefaultLimit    = 100
+
+	DirectionSend    = "send"
+	DirectionReceive = "receive"
+
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+)
+
+// TransferHistoryItem is an ended file transfer, the times are unix milli and AvgSpeed is bytes per second.
+// LocalPath is the download folder if it's received, and the source root folder if it's sent.
+type TransferHistoryItem struct {
+	Index      int64
+	ID         string
+	ClientName string
+	Platform   string
+	Direction  string
+	TimeStamp  uint64 // the file transfer ID
+	FileCnt    uint32
+	FolderCnt  uint32
+	FileNames  []string
+	TotalDesc  string
+	LocalPath  string
+	TotalSize  uint64
+	TransSize  uint64
+	StartTime  int64
+	EndTime    int64
+	Duration   int64
+	AvgSpeed   uint64
+	ResultCode rtkMisc.CrossShareErr
+}
+
+// TransferHistoryFilter the empty field is not filtered, Keyword is searched in file names, client name and local path
+type TransferHistoryFilter struct {
+	Index     int64
+	ID        string
+	Direction string // DirectionSend or DirectionReceive
+	Result    string // ResultSuccess or ResultFailed
+	Keyword   string
+	StartTime int64 // the range of EndTime in unix milli
+	EndTime   int64
+	Offset    int
+	Limit     int // default is transferHistoryDefaultLimit
+}
+
+type transferKey struct {
+	id        string
+	timestamp uint64
+}
+
+type transferProgress struct {
+	startTime int64
+	transSize uint64
+}
+
+var (
+	transferProgressMap   = make(map[transferKey]transferProgress)
+	transferProgressMutex sync.Mutex
+
+	transferHistoryMaxCount = TransferHistoryDefaultMaxCount
+	transferHistoryMaxDays  = TransferHistoryDefaultMaxDays
+	transferHistoryMutex    sync.RWMutex
+)
+
+func init() {
+	rtkEvent.Subscribe(rtkEvent.Filter{Types: []rtkEvent.EventType{rtkEvent.EventTransferProgress}}, func(ev rtkEvent.Event) {
+		e, ok := ev.(rtkEvent.TransferProgressEvent)
+		if !ok {
+			return
+		}
+		transferProgressMutex.Lock()
+		defer transferProgressMutex.Unlock()
+		key := transferKey{e.ID, e.TimeStamp}
+		progress, exist := transferProgressMap[key]
+		if !exist {
+			progress.startTime = time.Now().UnixMilli()
+		}
+		progress.transSize = e.TransSize
+		transferProgressMap[key] = progress
+	})
+}
+
+func takeTransferProgress(id string, timestamp uint64) (transferProgress, bool) {
+	transferProgressMutex.Lock()
+	defer transferProgressMutex.Unlock()
+	key := transferKey{id, timestamp}
+	progress, ok := transferProgressMap[key]
+	delete(transferProgressMap, key)
+	return progress, ok
+}
+
+// AddTransferHistory adds the ended file transfer, the times, TransSize and AvgSpeed are filled by its progress.
+// The duration is from the first progress to the end, so it's 0 if the transfer fails before any progress.
+func AddTransferHistory(item TransferHistoryItem) {
+	transferHistoryMutex.RLock()
+	maxCount, maxDays := transferHistoryMaxCount, transferHistoryMaxDays
+	transferHistoryMutex.RUnlock()
+
+	item.EndTime = time.Now().UnixMilli()
+	item.StartTime = item.EndTime
+	if progress, ok := takeTransferProgress(item.ID, item.TimeStamp); ok {
+		item.StartTime = progress.startTime
+		item.TransSize = progress.transSize
+	}
+	if item.ResultCode == rtkMisc.SUCCESS {
+		item.TransSize = item.TotalSize
+	}
+	if len(item.FileNames) > transferHistoryFileNameMax {
+		item.FileNames = item.FileNames[:transferHistoryFileNameMax]
+	}
+	if item.Duration = item.EndTime - item.StartTime; item.Duration > 0 {
+		item.AvgSpeed = item.TransSize * 1000 / uint64(item.Duration)
+	}
+	if maxCount <= 0 {
+		return
+	}
+
+	if errCode := insertTransferHistory(&item, maxCount, maxDays); errCode != rtkMisc.SUCCESS {
+		log.Printf("[%s] ID:[%s] timestamp:[%d] add transfer history errCode:[%d]", rtkMisc.GetFuncInfo(), item.ID, item.TimeStamp, errCode)
+		return
+	}
+	log.Printf("[%s] ID:[%s] timestamp:[%d] add transfer history index:[%d] direction:[%s] result:[%d]",
+		rtkMisc.GetFuncInfo(), item.ID, item.TimeStamp, item.Index, item.Direction, item.ResultCode)
+}
+
+// SetTransferHistoryLimit sets the max count and days of history, count 0 disables the history and clears it, days 0 is no time limit
+func SetTransferHistoryLimit(maxCount, maxDays int) {
+	transferHistoryMutex.Lock()
+	transferHistoryMaxCount = max(maxCount, 0)
+	transferHistoryMaxDays = max(maxDays, 0)
+	transferHistoryMutex.Unlock()
+	log.Printf("[%s] transfer history max count:[%d] max days:[%d]", rtkMisc.GetFuncInfo(), maxCount, maxDays)
+
+	if maxCount <= 0 {
+		ClearTransferHistory()
+		return
+	}
+	execDeleteHistory(sqlDeleteOverflowHistory, maxCount)
+	if maxDays > 0 {
+		execDeleteHistory(sqlDeleteExpiredHistory, time.Now().AddDate(0, 0, -maxDays).UnixMilli())
+	}
+}
+
+// QueryTransferHistory returns the history matched filter, the newest is the first
+func QueryTransferHistory(filter TransferHistoryFilter) ([]TransferHistoryItem, rtkMisc.CrossShareErr) {
+	if filter.Limit <= 0 {
+		filter.Limit = transferHistoryDefaultLimit
+	}
+	filter.Offset = max(filter.Offset, 0)
+	filter.Keyword = strings.TrimSpace(filter.Keyword)
+	return queryTransferHistory(&filter)
+}
+
+// GetTransferHistoryList returns JSON of []TransferHistoryItem, filterJson is TransferHistoryFilter and empty is all
+func GetTransferHistoryList(filterJson string) string {
+	var filter TransferHistoryFilter
+	if filterJson != "" {
+		if err := json.Unmarshal([]byte(filterJson), &filter); err != nil {
+			log.Printf("[%s] Unmarshal filter:[%s] err:%+v", rtkMisc.GetFuncInfo(), filterJson, err)
+			return ""
+		}
+	}
+
+	itemList, errCode := QueryTransferHistory(filter)
+	if errCode != rtkMisc.SUCCESS {
+		return ""
+	}
+	encodedData, err := json.Marshal(itemList)
+	if err != nil {
+		log.Printf("[%s] Marshal transfer history list err:%+v", rtkMisc.GetFuncInfo(), err)
+		return ""
+	}
+	return string(encodedData)
+}
+
+func DeleteTransferHistory(index int64) bool {
+	nCount, errCode := execDeleteHistory(sqlDeleteHistory, index)
+	return errCode == rtkMisc.SUCCESS && nCount > 0
+}
+
+func ClearTransferHistory() {
+	if _, errCode := execDeleteHistory(sqlDeleteAllHistory); errCode == rtkMisc.SUCCESS {
+		log.Printf("[%s] clear transfer history success", rtkMisc.GetFuncInfo())
+	}
+}
+
+// RevealTransferHistory returns the local path of history to be shown in file manager, empty if it's not found or not exist.
+// The received is the first file or folder in download folder, or the download folder if it is moved.
+func RevealTransferHistory(index int64) string {
+	if index <= 0 {
+		return ""
+	}
+	itemList, errCode := QueryTransferHistory(TransferHistoryFilter{Index: index, Limit: 1})
+	if errCode != rtkMisc.SUCCESS || len(itemList) == 0 {
+		log.Printf("[%s] transfer history index:[%d] is not found", rtkMisc.GetFuncInfo(), index)
+		return ""
+	}
+
+	item := itemList[0]
+	pathList := make([]string, 0, 2)
+	if item.Direction == DirectionReceive && len(item.FileNames) > 0 {
+		topName, _, _ := strings.Cut(filepath.ToSlash(item.FileNames[0]), "/")
+		pathList = append(pathList, filepath.Join(item.LocalPath, topName))
+	}
+	pathList = append(pathList, item.LocalPath)
+	for _, path := range pathList {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	log.Printf("[%s] transfer history index:[%d] path:[%s] is not exist", rtkMisc.GetFuncInfo(), index, item.LocalPath)
+	return ""
+}
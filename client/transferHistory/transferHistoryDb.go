@@ -0,0 +1,270 @@
+package transferHistory
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	rtkPlatform "rtk-cross-share/client/platform"
+	rtkMisc "rtk-cross-share/misc"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+const (
+	sqlCreateTable = `
+		CREATE TABLE IF NOT EXISTS t_transfer_history (
+			PkIndex		INTEGER PRIMARY KEY AUTOINCREMENT,
+			ClientId	TEXT NOT NULL,
+			ClientName	TEXT NOT NULL DEFAULT '',
+			Platform	TEXT NOT NULL DEFAULT '',
+			Direction	TEXT NOT NULL,
+			TimeStamp	INTEGER NOT NULL,
+			FileCnt		INTEGER NOT NULL DEFAULT 0,
+			FolderCnt	INTEGER NOT NULL DEFAULT 0,
+			FileNames	TEXT NOT NULL DEFAULT '[]',
+			TotalDesc	TEXT NOT NULL DEFAULT '',
+			LocalPath	TEXT NOT NULL DEFAULT '',
+			TotalSize	INTEGER NOT NULL DEFAULT 0,
+			TransSize	INTEGER NOT NULL DEFAULT 0,
+			StartTime	INTEGER NOT NULL,
+			EndTime		INTEGER NOT NULL,
+			AvgSpeed	INTEGER NOT NULL DEFAULT 0,
+			ResultCode	INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_transfer_history_end_time ON t_transfer_history (EndTime);`
+
+	sqlInsertHistory = `
+		INSERT INTO t_transfer_history (ClientId, ClientName, Platform, Direction, TimeStamp, FileCnt, FolderCnt, FileNames,
+			TotalDesc, LocalPath, TotalSize, TransSize, StartTime, EndTime, AvgSpeed, ResultCode)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	sqlQueryHistory = `
+		SELECT PkIndex, ClientId, ClientName, Platform, Direction, TimeStamp, FileCnt, FolderCnt, FileNames,
+			TotalDesc, LocalPath, TotalSize, TransSize, StartTime, EndTime, AvgSpeed, ResultCode
+		FROM t_transfer_history`
+
+	sqlDeleteExpiredHistory  = `DELETE FROM t_transfer_history WHERE EndTime < ?`
+	sqlDeleteOverflowHistory = `
+		DELETE FROM t_transfer_history WHERE PkIndex NOT IN (
+			SELECT PkIndex FROM t_transfer_history ORDER BY EndTime DESC, PkIndex DESC LIMIT ?)`
+	sqlDeleteHistory    = `DELETE FROM t_transfer_history WHERE PkIndex = ?`
+	sqlDeleteAllHistory = `DELETE FROM t_transfer_history`
+)
+
+var (
+	historyDb *sql.DB
+	dbMutex   sync.Mutex
+)
+
+// getDb opens the database at GetTransferHistoryPath when it's used first time, it must be called with dbMutex locked
+func getDb() (*sql.DB, bool) {
+	if historyDb != nil {
+		return historyDb, true
+	}
+
+	dbPath := rtkPlatform.GetTransferHistoryPath()
+	if dbPath == "" || !filepath.IsAbs(dbPath) {
+		log.Printf("[%s] invalid transfer history path:[%s]", rtkMisc.GetFuncInfo(), dbPath)
+		return nil, false
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		log.Printf("[%s] create transfer history folder err:%+v", rtkMisc.GetFuncInfo(), err)
+		return nil, false
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		log.Printf("[%s] open transfer history:[%s] err:%+v", rtkMisc.GetFuncInfo(), dbPath, err)
+		return nil, false
+	}
+	db.SetMaxOpenConns(1) // all of queries are serialized by dbMutex
+	if _, err = db.Exec(sqlCreateTable); err != nil {
+		log.Printf("[%s] create transfer history table err:%+v", rtkMisc.GetFuncInfo(), err)
+		db.Close()
+		return nil, false
+	}
+
+	historyDb = db
+	log.Printf("[%s] open transfer history:[%s] success", rtkMisc.GetFuncInfo(), dbPath)
+	return historyDb, true
+}
+
+func insertTransferHistory(item *TransferHistoryItem, maxCount, maxDays int) rtkMisc.CrossShareErr {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	db, ok := getDb()
+	if !ok {
+		return rtkMisc.ERR_DB_SQLITE_INSTANCE_NULL
+	}
+	fileNames, err := marshalFileNames(item.FileNames)
+	if err != nil {
+		log.Printf("[%s] Marshal file names err:%+v", rtkMisc.GetFuncInfo(), err)
+		return rtkMisc.ERR_DB_SQLITE_INVALID_ARGS
+	}
+
+	result, err := db.Exec(sqlInsertHistory, item.ID, item.ClientName, item.Platform, item.Direction, int64(item.TimeStamp),
+		item.FileCnt, item.FolderCnt, string(fileNames), item.TotalDesc, item.LocalPath, int64(item.TotalSize), int64(item.TransSize),
+		item.StartTime, item.EndTime, int64(item.AvgSpeed), int(item.ResultCode))
+	if err != nil {
+		log.Printf("[%s] Exec err:%+v", rtkMisc.GetFuncInfo(), err)
+		return rtkMisc.ERR_DB_SQLITE_EXEC
+	}
+	if item.Index, err = result.LastInsertId(); err != nil {
+		log.Printf("[%s] LastInsertId err:%+v", rtkMisc.GetFuncInfo(), err)
+		return rtkMisc.ERR_DB_SQLITE_LAST_INSERTID
+	}
+
+	if maxDays > 0 {
+		if _, err = db.Exec(sqlDeleteExpiredHistory, time.Now().AddDate(0, 0, -maxDays).UnixMilli()); err != nil {
+			log.Printf("[%s] delete expired history err:%+v", rtkMisc.GetFuncInfo(), err)
+		}
+	}
+	if _, err = db.Exec(sqlDeleteOverflowHistory, maxCount); err != nil {
+		log.Printf("[%s] delete overflow history err:%+v", rtkMisc.GetFuncInfo(), err)
+	}
+	return rtkMisc.SUCCESS
+}
+
+// marshalFileNames keeps '&', '<' and '>' as they are, so the keyword is found in FileNames column
+func marshalFileNames(fileNames []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(fileNames); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// getFileNamesKeyword returns the keyword escaped as a string in FileNames column, e.g. '"' and '\' are escaped by '\'
+func getFileNamesKeyword(keyword string) string {
+	encodedKeyword, err := marshalFileNames([]string{keyword})
+	if err != nil {
+		return keyword
+	}
+	return string(encodedKeyword[2 : len(encodedKeyword)-2]) // trim '["' and '"]'
+}
+
+// escapeLike escapes the wildcards of LIKE pattern by '\'
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
+
+func buildQueryCond(filter *TransferHistoryFilter) (string, []any) {
+	condList := make([]string, 0)
+	args := make([]any, 0)
+	if filter.Index > 0 {
+		condList = append(condList, "PkIndex = ?")
+		args = append(args, filter.Index)
+	}
+	if filter.ID != "" {
+		condList = append(condList, "ClientId = ?")
+		args = append(args, filter.ID)
+	}
+	if filter.Direction != "" {
+		condList = append(condList, "Direction = ?")
+		args = append(args, filter.Direction)
+	}
+	if filter.Result == ResultSuccess {
+		condList = append(condList, "ResultCode = ?")
+		args = append(args, int(rtkMisc.SUCCESS))
+	} else if filter.Result == ResultFailed {
+		condList = append(condList, "ResultCode != ?")
+		args = append(args, int(rtkMisc.SUCCESS))
+	}
+	if filter.StartTime > 0 {
+		condList = append(condList, "EndTime >= ?")
+		args = append(args, filter.StartTime)
+	}
+	if filter.EndTime > 0 {
+		condList = append(condList, "EndTime <= ?")
+		args = append(args, filter.EndTime)
+	}
+	if filter.Keyword != "" {
+		pattern := "%" + escapeLike(filter.Keyword) + "%"
+		fileNamesPattern := "%" + escapeLike(getFileNamesKeyword(filter.Keyword)) + "%"
+		condList = append(condList, `(FileNames LIKE ? ESCAPE '\' OR ClientName LIKE ? ESCAPE '\' OR LocalPath LIKE ? ESCAPE '\')`)
+		args = append(args, fileNamesPattern, pattern, pattern)
+	}
+
+	if len(condList) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(condList, " AND "), args
+}
+
+func queryTransferHistory(filter *TransferHistoryFilter) ([]TransferHistoryItem, rtkMisc.CrossShareErr) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	db, ok := getDb()
+	if !ok {
+		return nil, rtkMisc.ERR_DB_SQLITE_INSTANCE_NULL
+	}
+
+	where, args := buildQueryCond(filter)
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := db.Query(sqlQueryHistory+where+" ORDER BY EndTime DESC, PkIndex DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		log.Printf("[%s] Query err:%+v", rtkMisc.GetFuncInfo(), err)
+		return nil, rtkMisc.ERR_DB_SQLITE_QUERY
+	}
+	defer rows.Close()
+
+	itemList := make([]TransferHistoryItem, 0)
+	for rows.Next() {
+		var item TransferHistoryItem
+		var timeStamp, totalSize, transSize, avgSpeed int64
+		var fileNames string
+		var resultCode int
+		if err = rows.Scan(&item.Index, &item.ID, &item.ClientName, &item.Platform, &item.Direction, &timeStamp, &item.FileCnt, &item.FolderCnt,
+			&fileNames, &item.TotalDesc, &item.LocalPath, &totalSize, &transSize, &item.StartTime, &item.EndTime, &avgSpeed, &resultCode); err != nil {
+			log.Printf("[%s] Scan err:%+v", rtkMisc.GetFuncInfo(), err)
+			return nil, rtkMisc.ERR_DB_SQLITE_SCAN
+		}
+		item.TimeStamp = uint64(timeStamp)
+		item.TotalSize = uint64(totalSize)
+		item.TransSize = uint64(transSize)
+		item.AvgSpeed = uint64(avgSpeed)
+		item.ResultCode = rtkMisc.CrossShareErr(resultCode)
+		item.Duration = item.EndTime - item.StartTime
+		if err = json.Unmarshal([]byte(fileNames), &item.FileNames); err != nil {
+			log.Printf("[%s] Index:[%d] Unmarshal file names err:%+v", rtkMisc.GetFuncInfo(), item.Index, err)
+		}
+		itemList = append(itemList, item)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("[%s] rows err:%+v", rtkMisc.GetFuncInfo(), err)
+		return nil, rtkMisc.ERR_DB_SQLITE_ROWS
+	}
+	return itemList, rtkMisc.SUCCESS
+}
+
+func execDeleteHistory(query string, args ...any) (int64, rtkMisc.CrossShareErr) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	db, ok := getDb()
+	if !ok {
+		return 0, rtkMisc.ERR_DB_SQLITE_INSTANCE_NULL
+	}
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		log.Printf("[%s] Exec err:%+v", rtkMisc.GetFuncInfo(), err)
+		return 0, rtkMisc.ERR_DB_SQLITE_EXEC
+	}
+	nCount, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("[%s] RowsAffected err:%+v", rtkMisc.GetFuncInfo(), err)
+		return 0, rtkMisc.ERR_DB_SQLITE_EXEC
+	}
+	return nCount, rtkMisc.SUCCESS
+}
@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	rtkCommon "rtk-cross-share/client/common"
+	rtkMisc "rtk-cross-share/misc"
+	"time"
+)
+
+// Clipboard image transcoding: BMP codec registered to image package, downscale and convert to the format accepted by peer
+const (
+	bmpFileHeaderSize      = 14
+	bmpInfoHeaderSize      = 40
+	bmpCompressRGB         = 0
+	bmpCompressBitFields   = 3
+	kImgTranscodeMaxRetry  = 4
+	kImgTranscodeScaleStep = 0.9 // the extra scale for byte budget, so the retry count is small
+)
+
+type bmpInfo struct {
+	width      int
+	height     int
+	isTopDown  bool
+	bitCount   int
+	dataOffset int
+	masks      [4]uint32 // R, G, B, A
+}
+
+func init() {
+	image.RegisterFormat(rtkCommon.ImageFormatBmp, "BM", decodeBmp, decodeBmpConfig)
+}
+
+func readBmpInfo(data []byte) (bmpInfo, error) {
+	var info bmpInfo
+	if len(data) < bmpFileHeaderSize+bmpInfoHeaderSize || data[0] != 'B' || data[1] != 'M' {
+		return info, errors.New("invalid bmp header")
+	}
+	info.dataOffset = int(binary.LittleEndian.Uint32(data[10:14]))
+	header := data[bmpFileHeaderSize:]
+	headerSize := int(binary.LittleEndian.Uint32(header[0:4]))
+	if headerSize < bmpInfoHeaderSize || bmpFileHeaderSize+headerSize > len(data) {
+		return info, fmt.Errorf("unsupported bmp info header size:[%d]", headerSize)
+	}
+	info.width = int(int32(binary.LittleEndian.Uint32(header[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(header[8:12])))
+	info.isTopDown = height < 0
+	info.height = max(height, -height)
+	info.bitCount = int(binary.LittleEndian.Uint16(header[14:16]))
+	compression := binary.LittleEndian.Uint32(header[16:20])
+	if info.width <= 0 || info.height <= 0 {
+		return info, fmt.Errorf("invalid bmp size:(%d,%d)", info.width, info.height)
+	}
+
+	switch {
+	case info.bitCount == 24 && compression == bmpCompressRGB:
+	case info.bitCount == 32 && compression == bmpCompressRGB:
+		info.masks = [4]uint32{0x00FF0000, 0x0000FF00, 0x000000FF, 0xFF000000}
+	case info.bitCount == 32 && compression == bmpCompressBitFields:
+		masks := header[bmpInfoHeaderSize:] // V4/V5 header has the masks inside, BITMAPINFOHEADER has them after
+		if len(masks) < 12 {
+			return info, errors.New("invalid bmp bit fields")
+		}
+		for i := 0; i < 3; i++ {
+			info.masks[i] = binary.LittleEndian.Uint32(masks[i*4 : i*4+4])
+		}
+		if headerSize >= bmpInfoHeaderSize+16 {
+			info.masks[3] = binary.LittleEndian.Uint32(masks[12:16])
+		}
+	default:
+		return info, fmt.Errorf("unsupported bmp bit count:[%d] compression:[%d]", info.bitCount, compression)
+	}
+	return info, nil
+}
+
+func decodeBmpConfig(r io.Reader) (image.Config, error) {
+	header := make([]byte, bmpFileHeaderSize+bmpInfoHeaderSize+16)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return image.Config{}, err
+	}
+	info, err := readBmpInfo(header[:n])
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: info.width, Height: info.height}, nil
+}
+
+func getMaskValue(pixel, mask uint32) uint8 {
+	if mask == 0 {
+		return 0
+	}
+	shift := 0
+	for mask&1 == 0 {
+		mask >>= 1
+		shift++
+	}
+	return uint8(uint64((pixel>>shift)&mask) * 255 / uint64(mask))
+}
+
+func decodeBmp(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	info, err := readBmpInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	bytesPerPixel := info.bitCount / 8
+	rowSize := (info.width*bytesPerPixel + 3) &^ 3
+	if info.dataOffset < 0 || info.dataOffset+rowSize*info.height > len(data) {
+		return nil, errors.New("invalid bmp data size")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, info.width, info.height))
+	isAlphaUsed := false
+	for y := 0; y < info.height; y++ {
+		srcY := info.height - 1 - y
+		if info.isTopDown {
+			srcY = y
+		}
+		row := data[info.dataOffset+srcY*rowSize:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < info.width; x++ {
+			if bytesPerPixel == 3 {
+				dst[x*4+0], dst[x*4+1], dst[x*4+2], dst[x*4+3] = row[x*3+2], row[x*3+1], row[x*3], 0xFF
+				continue
+			}
+			pixel := binary.LittleEndian.Uint32(row[x*4:])
+			dst[x*4+0] = getMaskValue(pixel, info.masks[0])
+			dst[x*4+1] = getMaskValue(pixel, info.masks[1])
+			dst[x*4+2] = getMaskValue(pixel, info.masks[2])
+			dst[x*4+3] = getMaskValue(pixel, info.masks[3])
+			isAlphaUsed = isAlphaUsed || dst[x*4+3] != 0
+		}
+	}
+	if bytesPerPixel == 4 && !isAlphaUsed { // the alpha of most 32 bits bitmap is reserved as 0
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xFF
+		}
+	}
+	return img, nil
+}
+
+// encodeBmp writes 32 bits BGRA bottom-up bitmap
+func encodeBmp(img image.Image) []byte {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	dataOffset := bmpFileHeaderSize + bmpInfoHeaderSize
+	data := make([]byte, dataOffset+width*height*4)
+	data[0], data[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(data[2:6], uint32(len(data)))
+	binary.LittleEndian.PutUint32(data[10:14], uint32(dataOffset))
+	header := data[bmpFileHeaderSize:]
+	binary.LittleEndian.PutUint32(header[0:4], bmpInfoHeaderSize)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], 32)
+	binary.LittleEndian.PutUint32(header[20:24], uint32(width*height*4))
+
+	for y := 0; y < height; y++ {
+		src := nrgba.Pix[y*nrgba.Stride:]
+		dst := data[dataOffset+(height-1-y)*width*4:]
+		for x := 0; x < width; x++ {
+			dst[x*4+0], dst[x*4+1], dst[x*4+2], dst[x*4+3] = src[x*4+2], src[x*4+1], src[x*4], src[x*4+3]
+		}
+	}
+	return data
+}
+
+// scaleImage downscales img to width x height by averaging the source pixels of every target pixel
+func scaleImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += uint64(row[sx*4+c])
+					}
+				}
+			}
+			count := uint64((y1 - y0) * (x1 - x0))
+			for c := 0; c < 4; c++ {
+				dst.Pix[y*dst.Stride+x*4+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+	return dst
+}
+
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	switch format {
+	case rtkCommon.ImageFormatBmp:
+		return encodeBmp(img), nil
+	case rtkCommon.ImageFormatPng:
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case rtkCommon.ImageFormatJpeg:
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: kImgQuality}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported image format:[%s]", format)
+}
+
+// ImageToClipboardFormat keeps the image in png, jpeg or bmp as it is, and converts the other formats to png
+func ImageToClipboardFormat(format string, data []byte) ([]byte, error) {
+	switch format {
+	case rtkCommon.ImageFormatPng, rtkCommon.ImageFormatJpeg, rtkCommon.ImageFormatBmp:
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("[%s] decode img err: %v", rtkMisc.GetFuncInfo(), err)
+		return nil, err
+	}
+	return encodeImage(img, rtkCommon.ImageFormatPng)
+}
+
+// TranscodeImage converts the image to dstFormat, and downscales it if it has more than maxPixels or the output has more than maxBytes, 0 means no limit.
+// The data is returned as it is if it is dstFormat already and in the budget.
+func TranscodeImage(data []byte, dstFormat string, maxPixels, maxBytes int64) ([]byte, int, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	width, height := cfg.Width, cfg.Height
+	isOverPixels := maxPixels > 0 && int64(width)*int64(height) > maxPixels
+	if format == dstFormat && !isOverPixels && (maxBytes <= 0 || int64(len(data)) <= maxBytes) {
+		return data, width, height, nil
+	}
+
+	startTime := time.Now().UnixMilli()
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	scaleSize := func(scale float64) {
+		width, height = max(int(float64(width)*scale), 1), max(int(float64(height)*scale), 1)
+	}
+	if isOverPixels {
+		scaleSize(math.Sqrt(float64(maxPixels) / float64(int64(width)*int64(height))))
+	}
+
+	var output []byte
+	for i := 0; ; i++ {
+		var dstImg image.Image = img
+		if width != img.Bounds().Dx() || height != img.Bounds().Dy() {
+			dstImg = scaleImage(img, width, height)
+		}
+		if output, err = encodeImage(dstImg, dstFormat); err != nil {
+			return nil, 0, 0, err
+		}
+		if maxBytes <= 0 || int64(len(output)) <= maxBytes || i >= kImgTranscodeMaxRetry {
+			break
+		}
+		scaleSize(math.Sqrt(float64(maxBytes)/float64(len(output))) * kImgTranscodeScaleStep)
+	}
+
+	log.Printf("[%s] transcode %s(%d,%d) size:[%d] to %s(%d,%d) size:[%d] use [%d] ms", rtkMisc.GetFuncInfo(), format, cfg.Width, cfg.Height, len(data),
+		dstFormat, width, height, len(output), time.Now().UnixMilli()-startTime)
+	return output, width, height, nil
+}
@@ -16,6 +16,7 @@ import (
 	rtkCommon "rtk-cross-share/client/common"
 	rtkGlobal "rtk-cross-share/client/global"
 	rtkMisc "rtk-cross-share/misc"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -266,16 +267,59 @@ func GetClientIp(id string) (string, bool) {
 	return "", false
 }
 
-func InsertClientInfoMap(id, ipAddr, platform, name, srcPortType, ver, fileTransId, udpPort string) {
+// GetClientCapabilities returns the capabilities advertised to peers, with the accepted clipboard image formats
+func GetClientCapabilities() []string {
+	capabilities := slices.Clone(rtkGlobal.ClientCapabilities)
+	for _, format := range rtkGlobal.ClientImageFormats {
+		capabilities = append(capabilities, rtkCommon.CapabilityImagePrefix+format)
+	}
+	return capabilities
+}
+
+// getImageFormats returns the image formats in the capabilities advertised by peer, nil if there is none
+func getImageFormats(capabilities []string) []string {
+	var imageFormats []string
+	for _, capability := range capabilities {
+		if format, ok := strings.CutPrefix(capability, rtkCommon.CapabilityImagePrefix); ok && !slices.Contains(imageFormats, format) {
+			imageFormats = append(imageFormats, format)
+		}
+	}
+	return imageFormats
+}
+
+// NegotiateCapabilities returns the capabilities supported by both this client and the peer
+func NegotiateCapabilities(peerCapabilities []string) []string {
+	negotiated := make([]string, 0, len(peerCapabilities))
+	for _, capability := range peerCapabilities {
+		if slices.Contains(rtkGlobal.ClientCapabilities, capability) && !slices.Contains(negotiated, capability) {
+			negotiated = append(negotiated, capability)
+		}
+	}
+	return negotiated
+}
+
+func InsertClientInfoMap(id, ipAddr, platform, name, srcPortType, ver, fileTransId, udpPort string, capabilities []string) {
 	rtkGlobal.ClientListRWMutex.Lock()
 	defer rtkGlobal.ClientListRWMutex.Unlock()
 
-	peerVerSerial := rtkMisc.GetVersionSerialValue(ver)
-	isSupportXClip := peerVerSerial >= rtkGlobal.ClientXClipVerSerial
-	isSupportQueueFileTrans := peerVerSerial >= rtkGlobal.ClientQueueFileTransVerSerial
-	isRmFileCountLimit := peerVerSerial >= rtkGlobal.ClientRmFileLimitVerSerial
+	var isSupportXClip, isSupportQueueFileTrans, isRmFileCountLimit, isSupportQuicXClip bool
+	var negotiated []string
+	if capabilities == nil { // old version peer without capability list, fall back to version rules
+		peerVerSerial := rtkMisc.GetVersionSerialValue(ver)
+		isSupportXClip = peerVerSerial >= rtkGlobal.ClientXClipVerSerial
+		isSupportQueueFileTrans = peerVerSerial >= rtkGlobal.ClientQueueFileTransVerSerial
+		isRmFileCountLimit = peerVerSerial >= rtkGlobal.ClientRmFileLimitVerSerial
+	} else {
+		negotiated = NegotiateCapabilities(capabilities)
+		isSupportXClip = slices.Contains(negotiated, rtkCommon.CapabilityXClip)
+		isSupportQueueFileTrans = slices.Contains(negotiated, rtkCommon.CapabilityQueueFileTrans)
+		isRmFileCountLimit = slices.Contains(negotiated, rtkCommon.CapabilityRmFileCntLimit)
+		isSupportQuicXClip = slices.Contains(negotiated, rtkCommon.CapabilityQuicXClip)
+	}
 
-	log.Printf("ID:[%s] version:[%s] Supported: XClip[%v], QueueFileTrans[%v], RmFileCountLimit:[%v]", id, ver, isSupportXClip, isSupportQueueFileTrans, isRmFileCountLimit)
+	imageFormats := getImageFormats(capabilities)
+	log.Printf("ID:[%s] version:[%s] capabilities:%v negotiated:%v Supported: XClip[%v], QueueFileTrans[%v], RmFileCountLimit:[%v], QuicXClip:[%v] ImageFormats:%v",
+		id, ver, capabilities, negotiated, isSupportXClip, isSupportQueueFileTrans, isRmFileCountLimit, isSupportQuicXClip, imageFormats)
 
 	rtkGlobal.ClientInfoMap[id] = rtkCommon.ClientInfoEx{
 		ClientInfo: rtkMisc.ClientInfo{
@@ -289,11 +333,26 @@ func InsertClientInfoMap(id, ipAddr, platform, name, srcPortType, ver, fileTrans
 		IsSupportXClip:      isSupportXClip,
 		IsSupportQueueTrans: isSupportQueueFileTrans,
 		IsRmFileCntLimit:    isRmFileCountLimit,
+		IsSupportQuicXClip:  isSupportQuicXClip,
+		Capabilities:        negotiated,
+		ImageFormats:        imageFormats,
 		FileTransNodeID:     fileTransId,
 		UpdPort:             udpPort,
 	}
 }
 
+func GetClientIdByFileTransId(fileTransId string) (string, bool) {
+	rtkGlobal.ClientListRWMutex.RLock()
+	defer rtkGlobal.ClientListRWMutex.RUnlock()
+
+	for id, val := range rtkGlobal.ClientInfoMap {
+		if val.FileTransNodeID == fileTransId {
+			return id, true
+		}
+	}
+	return "", false
+}
+
 func LostClientInfoMap(id string) {
 	rtkGlobal.ClientListRWMutex.Lock()
 	defer rtkGlobal.ClientListRWMutex.Unlock()
@@ -402,6 +461,43 @@ func GetPeerClientIsRmFCL(id string) bool {
 	return clientInfo.IsRmFileCntLimit
 }
 
+func GetPeerClientIsSupportQuicXClip(id string) bool {
+	rtkGlobal.ClientListRWMutex.RLock()
+	defer rtkGlobal.ClientListRWMutex.RUnlock()
+	clientInfo, ok := rtkGlobal.ClientInfoMap[id]
+	if !ok {
+		log.Printf("[%s] not found ClientInfo by id:%s", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+
+	return clientInfo.IsSupportQuicXClip
+}
+
+// GetPeerClientIsSupportCapability reports whether capability is negotiated with the peer, always false for old version peers
+func GetPeerClientIsSupportCapability(id, capability string) bool {
+	rtkGlobal.ClientListRWMutex.RLock()
+	defer rtkGlobal.ClientListRWMutex.RUnlock()
+	clientInfo, ok := rtkGlobal.ClientInfoMap[id]
+	if !ok {
+		log.Printf("[%s] not found ClientInfo by id:%s", rtkMisc.GetFuncInfo(), id)
+		return false
+	}
+
+	return slices.Contains(clientInfo.Capabilities, capability)
+}
+
+func GetPeerClientImageFormats(id string) []string {
+	rtkGlobal.ClientListRWMutex.RLock()
+	defer rtkGlobal.ClientListRWMutex.RUnlock()
+	clientInfo, ok := rtkGlobal.ClientInfoMap[id]
+	if !ok {
+		log.Printf("[%s] not found ClientInfo by id:%s", rtkMisc.GetFuncInfo(), id)
+		return nil
+	}
+
+	return clientInfo.ImageFormats
+}
+
 func WalkPath(dirPath string, pathList *[]string, fileInfoList *[]rtkCommon.FileInfo, totalSize *uint64) error {
 	rootPath := filepath.Dir(dirPath)
 
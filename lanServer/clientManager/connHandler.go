@@ -3,9 +3,12 @@ package clientManager
 import (
 	"bufio"
 	"bytes"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	rtkMisc "rtk-cross-share/misc"
+	"sort"
 	"sync"
 )
 
@@ -58,6 +61,30 @@ func getConn(id string) (net.Conn, bool) {
 	return nil, false
 }
 
+func GetConnectedClientCount() int {
+	clientConnMutex.RLock()
+	defer clientConnMutex.RUnlock()
+	return len(clientConnMap)
+}
+
+// DumpClientConn writes the connected clients sorted by ID, it's for diagnostics
+func DumpClientConn(w io.Writer) error {
+	clientConnMutex.RLock()
+	idList := make([]string, 0, len(clientConnMap))
+	for id := range clientConnMap {
+		idList = append(idList, id)
+	}
+	sort.Strings(idList)
+	for _, id := range idList {
+		client := clientConnMap[id]
+		fmt.Fprintf(w, "ID:[%s] IPAddr:[%s] timestamp:[%d]\n", id, client.conn.RemoteAddr(), client.timeStamp)
+	}
+	clientConnMutex.RUnlock()
+
+	_, err := fmt.Fprintf(w, "total:[%d]\n", len(idList))
+	return err
+}
+
 func write(b []byte, id string, timestamp int64) rtkMisc.CrossShareErr {
 	clientConnMutex.RLock()
 	defer clientConnMutex.RUnlock()
@@ -72,14 +99,18 @@ func write(b []byte, id string, timestamp int64) rtkMisc.CrossShareErr {
 		return rtkMisc.ERR_BIZ_S2C_GET_CONNECT_RESET
 	}
 
+	return writeToConn(clientInfo.conn, b, id)
+}
+
+func writeToConn(conn net.Conn, b []byte, id string) rtkMisc.CrossShareErr {
 	encodedData := bytes.Trim(b, "\x00")
-	_, err := clientInfo.conn.Write(append(encodedData, '\n'))
+	_, err := conn.Write(append(encodedData, '\n'))
 	if err != nil {
 		log.Printf("[%s] ID:[%s] write Error:%+v ", rtkMisc.GetFuncInfo(), id, err.Error())
 		return rtkMisc.ERR_NETWORK_S2C_WRITE
 	}
 
-	err = bufio.NewWriter(clientInfo.conn).Flush()
+	err = bufio.NewWriter(conn).Flush()
 	if err != nil {
 		log.Printf("[%s] ID:[%s] Flush Error:%+v ", rtkMisc.GetFuncInfo(), id, err.Error())
 		return rtkMisc.ERR_NETWORK_S2C_FLUSH
@@ -7,10 +7,12 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"log/slog"
 	"net"
 	rtkCommon "rtk-cross-share/lanServer/common"
 	rtkdbManager "rtk-cross-share/lanServer/dbManager"
 	rtkGlobal "rtk-cross-share/lanServer/global"
+	rtkMetrics "rtk-cross-share/lanServer/metrics"
 	rtkMisc "rtk-cross-share/misc"
 	"syscall"
 	"time"
@@ -20,6 +22,8 @@ const (
 	periodicNotifyInternal = 5 * time.Second
 )
 
+var logger = rtkMisc.NewLogger("clientManager")
+
 // =============================
 // CaptureColorData get event
 // =============================
@@ -42,56 +46,84 @@ func SetNotifyGetTimingDataCallback(cb NotifyGetTimingDataCallback) {
 	notifyGetTimingDataCallback = cb
 }
 
-func handleReadFromClientMsg(ctx context.Context, buffer []byte, IPAddr string, MsgRsp *rtkMisc.C2SMessage, timeStamp int64) rtkMisc.CrossShareErr {
+// c2sMsgHandler fills rsp by the decoded msg, it returns false if rsp is written asynchronously.
+// The ExtData type of msg is registered in rtkMisc C2SMsgSchema, a new MsgType needs only its schema and handler.
+type c2sMsgHandler func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool
+
+var c2sMsgHandlerMap = map[rtkMisc.C2SMsgType]c2sMsgHandler{
+	rtkMisc.C2SMsg_CLIENT_HEARTBEAT: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		return true
+	},
+	rtkMisc.C2SMsg_INIT_CLIENT: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rsp.ClientIndex, rsp.ExtData = dealC2SMsgInitClient(msg.ExtData.(rtkMisc.InitClientMessageReq))
+		return true
+	},
+	rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rtkMisc.GoSafe(func() {
+			rsp.ExtData = dealC2SMsgMobileAuthDataIndex(ctx, msg.ClientID, msg.ClientIndex, msg.ExtData.(rtkMisc.AuthDataIndexMobileReq))
+			writeMsg(rsp, timeStamp)
+		})
+		return false
+	},
+	rtkMisc.C2SMsg_REQ_CLIENT_LIST: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rsp.ExtData = dealC2SMsgReqClientList(msg.ClientIndex)
+		return true
+	},
+	rtkMisc.CS2Msg_MESSAGE_EVENT: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rsp.ExtData = dealC2SMsgReqPlatformMsgEvent(msg.ClientID, msg.ExtData.(rtkMisc.PlatformMsgEventReq))
+		return true
+	},
+	rtkMisc.CS2Msg_UPDATE_SRCPORT_INFO: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rsp.ExtData = dealC2SMsgReqUpdateClientSrcPortInfo(msg.ClientID, msg.ClientIndex, msg.ExtData.(rtkMisc.UpdateClientSrcPortInfoReq))
+		return true
+	},
+	rtkMisc.C2SMsg_DRAG_FILE_START: func(ctx context.Context, msg, rsp *rtkMisc.C2SMessage, timeStamp int64) bool {
+		rsp.ExtData = dealC2SMsgReqDragFileListStart(msg.ClientID, msg.ExtData.(rtkMisc.DragFileStartInfo))
+		return true
+	},
+}
+
+// handleReadFromClientMsg returns true if MsgRsp is ready to write, MsgRsp is the error response if the error code is returned
+func handleReadFromClientMsg(ctx context.Context, buffer []byte, IPAddr, authClientID string, MsgRsp *rtkMisc.C2SMessage, timeStamp int64) (bool, rtkMisc.CrossShareErr) {
 	if len(buffer) == 0 {
-		log.Printf("[%s] buffer is null!", rtkMisc.GetFuncInfo())
-		return rtkMisc.ERR_BIZ_S2C_READ_EMPTY_DATA
+		logger.Warn("read buffer is empty", rtkMisc.IPAttr(IPAddr))
+		return false, rtkMisc.ERR_BIZ_S2C_READ_EMPTY_DATA
 	}
 	buffer = bytes.Trim(buffer, "\x00")
 
-	type TempMsg struct {
-		ExtData json.RawMessage
-		rtkMisc.C2SMessage
+	msg, errCode := rtkMisc.DecodeC2SMessage(buffer, false)
+	if errCode == rtkMisc.ERR_BIZ_JSON_UNMARSHAL {
+		return false, errCode
 	}
-	var msg TempMsg
-	err := json.Unmarshal(buffer, &msg)
-	if err != nil {
-		log.Println("Failed to unmarshal C2SMessage data: ", err.Error())
-		log.Printf("Err JSON len[%d] data:[%s] ", len(buffer), string(buffer))
-		return rtkMisc.ERR_BIZ_JSON_UNMARSHAL
+	rtkMetrics.IncC2SMessage(msg.MsgType)
+
+	// the legacy plaintext client has no authClientID
+	if authClientID != "" && msg.ClientID != authClientID {
+		logger.Warn("client ID mismatch with certificate, skip msg", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.IPAttr(IPAddr), rtkMisc.MsgTypeAttr(string(msg.MsgType)), "cert_id", authClientID)
+		return false, rtkMisc.ERR_BIZ_S2C_CLIENT_ID_MISMATCH
 	}
 
-	if msg.MsgType != rtkMisc.C2SMsg_CLIENT_HEARTBEAT {
-		log.Printf("Received a msg from clientID:[%s] ClientIndex:[%d] IPAddr:[%s] MsgType:[%s]", msg.ClientID, msg.ClientIndex, IPAddr, msg.MsgType)
+	msgLevel := slog.LevelInfo
+	if msg.MsgType == rtkMisc.C2SMsg_CLIENT_HEARTBEAT {
+		msgLevel = slog.LevelDebug
 	}
+	logger.Log(ctx, msgLevel, "received msg", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.IPAttr(IPAddr), rtkMisc.MsgTypeAttr(string(msg.MsgType)), "client_index", msg.ClientIndex, "protocol_version", msg.ProtocolVersion)
+
+	handler, ok := c2sMsgHandlerMap[msg.MsgType]
+	if errCode == rtkMisc.SUCCESS && !ok {
+		logger.Warn("msg type has no handler", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.MsgTypeAttr(string(msg.MsgType)))
+		errCode = rtkMisc.ERR_BIZ_C2S_UNSUPPORTED_MSG_TYPE
+	}
+	if errCode != rtkMisc.SUCCESS {
+		*MsgRsp = rtkMisc.NewC2SErrorResponse(msg, errCode)
+		return true, errCode
+	}
+
 	MsgRsp.ClientID = msg.ClientID
 	MsgRsp.MsgType = msg.MsgType
 	MsgRsp.ClientIndex = msg.ClientIndex
 	MsgRsp.TimeStamp = msg.TimeStamp
-
-	switch msg.MsgType {
-	case rtkMisc.C2SMsg_CLIENT_HEARTBEAT:
-	case rtkMisc.C2SMsg_INIT_CLIENT:
-		MsgRsp.ClientIndex, MsgRsp.ExtData = dealC2SMsgInitClient(&msg.ExtData)
-	case rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE:
-		rtkMisc.GoSafe(func() {
-			MsgRsp.ExtData = dealC2SMsgMobileAuthDataIndex(ctx, msg.ClientID, msg.ClientIndex, &msg.ExtData)
-			writeMsg(MsgRsp, timeStamp)
-		})
-	case rtkMisc.C2SMsg_REQ_CLIENT_LIST:
-		MsgRsp.ExtData = dealC2SMsgReqClientList(msg.ClientIndex)
-	case rtkMisc.CS2Msg_MESSAGE_EVENT:
-		MsgRsp.ExtData = dealC2SMsgReqPlatformMsgEvent(msg.ClientID, &msg.ExtData)
-	case rtkMisc.CS2Msg_UPDATE_SRCPORT_INFO:
-		MsgRsp.ExtData = dealC2SMsgReqUpdateClientSrcPortInfo(msg.ClientID, msg.ClientIndex, &msg.ExtData)
-	case rtkMisc.C2SMsg_DRAG_FILE_START:
-		MsgRsp.ExtData = dealC2SMsgReqDragFileListStart(msg.ClientID, &msg.ExtData)
-	default:
-		log.Printf("[%s]Unknown MsgType:[%s]", rtkMisc.GetFuncInfo(), msg.MsgType)
-		return rtkMisc.ERR_BIZ_S2C_UNKNOWN_MSG_TYPE
-	}
-
-	return rtkMisc.SUCCESS
+	return handler(ctx, &msg, MsgRsp, timeStamp), rtkMisc.SUCCESS
 }
 
 func checkCaptureResult(ctx context.Context, maxRetryCnt int, clientIndex int) (bool, int, int) {
@@ -223,6 +255,14 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 	clientID := ""
 	clientIPAddr := conn.RemoteAddr().String()
 
+	secureConn, authClientID, errCode := acceptSecureConn(conn)
+	if errCode != rtkMisc.SUCCESS {
+		logger.Error("accept connect failed", rtkMisc.IPAttr(clientIPAddr), rtkMisc.ErrCodeAttr(errCode))
+		conn.Close()
+		return
+	}
+	conn = secureConn
+
 	defer func() {
 		if closeConn(clientID, timestamp) {
 			rtkdbManager.UpdateClientOffline(int(clientIndex))
@@ -247,12 +287,12 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("IPAddr:[%s] connect cancel by context! ", clientIPAddr)
+				logger.Info("connect cancel by context", rtkMisc.IPAttr(clientIPAddr))
 				return
 			default:
 				err := conn.SetDeadline(time.Now().Add(time.Duration(rtkMisc.ClientHeartbeatInterval+5) * time.Second))
 				if err != nil {
-					log.Printf("IPAddr:[%s] connect SetDeadline err:%+v !", clientIPAddr, err)
+					logger.Warn("connect SetDeadline failed", rtkMisc.IPAttr(clientIPAddr), rtkMisc.ErrAttr(err))
 					time.Sleep(100 * time.Millisecond)
 					if errCnt >= 3 {
 						return
@@ -269,9 +309,9 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 				readStrLine, err := bufio.NewReader(conn).ReadString('\n')
 				if err != nil {
 					if opErr, ok := err.(*net.OpError); ok {
-						log.Printf("[%s] TCP Read OpError: %v, Op: %s, Net: %s, Err: %v", rtkMisc.GetFuncInfo(), opErr, opErr.Op, opErr.Net, opErr.Err)
+						logger.Warn("TCP read OpError", rtkMisc.IPAttr(clientIPAddr), "op", opErr.Op, "net", opErr.Net, rtkMisc.ErrAttr(opErr.Err))
 						if opErr.Temporary() && !opErr.Timeout() {
-							log.Println("Temporary error, continuing...")
+							logger.Info("TCP read temporary error, continuing", rtkMisc.IPAttr(clientIPAddr))
 							time.Sleep(50 * time.Millisecond)
 							continue
 						}
@@ -285,12 +325,14 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 
 				if err != nil {
 					if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
-						log.Printf("IPAddr:[%s] ClientIndex:[%d] ReadString timeout: %+v", clientIPAddr, clientIndex, err)
+						logger.Warn("read timeout without heartbeat", rtkMisc.IPAttr(clientIPAddr), "client_index", clientIndex, rtkMisc.ErrAttr(err))
+						rtkMetrics.IncHeartbeatTimeout()
 					} else {
-						log.Printf("IPAddr:[%s] ClientIndex:[%d] ReadString error:%+v", clientIPAddr, clientIndex, err)
 						var errno syscall.Errno
 						if errors.As(err, &errno) {
-							log.Printf("[%s] Read errno: %v", rtkMisc.GetFuncInfo(), errno)
+							logger.Warn("read failed", rtkMisc.IPAttr(clientIPAddr), "client_index", clientIndex, rtkMisc.ErrAttr(err), "errno", int(errno))
+						} else {
+							logger.Warn("read failed", rtkMisc.IPAttr(clientIPAddr), "client_index", clientIndex, rtkMisc.ErrAttr(err))
 						}
 					}
 					return
@@ -308,8 +350,11 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 		buffer = []byte(readData.buffer)
 
 		var C2SRsp rtkMisc.C2SMessage
-		errCode := handleReadFromClientMsg(ctx, buffer, clientIPAddr, &C2SRsp, timestamp)
+		isRspReady, errCode := handleReadFromClientMsg(ctx, buffer, clientIPAddr, authClientID, &C2SRsp, timestamp)
 		if errCode != rtkMisc.SUCCESS {
+			if isRspReady {
+				writeMsgToConn(conn, &C2SRsp)
+			}
 			continue
 		}
 
@@ -319,7 +364,7 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 			updateConn(clientID, timestamp, conn)
 		}
 
-		if C2SRsp.MsgType != rtkMisc.C2SMsg_AUTH_DATA_INDEX_MOBILE { // asynchronous response message
+		if isRspReady {
 			if writeMsg(&C2SRsp, timestamp) != rtkMisc.SUCCESS {
 				return
 			}
@@ -328,14 +373,30 @@ func HandleClient(mainCtx context.Context, conn net.Conn, timestamp int64) {
 }
 
 func writeMsg(msg *rtkMisc.C2SMessage, timestamp int64) rtkMisc.CrossShareErr {
+	msg.ProtocolVersion = rtkMisc.C2SProtocolVersion
 	encodedData, err := json.Marshal(msg)
 	if err != nil {
-		log.Println("Failed to marshal C2SMessage data:", err)
+		logger.Error("marshal C2SMessage failed", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.MsgTypeAttr(string(msg.MsgType)), rtkMisc.ErrAttr(err))
 		return rtkMisc.ERR_BIZ_JSON_MARSHAL
 	}
 
-	if msg.MsgType != rtkMisc.C2SMsg_CLIENT_HEARTBEAT && msg.MsgType != rtkMisc.CS2Msg_PERIODIC_NOTIFY {
-		log.Printf("Write a msg to clientID:[%s] ClientIndex:[%d] MsgType:[%s]", msg.ClientID, msg.ClientIndex, msg.MsgType)
+	msgLevel := slog.LevelInfo
+	if msg.MsgType == rtkMisc.C2SMsg_CLIENT_HEARTBEAT || msg.MsgType == rtkMisc.CS2Msg_PERIODIC_NOTIFY {
+		msgLevel = slog.LevelDebug
 	}
+	logger.Log(context.Background(), msgLevel, "write msg", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.MsgTypeAttr(string(msg.MsgType)), "client_index", msg.ClientIndex)
 	return write(encodedData, msg.ClientID, timestamp)
 }
+
+// writeMsgToConn writes the error response to conn directly, the conn may not be updated by client ID yet
+func writeMsgToConn(conn net.Conn, msg *rtkMisc.C2SMessage) rtkMisc.CrossShareErr {
+	msg.ProtocolVersion = rtkMisc.C2SProtocolVersion
+	encodedData, err := json.Marshal(msg)
+	if err != nil {
+		logger.Error("marshal C2SMessage failed", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.MsgTypeAttr(string(msg.MsgType)), rtkMisc.ErrAttr(err))
+		return rtkMisc.ERR_BIZ_JSON_MARSHAL
+	}
+
+	logger.Info("write msg to conn", rtkMisc.PeerIDAttr(msg.ClientID), rtkMisc.IPAttr(conn.RemoteAddr().String()), rtkMisc.MsgTypeAttr(string(msg.MsgType)))
+	return writeToConn(conn, encodedData, msg.ClientID)
+}
@@ -0,0 +1,178 @@
+package clientManager
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	rtkGlobal "rtk-cross-share/lanServer/global"
+	rtkMisc "rtk-cross-share/misc"
+	"time"
+
+	libp2pCrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const secureHandshakeTimeout = 10 * time.Second
+
+var serverTlsConfig *tls.Config
+
+// peekedConn reads the peeked first byte of plaintext client again
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func loadServerKey(keyPath string) (ed25519.PrivateKey, error) {
+	content, err := os.ReadFile(keyPath)
+	if err == nil {
+		block, _ := pem.Decode(content)
+		if block == nil || block.Type != "PRIVATE KEY" {
+			return nil, fmt.Errorf("invalid PEM block in %s", keyPath)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid key type %T in %s", key, keyPath)
+		}
+		return priv, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := keyPath + ".tmp"
+	if err = os.WriteFile(tmpPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, keyPath); err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] generate a new server key:[%s]", rtkMisc.GetFuncInfo(), keyPath)
+	return priv, nil
+}
+
+// InitServerIdentity loads the persistent server key, it must be called before mDNS register
+func InitServerIdentity() error {
+	priv, err := loadServerKey(filepath.Join(rtkGlobal.DB_PATH, rtkGlobal.SERVER_KEY_NAME))
+	if err != nil {
+		return err
+	}
+	serverCert, err := rtkMisc.GenC2SCertificate(priv)
+	if err != nil {
+		return err
+	}
+	serverKey, err := rtkMisc.GetC2SPublicKey(priv.Public())
+	if err != nil {
+		return err
+	}
+
+	serverTlsConfig = rtkMisc.NewC2SServerTlsConfig(serverCert)
+	rtkGlobal.ServerPublicKey = serverKey
+	log.Printf("[%s] server key:[%s] AllowPlaintextClient:[%+v]", rtkMisc.GetFuncInfo(), serverKey, rtkGlobal.AllowPlaintextClient)
+	return nil
+}
+
+// getClientIDFromPublicKey returns the libp2p peer ID of client certificate key
+func getClientIDFromPublicKey(pub crypto.PublicKey) (string, error) {
+	var p2pPubKey libp2pCrypto.PubKey
+	var err error
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		var pubDer []byte
+		if pubDer, err = x509.MarshalPKIXPublicKey(key); err == nil {
+			p2pPubKey, err = libp2pCrypto.UnmarshalRsaPublicKey(pubDer)
+		}
+	case *ecdsa.PublicKey:
+		var pubDer []byte
+		if pubDer, err = x509.MarshalPKIXPublicKey(key); err == nil {
+			p2pPubKey, err = libp2pCrypto.UnmarshalECDSAPublicKey(pubDer)
+		}
+	case ed25519.PublicKey:
+		p2pPubKey, err = libp2pCrypto.UnmarshalEd25519PublicKey(key)
+	default:
+		err = fmt.Errorf("unsupported client key type %T", pub)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id, err := peer.IDFromPublicKey(p2pPubKey)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// acceptSecureConn returns TLS connection and the client ID authenticated by client certificate.
+// The legacy plaintext client gets an empty client ID, and it is refused unless AllowPlaintextClient is on.
+func acceptSecureConn(conn net.Conn) (net.Conn, string, rtkMisc.CrossShareErr) {
+	clientIPAddr := conn.RemoteAddr().String()
+	conn.SetDeadline(time.Now().Add(secureHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	firstByte, err := reader.Peek(1)
+	if err != nil {
+		log.Printf("[%s] IPAddr:[%s] peek first byte err:%+v", rtkMisc.GetFuncInfo(), clientIPAddr, err)
+		return nil, "", rtkMisc.ERR_NETWORK_S2C_READ
+	}
+	pConn := &peekedConn{Conn: conn, reader: reader}
+
+	if firstByte[0] != rtkMisc.C2STlsRecordTypeHandshake {
+		if !rtkGlobal.AllowPlaintextClient {
+			log.Printf("[%s] IPAddr:[%s] plaintext client is refused", rtkMisc.GetFuncInfo(), clientIPAddr)
+			return nil, "", rtkMisc.ERR_BIZ_S2C_PLAINTEXT_REFUSED
+		}
+		log.Printf("[%s] IPAddr:[%s] WARNING: accept legacy plaintext client", rtkMisc.GetFuncInfo(), clientIPAddr)
+		return pConn, "", rtkMisc.SUCCESS
+	}
+
+	if serverTlsConfig == nil {
+		log.Printf("[%s] IPAddr:[%s] server identity is not initialized", rtkMisc.GetFuncInfo(), clientIPAddr)
+		return nil, "", rtkMisc.ERR_NETWORK_S2C_TLS_HANDSHAKE
+	}
+	tlsConn := tls.Server(pConn, serverTlsConfig)
+	if err = tlsConn.Handshake(); err != nil {
+		log.Printf("[%s] IPAddr:[%s] TLS handshake err:%+v", rtkMisc.GetFuncInfo(), clientIPAddr, err)
+		return nil, "", rtkMisc.ERR_NETWORK_S2C_TLS_HANDSHAKE
+	}
+
+	pub, err := rtkMisc.GetC2SPeerPublicKey(tlsConn.ConnectionState())
+	if err != nil {
+		log.Printf("[%s] IPAddr:[%s] get client certificate err:%+v", rtkMisc.GetFuncInfo(), clientIPAddr, err)
+		return nil, "", rtkMisc.ERR_NETWORK_S2C_TLS_HANDSHAKE
+	}
+	clientID, err := getClientIDFromPublicKey(pub)
+	if err != nil {
+		log.Printf("[%s] IPAddr:[%s] get client ID from certificate err:%+v", rtkMisc.GetFuncInfo(), clientIPAddr, err)
+		return nil, "", rtkMisc.ERR_NETWORK_S2C_TLS_HANDSHAKE
+	}
+	log.Printf("[%s] IPAddr:[%s] ClientID:[%s] TLS handshake success", rtkMisc.GetFuncInfo(), clientIPAddr, clientID)
+	return tlsConn, clientID, rtkMisc.SUCCESS
+}
@@ -2,7 +2,6 @@ package clientManager
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	rtkCommon "rtk-cross-share/lanServer/common"
 	rtkdbManager "rtk-cross-share/lanServer/dbManager"
@@ -134,15 +133,8 @@ func sendPeriodicNotify(id string, extData rtkMisc.PeriodicNotifyReq) rtkMisc.Cr
 	return writeMsg(&msg, 0)
 }
 
-func dealC2SMsgInitClient(ext *json.RawMessage) (uint32, interface{}) {
-	var extData rtkMisc.InitClientMessageReq
+func dealC2SMsgInitClient(extData rtkMisc.InitClientMessageReq) (uint32, interface{}) {
 	initClientRsp := rtkMisc.InitClientMessageResponse{Response: rtkMisc.GetResponse(rtkMisc.SUCCESS), ClientIndex: 0, ClientVersion: ""}
-	err := json.Unmarshal(*ext, &extData)
-	if err != nil {
-		log.Printf("clientID:[%s] decode ExtDataText Err: %s", extData.ClientID, err.Error())
-		initClientRsp.Response = rtkMisc.GetResponse(rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL)
-		return 0, initClientRsp
-	}
 
 	if VERSION_CONTROL {
 		if !rtkMisc.CheckFullVersionVaild(extData.ClientVersion) {
@@ -199,15 +191,8 @@ func dealC2SMsgInitClient(ext *json.RawMessage) (uint32, interface{}) {
 	return initClientRsp.ClientIndex, initClientRsp
 }
 
-func dealC2SMsgMobileAuthDataIndex(ctx context.Context, id string, clientIndex uint32, ext *json.RawMessage) interface{} {
+func dealC2SMsgMobileAuthDataIndex(ctx context.Context, id string, clientIndex uint32, extData rtkMisc.AuthDataIndexMobileReq) interface{} {
 	authDataIndexMobileRsp := rtkMisc.AuthDataIndexMobileResponse{Response: rtkMisc.GetResponse(rtkMisc.SUCCESS), AuthStatus: false}
-	var extData rtkMisc.AuthDataIndexMobileReq
-	err := json.Unmarshal(*ext, &extData)
-	if err != nil {
-		log.Printf("[%s] clientID:[%s] decode ExtDataText Err: %s", rtkMisc.GetFuncInfo(), id, err.Error())
-		authDataIndexMobileRsp.Response = rtkMisc.GetResponse(rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL)
-		return authDataIndexMobileRsp
-	}
 
 	clientInfo, errQueryClient := rtkdbManager.QueryClientInfoByIndex(int(clientIndex))
 	if errQueryClient != rtkMisc.SUCCESS {
@@ -274,30 +259,16 @@ func dealC2SMsgReqClientList(clientIndex uint32) interface{} {
 	return getClientListRsp
 }
 
-func dealC2SMsgReqPlatformMsgEvent(id string, ext *json.RawMessage) interface{} {
-	var extData rtkMisc.PlatformMsgEventReq
+func dealC2SMsgReqPlatformMsgEvent(id string, extData rtkMisc.PlatformMsgEventReq) interface{} {
 	msgEventRsp := rtkMisc.PlatformMsgEventResponse{Response: rtkMisc.GetResponse(rtkMisc.SUCCESS)}
-	err := json.Unmarshal(*ext, &extData)
-	if err != nil {
-		log.Printf("clientID:[%s] decode ExtDataText Err: %s", id, err.Error())
-		msgEventRsp.Response = rtkMisc.GetResponse(rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL)
-		return msgEventRsp
-	}
 	log.Printf("[%s] id:[%s] event:[%d], arg1:%s, arg2:%s, arg3:%s, arg4:%s\n", rtkMisc.GetFuncInfo(), id, extData.Event, extData.Arg1, extData.Arg2, extData.Arg3, extData.Arg4)
 	sendPlatformMsgEventCallback(int(extData.Event), extData.Arg1, extData.Arg2, extData.Arg3, extData.Arg4)
 
 	return msgEventRsp
 }
 
-func dealC2SMsgReqUpdateClientSrcPortInfo(id string, clientIndex uint32, ext *json.RawMessage) interface{} {
-	var extData rtkMisc.UpdateClientSrcPortInfoReq
+func dealC2SMsgReqUpdateClientSrcPortInfo(id string, clientIndex uint32, extData rtkMisc.UpdateClientSrcPortInfoReq) interface{} {
 	updateSrcPortInfoRsp := rtkMisc.UpdateClientSrcPortInfoResponse{Response: rtkMisc.GetResponse(rtkMisc.SUCCESS)}
-	err := json.Unmarshal(*ext, &extData)
-	if err != nil {
-		log.Printf("clientID:[%s] decode ExtDataText Err: %s", id, err.Error())
-		updateSrcPortInfoRsp.Response = rtkMisc.GetResponse(rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL)
-		return updateSrcPortInfoRsp
-	}
 
 	errCode := rtkdbManager.UpdateSrcPortInfo(int(clientIndex), extData.ClientIndex, extData.SourcePortInfoList)
 	if errCode != rtkMisc.SUCCESS {
@@ -311,15 +282,8 @@ func dealC2SMsgReqUpdateClientSrcPortInfo(id string, clientIndex uint32, ext *js
 	return updateSrcPortInfoRsp
 }
 
-func dealC2SMsgReqDragFileListStart(id string, ext *json.RawMessage) interface{} {
+func dealC2SMsgReqDragFileListStart(id string, dragFileListStartReq rtkMisc.DragFileStartInfo) interface{} {
 	dragFileListStartRsp := rtkMisc.DragFileStartResponse{Response: rtkMisc.GetResponse(rtkMisc.SUCCESS)}
-	var dragFileListStartReq rtkMisc.DragFileStartInfo
-	err := json.Unmarshal(*ext, &dragFileListStartReq)
-	if err != nil {
-		log.Printf("clientID:[%s] decode ExtDataText Err: %s", id, err.Error())
-		dragFileListStartRsp.Response = rtkMisc.GetResponse(rtkMisc.ERR_BIZ_JSON_EXTDATA_UNMARSHAL)
-		return dragFileListStartRsp
-	}
 
 	resizeWidth, resizeHeight, resizeX, resizeY := resizeMobileRect(
 		rtkMisc.SourcePort{Source: dragFileListStartReq.Source, Port: dragFileListStartReq.Port},
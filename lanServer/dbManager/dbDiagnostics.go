@@ -0,0 +1,115 @@
+package dbManager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The columns which may identify the user are replaced by a short hash, the same value always has the same hash
+var sanitizedColumnMap = map[string]bool{
+	"host":       true,
+	"devicename": true,
+	"link":       true,
+	"ipaddr":     true,
+	"clientid":   true,
+}
+
+func formatValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func sanitizeValue(val any) string {
+	text := formatValue(val)
+	if val == nil || text == "" {
+		return text
+	}
+	sum := sha256.Sum256([]byte(text))
+	return "<sha256:" + hex.EncodeToString(sum[:4]) + ">"
+}
+
+func dumpTable(w io.Writer, tableName string) error {
+	db, ok := getDb()
+	if !ok {
+		return errors.New("database instance is null")
+	}
+	rows, err := db.Query(`SELECT * FROM "` + tableName + `"`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnList, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "== %s\n%s\n", tableName, strings.Join(columnList, "\t"))
+
+	valueList := make([]any, len(columnList))
+	ptrList := make([]any, len(columnList))
+	for i := range valueList {
+		ptrList[i] = &valueList[i]
+	}
+	fieldList := make([]string, len(columnList))
+	for rows.Next() {
+		if err = rows.Scan(ptrList...); err != nil {
+			return err
+		}
+		for i, column := range columnList {
+			if sanitizedColumnMap[strings.ToLower(column)] {
+				fieldList[i] = sanitizeValue(valueList[i])
+			} else {
+				fieldList[i] = formatValue(valueList[i])
+			}
+		}
+		fmt.Fprintf(w, "%s\n", strings.Join(fieldList, "\t"))
+	}
+	fmt.Fprintln(w)
+	return rows.Err()
+}
+
+// DumpSanitizedTables writes all of tables with the host, device name and link hashed, it's for diagnostics
+func DumpSanitizedTables(w io.Writer) error {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	db, ok := getDb()
+	if !ok {
+		return errors.New("database instance is null")
+	}
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' ORDER BY name")
+	if err != nil {
+		return err
+	}
+	tableList := make([]string, 0)
+	for rows.Next() {
+		var tableName string
+		if err = rows.Scan(&tableName); err != nil {
+			rows.Close()
+			return err
+		}
+		tableList = append(tableList, tableName)
+	}
+	rows.Close()
+
+	var errList []string
+	for _, tableName := range tableList {
+		if err = dumpTable(w, tableName); err != nil {
+			errList = append(errList, fmt.Sprintf("%s: %v", tableName, err))
+		}
+	}
+	if len(errList) > 0 {
+		return errors.New(strings.Join(errList, "; "))
+	}
+	return nil
+}
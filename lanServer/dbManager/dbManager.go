@@ -8,6 +8,7 @@ import (
 	"os"
 	rtkCommon "rtk-cross-share/lanServer/common"
 	rtkGlobal "rtk-cross-share/lanServer/global"
+	rtkMetrics "rtk-cross-share/lanServer/metrics"
 	rtkMisc "rtk-cross-share/misc"
 	"strings"
 	"sync"
@@ -244,6 +245,7 @@ func upgradeDb() {
 func upsertClientInfo(pkIndex *int, clientId, host, ipAddr, deviceName, platform, version string) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("upsertClientInfo", time.Now())
 
 	if pkIndex == nil {
 		log.Printf("[%s] pkIndex is null", rtkMisc.GetFuncInfo())
@@ -331,6 +333,7 @@ func upsertClientInfo(pkIndex *int, clientId, host, ipAddr, deviceName, platform
 func upsertAuthStatus(authPkIndex *int, clientPkIndex int, authStatus bool) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("upsertAuthStatus", time.Now())
 
 	if authPkIndex == nil {
 		log.Printf("[%s] pkIndex is null", rtkMisc.GetFuncInfo())
@@ -364,6 +367,7 @@ func upsertAuthStatus(authPkIndex *int, clientPkIndex int, authStatus bool) rtkM
 func updateClientInfo(pkIndexList *[]int, setConds []SqlCond, whereConds []SqlCond, args ...any) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("updateClientInfo", time.Now())
 
 	if pkIndexList == nil {
 		log.Printf("[%s] pkIndexList is null", rtkMisc.GetFuncInfo())
@@ -450,6 +454,7 @@ func queryClientInfoInternal(clientInfoList *[]rtkCommon.ClientInfoTb, conds []S
 func queryClientInfo(clientInfoList *[]rtkCommon.ClientInfoTb, conds []SqlCond, args ...any) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("queryClientInfo", time.Now())
 
 	return queryClientInfoInternal(clientInfoList, conds, args...)
 }
@@ -457,6 +462,7 @@ func queryClientInfo(clientInfoList *[]rtkCommon.ClientInfoTb, conds []SqlCond,
 func clientQueryClientList(clientPkIndex int, clientInfoList *[]rtkCommon.ClientInfoTb) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("clientQueryClientList", time.Now())
 	if clientPkIndex <= 0 {
 		log.Printf("[%s] clientPkIndex:[%d] is invalid!", rtkMisc.GetFuncInfo(), clientPkIndex)
 		return rtkMisc.ERR_DB_SQLITE_INVALID_ARGS
@@ -486,6 +492,7 @@ func clientQueryClientList(clientPkIndex int, clientInfoList *[]rtkCommon.Client
 func upsertTimingInfo(source, port, width, height, framerate int) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("upsertTimingInfo", time.Now())
 
 	sqlData := SqlDataUpsertTimingInfo
 	param := []any{source, port, width, height, framerate}
@@ -510,6 +517,7 @@ func upsertTimingInfo(source, port, width, height, framerate int) rtkMisc.CrossS
 func upsertLinkInfo(pkIndex int, link string) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("upsertLinkInfo", time.Now())
 
 	sqlData := SqlDataUpsertLinkInfo
 	param := []any{pkIndex, link}
@@ -534,6 +542,7 @@ func upsertLinkInfo(pkIndex int, link string) rtkMisc.CrossShareErr {
 func queryLinkInfo(conds []SqlCond, args ...any) (string, rtkMisc.CrossShareErr) {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("queryLinkInfo", time.Now())
 
 	sqlData := SqlDataQueryLinkInfo.withCond_WHERE(conds...)
 	if sqlData.checkArgsCount(args) == false {
@@ -557,6 +566,7 @@ func queryLinkInfo(conds []SqlCond, args ...any) (string, rtkMisc.CrossShareErr)
 func upsertSrcPortInfo(clientPkIndex int, srcPortInfoList []rtkMisc.SourcePortInfo) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("upsertSrcPortInfo", time.Now())
 
 	if clientPkIndex < 0 || len(srcPortInfoList) <= 0 {
 		log.Printf("[%s] clientPkIndex:%d srcPortList len:%d, args is invalid!", rtkMisc.GetFuncInfo(), clientPkIndex, len(srcPortInfoList))
@@ -614,6 +624,7 @@ func querySrcPortInfo(srcPortInfoList *[]rtkCommon.SourcePortInfoTb, conds []Sql
 
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("querySrcPortInfo", time.Now())
 
 	sqlData := SqlDataQuerySrcPortInfo.withCond_WHERE(conds...)
 	if sqlData.checkArgsCount(args) == false {
@@ -654,6 +665,7 @@ func querySrcPortInfo(srcPortInfoList *[]rtkCommon.SourcePortInfoTb, conds []Sql
 func resetSrcPortInfo(pkIndexList []int) rtkMisc.CrossShareErr {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
+	defer rtkMetrics.ObserveDbQuery("resetSrcPortInfo", time.Now())
 
 	if len(pkIndexList) == 0 {
 		log.Printf("[%s] pkIndexList is null!", rtkMisc.GetFuncInfo())
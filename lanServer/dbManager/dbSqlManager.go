@@ -72,6 +72,14 @@ func QueryOnlineClientList(clientInfoList *[]rtkCommon.ClientInfoTb) rtkMisc.Cro
 	return rtkMisc.SUCCESS
 }
 
+func QueryAuthorizedClientCount() int {
+	clientInfoList := make([]rtkCommon.ClientInfoTb, 0)
+	if queryClientInfo(&clientInfoList, []SqlCond{SqlCondOnline, SqlCondAuthStatusIsTrue}) != rtkMisc.SUCCESS {
+		return 0
+	}
+	return len(clientInfoList)
+}
+
 func ClientQueryOnlineClientList(pkIndex int, clientInfoList *[]rtkCommon.ClientInfoTb) rtkMisc.CrossShareErr {
 	err := clientQueryClientList(
 		pkIndex,
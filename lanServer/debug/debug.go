@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	rtkdbManager "rtk-cross-share/lanServer/dbManager"
+	rtkDiagnostics "rtk-cross-share/lanServer/diagnostics"
 	rtkIfaceMgr "rtk-cross-share/lanServer/interfaceMgr"
 	rtkMisc "rtk-cross-share/misc"
 	"strconv"
@@ -21,6 +22,9 @@ var (
 		{"UpdateDeviceName", func() { updateDeviceName(scanner) }},
 		{"SendDragFileStart", func() { sendDragFileStart(scanner) }},
 		{"QueryClientInfoBySrcPort(Database)", func() { queryClientInfoBySrcPort(scanner) }},
+		{"SetLogLevel", func() { setLogLevel(scanner) }},
+		{"SetLogFormat", func() { setLogFormat(scanner) }},
+		{"CollectDiagnostics", func() { collectDiagnostics(scanner) }},
 	}
 )
 
@@ -144,3 +148,28 @@ func sendDragFileStart(scanner *bufio.Scanner) {
 	}
 	rtkIfaceMgr.GetInterfaceMgr().TriggerDragFileStart(src, port, horzSize, vertSize, posX, posY)
 }
+
+func setLogLevel(scanner *bufio.Scanner) {
+	fmt.Println("Levels:", rtkMisc.GetLogLevels())
+	spec := readTextInput("Level spec(e.g. info,clientManager=debug): ", scanner)
+	if !rtkMisc.SetLogLevelSpec(spec) {
+		fmt.Println("Invalid level spec")
+	}
+}
+
+func setLogFormat(scanner *bufio.Scanner) {
+	format := readTextInput("Format(text, json): ", scanner)
+	if !rtkMisc.SetLogFormat(format) {
+		fmt.Println("Invalid format")
+	}
+}
+
+func collectDiagnostics(scanner *bufio.Scanner) {
+	outputDir := readTextInput("Output folder(empty is log folder): ", scanner)
+	path, err := rtkDiagnostics.Collect(outputDir)
+	if err != rtkMisc.SUCCESS {
+		fmt.Println("Err: ", err)
+		return
+	}
+	fmt.Println("Diagnostics archive:", path)
+}
@@ -0,0 +1,38 @@
+package diagnostics
+
+import (
+	"log"
+	rtkBuildConfig "rtk-cross-share/lanServer/buildConfig"
+	rtkClientManager "rtk-cross-share/lanServer/clientManager"
+	rtkdbManager "rtk-cross-share/lanServer/dbManager"
+	rtkGlobal "rtk-cross-share/lanServer/global"
+	rtkMisc "rtk-cross-share/misc"
+)
+
+const bundleName = "crossShareServerDiag"
+
+// Collect writes the diagnostics archive into outputDir and returns its path, empty outputDir is LOG_PATH
+func Collect(outputDir string) (string, rtkMisc.CrossShareErr) {
+	if outputDir == "" {
+		outputDir = rtkGlobal.LOG_PATH
+	}
+
+	bundle, err := rtkMisc.NewDiagBundle(outputDir, bundleName)
+	if err != nil {
+		log.Printf("[%s] create diagnostics archive in [%s] err:%+v", rtkMisc.GetFuncInfo(), outputDir, err)
+		return "", rtkMisc.ERR_BIZ_DIAG_CREATE_FILE
+	}
+
+	bundle.AddSystemInfo(rtkBuildConfig.ServerName, rtkGlobal.LanServerVersion, rtkBuildConfig.BuildDate)
+	bundle.AddWriterFunc("clientConn.txt", rtkClientManager.DumpClientConn)
+	bundle.AddWriterFunc("database.txt", rtkdbManager.DumpSanitizedTables)
+	bundle.AddLogFiles()
+
+	path, err := bundle.Close()
+	if err != nil {
+		log.Printf("[%s] close diagnostics archive err:%+v", rtkMisc.GetFuncInfo(), err)
+		return "", rtkMisc.ERR_BIZ_DIAG_CREATE_FILE
+	}
+	log.Printf("[%s] diagnostics archive:[%s]", rtkMisc.GetFuncInfo(), path)
+	return path, rtkMisc.SUCCESS
+}
@@ -1,7 +1,7 @@
 package global
 
 const (
-	LanServerVersion            = "2.2.37" // it must notify client and update client version and VersionReadme.txt  when intermediate version is update
+	LanServerVersion            = "2.2.38" // it must notify client and update client version and VersionReadme.txt  when intermediate version is update
 	ClientBaseVersion           = "2.3"    // Deprecated: unused
 	ClientCaptureIndexVerSerial = 48       // the client build ClientIndex color block on verification dialog
 
@@ -9,6 +9,7 @@ const (
 	SOCKET_PATH_ROOT = "/mnt/vendor/tvdata/database/cross_share/"
 	DB_PATH          = "/mnt/vendor/tvdata/database/cross_share/"
 	DB_NAME          = "cross_share.db"
+	SERVER_KEY_NAME  = "lanServerKey.pem" // C2S TLS identity key, its public key is published in mDNS text record
 	
 	BridgeInterfaceName   = "br0"
 
@@ -10,4 +10,7 @@ var (
 	ServerProductName string = ""
 	Scenario          rtkMisc.ScenarioType
 	Capability        int
+
+	ServerPublicKey      string // base64 PKIX public key of C2S TLS
+	AllowPlaintextClient bool   // compatibility switch for the legacy client without C2S TLS
 )
@@ -16,6 +16,7 @@ import (
 	rtkDebug "rtk-cross-share/lanServer/debug"
 	rtkGlobal "rtk-cross-share/lanServer/global"
 	rtkIfaceMgr "rtk-cross-share/lanServer/interfaceMgr"
+	rtkMetrics "rtk-cross-share/lanServer/metrics"
 	rtkNetwork "rtk-cross-share/lanServer/network"
 	"strconv"
 	"syscall"
@@ -33,6 +34,10 @@ var (
 	domain           = flag.String("domain", rtkMisc.LanServerDomain, "Set the network domain. Default should be fine.")
 	port             = flag.Int("port", rtkMisc.LanServerPort, "Set the port the service is listening to.")
 	serviceForServer = flag.String("serviceForServer", rtkMisc.LanServiceTypeForServer, "Set the service type of the new service.")
+	allowPlaintext   = flag.Bool("allowPlaintextClient", false, "Accept the legacy client without TLS on the control channel.")
+	logLevel         = flag.String("logLevel", "", "Set the log levels like 'info,clientManager=debug', a level without subsystem is for all.")
+	logFormat        = flag.String("logFormat", "", "Set the log format 'text' or 'json'. Default is text.")
+	metricsAddr      = flag.String("metrics", "", "Export Prometheus metrics on this loopback address, e.g. 127.0.0.1:9465. Default is disabled.")
 
 	g_foundOtherServer bool     = false
 	lockFd             *os.File = nil
@@ -41,11 +46,18 @@ var (
 
 func init() {
 	flag.Parse()
+	rtkGlobal.AllowPlaintextClient = *allowPlaintext
 
 	logFile := fmt.Sprintf("%s%s.log", rtkGlobal.LOG_PATH, rtkBuildConfig.ServerName)
 	crashLogFile := fmt.Sprintf("%s%sCrash.log", rtkGlobal.LOG_PATH, rtkBuildConfig.ServerName)
 	rtkMisc.InitLog(logFile, crashLogFile, 32)
 	rtkMisc.SetupLogConsoleFile()
+	if *logFormat != "" {
+		rtkMisc.SetLogFormat(*logFormat)
+	}
+	if *logLevel != "" {
+		rtkMisc.SetLogLevelSpec(*logLevel)
+	}
 
 	rtkMisc.CreateDir(rtkGlobal.SOCKET_PATH_ROOT, os.ModePerm)
 
@@ -157,6 +169,21 @@ func MainInit(shutdownCtx context.Context) {
 	rtkdbManager.InitSqlite(runCtx)
 	initDpSrcType()
 
+	if err = rtkClientManager.InitServerIdentity(); err != nil {
+		log.Printf("Init server identity failed:%+v, %s is not start!", err, rtkBuildConfig.ServerName)
+		return
+	}
+
+	if *metricsAddr != "" {
+		rtkMetrics.SetGetConnectedClientCountCallback(rtkClientManager.GetConnectedClientCount)
+		rtkMetrics.SetGetAuthorizedClientCountCallback(rtkdbManager.QueryAuthorizedClientCount)
+		if err = rtkMetrics.StartServer(*metricsAddr); err != nil {
+			log.Printf("Start metrics on [%s] failed:%+v", *metricsAddr, err)
+		} else {
+			defer rtkMetrics.StopServer()
+		}
+	}
+
 	var printErrNetwork = true
 	for {
 		if rtkMisc.IsNetworkConnected() {
@@ -316,7 +343,8 @@ func registerMdns(server **zeroconf.Server, serverForSearch **zeroconf.Server) [
 		textRecordProductName := getTextRecord(rtkMisc.TextRecordKeyProductName, rtkGlobal.ServerProductName)
 		textRecordTimestamp := getTextRecord(rtkMisc.TextRecordKeyTimestamp, strconv.FormatInt(time.Now().UnixMilli(), 10))
 		textRecordVersion := getTextRecord(rtkMisc.TextRecordKeyVersion, rtkGlobal.LanServerVersion)
-		textRecords := []string{textRecordIp, textRecordMonitorName, textRecordProductName, textRecordTimestamp, textRecordVersion}
+		textRecordServerKey := getTextRecord(rtkMisc.TextRecordKeyServerKey, rtkGlobal.ServerPublicKey)
+		textRecords := []string{textRecordIp, textRecordMonitorName, textRecordProductName, textRecordTimestamp, textRecordVersion, textRecordServerKey}
 		*server, err = zeroconf.Register(rtkGlobal.ServerMdnsId, *service, *domain, *port, textRecords, []net.Interface{*iface})
 		*serverForSearch, _ = zeroconf.Register(rtkGlobal.ServerMdnsId, *serviceForServer, *domain, *port, []string{}, []net.Interface{*iface})
 		(*server).TTL(60)
@@ -129,6 +129,7 @@ import (
 	"reflect"
 	"sync"
 	rtkCommon "rtk-cross-share/lanServer/common"
+	rtkDiagnostics "rtk-cross-share/lanServer/diagnostics"
 	rtkGlobal "rtk-cross-share/lanServer/global"
 	rtkIfaceMgr "rtk-cross-share/lanServer/interfaceMgr"
 	rtkMisc "rtk-cross-share/misc"
@@ -298,6 +299,34 @@ func EnableCrossShare(cEnable C.int) {
 	rtkIfaceMgr.GetInterfaceMgr().EnableCrossShare(enable)
 }
 
+//export SetAllowPlaintextClient
+func SetAllowPlaintextClient(cEnable C.int) {
+	rtkGlobal.AllowPlaintextClient = int(cEnable) != 0
+	log.Printf("[%s][%s] AllowPlaintextClient:[%+v]", tag, rtkMisc.GetFuncInfo(), rtkGlobal.AllowPlaintextClient)
+}
+
+//export SetLogLevel
+func SetLogLevel(cLevelSpec *C.char) C.int {
+	if rtkMisc.SetLogLevelSpec(C.GoString(cLevelSpec)) {
+		return 1
+	}
+	return 0
+}
+
+//export SetLogFormat
+func SetLogFormat(cFormat *C.char) C.int {
+	if rtkMisc.SetLogFormat(C.GoString(cFormat)) {
+		return 1
+	}
+	return 0
+}
+
+//export CollectDiagnostics
+func CollectDiagnostics(cOutputDir *C.char) *C.char {
+	path, _ := rtkDiagnostics.Collect(C.GoString(cOutputDir))
+	return C.CString(path)
+}
+
 //export UpdateSrcPlugging
 func UpdateSrcPlugging(cSource, cPort, cPlugEvent C.int) {
 	source := int(cSource)
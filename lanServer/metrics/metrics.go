@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	rtkMisc "rtk-cross-share/misc"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The metrics are always counted, they are only exported after StartServer is called
+const (
+	namespace = "crossshare"
+	subsystem = "lanserver"
+)
+
+// =============================
+// Client count get event
+// =============================
+type GetClientCountCallback func() int
+
+var (
+	getConnectedClientCountCallback  GetClientCountCallback
+	getAuthorizedClientCountCallback GetClientCountCallback
+)
+
+func SetGetConnectedClientCountCallback(cb GetClientCountCallback) {
+	getConnectedClientCountCallback = cb
+}
+
+func SetGetAuthorizedClientCountCallback(cb GetClientCountCallback) {
+	getAuthorizedClientCountCallback = cb
+}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	connectedClients = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "connected_clients",
+		Help:      "Clients connected to the control channel.",
+	}, func() float64 {
+		if getConnectedClientCountCallback == nil {
+			return 0
+		}
+		return float64(getConnectedClientCountCallback())
+	})
+
+	authorizedClients = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "authorized_clients",
+		Help:      "Online clients with authorization in DB.",
+	}, func() float64 {
+		if getAuthorizedClientCountCallback == nil {
+			return 0
+		}
+		return float64(getAuthorizedClientCountCallback())
+	})
+
+	c2sMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "c2s_messages_total",
+		Help:      "Decoded C2S messages from clients by MsgType.",
+	}, []string{"msg_type"})
+
+	heartbeatTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "heartbeat_timeouts_total",
+		Help:      "Client connections closed by read timeout without heartbeat.",
+	})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of SQLite queries by DB function.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"query"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		connectedClients,
+		authorizedClients,
+		c2sMessages,
+		heartbeatTimeouts,
+		dbQueryDuration,
+	)
+}
+
+// IncC2SMessage the MsgType without registered schema is counted as unknown, to bound the label values
+func IncC2SMessage(msgType rtkMisc.C2SMsgType) {
+	label := string(msgType)
+	if _, ok := rtkMisc.GetC2SMsgSchema(msgType); !ok {
+		label = "unknown"
+	}
+	c2sMessages.WithLabelValues(label).Inc()
+}
+
+func IncHeartbeatTimeout() {
+	heartbeatTimeouts.Inc()
+}
+
+// ObserveDbQuery is used as: defer rtkMetrics.ObserveDbQuery("queryName", time.Now())
+func ObserveDbQuery(query string, startTime time.Time) {
+	dbQueryDuration.WithLabelValues(query).Observe(time.Since(startTime).Seconds())
+}
+
+var (
+	server      *http.Server
+	serverMutex sync.Mutex
+)
+
+// StartServer exports the metrics on http://addr/metrics, addr must be a loopback address
+func StartServer(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("metrics address [%s] is not a loopback address", addr)
+	}
+
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+	if server != nil {
+		return errors.New("metrics server is already started")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	srv := server
+	rtkMisc.GoSafe(func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[%s] metrics server err:%+v", rtkMisc.GetFuncInfo(), err)
+		}
+	})
+	log.Printf("[%s] metrics is exported on [http://%s/metrics]", rtkMisc.GetFuncInfo(), listener.Addr().String())
+	return nil
+}
+
+func StopServer() {
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+	if server == nil {
+		return
+	}
+	server.Close()
+	server = nil
+}
@@ -0,0 +1,147 @@
+package misc
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// C2SProtocolVersion is sent in every C2SMessage, the legacy peer without the field is version 0
+const C2SProtocolVersion = 1
+
+// C2SMsgSchema is the ExtData type of MsgType in both directions, ExtData is decoded and validated by registry before dispatch.
+// A nil type means the MsgType is never sent in that direction, and C2SNoExtData means it is sent without ExtData.
+type C2SMsgSchema struct {
+	Request    reflect.Type // ExtData from client
+	Response   reflect.Type // ExtData from lan server
+	MinVersion int          // the lowest protocol version of sender supports MsgType
+}
+
+// C2SExtDataValidator is implemented by the ExtData type which needs to check its fields after decoding
+type C2SExtDataValidator interface {
+	Validate() CrossShareErr
+}
+
+// C2SExtDataDefaulter is implemented by the pointer of ExtData type which has the non-zero default value of absent fields
+type C2SExtDataDefaulter interface {
+	SetDefault()
+}
+
+// C2SErrorResponse is the ExtData of C2SMsg_ERROR_RESPONSE for the unknown or unsupported MsgType
+type C2SErrorResponse struct {
+	Response
+	ReqMsgType C2SMsgType
+}
+
+var C2SNoExtData = reflect.TypeOf(struct{}{})
+
+var (
+	c2sMsgSchemaMap = map[C2SMsgType]C2SMsgSchema{
+		C2SMsg_INIT_CLIENT:            {Request: reflect.TypeOf(InitClientMessageReq{}), Response: reflect.TypeOf(InitClientMessageResponse{})},
+		C2SMsg_AUTH_DATA_INDEX_MOBILE: {Request: reflect.TypeOf(AuthDataIndexMobileReq{}), Response: reflect.TypeOf(AuthDataIndexMobileResponse{})},
+		C2SMsg_REQ_CLIENT_LIST:        {Request: C2SNoExtData, Response: reflect.TypeOf(GetClientListResponse{})},
+		C2SMsg_CLIENT_HEARTBEAT:       {Request: C2SNoExtData, Response: C2SNoExtData},
+		C2SMsg_DRAG_FILE_START:        {Request: reflect.TypeOf(DragFileStartInfo{}), Response: reflect.TypeOf(DragFileStartResponse{})},
+		C2SMsg_DRAG_FILE_END:          {Response: reflect.TypeOf("")},
+		CS2Msg_PERIODIC_NOTIFY:        {Response: reflect.TypeOf(PeriodicNotifyReq{})},
+		CS2Msg_NOTIFY_CLIENT_VERSION:  {Response: reflect.TypeOf(NotifyClientVersionReq{})},
+		CS2Msg_MESSAGE_EVENT:          {Request: reflect.TypeOf(PlatformMsgEventReq{}), Response: reflect.TypeOf(PlatformMsgEventResponse{})},
+		CS2Msg_UPDATE_SRCPORT_INFO:    {Request: reflect.TypeOf(UpdateClientSrcPortInfoReq{}), Response: reflect.TypeOf(UpdateClientSrcPortInfoResponse{})},
+		CS2Msg_UPDATE_PLUG_EVENT:      {Response: reflect.TypeOf(UpdatePlugEventReq{})},
+		C2SMsg_ERROR_RESPONSE:         {Response: reflect.TypeOf(C2SErrorResponse{}), MinVersion: 1},
+	}
+	c2sMsgSchemaMutex sync.RWMutex
+)
+
+// RegisterC2SMsgSchema adds or replaces the schema of MsgType, it must be called before the connection is started
+func RegisterC2SMsgSchema(msgType C2SMsgType, schema C2SMsgSchema) {
+	c2sMsgSchemaMutex.Lock()
+	defer c2sMsgSchemaMutex.Unlock()
+	c2sMsgSchemaMap[msgType] = schema
+}
+
+func GetC2SMsgSchema(msgType C2SMsgType) (C2SMsgSchema, bool) {
+	c2sMsgSchemaMutex.RLock()
+	defer c2sMsgSchemaMutex.RUnlock()
+	schema, ok := c2sMsgSchemaMap[msgType]
+	return schema, ok
+}
+
+// DecodeC2SMessage decodes the envelope and the typed ExtData by MsgType schema, isFromServer is the direction of buffer.
+// The envelope fields are kept in msg even if the error code is returned, so the error response can be built from it.
+func DecodeC2SMessage(buffer []byte, isFromServer bool) (C2SMessage, CrossShareErr) {
+	type TempMsg struct {
+		ExtData json.RawMessage
+		C2SMessage
+	}
+	var tempMsg TempMsg
+	if err := json.Unmarshal(buffer, &tempMsg); err != nil {
+		log.Printf("[%s] Failed to unmarshal C2SMessage data:%+v, len[%d] data:[%s]", GetFuncInfo(), err, len(buffer), string(buffer))
+		return C2SMessage{}, ERR_BIZ_JSON_UNMARSHAL
+	}
+	msg := tempMsg.C2SMessage
+	msg.ExtData = nil
+
+	schema, ok := GetC2SMsgSchema(msg.MsgType)
+	if !ok {
+		log.Printf("[%s] Unknown MsgType:[%s]", GetFuncInfo(), msg.MsgType)
+		return msg, ERR_BIZ_C2S_UNKNOWN_MSG_TYPE
+	}
+	extDataType := schema.Request
+	if isFromServer {
+		extDataType = schema.Response
+	}
+	if extDataType == nil || msg.ProtocolVersion < schema.MinVersion {
+		log.Printf("[%s] MsgType:[%s] ProtocolVersion:[%d] isFromServer:[%+v] is unsupported", GetFuncInfo(), msg.MsgType, msg.ProtocolVersion, isFromServer)
+		return msg, ERR_BIZ_C2S_UNSUPPORTED_MSG_TYPE
+	}
+	if extDataType == C2SNoExtData {
+		return msg, SUCCESS
+	}
+
+	if len(tempMsg.ExtData) == 0 || string(tempMsg.ExtData) == "null" {
+		log.Printf("[%s] MsgType:[%s] ext data is null!", GetFuncInfo(), msg.MsgType)
+		return msg, ERR_BIZ_C2S_EXT_DATA_EMPTY
+	}
+	extData := reflect.New(extDataType)
+	if defaulter, ok := extData.Interface().(C2SExtDataDefaulter); ok {
+		defaulter.SetDefault()
+	}
+	if err := json.Unmarshal(tempMsg.ExtData, extData.Interface()); err != nil {
+		log.Printf("[%s] MsgType:[%s] decode ExtData Err:%+v", GetFuncInfo(), msg.MsgType, err)
+		return msg, ERR_BIZ_JSON_EXTDATA_UNMARSHAL
+	}
+	msg.ExtData = extData.Elem().Interface()
+
+	if validator, ok := msg.ExtData.(C2SExtDataValidator); ok {
+		if errCode := validator.Validate(); errCode != SUCCESS {
+			log.Printf("[%s] MsgType:[%s] invalid ExtData, errCode:[%d]", GetFuncInfo(), msg.MsgType, errCode)
+			return msg, errCode
+		}
+	}
+	return msg, SUCCESS
+}
+
+// NewC2SErrorResponse builds the response of msg which is failed to decode or dispatch.
+// The invalid ExtData of known MsgType is responded in the same MsgType, the legacy peer can get the error code from it,
+// since every response ExtData has Response embedded. The others are responded in C2SMsg_ERROR_RESPONSE.
+func NewC2SErrorResponse(msg C2SMessage, errCode CrossShareErr) C2SMessage {
+	rsp := C2SMessage{
+		ClientID:    msg.ClientID,
+		ClientIndex: msg.ClientIndex,
+		MsgType:     msg.MsgType,
+		TimeStamp:   msg.TimeStamp,
+		ExtData:     GetResponse(errCode),
+	}
+
+	schema, ok := GetC2SMsgSchema(msg.MsgType)
+	isExtDataErr := errCode == ERR_BIZ_C2S_EXT_DATA_EMPTY || errCode == ERR_BIZ_JSON_EXTDATA_UNMARSHAL || errCode == ERR_BIZ_C2S_EXT_DATA_INVALID
+	if ok && isExtDataErr && schema.Response != nil && schema.Response != C2SNoExtData {
+		return rsp
+	}
+
+	rsp.MsgType = C2SMsg_ERROR_RESPONSE
+	rsp.ExtData = C2SErrorResponse{Response: GetResponse(errCode), ReqMsgType: msg.MsgType}
+	return rsp
+}
@@ -0,0 +1,118 @@
+package misc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// C2S TLS: the control channel between client and lan server is TLS 1.3 on the same port.
+// There is no CA, lan server is identified by its public key published in mDNS text record (TextRecordKeyServerKey),
+// and client is identified by its libp2p key, so the client ID in C2SMessage must match the client certificate.
+const (
+	C2STlsRecordTypeHandshake = 0x16     // the first byte of TLS ClientHello, the plaintext JSON begins with '{'
+	C2STlsMinServerVersion    = "2.2.38" // lan server supports C2S TLS since this version
+)
+
+// GenC2SCertificate builds a self-signed certificate of priv, only its public key is verified by peer
+func GenC2SCertificate(priv crypto.Signer) (tls.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "rtk-cross-share"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(20, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{certDer}, PrivateKey: priv}, nil
+}
+
+// GetC2SPublicKey returns the base64 of PKIX public key, it is published in mDNS text record and pinned by client
+func GetC2SPublicKey(pub crypto.PublicKey) (string, error) {
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pubDer), nil
+}
+
+// GetC2SPeerPublicKey returns the public key of peer certificate after TLS handshake
+func GetC2SPeerPublicKey(state tls.ConnectionState) (crypto.PublicKey, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no peer certificate")
+	}
+	return state.PeerCertificates[0].PublicKey, nil
+}
+
+// NewC2SClientTlsConfig verifies lan server by serverKey, any server key is accepted if serverKey is empty,
+// and client must pin the key only after authorization in this case
+func NewC2SClientTlsConfig(clientCert tls.Certificate, serverKey string) *tls.Config {
+	return &tls.Config{
+		Certificates:       []tls.Certificate{clientCert},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // no CA, the server key is verified in VerifyPeerCertificate
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no server certificate")
+			}
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			if serverKey == "" {
+				return nil
+			}
+			key, err := GetC2SPublicKey(cert.PublicKey)
+			if err != nil {
+				return err
+			}
+			if key != serverKey {
+				return fmt.Errorf("server key:[%s] mismatch, expect:[%s]", key, serverKey)
+			}
+			return nil
+		},
+	}
+}
+
+// NewC2SServerTlsConfig requires client certificate, the client ID is derived from it by lan server
+func NewC2SServerTlsConfig(serverCert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		MinVersion:   tls.VersionTLS13,
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+}
+
+// IsLanServerSupportC2STls is used when lan server key is unknown (e.g. mobile browse result without text record)
+func IsLanServerSupportC2STls(ver string) bool {
+	if !CheckFullVersionVaild(ver) {
+		return false
+	}
+	verIdList := strings.Split(ver, ".")
+	minIdList := strings.Split(C2STlsMinServerVersion, ".")
+	for i := range verIdList {
+		verVal, _ := strconv.Atoi(verIdList[i])
+		minVal, _ := strconv.Atoi(minIdList[i])
+		if verVal != minVal {
+			return verVal > minVal
+		}
+	}
+	return true
+}
@@ -16,6 +16,7 @@ const (
 	CS2Msg_MESSAGE_EVENT          C2SMsgType = "MESSAGE_EVENT"
 	CS2Msg_UPDATE_SRCPORT_INFO    C2SMsgType = "UPDATE_SRCPORT_INFO"
 	CS2Msg_UPDATE_PLUG_EVENT      C2SMsgType = "UPDATE_PLUG_EVENT"
+	C2SMsg_ERROR_RESPONSE         C2SMsgType = "ERROR_RESPONSE" // the response of unknown or unsupported MsgType
 )
 
 type ScenarioType int
@@ -54,6 +55,13 @@ type InitClientMessageReq struct {
 	AppStoreLink  string
 }
 
+func (req InitClientMessageReq) Validate() CrossShareErr {
+	if req.ClientID == "" || req.Platform == "" {
+		return ERR_BIZ_C2S_EXT_DATA_INVALID
+	}
+	return SUCCESS
+}
+
 type PlatformMsgEventResponse struct {
 	Response
 }
@@ -66,6 +74,11 @@ type InitClientMessageResponse struct {
 	IsSupportFileDrag bool
 }
 
+// SetDefault keeps the scenario of legacy lan server which has no Scenario field
+func (rsp *InitClientMessageResponse) SetDefault() {
+	rsp.Scenario = ScenarioType_ViewManager
+}
+
 type ResetClientResponse struct {
 	Response
 }
@@ -127,13 +140,17 @@ type PeriodicNotifyReq struct {
 	Scenario      ScenarioType
 }
 
+func (req *PeriodicNotifyReq) SetDefault() {
+	req.Scenario = ScenarioType_ViewManager
+}
+
 type C2SMessage struct {
-	ClientID    string
-	ClientIndex uint32
-	MsgType     C2SMsgType
-	TimeStamp   int64
-	ExtData     interface{} //InitClientMessageReq InitClientMessageResponse GetClientListResponse ResetClientResponse ReconnClientListReq AuthDataIndexMobileReq
-	// NotifyClientVersionReq PlatformMsgEventReq UpdateClientSrcPortInfoReq UpdateClientSrcPortInfoResponse    UpdatePlugEventReq
+	ClientID        string
+	ClientIndex     uint32
+	MsgType         C2SMsgType
+	TimeStamp       int64
+	ProtocolVersion int         `json:",omitempty"`
+	ExtData         interface{} // the type of MsgType in C2SMsgSchema
 }
 
 type SourcePort struct {
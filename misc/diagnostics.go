@@ -0,0 +1,172 @@
+package misc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// DiagBundle is the zip archive of one-shot diagnostics. The failure of an entry doesn't fail the bundle,
+// it is written into errors.txt, so the archive always has as much state as it can collect.
+type DiagBundle struct {
+	path    string
+	file    *os.File
+	zw      *zip.Writer
+	errList []string
+}
+
+// NewDiagBundle creates dir/name_YYYYMMDD_hhmmss.zip
+func NewDiagBundle(dir, name string) (*DiagBundle, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.zip", name, time.Now().Format("20060102_150405")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &DiagBundle{path: path, file: file, zw: zip.NewWriter(file)}, nil
+}
+
+func (b *DiagBundle) addErr(name string, err error) {
+	log.Printf("[%s] diagnostics entry:[%s] err:%+v", GetFuncInfo(), name, err)
+	b.errList = append(b.errList, fmt.Sprintf("%s: %v", name, err))
+}
+
+func (b *DiagBundle) create(name string, modTime time.Time) (io.Writer, bool) {
+	w, err := b.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		b.addErr(name, err)
+		return nil, false
+	}
+	return w, true
+}
+
+// AddWriterFunc adds the entry written by fn, the written part is kept if fn returns error
+func (b *DiagBundle) AddWriterFunc(name string, fn func(w io.Writer) error) {
+	w, ok := b.create(name, time.Now())
+	if !ok {
+		return
+	}
+	if err := fn(w); err != nil {
+		b.addErr(name, err)
+	}
+}
+
+func (b *DiagBundle) AddText(name, text string) {
+	b.AddWriterFunc(name, func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	})
+}
+
+func (b *DiagBundle) AddJson(name string, v interface{}) {
+	b.AddWriterFunc(name, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	})
+}
+
+func (b *DiagBundle) AddFile(name, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		b.addErr(name, err)
+		return
+	}
+	defer file.Close()
+
+	modTime := time.Now()
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+	w, ok := b.create(name, modTime)
+	if !ok {
+		return
+	}
+	if _, err = io.Copy(w, file); err != nil {
+		b.addErr(name, err)
+	}
+}
+
+// getRotatedFileList returns the file and its backups rotated by lumberjack, like name-2024-01-02T15-04-05.000.log.gz
+func getRotatedFileList(path string) []string {
+	if path == "" {
+		return nil
+	}
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext)
+	fileList, _ := filepath.Glob(prefix + "-*" + ext + "*")
+	if FileExists(path) {
+		fileList = append([]string{path}, fileList...)
+	}
+	return fileList
+}
+
+// AddLogFiles adds the log and crash log of InitLog with their rotated backups into folder logs/
+func (b *DiagBundle) AddLogFiles() {
+	for _, path := range append(getRotatedFileList(LogPath), getRotatedFileList(CrashLogPath)...) {
+		b.AddFile("logs/"+filepath.Base(path), path)
+	}
+}
+
+// AddSystemInfo adds version.txt with the version and build info, and network.txt with the network interfaces
+func (b *DiagBundle) AddSystemInfo(name, version, buildDate string) {
+	var info strings.Builder
+	fmt.Fprintf(&info, "name: %s\nversion: %s\nbuildDate: %s\n", name, version, buildDate)
+	fmt.Fprintf(&info, "os: %s\narch: %s\ngo: %s\ncpu: %d\ngoroutine: %d\n", runtime.GOOS, runtime.GOARCH, runtime.Version(), runtime.NumCPU(), runtime.NumGoroutine())
+	fmt.Fprintf(&info, "collectTime: %s\n", time.Now().Format(time.RFC3339))
+	if hostName, err := os.Hostname(); err == nil {
+		fmt.Fprintf(&info, "hostName: %s\n", hostName)
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&info, "\n%s", buildInfo.String())
+	}
+	b.AddText("version.txt", info.String())
+
+	b.AddWriterFunc("network.txt", func(w io.Writer) error {
+		ifaceList, err := net.Interfaces()
+		if err != nil {
+			return err
+		}
+		for _, iface := range ifaceList {
+			fmt.Fprintf(w, "%d %s mtu:%d flags:[%s] mac:[%s]\n", iface.Index, iface.Name, iface.MTU, iface.Flags.String(), iface.HardwareAddr.String())
+			addrList, err := iface.Addrs()
+			if err != nil {
+				fmt.Fprintf(w, "\taddrs err:%v\n", err)
+				continue
+			}
+			for _, addr := range addrList {
+				fmt.Fprintf(w, "\t%s\n", addr.String())
+			}
+		}
+		return nil
+	})
+}
+
+// Close writes errors.txt if any entry failed and returns the archive path
+func (b *DiagBundle) Close() (string, error) {
+	if len(b.errList) > 0 {
+		if w, err := b.zw.Create("errors.txt"); err == nil {
+			io.WriteString(w, strings.Join(b.errList, "\n")+"\n")
+		}
+	}
+	err := b.zw.Close()
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(b.path)
+		return "", err
+	}
+	return b.path, nil
+}
@@ -65,6 +65,7 @@ const (
 	ERR_NETWORK_C2S_READ
 	ERR_NETWORK_C2S_READ_TIME_OUT
 	ERR_NETWORK_C2S_READ_EOF
+	ERR_NETWORK_C2S_TLS_HANDSHAKE
 )
 
 // lanserver to client connect error
@@ -74,6 +75,7 @@ const (
 	ERR_NETWORK_S2C_READ
 	ERR_NETWORK_S2C_FLUSH
 	ERR_NETWORK_S2C_WRITE
+	ERR_NETWORK_S2C_TLS_HANDSHAKE
 )
 
 /***************************************  DB info error code, begin with 4000  ****************************************************/
@@ -104,6 +106,7 @@ const ( // public business error code
 	ERR_BIZ_GET_CALLBACK_INSTANCE_NULL
 	ERR_BIZ_VERSION_INVALID
 	ERR_BIZ_SOURCE_PORT_INVALID
+	ERR_BIZ_DIAG_CREATE_FILE
 )
 
 // client to lan server business error code
@@ -114,6 +117,10 @@ const (
 	ERR_BIZ_C2S_READ_EMPTY_DATA
 	ERR_BIZ_C2S_GET_EMPTY_CONNECT
 	ERR_BIZ_C2S_EXT_DATA_EMPTY
+	ERR_BIZ_C2S_SERVER_KEY_MISMATCH
+	ERR_BIZ_C2S_UNSUPPORTED_MSG_TYPE
+	ERR_BIZ_C2S_EXT_DATA_INVALID
+	ERR_BIZ_C2S_PLAINTEXT_REFUSED
 )
 
 // lan server to client business error code
@@ -126,6 +133,8 @@ const (
 	ERR_BIZ_S2C_UNAUTH
 	ERR_BIZ_S2C_CALLBACK_INVALID
 	ERR_BIZ_S2C_GET_CONNECT_RESET
+	ERR_BIZ_S2C_PLAINTEXT_REFUSED
+	ERR_BIZ_S2C_CLIENT_ID_MISMATCH
 )
 
 // peer to peer business error code
@@ -135,6 +144,12 @@ const (
 	ERR_BIZ_P2P_GET_EMPTY_STREAM
 	ERR_BIZ_P2P_PEER_DECODE
 	ERR_BIZ_P2P_NODE_NULL
+	ERR_BIZ_P2P_MSG_OVER_RANGE
+	ERR_BIZ_P2P_MSG_FRAME_INVALID
+	ERR_BIZ_P2P_PEER_UNTRUSTED
+	ERR_BIZ_P2P_PEER_REVOKED
+	ERR_BIZ_P2P_PAIRING_TIMEOUT
+	ERR_BIZ_P2P_PAIRING_NO_HANDLER
 )
 
 // clipboard business error code
@@ -190,6 +205,9 @@ const (
 	ERR_BIZ_FT_COPY_DETAILS
 	ERR_BIZ_FT_DST_COPY_DETAILS
 	ERR_BIZ_FT_INTERRUPT_INFO_INVALID
+	ERR_BIZ_FD_FILE_HASH_MISMATCH
+	ERR_BIZ_FD_REJECTED_BY_RULE
+	ERR_BIZ_FD_CONFIRM_TIMEOUT
 )
 
 var errInfoMap = map[CrossShareErr]string{
@@ -210,4 +228,26 @@ var errInfoMap = map[CrossShareErr]string{
 	ERR_BIZ_S2C_INVALID_INDEX:      "client index is invalid",
 	ERR_BIZ_S2C_UNAUTH:             "unauthorized device",
 	ERR_BIZ_SOURCE_PORT_INVALID:    "invalid source and port",
+	ERR_BIZ_DIAG_CREATE_FILE:       "create diagnostics archive failed",
+	ERR_BIZ_P2P_MSG_OVER_RANGE:     "p2p message is too long and over range",
+	ERR_BIZ_P2P_MSG_FRAME_INVALID:  "p2p message frame length is invalid",
+	ERR_BIZ_P2P_PEER_UNTRUSTED:     "peer pairing is rejected",
+	ERR_BIZ_P2P_PEER_REVOKED:       "peer is revoked from trust store",
+	ERR_BIZ_P2P_PAIRING_TIMEOUT:    "peer pairing is not confirmed in time",
+	ERR_BIZ_P2P_PAIRING_NO_HANDLER: "peer pairing is not supported by platform",
+	ERR_BIZ_FD_FILE_HASH_MISMATCH:  "file hash mismatch after retry",
+	ERR_BIZ_FD_REJECTED_BY_RULE:    "file drop is rejected by rule",
+	ERR_BIZ_FD_CONFIRM_TIMEOUT:     "file drop is not confirmed in time",
+
+	ERR_NETWORK_C2S_TLS_HANDSHAKE:   "lan server TLS handshake failed",
+	ERR_NETWORK_S2C_TLS_HANDSHAKE:   "client TLS handshake failed",
+	ERR_BIZ_C2S_SERVER_KEY_MISMATCH: "lan server key mismatch with the pinned key",
+	ERR_BIZ_C2S_PLAINTEXT_REFUSED:   "plaintext lan server is refused",
+	ERR_BIZ_S2C_PLAINTEXT_REFUSED:   "plaintext client is refused",
+	ERR_BIZ_S2C_CLIENT_ID_MISMATCH:  "client ID mismatch with certificate",
+
+	ERR_BIZ_C2S_UNKNOWN_MSG_TYPE:     "unknown message type",
+	ERR_BIZ_C2S_UNSUPPORTED_MSG_TYPE: "unsupported message type or protocol version",
+	ERR_BIZ_C2S_EXT_DATA_EMPTY:       "message ext data is empty",
+	ERR_BIZ_C2S_EXT_DATA_INVALID:     "message ext data is invalid",
 }
@@ -31,7 +31,7 @@ func InitLog(logPath, crashLogPath string, maxsize int) {
 		maxSize = maxsize
 	}
 
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	initStructuredLog()
 	log.Printf("InitLog logPath:[%s] maxSize:[%d] maxBackups:[%d] maxAge:[%d]!", LogPath, maxSize, maxBackups, maxAge)
 	log.Printf("InitLog crashLogPath:[%s]", CrashLogPath)
 }
@@ -48,7 +48,7 @@ func SetupLogFile() {
 		LocalTime:  true,
 	}
 
-	log.SetOutput(&LoggerWriteFile)
+	setLogOutput(&LoggerWriteFile)
 	log.Println("begin to write log to file!")
 	os.Chmod(LogPath, 0644)
 }
@@ -56,13 +56,13 @@ func SetupLogFile() {
 func SetupLogShut() {
 	log.Println("set log shut down !\n")
 	LoggerWriteFile.Close()
-	log.SetOutput(io.Discard)
+	setLogOutput(io.Discard)
 }
 
 func SetupLogConsole() {
 	log.Println("Set log printed to the console !\n")
 	LoggerWriteFile.Close()
-	log.SetOutput(os.Stdout)
+	setLogOutput(os.Stdout)
 }
 
 func SetupLogConsoleFile() {
@@ -77,7 +77,7 @@ func SetupLogConsoleFile() {
 		LocalTime:  true,
 	}
 
-	log.SetOutput(io.MultiWriter(os.Stdout, &LoggerWriteFile))
+	setLogOutput(io.MultiWriter(os.Stdout, &LoggerWriteFile))
 	log.Println("begin to write log to file and printed to console!")
 	os.Chmod(LogPath, 0644)
 }
@@ -0,0 +1,259 @@
+package misc
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The structured logger is log/slog with levels per subsystem, the legacy log.Printf is bridged to the subsystem
+// LogSubsystemDefault at Info level. All of them are written to the output of SetupLogXXX in text or JSON format.
+
+const (
+	LogSubsystemDefault = "default"
+	LogSubsystemAll     = "all"
+
+	LogFormatText = "text"
+	LogFormatJson = "json"
+)
+
+// The consistent field keys of structured logs
+const (
+	LogKeySubsystem  = "subsystem"
+	LogKeyPeerID     = "peer_id"
+	LogKeyIP         = "ip"
+	LogKeyTransferID = "transfer_id"
+	LogKeyMsgType    = "msg_type"
+	LogKeyErrCode    = "err_code"
+)
+
+var (
+	logOutput      io.Writer = os.Stderr
+	logOutputMutex sync.Mutex
+
+	logHandler      slog.Handler
+	logHandlerMutex sync.RWMutex
+
+	logLevelMap      = make(map[string]slog.Level) // KEY: subsystem, the registered subsystems without own level use defaultLogLevel
+	defaultLogLevel  = slog.LevelInfo
+	logLevelMapMutex sync.RWMutex
+)
+
+func init() {
+	logHandler = newLogFormatHandler(LogFormatText)
+}
+
+// logSink is the stable writer of slog handlers, the output is switched by SetupLogXXX
+type logSink struct{}
+
+func (logSink) Write(p []byte) (int, error) {
+	logOutputMutex.Lock()
+	defer logOutputMutex.Unlock()
+	return logOutput.Write(p)
+}
+
+func setLogOutput(w io.Writer) {
+	logOutputMutex.Lock()
+	logOutput = w
+	logOutputMutex.Unlock()
+}
+
+func newLogFormatHandler(format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug, // the level is filtered by subsystemHandler
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.String(slog.TimeKey, a.Value.Time().Format("2006-01-02T15:04:05.000000Z07:00"))
+			}
+			if a.Key == slog.SourceKey && len(groups) == 0 {
+				src, ok := a.Value.Any().(*slog.Source)
+				if !ok || src.File == "" { // the legacy log.Printf has no source, its msg has GetFuncInfo
+					return slog.Attr{}
+				}
+				return slog.String(slog.SourceKey, filepath.Base(src.File)+":"+strconv.Itoa(src.Line))
+			}
+			return a
+		},
+	}
+	if format == LogFormatJson {
+		return slog.NewJSONHandler(logSink{}, opts)
+	}
+	return slog.NewTextHandler(logSink{}, opts)
+}
+
+func getLogHandler() slog.Handler {
+	logHandlerMutex.RLock()
+	defer logHandlerMutex.RUnlock()
+	return logHandler
+}
+
+// SetLogFormat switches all of logs to LogFormatText or LogFormatJson
+func SetLogFormat(format string) bool {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != LogFormatText && format != LogFormatJson {
+		log.Printf("[%s] unknown log format:[%s]", GetFuncInfo(), format)
+		return false
+	}
+
+	logHandlerMutex.Lock()
+	logHandler = newLogFormatHandler(format)
+	logHandlerMutex.Unlock()
+	log.Printf("[%s] set log format:[%s]", GetFuncInfo(), format)
+	return true
+}
+
+// subsystemHandler filters by the level of subsystem and delegates to the current format handler
+type subsystemHandler struct {
+	subsystem string
+	wrapList  []func(slog.Handler) slog.Handler // WithAttrs and WithGroup in order
+}
+
+func (h *subsystemHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= getLogLevel(h.subsystem)
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := getLogHandler()
+	for _, wrap := range h.wrapList {
+		handler = wrap(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) with(wrap func(slog.Handler) slog.Handler) *subsystemHandler {
+	wrapList := make([]func(slog.Handler) slog.Handler, 0, len(h.wrapList)+1)
+	wrapList = append(wrapList, h.wrapList...)
+	return &subsystemHandler{subsystem: h.subsystem, wrapList: append(wrapList, wrap)}
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+// NewLogger returns the structured logger of subsystem, it is usually a package level var
+func NewLogger(subsystem string) *slog.Logger {
+	logLevelMapMutex.Lock()
+	if _, ok := logLevelMap[subsystem]; !ok {
+		logLevelMap[subsystem] = defaultLogLevel
+	}
+	logLevelMapMutex.Unlock()
+
+	logger := slog.New(&subsystemHandler{subsystem: subsystem})
+	if subsystem == LogSubsystemDefault {
+		return logger
+	}
+	return logger.With(LogKeySubsystem, subsystem)
+}
+
+// initStructuredLog bridges the legacy log.Printf to the structured logger
+func initStructuredLog() {
+	slog.SetDefault(NewLogger(LogSubsystemDefault))
+}
+
+func getLogLevel(subsystem string) slog.Level {
+	logLevelMapMutex.RLock()
+	defer logLevelMapMutex.RUnlock()
+	if level, ok := logLevelMap[subsystem]; ok {
+		return level
+	}
+	return defaultLogLevel
+}
+
+func parseLogLevel(level string) (slog.Level, bool) {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo, false
+	}
+	return slogLevel, true
+}
+
+// SetLogLevel sets level(debug, info, warn, error) of subsystem at runtime, LogSubsystemAll sets all of subsystems
+func SetLogLevel(subsystem, level string) bool {
+	slogLevel, ok := parseLogLevel(level)
+	if !ok {
+		log.Printf("[%s] subsystem:[%s] unknown log level:[%s]", GetFuncInfo(), subsystem, level)
+		return false
+	}
+
+	logLevelMapMutex.Lock()
+	if subsystem == LogSubsystemAll || subsystem == "" {
+		defaultLogLevel = slogLevel
+		for key := range logLevelMap {
+			logLevelMap[key] = slogLevel
+		}
+	} else {
+		logLevelMap[subsystem] = slogLevel
+	}
+	logLevelMapMutex.Unlock()
+
+	log.Printf("[%s] set log level subsystem:[%s] level:[%s]", GetFuncInfo(), subsystem, slogLevel.String())
+	return true
+}
+
+// SetLogLevelSpec sets levels by spec like "info,peer2peer=debug,connection=warn", the item without subsystem is for all
+func SetLogLevelSpec(spec string) bool {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		subsystem, level, found := strings.Cut(item, "=")
+		if !found {
+			subsystem, level = LogSubsystemAll, item
+		}
+		if !SetLogLevel(strings.TrimSpace(subsystem), level) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetLogLevels returns the levels of all registered subsystems, like "connection=INFO,default=INFO"
+func GetLogLevels() string {
+	logLevelMapMutex.RLock()
+	levelList := make([]string, 0, len(logLevelMap))
+	for subsystem, level := range logLevelMap {
+		levelList = append(levelList, subsystem+"="+level.String())
+	}
+	logLevelMapMutex.RUnlock()
+
+	sort.Strings(levelList)
+	return strings.Join(levelList, ",")
+}
+
+func PeerIDAttr(id string) slog.Attr {
+	return slog.String(LogKeyPeerID, id)
+}
+
+func IPAttr(ipAddr string) slog.Attr {
+	return slog.String(LogKeyIP, ipAddr)
+}
+
+func TransferIDAttr(timestamp uint64) slog.Attr {
+	return slog.Uint64(LogKeyTransferID, timestamp)
+}
+
+func MsgTypeAttr(msgType string) slog.Attr {
+	return slog.String(LogKeyMsgType, msgType)
+}
+
+func ErrCodeAttr(errCode CrossShareErr) slog.Attr {
+	return slog.Int(LogKeyErrCode, int(errCode))
+}
+
+// ErrAttr is the field of go error, it is named as slog convention
+func ErrAttr(err error) slog.Attr {
+	return slog.Any("err", err)
+}
@@ -30,6 +30,7 @@ func GoSafeWithParam(fn func(args ...any), args ...any) {
 					Compress:   true,
 				}
 				log.SetOutput(&LoggerCrashWriteFile)
+				log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
 				log.Printf("Recovered from panic: %v\n", r)
 				log.Printf("Stack trace:\n%s", debug.Stack())
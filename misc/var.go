@@ -17,12 +17,14 @@ const (
 	TextRecordKeyMonitorName = "mName"
 	TextRecordKeyTimestamp   = "timestamp"
 	TextRecordKeyVersion     = "ver"
+	TextRecordKeyServerKey   = "skey" // lan server identity public key of C2S TLS
 
 	PlatformAndroid = "android"
 	PlatformWindows = "windows"
 	PlatformMac     = "macOs"
 	PlatformiOS     = "iOS"
 	PlatformMnt     = "mnt"
+	PlatformLinux   = "linux"
 
 	//PlatformType
 	PlatformType_Mobile = 0